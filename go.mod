@@ -2,4 +2,4 @@ module github.com/cdlewis/nigel
 
 go 1.21
 
-require gopkg.in/yaml.v3 v3.0.1 // indirect
+require gopkg.in/yaml.v3 v3.0.1
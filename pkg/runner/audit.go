@@ -0,0 +1,123 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// AuditEntry is one line of the audit log: a command nigel ran.
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Dir        string    `json:"dir"`
+	DurationMS int64     `json:"duration_ms"`
+	Status     string    `json:"status"` // ok, failed (non-zero exit), timeout, or the error starting it
+}
+
+// auditLog records every shell command a run executes (audit_log: true), one
+// JSON object per line, for reviewing what the automation did on the machine.
+type auditLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// AuditLogPath returns the audit log for a run of task started at t: one file
+// per session in an audit/ directory next to the task's claude.log.
+func (e *Environment) AuditLogPath(task Task, t time.Time) string {
+	return filepath.Join(e.logDir(task), "audit", task.Name+"-"+t.Format("20060102-150405")+".jsonl")
+}
+
+func openAuditLog(path string) (*auditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &auditLog{file: file}, nil
+}
+
+// record appends a command that started at start and finished with ok and err.
+// A nil log records nothing.
+func (l *auditLog) record(command, dir string, start time.Time, ok bool, err error) {
+	if l == nil {
+		return
+	}
+	var exitErr *exec.ExitError
+	status := "ok"
+	if _, isTimeout := err.(*timeoutError); isTimeout {
+		status = "timeout"
+	} else if errors.As(err, &exitErr) {
+		status = "failed"
+	} else if err != nil {
+		status = err.Error()
+	} else if !ok {
+		status = "failed"
+	}
+	entry := AuditEntry{
+		Time:       start,
+		Command:    command,
+		Dir:        dir,
+		DurationMS: time.Since(start).Milliseconds(),
+		Status:     status,
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	json.NewEncoder(l.file).Encode(entry)
+}
+
+func (l *auditLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// auditExecutor records each shell command in the audit log after running it.
+type auditExecutor struct {
+	CommandExecutor
+	log *auditLog
+}
+
+func (e auditExecutor) Run(command, workDir string) (bool, error) {
+	start := time.Now()
+	ok, err := e.CommandExecutor.Run(command, workDir)
+	e.log.record(command, workDir, start, ok, err)
+	return ok, err
+}
+
+func (e auditExecutor) RunSilent(command, workDir string) (bool, error) {
+	start := time.Now()
+	ok, err := e.CommandExecutor.RunSilent(command, workDir)
+	e.log.record(command, workDir, start, ok, err)
+	return ok, err
+}
+
+func (e auditExecutor) RunShowOnFail(command, workDir string) (bool, string, error) {
+	start := time.Now()
+	ok, output, err := e.CommandExecutor.RunShowOnFail(command, workDir)
+	e.log.record(command, workDir, start, ok, err)
+	return ok, output, err
+}
+
+func (e auditExecutor) RunWatched(command, workDir string, onLine func(string)) (bool, string, error) {
+	start := time.Now()
+	ok, output, err := e.CommandExecutor.RunWatched(command, workDir, onLine)
+	e.log.record(command, workDir, start, ok, err)
+	return ok, output, err
+}
+
+func (e auditExecutor) RunWithTimeout(command, workDir string, timeout time.Duration) (bool, error) {
+	start := time.Now()
+	ok, err := e.CommandExecutor.RunWithTimeout(command, workDir, timeout)
+	e.log.record(command, workDir, start, ok, err)
+	return ok, err
+}
@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuditExecutor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "lint.jsonl")
+	log, err := openAuditLog(path)
+	if err != nil {
+		t.Fatalf("openAuditLog failed: %v", err)
+	}
+
+	mock := NewMockCommandExecutor()
+	mock.SetResult("make", false, nil)
+	mock.SetResult("slow", false, &timeoutError{duration: time.Minute})
+	executor := auditExecutor{CommandExecutor: mock, log: log}
+
+	executor.RunSilent("git reset --hard", "/repo")
+	executor.RunShowOnFail("make", "/repo/api")
+	executor.RunWithTimeout("slow", "/repo", time.Minute)
+	log.record("claude -p <prompt>", "/repo", time.Now(), true, nil)
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("bad audit line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	want := []struct{ command, dir, status string }{
+		{"git reset --hard", "/repo", "ok"},
+		{"make", "/repo/api", "failed"},
+		{"slow", "/repo", "timeout"},
+		{"claude -p <prompt>", "/repo", "ok"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d audit entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Command != w.command || e.Dir != w.dir || e.Status != w.status || e.Time.IsZero() {
+			t.Errorf("entry %d = %+v, want %+v", i, e, w)
+		}
+	}
+
+	// Without audit_log there's no log, and recording is a no-op
+	var none *auditLog
+	none.record("true", "/repo", time.Now(), true, nil)
+	if err := none.Close(); err != nil {
+		t.Errorf("Close on a nil log = %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Built-in `banner:` settings; any other value is a file of ASCII art.
+const (
+	BannerCat     = "cat"     // The cat (default)
+	BannerMinimal = "minimal" // A single line
+)
+
+// loadBannerArt reads the custom art a `banner:` setting names, relative to
+// the nigel/ directory. Built-in settings have no art to load.
+func loadBannerArt(banner, runnerDir string) ([]string, error) {
+	if banner == "" || banner == BannerCat || banner == BannerMinimal {
+		return nil, nil
+	}
+	path := expandTilde(banner)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(runnerDir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read banner art: %w", err)
+	}
+	art := strings.TrimRight(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if strings.TrimSpace(art) == "" {
+		return nil, fmt.Errorf("banner art %s is empty", banner)
+	}
+	return strings.Split(art, "\n"), nil
+}
+
+// StartupBanner returns the startup banner chosen by the `banner:` setting.
+// Art is only drawn on a terminal; piped into a log, the banner is one line.
+func (e *Environment) StartupBanner(taskName, logPath, mode string) string {
+	if e.Config.Banner == BannerMinimal || !isTerminal(os.Stdout) {
+		return MinimalBanner(taskName, logPath, mode)
+	}
+	if e.bannerArt != nil {
+		return ArtBanner(e.bannerArt, taskName, logPath, mode)
+	}
+	return StartupBanner(taskName, logPath, mode)
+}
+
+// MinimalBanner creates a one-line startup banner.
+func MinimalBanner(taskName, logPath, mode string) string {
+	line := ColorBold("Nigel") + " · Task: " + taskName + " · Mode: " + mode
+	if logPath != "" {
+		line += " · Logs: " + logPath
+	}
+	return line + "\n"
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadBannerArt(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "art.txt"), []byte(" /\\_/\\\n( o.o )\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "blank.txt"), []byte("\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, banner := range []string{"", BannerCat, BannerMinimal} {
+		if art, err := loadBannerArt(banner, dir); art != nil || err != nil {
+			t.Errorf("loadBannerArt(%q) = %q, %v; want no art", banner, art, err)
+		}
+	}
+
+	art, err := loadBannerArt("art.txt", dir)
+	if err != nil {
+		t.Fatalf("loadBannerArt failed: %v", err)
+	}
+	if len(art) != 2 || art[1] != "( o.o )" {
+		t.Errorf("art = %q", art)
+	}
+
+	if _, err := loadBannerArt("missing.txt", dir); err == nil {
+		t.Error("expected an error for a missing art file")
+	}
+	if _, err := loadBannerArt("blank.txt", dir); err == nil {
+		t.Error("expected an error for an empty art file")
+	}
+}
+
+func TestArtBannerPadsShortArt(t *testing.T) {
+	result := ArtBanner([]string{"<nigel>"}, "my-task", "claude.log", "standard")
+	for _, want := range []string{"<nigel>", "Task: my-task", "Logs: claude.log", "Mode: standard"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("banner missing %q:\n%s", want, result)
+		}
+	}
+}
+
+func TestEnvironmentStartupBannerWithoutTerminal(t *testing.T) {
+	// Test output isn't a terminal, so even the cat is reduced to one line
+	env := &Environment{}
+	result := env.StartupBanner("my-task", "claude.log", "standard")
+	if strings.Count(result, "\n") != 1 || !strings.Contains(result, "Task: my-task") || strings.Contains(result, "フ") {
+		t.Errorf("StartupBanner = %q, want the one-line banner", result)
+	}
+}
+
+func TestMinimalBanner(t *testing.T) {
+	if got := MinimalBanner("p", "", "playlist (round-robin)"); strings.Contains(got, "Logs") {
+		t.Errorf("MinimalBanner without a log path = %q", got)
+	}
+}
@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// pendingBatches records the temporary commits each runner has on its branch
+// for a batched commit_mode, so an interrupt can squash them before exiting.
+// It holds copies rather than the runners' own state, which the signal
+// handler can't read safely.
+var pendingBatches = struct {
+	sync.Mutex
+	byRunner map[*Runner]pendingBatch
+}{byRunner: map[*Runner]pendingBatch{}}
+
+// pendingBatch is a runner's batch of pending commits from base to tip.
+type pendingBatch struct {
+	executor  CommandExecutor
+	workDir   string
+	base, tip string
+	keys      []string
+}
+
+// trackPending records the runner's current batch, or forgets it once the
+// batch has been committed.
+func (r *Runner) trackPending() {
+	pendingBatches.Lock()
+	defer pendingBatches.Unlock()
+	if len(r.pending) == 0 {
+		delete(pendingBatches.byRunner, r)
+		return
+	}
+	keys := make([]string, len(r.pending))
+	for i, p := range r.pending {
+		keys[i] = p.key
+	}
+	pendingBatches.byRunner[r] = pendingBatch{
+		executor: r.executor,
+		workDir:  r.workDir(),
+		base:     r.batchBase,
+		tip:      r.batchTip,
+		keys:     keys,
+	}
+}
+
+// abandonPending squashes the pending commits of a run stopped by an error
+// back into the working tree, uncommitted, and prints which fixes were left.
+func (r *Runner) abandonPending() {
+	if len(r.pending) == 0 {
+		return
+	}
+	pendingBatches.Lock()
+	batch, ok := pendingBatches.byRunner[r]
+	delete(pendingBatches.byRunner, r)
+	pendingBatches.Unlock()
+	if ok {
+		batch.abandon()
+	}
+	r.pending = nil
+}
+
+// abandonAllPending squashes the pending commits of every runner, for an
+// interrupt.
+func abandonAllPending() {
+	pendingBatches.Lock()
+	defer pendingBatches.Unlock()
+	for r, batch := range pendingBatches.byRunner {
+		batch.abandon()
+		delete(pendingBatches.byRunner, r)
+	}
+}
+
+// abandon resets the branch to the batch's base, keeping the fixes staged.
+// If anything was committed on top of the batch it leaves the commits alone.
+func (b pendingBatch) abandon() {
+	fixes := fmt.Sprintf("%d batched fix(es) (%s)", len(b.keys), strings.Join(b.keys, ", "))
+	head, err := b.executor.CurrentRevision(b.workDir)
+	if err != nil || head != b.tip {
+		fmt.Println(ColorWarning(fmt.Sprintf("Left %s as 'nigel: pending' commits on top of %s in %s",
+			fixes, shortRevision(b.base), b.workDir)))
+		return
+	}
+	if ok, err := b.executor.RunSilent("git reset -q --soft "+shellQuote(b.base), b.workDir); err != nil || !ok {
+		fmt.Println(ColorWarning(fmt.Sprintf("Failed to squash pending commits: left %s as 'nigel: pending' commits on top of %s in %s",
+			fixes, shortRevision(b.base), b.workDir)))
+		return
+	}
+	fmt.Println(ColorWarning(fmt.Sprintf("Left %s uncommitted (staged) in %s", fixes, b.workDir)))
+}
@@ -0,0 +1,888 @@
+package runner
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Candidate represents a work item from the candidate source output.
+// It can be a string, array, or map - stored as raw JSON for flexible access.
+type Candidate struct {
+	Key  string          // JSON serialization of the full candidate (for uniqueness), hashed if longer than maxKeyLength
+	Data json.RawMessage // Raw JSON data (string, array, or map)
+}
+
+// Keys longer than maxKeyLength are replaced by an excerpt of keyExcerptLength
+// characters and a hash of the whole key, so a multi-KB candidate doesn't
+// bloat ignored.log, commit messages and output. Data keeps the full payload.
+const (
+	maxKeyLength     = 1024
+	keyExcerptLength = 60
+)
+
+// stableKey returns key, or if it's longer than maxKeyLength, its excerpt
+// followed by a hash of the whole key, which is the same on every run.
+func stableKey(key string) string {
+	if len(key) <= maxKeyLength {
+		return key
+	}
+	excerpt := []rune(strings.Join(strings.Fields(key), " "))
+	if len(excerpt) > keyExcerptLength {
+		excerpt = excerpt[:keyExcerptLength]
+	}
+	sum := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("%s... #%x", string(excerpt), sum[:8])
+}
+
+// HashPartition specifies which partition of candidates a worker should process
+type HashPartition struct {
+	WorkerCount int // Total number of parallel workers (N)
+	WorkerIndex int // This worker's index (0 to N-1)
+}
+
+// NoFilter returns a HashPartition that processes all candidates
+func NoFilter() HashPartition {
+	return HashPartition{WorkerCount: 1, WorkerIndex: 0}
+}
+
+// ParseCandidates parses the output from a candidate source.
+// Supports JSON arrays like ["a", "b"], [["a", "x"], ["b", "y"]], or [{"file": "a"}, {"file": "b"}]
+// If JSON parsing fails, treats input as newline-separated plain text (one candidate per non-empty line).
+func ParseCandidates(data []byte) ([]Candidate, error) {
+	// First try JSON parsing
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err == nil {
+		return parseJsonCandidates(raw)
+	}
+
+	// JSON failed - treat as newline-separated plain text
+	lines := strings.Split(string(data), "\n")
+	candidates := make([]Candidate, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			// Wrap as JSON string for Data field compatibility
+			jsonStr := `"` + jsonEscape(line) + `"`
+			candidates = append(candidates, Candidate{
+				Key:  stableKey(line),
+				Data: json.RawMessage(jsonStr),
+			})
+		}
+	}
+	return candidates, nil
+}
+
+// ParseTaskCandidates parses candidate source output for a task and checks it
+// against the task's candidate_schema, if any. With strict_parsing disabled,
+// malformed entries (empty, unparseable, or not matching the schema) are
+// skipped and described in the returned warnings instead of failing the parse.
+func ParseTaskCandidates(data []byte, task Task) ([]Candidate, []string, error) {
+	if task.IsStrictParsing() {
+		candidates, err := ParseCandidates(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := ValidateCandidates(candidates, task.CandidateSchema); err != nil {
+			return nil, nil, err
+		}
+		return candidates, nil, nil
+	}
+
+	// Parse entries one at a time so a bad one can be skipped. Plain-text
+	// output has one entry per line and is parsed as a whole.
+	var raw []json.RawMessage
+	var candidates []Candidate
+	if err := json.Unmarshal(data, &raw); err != nil {
+		candidates, _ = ParseCandidates(data)
+	}
+
+	var warnings []string
+	valid := make([]Candidate, 0, len(raw)+len(candidates))
+	check := func(index int, c Candidate, err error) {
+		if err == nil && task.CandidateSchema != nil {
+			if err = c.checkSchema(*task.CandidateSchema); err != nil {
+				err = fmt.Errorf("%s: %s", err, c.Key)
+			}
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("candidate %d %v", index+1, err))
+			return
+		}
+		valid = append(valid, c)
+	}
+	for i, item := range raw {
+		if isEmptyJSON(item) {
+			check(i, Candidate{}, fmt.Errorf("is empty (%s)", item))
+			continue
+		}
+		parsed, err := parseJsonCandidates([]json.RawMessage{item})
+		if err != nil {
+			check(i, Candidate{}, fmt.Errorf("is not valid JSON: %w", err))
+			continue
+		}
+		check(i, parsed[0], nil)
+	}
+	for i, c := range candidates {
+		check(i, c, nil)
+	}
+	return valid, warnings, nil
+}
+
+// parseJsonCandidates parses a JSON array of candidates.
+func parseJsonCandidates(raw []json.RawMessage) ([]Candidate, error) {
+	candidates := make([]Candidate, 0, len(raw))
+	for _, item := range raw {
+		// For simple strings, use the unquoted value as the key
+		var str string
+		if err := json.Unmarshal(item, &str); err == nil {
+			candidates = append(candidates, Candidate{
+				Key:  stableKey(str),
+				Data: item,
+			})
+			continue
+		}
+
+		// For objects, normalize by sorting keys for deterministic key generation
+		if len(item) > 0 && item[0] == '{' {
+			var m map[string]json.RawMessage
+			if err := json.Unmarshal(item, &m); err == nil {
+				key := normalizeMapKey(m)
+				candidates = append(candidates, Candidate{
+					Key:  stableKey(key),
+					Data: item,
+				})
+				continue
+			}
+		}
+
+		// For arrays or anything else, use compacted JSON as key
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, item); err != nil {
+			return nil, fmt.Errorf("failed to compact JSON: %w", err)
+		}
+
+		candidates = append(candidates, Candidate{
+			Key:  stableKey(buf.String()),
+			Data: item,
+		})
+	}
+
+	return candidates, nil
+}
+
+// isEmptyJSON reports whether a JSON value is null or an empty string, array or object.
+func isEmptyJSON(item json.RawMessage) bool {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, item); err != nil {
+		return false
+	}
+	switch buf.String() {
+	case "null", `""`, "[]", "{}":
+		return true
+	}
+	return false
+}
+
+// DedupeCandidates removes candidates whose key has already been seen, keeping
+// the first occurrence. Returns the deduplicated list and the number dropped.
+func DedupeCandidates(candidates []Candidate) ([]Candidate, int) {
+	seen := make(map[string]bool, len(candidates))
+	deduped := make([]Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if seen[c.Key] {
+			continue
+		}
+		seen[c.Key] = true
+		deduped = append(deduped, c)
+	}
+	return deduped, len(candidates) - len(deduped)
+}
+
+// IntroducedCandidates returns the keys in after that were not in before,
+// i.e. candidates that appeared as a side effect of a change.
+func IntroducedCandidates(before, after []Candidate) []string {
+	existing := make(map[string]bool, len(before))
+	for _, c := range before {
+		existing[c.Key] = true
+	}
+	var introduced []string
+	for _, c := range after {
+		if !existing[c.Key] {
+			introduced = append(introduced, c.Key)
+		}
+	}
+	return introduced
+}
+
+// candidateType returns the schema type name of a candidate's data.
+func (c *Candidate) candidateType() string {
+	switch {
+	case c.IsString():
+		return "string"
+	case c.IsArray():
+		return "array"
+	case c.IsMap():
+		return "object"
+	}
+	return "value"
+}
+
+// checkSchema reports how a candidate deviates from a schema, or nil if it matches.
+func (c *Candidate) checkSchema(schema CandidateSchema) error {
+	if actual := c.candidateType(); actual != schema.Type {
+		return fmt.Errorf("is %s %s, expected %s", article(actual), actual, article(schema.Type)+" "+schema.Type)
+	}
+	for _, key := range schema.Required {
+		if _, ok := c.GetKey(key); !ok {
+			return fmt.Errorf("is missing required key %q", key)
+		}
+	}
+	if schema.MinItems > 0 {
+		var arr []json.RawMessage
+		json.Unmarshal(c.Data, &arr)
+		if len(arr) < schema.MinItems {
+			return fmt.Errorf("has %d item(s), expected at least %d", len(arr), schema.MinItems)
+		}
+	}
+	return nil
+}
+
+// ValidateCandidates checks every candidate against a schema. The error names
+// the first offending candidate and how many did not match.
+func ValidateCandidates(candidates []Candidate, schema *CandidateSchema) error {
+	if schema == nil {
+		return nil
+	}
+	var first error
+	invalid := 0
+	for i, c := range candidates {
+		if err := c.checkSchema(*schema); err != nil {
+			if first == nil {
+				first = fmt.Errorf("candidate %d %s: %s", i+1, err, c.Key)
+			}
+			invalid++
+		}
+	}
+	if first != nil {
+		return &schemaMismatchError{invalid: invalid, total: len(candidates), first: first}
+	}
+	return nil
+}
+
+// schemaMismatchError is candidate source output that doesn't match
+// candidate_schema. Running the source again won't help.
+type schemaMismatchError struct {
+	invalid, total int
+	first          error
+}
+
+func (e *schemaMismatchError) Error() string {
+	return fmt.Sprintf("%d of %d candidate(s) do not match candidate_schema; first: %v", e.invalid, e.total, e.first)
+}
+
+func (e *schemaMismatchError) Unwrap() error {
+	return e.first
+}
+
+// article returns the indefinite article for a schema type name.
+func article(typeName string) string {
+	if typeName == "array" || typeName == "object" {
+		return "an"
+	}
+	return "a"
+}
+
+// jsonEscape escapes special characters in a string for JSON encoding.
+func jsonEscape(s string) string {
+	// Use encoding/json to properly escape the string
+	buf, _ := json.Marshal(s)
+	return string(buf[1 : len(buf)-1]) // Remove surrounding quotes
+}
+
+// normalizeMapKey creates a deterministic key for map candidates by sorting keys.
+func normalizeMapKey(m map[string]json.RawMessage) string {
+	// Get sorted keys
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	// Simple string sort is sufficient for our use case
+	for i := 0; i < len(keys); i++ {
+		for j := i + 1; j < len(keys); j++ {
+			if keys[i] > keys[j] {
+				keys[i], keys[j] = keys[j], keys[i]
+			}
+		}
+	}
+
+	// Build JSON with sorted keys
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		// Write key (quoted)
+		buf.WriteByte('"')
+		buf.WriteString(k)
+		buf.WriteByte('"')
+		buf.WriteByte(':')
+		// Write value (compact it)
+		if err := json.Compact(&buf, m[k]); err != nil {
+			// Fallback: just write the raw value
+			buf.Write(m[k])
+		}
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// IsArray returns true if the candidate data is a JSON array.
+func (c *Candidate) IsArray() bool {
+	return len(c.Data) > 0 && c.Data[0] == '['
+}
+
+// IsMap returns true if the candidate data is a JSON object.
+func (c *Candidate) IsMap() bool {
+	return len(c.Data) > 0 && c.Data[0] == '{'
+}
+
+// IsString returns true if the candidate data is a JSON string.
+func (c *Candidate) IsString() bool {
+	return len(c.Data) > 0 && c.Data[0] == '"'
+}
+
+// GetIndex returns the element at the given index (0-based) for array candidates.
+// Returns the value as a string (JSON-serialized if not a string type).
+func (c *Candidate) GetIndex(i int) (string, bool) {
+	if !c.IsArray() {
+		return "", false
+	}
+
+	var arr []json.RawMessage
+	if err := json.Unmarshal(c.Data, &arr); err != nil {
+		return "", false
+	}
+
+	if i < 0 || i >= len(arr) {
+		return "", false
+	}
+
+	return rawToString(arr[i]), true
+}
+
+// GetSlice returns elements from the given index to the end as a JSON array.
+func (c *Candidate) GetSlice(start int) (string, bool) {
+	if !c.IsArray() {
+		return "", false
+	}
+
+	var arr []json.RawMessage
+	if err := json.Unmarshal(c.Data, &arr); err != nil {
+		return "", false
+	}
+
+	if start < 0 || start >= len(arr) {
+		return "[]", true
+	}
+
+	slice := arr[start:]
+	result, err := json.Marshal(slice)
+	if err != nil {
+		return "", false
+	}
+
+	return string(result), true
+}
+
+// GetKey returns the value for the given key in a map candidate.
+// Returns the value as a string (JSON-serialized if not a string type).
+func (c *Candidate) GetKey(key string) (string, bool) {
+	if !c.IsMap() {
+		return "", false
+	}
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(c.Data, &m); err != nil {
+		return "", false
+	}
+
+	val, ok := m[key]
+	if !ok {
+		return "", false
+	}
+
+	return rawToString(val), true
+}
+
+// Field returns the value of a map candidate's key, or of an array
+// candidate's element if field is an index, or "" if it has no such field
+// (including string candidates, which have no fields).
+func (c *Candidate) Field(field string) string {
+	if c.IsMap() {
+		value, _ := c.GetKey(field)
+		return value
+	}
+	if i, err := strconv.Atoi(field); err == nil && c.IsArray() {
+		value, _ := c.GetIndex(i)
+		return value
+	}
+	return ""
+}
+
+// String returns the candidate data as a string.
+// Single-item arrays are unwrapped for convenience.
+func (c *Candidate) String() string {
+	// For strings, return the unquoted value
+	if c.IsString() {
+		var str string
+		if err := json.Unmarshal(c.Data, &str); err == nil {
+			return str
+		}
+	}
+
+	// For single-item arrays, unwrap and return the item
+	if c.IsArray() {
+		var arr []json.RawMessage
+		if err := json.Unmarshal(c.Data, &arr); err == nil && len(arr) == 1 {
+			return rawToString(arr[0])
+		}
+	}
+
+	// Otherwise return the full JSON
+	return string(c.Data)
+}
+
+// rawToString converts a json.RawMessage to a string.
+// If it's a JSON string, returns the unquoted value.
+// Otherwise returns the JSON representation.
+func rawToString(raw json.RawMessage) string {
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return str
+	}
+	return string(raw)
+}
+
+// FilterByPartition filters candidates by MD5 hash modulo.
+func FilterByPartition(candidates []Candidate, partition HashPartition) []Candidate {
+	if partition.WorkerCount <= 1 {
+		return candidates
+	}
+
+	filtered := make([]Candidate, 0, len(candidates)/partition.WorkerCount)
+	for _, c := range candidates {
+		if shardOf(c.Key, partition.WorkerCount) == partition.WorkerIndex {
+			filtered = append(filtered, c)
+		}
+	}
+
+	return filtered
+}
+
+// shardOf returns the 0-based index of the worker a key belongs to when the
+// candidates are split across workers.
+func shardOf(key string, workers int) int {
+	hash := md5.Sum([]byte(key))
+	return int(binary.LittleEndian.Uint64(hash[:8]) % uint64(workers))
+}
+
+// IgnoredList manages the list of already-processed candidates.
+type IgnoredList struct {
+	path         string
+	command      string               // ignore_list command the list came from, for Refresh
+	workDir      string               // Where command runs
+	once         sync.Once            // Loads the files' contents on first use
+	content      [3][]byte            // ignored.log, attempts.log and cooldown.log as read, until loaded
+	loadErr      error                // Error indexing the files while loading them
+	index        *keyIndex            // ignored.log as loaded
+	keys         []string             // Keys persisted since, or listed by the command, in order
+	entries      map[string]bool      // The same keys, for lookups
+	attempts     map[string]int       // Track attempts per candidate key
+	maxRepeat    int                  // When > 0, track attempts instead of permanent ignore
+	attemptsPath string               // attempts.log: one line per attempt of a key still under maxRepeat
+	cooldowns    map[string]time.Time // Keys on cooldown, until when
+	cooldownPath string               // cooldown.log, where cooldowns are kept between runs
+	skipped      map[string]bool      // Skipped for this run only, never persisted
+}
+
+// NewIgnoredList loads the task's ignored.log. Attempts at keys that haven't
+// reached the repeat limit yet are loaded from attempts.log alongside it, so
+// the count carries over between runs, and candidates on cooldown from
+// cooldown.log.
+// The files are read up front but only indexed when the list is first used,
+// and ignored.log is indexed in place (keyIndex), so a list of hundreds of
+// thousands of keys loads without a map entry or string per key. If
+// ignored.log has duplicate keys, loading rewrites it without them.
+func NewIgnoredList(taskDir string) (*IgnoredList, error) {
+	path := filepath.Join(taskDir, "ignored.log")
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read ignored list: %w", err)
+	}
+	attemptsPath := filepath.Join(taskDir, "attempts.log")
+	attempts, err := os.ReadFile(attemptsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read attempts log: %w", err)
+	}
+	cooldownPath := filepath.Join(taskDir, "cooldown.log")
+	cooldowns, err := os.ReadFile(cooldownPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read cooldown log: %w", err)
+	}
+
+	return &IgnoredList{
+		path:         path,
+		attemptsPath: attemptsPath,
+		cooldownPath: cooldownPath,
+		content:      [3][]byte{data, attempts, cooldowns},
+		entries:      make(map[string]bool),
+		attempts:     make(map[string]int),
+		cooldowns:    make(map[string]time.Time),
+	}, nil
+}
+
+// load indexes the contents of ignored.log, attempts.log and cooldown.log,
+// compacting ignored.log if it has duplicate keys and attempts.log if it has
+// keys that have since been ignored.
+func (l *IgnoredList) load() {
+	ignored, attempts, cooldowns := l.content[0], l.content[1], l.content[2]
+	l.content = [3][]byte{}
+	if l.index, l.loadErr = newKeyIndex(ignored); l.loadErr != nil {
+		return
+	}
+	if l.index.duplicates > 0 {
+		if l.loadErr = l.compact(); l.loadErr != nil {
+			return
+		}
+	}
+
+	var partial []string
+	stale := 0
+	eachLine(string(attempts), func(line string) {
+		if l.persisted(line) {
+			stale++
+			return
+		}
+		partial = append(partial, line)
+		l.attempts[line]++
+	})
+	if stale > 0 {
+		if l.loadErr = writeLines(l.attemptsPath, partial); l.loadErr != nil {
+			return
+		}
+	}
+	l.loadErr = l.loadCooldowns(string(cooldowns))
+}
+
+// eachLine calls fn with each non-blank line of content, trimmed.
+func eachLine(content string, fn func(line string)) {
+	for content != "" {
+		line := content
+		if i := strings.IndexByte(content, '\n'); i >= 0 {
+			line, content = content[:i], content[i+1:]
+		} else {
+			content = ""
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			fn(line)
+		}
+	}
+}
+
+// wait loads the list if it hasn't been yet and returns any error loading it.
+func (l *IgnoredList) wait() error {
+	l.once.Do(l.load)
+	return l.loadErr
+}
+
+// NewIgnoredListFromCommand creates an IgnoredList by running a command.
+// Command should output one ignored key per line.
+func NewIgnoredListFromCommand(command, workDir string) (*IgnoredList, error) {
+	list := &IgnoredList{
+		path:      "", // No file path for command-based lists
+		command:   command,
+		workDir:   workDir,
+		entries:   make(map[string]bool),
+		attempts:  make(map[string]int),
+		cooldowns: make(map[string]time.Time),
+	}
+	if err := list.Refresh(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Refresh re-runs the ignore_list command (ignore_list_refresh: per-iteration),
+// so keys it starts or stops listing during a long run take effect. Keys this
+// run added stay ignored. Lists loaded from ignored.log are left as they are.
+func (l *IgnoredList) Refresh() error {
+	if l.command == "" {
+		return nil
+	}
+	cmd := exec.Command("sh", "-c", l.command)
+	cmd.Dir = l.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("ignore list command failed: %w", err)
+	}
+
+	entries := make(map[string]bool)
+	for _, line := range strings.Split(string(output), "\n") {
+		key := strings.TrimSpace(line)
+		if key != "" {
+			entries[key] = true
+			if l.attempts[key] == 0 {
+				l.attempts[key] = 1 // Existing entries count as 1 attempt
+			}
+		}
+	}
+	for _, key := range l.keys {
+		entries[key] = true
+	}
+	l.entries = entries
+	return nil
+}
+
+func (l *IgnoredList) Contains(key string) bool {
+	l.wait()
+	if l.skipped[key] || l.coolingDown(key) {
+		return true
+	}
+	if l.maxRepeat > 0 && l.attempts[key] >= l.maxRepeat {
+		return true
+	}
+	// Persisted keys are done, including existing entries in repeat mode
+	return l.persisted(key)
+}
+
+// persisted reports whether key is in ignored.log (or the ignore_list
+// command's output).
+func (l *IgnoredList) persisted(key string) bool {
+	return l.entries[key] || l.index.contains(key)
+}
+
+// attemptCount returns the attempts recorded at key. Keys already ignored
+// count as one attempt.
+func (l *IgnoredList) attemptCount(key string) int {
+	if n := l.attempts[key]; n > 0 {
+		return n
+	}
+	if l.persisted(key) {
+		return 1
+	}
+	return 0
+}
+
+// SetMaxRepeat sets the max repeat count. When repeat mode is enabled,
+// existing entries (from file) stay done so they won't be retried. Only new
+// candidates will get up to N attempts. It doesn't wait for the list to load.
+func (l *IgnoredList) SetMaxRepeat(n int) {
+	l.maxRepeat = n
+}
+
+func (l *IgnoredList) Add(key string) error {
+	if err := l.wait(); err != nil {
+		return err
+	}
+	// Increment attempt count
+	l.attempts[key] = l.attemptCount(key) + 1
+
+	// In repeat mode, only write to file when limit is reached
+	if l.maxRepeat > 0 {
+		if l.attempts[key] >= l.maxRepeat {
+			// Hit the repeat limit - persist to file
+			return l.persistKey(key)
+		}
+		return l.recordAttempt(key)
+	}
+
+	// Non-repeat mode - persist immediately
+	return l.persistKey(key)
+}
+
+// Attempts returns the number of recorded attempts for a key.
+func (l *IgnoredList) Attempts(key string) int {
+	l.wait()
+	return l.attemptCount(key)
+}
+
+// recordAttempt appends an attempt at a key under the repeat limit to
+// attempts.log. Command-based lists only count attempts in memory.
+func (l *IgnoredList) recordAttempt(key string) error {
+	if l.attemptsPath == "" {
+		return nil
+	}
+	file, err := os.OpenFile(l.attemptsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open attempts log for writing: %w", err)
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintln(file, key); err != nil {
+		return fmt.Errorf("failed to write to attempts log: %w", err)
+	}
+	return nil
+}
+
+// SkipForSession ignores a key for the remainder of this run without persisting it,
+// so it becomes eligible again next session.
+func (l *IgnoredList) SkipForSession(key string) {
+	if l.skipped == nil {
+		l.skipped = make(map[string]bool)
+	}
+	l.skipped[key] = true
+}
+
+// persistKey writes a key to the ignored log file and marks it in entries.
+// Command-based lists (no path) are only tracked in memory.
+func (l *IgnoredList) persistKey(key string) error {
+	if l.persisted(key) {
+		return nil
+	}
+
+	// Command-based lists have no file path - just mark in memory
+	if l.path == "" {
+		l.keys = append(l.keys, key)
+		l.entries[key] = true
+		return nil
+	}
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open ignored list for writing: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintln(file, key); err != nil {
+		return fmt.Errorf("failed to write to ignored list: %w", err)
+	}
+
+	l.keys = append(l.keys, key)
+	l.entries[key] = true
+	return nil
+}
+
+// Prune drops persisted keys that aren't among candidates (--prune), e.g.
+// issues fixed by hand since they were ignored, and rewrites the file without
+// them or any duplicate keys. Returns how many keys were dropped.
+// Command-based lists aren't pruned.
+func (l *IgnoredList) Prune(candidates []Candidate) (int, error) {
+	if l.path == "" {
+		return 0, nil
+	}
+	if err := l.wait(); err != nil {
+		return 0, err
+	}
+	current := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		current[c.Key] = true
+	}
+
+	var kept []string
+	lines := 0
+	seen := make(map[string]bool)
+	dropped := make(map[string]bool)
+	keep := func(key string) {
+		lines++
+		if !current[key] {
+			dropped[key] = true
+			delete(l.attempts, key)
+		} else if !seen[key] {
+			seen[key] = true
+			kept = append(kept, key)
+		}
+	}
+	l.index.each(keep)
+	for _, key := range l.keys {
+		keep(key)
+	}
+	if lines == len(kept) {
+		return 0, nil
+	}
+
+	content := joinLines(kept)
+	if err := writeFile(l.path, content); err != nil {
+		return 0, err
+	}
+	index, err := newKeyIndex(content)
+	if err != nil {
+		return 0, err
+	}
+	l.index, l.keys, l.entries = index, nil, make(map[string]bool)
+	return len(dropped), nil
+}
+
+// compact rewrites ignored.log without its duplicate keys, keeping the first
+// of each, and reindexes it.
+func (l *IgnoredList) compact() error {
+	var keys []string
+	seen := make(map[string]bool, len(l.index.entries)-l.index.duplicates)
+	l.index.each(func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	})
+	content := joinLines(keys)
+	if err := writeFile(l.path, content); err != nil {
+		return err
+	}
+	index, err := newKeyIndex(content)
+	if err != nil {
+		return err
+	}
+	l.index = index
+	return nil
+}
+
+// joinLines returns one line per key.
+func joinLines(keys []string) []byte {
+	var b bytes.Buffer
+	for _, key := range keys {
+		b.WriteString(key + "\n")
+	}
+	return b.Bytes()
+}
+
+// writeLines replaces the file at path with one line per key.
+func writeLines(path string, keys []string) error {
+	return writeFile(path, joinLines(keys))
+}
+
+// writeFile replaces the file at path with content.
+func writeFile(path string, content []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return fmt.Errorf("failed to rewrite %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to rewrite %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// SelectCandidate returns the first candidate not in the ignored list.
+// If ignored is nil, returns the first candidate (no filtering).
+func SelectCandidate(candidates []Candidate, ignored *IgnoredList) *Candidate {
+	if ignored == nil && len(candidates) > 0 {
+		return &candidates[0]
+	}
+	for _, c := range candidates {
+		if ignored == nil || !ignored.Contains(c.Key) {
+			return &c
+		}
+	}
+	return nil
+}
@@ -1,9 +1,10 @@
-package main
+package runner
 
 import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -59,8 +60,8 @@ func TestParseCandidates(t *testing.T) {
 			expectedKey: []string{"single.go"},
 		},
 		{
-			name:        "newline-separated with special characters",
-			input:       `file with "quotes".go
+			name: "newline-separated with special characters",
+			input: `file with "quotes".go
 file's with apostrophe.go`,
 			expectedKey: []string{`file with "quotes".go`, `file's with apostrophe.go`},
 		},
@@ -84,6 +85,38 @@ file's with apostrophe.go`,
 	}
 }
 
+func TestStableKey(t *testing.T) {
+	body := strings.Repeat("stack frame\n", 200)
+	input := fmt.Sprintf(`[{"rule": "panic", "body": %q}]`, body)
+
+	first, err := ParseCandidates([]byte(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, _ := ParseCandidates([]byte(input))
+	key := first[0].Key
+	if len(key) > keyExcerptLength+30 {
+		t.Errorf("key is %d bytes: %q", len(key), key)
+	}
+	if key != again[0].Key {
+		t.Errorf("key changed between parses: %q, %q", key, again[0].Key)
+	}
+	if !strings.HasPrefix(key, `{"body":"stack frame\nstack`) {
+		t.Errorf("key %q doesn't start with an excerpt of the candidate", key)
+	}
+	if body, _ := first[0].GetKey("body"); len(body) != 12*200 {
+		t.Errorf("Data lost the full body (%d bytes)", len(body))
+	}
+
+	other, _ := ParseCandidates([]byte(strings.Replace(input, "panic", "oops", 1)))
+	if other[0].Key == key {
+		t.Error("different candidates with the same excerpt got the same key")
+	}
+	if short, _ := ParseCandidates([]byte(`["short.go"]`)); short[0].Key != "short.go" {
+		t.Errorf("short key changed to %q", short[0].Key)
+	}
+}
+
 func TestCandidateAccessors(t *testing.T) {
 	t.Run("string candidate", func(t *testing.T) {
 		candidates, _ := ParseCandidates([]byte(`["hello"]`))
@@ -340,6 +373,53 @@ func TestIgnoredList(t *testing.T) {
 		}
 	})
 
+	t.Run("duplicates are dropped and the file compacted on load", func(t *testing.T) {
+		dir := t.TempDir()
+		ignoredPath := filepath.Join(dir, "ignored.log")
+		if err := os.WriteFile(ignoredPath, []byte("a.go\nb.go\na.go\n\nb.go\nc.go\n"), 0644); err != nil {
+			t.Fatalf("failed to create ignored.log: %v", err)
+		}
+
+		list, err := NewIgnoredList(dir)
+		if err != nil {
+			t.Fatalf("NewIgnoredList failed: %v", err)
+		}
+		if !list.Contains("a.go") || !list.Contains("c.go") {
+			t.Error("expected a.go and c.go to be ignored")
+		}
+
+		content, _ := os.ReadFile(ignoredPath)
+		if string(content) != "a.go\nb.go\nc.go\n" {
+			t.Errorf("file content = %q, want %q", string(content), "a.go\nb.go\nc.go\n")
+		}
+	})
+
+	t.Run("Prune drops keys no longer among the candidates", func(t *testing.T) {
+		dir := t.TempDir()
+		ignoredPath := filepath.Join(dir, "ignored.log")
+		if err := os.WriteFile(ignoredPath, []byte("a.go\nfixed-by-hand.go\nc.go\n"), 0644); err != nil {
+			t.Fatalf("failed to create ignored.log: %v", err)
+		}
+
+		list, err := NewIgnoredList(dir)
+		if err != nil {
+			t.Fatalf("NewIgnoredList failed: %v", err)
+		}
+		pruned, err := list.Prune([]Candidate{{Key: "a.go"}, {Key: "b.go"}, {Key: "c.go"}})
+		if err != nil {
+			t.Fatalf("Prune failed: %v", err)
+		}
+		if pruned != 1 || list.Contains("fixed-by-hand.go") || !list.Contains("a.go") {
+			t.Errorf("Prune = %d, want only fixed-by-hand.go dropped", pruned)
+		}
+
+		list.Add("b.go")
+		content, _ := os.ReadFile(ignoredPath)
+		if string(content) != "a.go\nc.go\nb.go\n" {
+			t.Errorf("file content = %q, want %q", string(content), "a.go\nc.go\nb.go\n")
+		}
+	})
+
 	t.Run("empty directory creates new list", func(t *testing.T) {
 		dir := t.TempDir()
 
@@ -486,6 +566,67 @@ func TestIgnoredList(t *testing.T) {
 			t.Error("candidate should be ignored after reload when limit was reached")
 		}
 	})
+
+	t.Run("attempts under the repeat limit carry over between runs", func(t *testing.T) {
+		dir := t.TempDir()
+
+		list, _ := NewIgnoredList(dir)
+		list.SetMaxRepeat(3)
+		list.Add("flaky.go")
+		list.Add("flaky.go")
+		list.Add("done.go")
+		list.Add("done.go")
+		list.Add("done.go")
+
+		list2, err := NewIgnoredList(dir)
+		if err != nil {
+			t.Fatalf("NewIgnoredList failed: %v", err)
+		}
+		list2.SetMaxRepeat(3)
+		if got := list2.Attempts("flaky.go"); got != 2 {
+			t.Errorf("Attempts(flaky.go) after reload = %d, want 2", got)
+		}
+		if list2.Contains("flaky.go") {
+			t.Fatal("flaky.go should have one attempt left")
+		}
+
+		// done.go's attempts are dropped now it's ignored
+		content, _ := os.ReadFile(filepath.Join(dir, "attempts.log"))
+		if string(content) != "flaky.go\nflaky.go\n" {
+			t.Errorf("attempts.log = %q, want only flaky.go's attempts", string(content))
+		}
+
+		list2.Add("flaky.go")
+		if !list2.Contains("flaky.go") {
+			t.Error("flaky.go should be ignored after its third attempt across runs")
+		}
+	})
+
+	t.Run("Refresh re-runs the ignore_list command", func(t *testing.T) {
+		dir := t.TempDir()
+		owned := filepath.Join(dir, "owned.txt")
+		os.WriteFile(owned, []byte("theirs.go\n"), 0644)
+
+		list, err := NewIgnoredListFromCommand("cat owned.txt", dir)
+		if err != nil {
+			t.Fatalf("NewIgnoredListFromCommand failed: %v", err)
+		}
+		list.Add("attempted.go")
+		if !list.Contains("theirs.go") {
+			t.Fatal("expected theirs.go to be ignored")
+		}
+
+		os.WriteFile(owned, []byte("other.go\n"), 0644)
+		if err := list.Refresh(); err != nil {
+			t.Fatalf("Refresh failed: %v", err)
+		}
+		if list.Contains("theirs.go") || !list.Contains("other.go") {
+			t.Error("Refresh didn't pick up the command's new output")
+		}
+		if !list.Contains("attempted.go") {
+			t.Error("Refresh dropped a key added during the run")
+		}
+	})
 }
 
 func TestDeterministicMapKeys(t *testing.T) {
@@ -618,3 +759,153 @@ func TestSelectCandidate(t *testing.T) {
 		}
 	})
 }
+
+func TestDedupeCandidates(t *testing.T) {
+	t.Run("drops repeated keys keeping first occurrence", func(t *testing.T) {
+		candidates, err := ParseCandidates([]byte(`["a", "b", "a", "c", "b"]`))
+		if err != nil {
+			t.Fatalf("ParseCandidates failed: %v", err)
+		}
+
+		deduped, dropped := DedupeCandidates(candidates)
+		if dropped != 2 {
+			t.Errorf("dropped = %d, want 2", dropped)
+		}
+		expected := []string{"a", "b", "c"}
+		if len(deduped) != len(expected) {
+			t.Fatalf("got %d candidates, want %d", len(deduped), len(expected))
+		}
+		for i, c := range deduped {
+			if c.Key != expected[i] {
+				t.Errorf("candidate[%d].Key = %q, want %q", i, c.Key, expected[i])
+			}
+		}
+	})
+
+	t.Run("map candidates with different key order are duplicates", func(t *testing.T) {
+		candidates, err := ParseCandidates([]byte(`[{"file":"a.go","line":1},{"line":1,"file":"a.go"}]`))
+		if err != nil {
+			t.Fatalf("ParseCandidates failed: %v", err)
+		}
+
+		deduped, dropped := DedupeCandidates(candidates)
+		if dropped != 1 || len(deduped) != 1 {
+			t.Errorf("got %d candidates (%d dropped), want 1 (1 dropped)", len(deduped), dropped)
+		}
+	})
+
+	t.Run("no duplicates", func(t *testing.T) {
+		candidates := []Candidate{{Key: "a"}, {Key: "b"}}
+		deduped, dropped := DedupeCandidates(candidates)
+		if dropped != 0 || len(deduped) != 2 {
+			t.Errorf("got %d candidates (%d dropped), want 2 (0 dropped)", len(deduped), dropped)
+		}
+	})
+}
+
+func TestIntroducedCandidates(t *testing.T) {
+	before := []Candidate{{Key: "a"}, {Key: "b"}, {Key: "c"}}
+
+	t.Run("returns keys only present after", func(t *testing.T) {
+		after := []Candidate{{Key: "b"}, {Key: "d"}, {Key: "c"}, {Key: "e"}}
+		introduced := IntroducedCandidates(before, after)
+		if len(introduced) != 2 || introduced[0] != "d" || introduced[1] != "e" {
+			t.Errorf("IntroducedCandidates = %v, want [d e]", introduced)
+		}
+	})
+
+	t.Run("fixes alone introduce nothing", func(t *testing.T) {
+		if introduced := IntroducedCandidates(before, before[1:]); len(introduced) != 0 {
+			t.Errorf("IntroducedCandidates = %v, want none", introduced)
+		}
+	})
+}
+
+func TestValidateCandidates(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		schema  *CandidateSchema
+		wantErr string
+	}{
+		{name: "no schema", input: `["a", {"b": 1}]`},
+		{name: "strings", input: `["a", "b"]`, schema: &CandidateSchema{Type: "string"}},
+		{name: "plain text lines are strings", input: "a\nb\n", schema: &CandidateSchema{Type: "string"}},
+		{name: "objects with required keys", input: `[{"file": "a", "line": 1}]`, schema: &CandidateSchema{Type: "object", Required: []string{"file", "line"}}},
+		{name: "arrays with enough items", input: `[["a", 1], ["b", 2, 3]]`, schema: &CandidateSchema{Type: "array", MinItems: 2}},
+		{
+			name:    "wrong type",
+			input:   "error: something broke\n",
+			schema:  &CandidateSchema{Type: "object"},
+			wantErr: "1 of 1 candidate(s) do not match candidate_schema; first: candidate 1 is a string, expected an object",
+		},
+		{
+			name:    "missing required key",
+			input:   `[{"file": "a", "line": 1}, {"file": "b"}, {"path": "c"}]`,
+			schema:  &CandidateSchema{Type: "object", Required: []string{"file", "line"}},
+			wantErr: `2 of 3 candidate(s) do not match candidate_schema; first: candidate 2 is missing required key "line"`,
+		},
+		{
+			name:    "too few items",
+			input:   `[["a"]]`,
+			schema:  &CandidateSchema{Type: "array", MinItems: 2},
+			wantErr: "has 1 item(s), expected at least 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseTaskCandidates([]byte(tt.input), Task{CandidateSchema: tt.schema})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseTaskCandidatesLenient(t *testing.T) {
+	strict := false
+	task := Task{
+		StrictParsing:   &strict,
+		CandidateSchema: &CandidateSchema{Type: "object", Required: []string{"file"}},
+	}
+
+	input := `[{"file": "a.go"}, null, {"path": "b.go"}, {}, {"file": "c.go"}, "oops"]`
+	candidates, warnings, err := ParseTaskCandidates([]byte(input), task)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var keys []string
+	for _, c := range candidates {
+		keys = append(keys, c.Key)
+	}
+	if got := strings.Join(keys, " "); got != `{"file":"a.go"} {"file":"c.go"}` {
+		t.Errorf("candidates = %s", got)
+	}
+
+	want := []string{
+		"candidate 2 is empty (null)",
+		`candidate 3 is missing required key "file": {"path":"b.go"}`,
+		"candidate 4 is empty ({})",
+		"candidate 6 is a string, expected an object: oops",
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("warnings = %q, want %q", warnings, want)
+	}
+	for i := range want {
+		if warnings[i] != want[i] {
+			t.Errorf("warning %d = %q, want %q", i, warnings[i], want[i])
+		}
+	}
+
+	if _, _, err := ParseTaskCandidates([]byte(input), Task{CandidateSchema: task.CandidateSchema}); err == nil {
+		t.Error("strict parsing should fail on malformed candidates")
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package runner
 
 import (
 	"fmt"
@@ -29,24 +29,24 @@ var gradientColors = []string{
 	"\033[35m", // magenta
 }
 
-// ColorSuccess returns green text
+// ColorSuccess returns text in the theme's success color (green by default)
 func ColorSuccess(text string) string {
-	return colorGreen + text + colorReset
+	return theme.Success + text + colorReset
 }
 
-// ColorError returns red text
+// ColorError returns text in the theme's error color (red by default)
 func ColorError(text string) string {
-	return colorRed + text + colorReset
+	return theme.Error + text + colorReset
 }
 
-// ColorWarning returns yellow text
+// ColorWarning returns text in the theme's warning color (yellow by default)
 func ColorWarning(text string) string {
-	return colorYellow + text + colorReset
+	return theme.Warning + text + colorReset
 }
 
-// ColorInfo returns cyan text
+// ColorInfo returns text in the theme's info color (cyan by default)
 func ColorInfo(text string) string {
-	return colorCyan + text + colorReset
+	return theme.Info + text + colorReset
 }
 
 // ColorBold returns bold text
@@ -54,14 +54,14 @@ func ColorBold(text string) string {
 	return colorBold + text + colorReset
 }
 
-// ColorDim returns dim grey text
+// ColorDim returns text in the theme's secondary color (dim grey by default)
 func ColorDim(text string) string {
-	return colorDim + text + colorReset
+	return theme.Dim + text + colorReset
 }
 
-// ColorClaude returns dim italic text for Claude output
+// ColorClaude returns text in the theme's color for Claude output (dim italic by default)
 func ColorClaude(text string) string {
-	return colorDim + colorItalic + text + colorReset
+	return theme.ClaudeStream + text + colorReset
 }
 
 // Gradient applies a rainbow gradient to text
@@ -83,9 +83,17 @@ func Gradient(text string) string {
 	return result.String()
 }
 
-// IterationBanner creates a colorful banner for iteration headers
-func IterationBanner(n int, timeStr string) string {
-	content := fmt.Sprintf("✦ Iteration %d (%s) ✦", n, timeStr)
+// maxBannerKeyWidth is how much of the candidate key the iteration banner shows.
+const maxBannerKeyWidth = 70
+
+// maxDisplayKeyWidth is how much of a candidate key other output shows.
+const maxDisplayKeyWidth = 120
+
+// IterationBanner creates a colorful banner for iteration headers. Underneath
+// are the candidate being worked on (if any) and the run's status: candidates
+// remaining and ignored, the candidate trend and the session's fix rate.
+func IterationBanner(summary RunSummary, candidate, timeStr string) string {
+	content := fmt.Sprintf("✦ Iteration %d (%s) ✦", summary.Iterations, timeStr)
 
 	// Calculate padding for centering
 	totalWidth := 40
@@ -97,17 +105,45 @@ func IterationBanner(n int, timeStr string) string {
 	top := "╔" + strings.Repeat("═", totalWidth-2) + "╗"
 	bottom := "╚" + strings.Repeat("═", totalWidth-2) + "╝"
 
-	// Cyan border, bold white text
-	middleFormatted := colorCyan + "║" + colorReset +
+	// Banner-colored border (cyan by default), bold text
+	middleFormatted := theme.Banner + "║" + colorReset +
 		strings.Repeat(" ", leftPad) +
 		colorBold + content + colorReset +
 		strings.Repeat(" ", rightPad) +
-		colorCyan + "║" + colorReset
+		theme.Banner + "║" + colorReset
 
-	return fmt.Sprintf("\n%s%s%s\n%s\n%s%s%s\n",
-		colorCyan, top, colorReset,
+	banner := fmt.Sprintf("\n%s%s%s\n%s\n%s%s%s\n",
+		theme.Banner, top, colorReset,
 		middleFormatted,
-		colorCyan, bottom, colorReset)
+		theme.Banner, bottom, colorReset)
+	if candidate != "" {
+		banner += "  " + ColorBold(truncateKey(candidate, maxBannerKeyWidth)) + "\n"
+	}
+	if status := summary.Status(); status != "" {
+		banner += ColorDim("  "+status) + "\n"
+	}
+	return banner
+}
+
+// truncateKey fits a candidate key on one line of at most width columns,
+// keeping the end (for paths, the file name) and marking the cut with "...".
+func truncateKey(key string, width int) string {
+	key = strings.Join(strings.Fields(key), " ")
+	if displayWidth(key) <= width {
+		return key
+	}
+	runes := []rune(key)
+	for i := range runes {
+		if tail := string(runes[i:]); displayWidth(tail) <= width-3 {
+			return "..." + tail
+		}
+	}
+	return "..."
+}
+
+// displayKey fits a candidate key on one line for output.
+func displayKey(key string) string {
+	return truncateKey(key, maxDisplayKeyWidth)
 }
 
 // displayWidth calculates the visual width of a string
@@ -132,19 +168,31 @@ func displayWidth(s string) int {
 	return width
 }
 
+// catArt is the default startup banner art.
+var catArt = []string{
+	"　　　　　   __",
+	"　　　　 ／フ   フ",
+	"　　　　|  .   .|",
+	"　 　　／`ミ__xノ",
+	"　 　 /　　 　 |",
+	"　　 /　 ヽ　　ﾉ",
+	" 　 │　　 | | |",
+	"／￣|　　 | | |",
+	"| (￣ヽ_ヽ)_)__)",
+	"＼二つ",
+}
+
 // StartupBanner creates the startup banner with cat ASCII art
 func StartupBanner(taskName, logPath, mode string) string {
-	cat := []string{
-		"　　　　　   __",
-		"　　　　 ／フ   フ",
-		"　　　　|  .   .|",
-		"　 　　／`ミ__xノ",
-		"　 　 /　　 　 |",
-		"　　 /　 ヽ　　ﾉ",
-		" 　 │　　 | | |",
-		"／￣|　　 | | |",
-		"| (￣ヽ_ヽ)_)__)",
-		"＼二つ",
+	return ArtBanner(catArt, taskName, logPath, mode)
+}
+
+// ArtBanner creates a startup banner with the task details beside the given
+// ASCII art, which is padded to fit them if it's shorter.
+func ArtBanner(art []string, taskName, logPath, mode string) string {
+	cat := art
+	for len(cat) < 7 {
+		cat = append(cat[:len(cat):len(cat)], "")
 	}
 
 	// Find the widest line
@@ -172,7 +220,7 @@ func StartupBanner(taskName, logPath, mode string) string {
 	var result strings.Builder
 
 	for i, line := range cat {
-		result.WriteString(colorCyan)
+		result.WriteString(theme.Banner)
 		result.WriteString(line)
 		result.WriteString(colorReset)
 
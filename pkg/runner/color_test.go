@@ -1,8 +1,9 @@
-package main
+package runner
 
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestColorFunctions(t *testing.T) {
@@ -55,7 +56,7 @@ func TestGradient(t *testing.T) {
 }
 
 func TestIterationBanner(t *testing.T) {
-	result := IterationBanner(1, "14:30:05")
+	result := IterationBanner(RunSummary{Iterations: 1}, "", "14:30:05")
 
 	// Should contain iteration text with sparkles
 	if !strings.Contains(result, "Iteration 1") {
@@ -91,6 +92,37 @@ func TestIterationBanner(t *testing.T) {
 	if !strings.Contains(result, colorBold) {
 		t.Error("Banner should contain bold formatting for text")
 	}
+
+	// Should show the candidate and status under the box when known
+	if strings.Count(result, "\n") != 4 {
+		t.Error("Banner without a candidate or status should not have lines under the box")
+	}
+	trend := &CandidateTrend{}
+	trend.Observe([]Candidate{{Key: "a.go"}, {Key: "b.go"}}, time.Now())
+	summary := RunSummary{
+		Iterations: 3,
+		Pending:    1,
+		Ignored:    1,
+		Trend:      trend,
+		Outcomes:   map[Outcome]int{OutcomeFixed: 1, OutcomeNotFixed: 1},
+	}
+	withStatus := IterationBanner(summary, "src/a.go", "14:35:00")
+	if !strings.HasSuffix(withStatus, "  "+ColorBold("src/a.go")+"\n"+
+		ColorDim("  1 remaining, 1 ignored · 2 candidates (+0 net, +0 new) · 1/2 fixed (50%)")+"\n") {
+		t.Errorf("Banner should end with the candidate and status lines, got %q", withStatus)
+	}
+}
+
+func TestTruncateKey(t *testing.T) {
+	if got := truncateKey("src/a.go", 20); got != "src/a.go" {
+		t.Errorf("truncateKey of a short key = %q", got)
+	}
+	if got := truncateKey("pkg/very/long/path/to/file.go", 12); got != "...o/file.go" {
+		t.Errorf("truncateKey = %q, want %q", got, "...o/file.go")
+	}
+	if got := truncateKey("{\n  \"file\": \"a.go\"\n}", 40); got != `{ "file": "a.go" }` {
+		t.Errorf("truncateKey of a multi-line key = %q", got)
+	}
 }
 
 func TestStartupBanner(t *testing.T) {
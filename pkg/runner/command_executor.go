@@ -0,0 +1,355 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// CommandExecutor executes shell commands.
+type CommandExecutor interface {
+	// Run executes a command with output to stdout/stderr.
+	Run(command, workDir string) (bool, error)
+
+	// RunSilent executes a command without output.
+	RunSilent(command, workDir string) (bool, error)
+
+	// RunShowOnFail executes a command, showing output only on failure.
+	// The combined output is also returned when the command fails.
+	RunShowOnFail(command, workDir string) (bool, string, error)
+
+	// RunWatched executes a command, capturing its output and passing each
+	// line to onLine (if not nil) as it arrives. Nothing is printed; the
+	// combined output is returned whether or not the command succeeds.
+	RunWatched(command, workDir string, onLine func(string)) (bool, string, error)
+
+	// RunWithTimeout executes a command with output to stdout/stderr, killing it
+	// after timeout. Returns a *timeoutError if the timeout is reached.
+	RunWithTimeout(command, workDir string, timeout time.Duration) (bool, error)
+
+	// HasUncommittedChanges checks if there are uncommitted git changes.
+	HasUncommittedChanges(workDir string) (bool, error)
+
+	// CurrentRevision returns the commit hash of HEAD.
+	CurrentRevision(workDir string) (string, error)
+
+	// TreeFingerprint returns a hash of the working tree contents, including
+	// uncommitted and untracked files.
+	TreeFingerprint(workDir string) (string, error)
+
+	// ChangedFiles lists modified, deleted and untracked files relative to workDir.
+	ChangedFiles(workDir string) ([]string, error)
+}
+
+// RealCommandExecutor executes actual shell commands.
+type RealCommandExecutor struct {
+	// Paths (git pathspecs from the top of the repository) whose changes
+	// HasUncommittedChanges, TreeFingerprint and ChangedFiles don't count
+	IgnoreDirtyPaths []string
+}
+
+// pathspec returns git pathspec arguments for base (":/" for the whole
+// repository, "." for workDir) minus IgnoreDirtyPaths, or nil without any.
+func (r *RealCommandExecutor) pathspec(base string) []string {
+	if len(r.IgnoreDirtyPaths) == 0 {
+		return nil
+	}
+	args := []string{"--", base}
+	for _, path := range r.IgnoreDirtyPaths {
+		args = append(args, ":(top,exclude)"+path)
+	}
+	return args
+}
+
+// Run executes a shell command and returns success status.
+func (r *RealCommandExecutor) Run(command, workDir string) (bool, error) {
+	cmd := exec.Command("bash", "-c", command)
+	cmd.Dir = workDir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err := cmd.Run()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// RunSilent executes a shell command without output and returns success status.
+func (r *RealCommandExecutor) RunSilent(command, workDir string) (bool, error) {
+	cmd := exec.Command("bash", "-c", command)
+	cmd.Dir = workDir
+
+	err := cmd.Run()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// RunShowOnFail executes a shell command, capturing output and only printing it if the command fails.
+// On failure the captured stdout and stderr are also returned.
+func (r *RealCommandExecutor) RunShowOnFail(command, workDir string) (bool, string, error) {
+	cmd := exec.Command("bash", "-c", command)
+	cmd.Dir = workDir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			// Command failed - print captured output
+			if stdout.Len() > 0 {
+				os.Stdout.Write(stdout.Bytes())
+			}
+			if stderr.Len() > 0 {
+				os.Stderr.Write(stderr.Bytes())
+			}
+			return false, stdout.String() + stderr.String(), nil
+		}
+		return false, "", err
+	}
+	return true, "", nil
+}
+
+// RunWatched executes a shell command, capturing its combined output without
+// printing it, and passes each line to onLine as it arrives.
+func (r *RealCommandExecutor) RunWatched(command, workDir string, onLine func(string)) (bool, string, error) {
+	cmd := exec.Command("bash", "-c", command)
+	cmd.Dir = workDir
+
+	var output bytes.Buffer
+	var w io.Writer = &output
+	if onLine != nil {
+		w = io.MultiWriter(&output, &lineWriter{onLine: onLine})
+	}
+	// The same writer for both, so exec calls it from one goroutine at a time
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	err := cmd.Run()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return false, output.String(), nil
+		}
+		return false, "", err
+	}
+	return true, output.String(), nil
+}
+
+// lineWriter calls onLine with each non-empty line written to it. Carriage
+// returns end a line too, for progress bars that redraw in place.
+type lineWriter struct {
+	onLine  func(string)
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b != '\n' && b != '\r' {
+			w.partial = append(w.partial, b)
+			continue
+		}
+		if line := strings.TrimSpace(string(w.partial)); line != "" {
+			w.onLine(line)
+		}
+		w.partial = w.partial[:0]
+	}
+	return len(p), nil
+}
+
+// RunWithTimeout executes a shell command and kills its process group if it
+// exceeds timeout. A zero timeout behaves like Run.
+func (r *RealCommandExecutor) RunWithTimeout(command, workDir string, timeout time.Duration) (bool, error) {
+	if timeout <= 0 {
+		return r.Run(command, workDir)
+	}
+
+	cmd := exec.Command("bash", "-c", command)
+	cmd.Dir = workDir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// Own process group so hooks spawned by the command are killed too
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if err := cmd.Start(); err != nil {
+		return false, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case <-time.After(timeout):
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return false, &timeoutError{duration: timeout}
+	case err := <-done:
+		if err != nil {
+			if _, ok := err.(*exec.ExitError); ok {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+// HasUncommittedChanges checks if there are uncommitted git changes.
+// Untracked files matched by .gitignore or IgnoreDirtyPaths don't count.
+func (r *RealCommandExecutor) HasUncommittedChanges(workDir string) (bool, error) {
+	pathspec := r.pathspec(":/")
+	cmd := exec.Command("git", append([]string{"diff", "--quiet"}, pathspec...)...)
+	cmd.Dir = workDir
+	err := cmd.Run()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return true, nil
+		}
+		return false, err
+	}
+
+	// Also check staged changes
+	cmd = exec.Command("git", append([]string{"diff", "--quiet", "--cached"}, pathspec...)...)
+	cmd.Dir = workDir
+	err = cmd.Run()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return true, nil
+		}
+		return false, err
+	}
+
+	// Also check untracked files
+	cmd = exec.Command("git", append([]string{"status", "--porcelain"}, pathspec...)...)
+	cmd.Dir = workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return false, err
+	}
+
+	return len(strings.TrimSpace(string(output))) > 0, nil
+}
+
+// CurrentRevision returns the commit hash of HEAD.
+func (r *RealCommandExecutor) CurrentRevision(workDir string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// TreeFingerprint hashes the working tree (tracked, modified and untracked
+// files, but not ignored ones) by writing it to a throwaway index. The result
+// depends only on file contents, so it is unchanged when the same changes are
+// later committed.
+func (r *RealCommandExecutor) TreeFingerprint(workDir string) (string, error) {
+	dir, err := os.MkdirTemp("", "nigel-index-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	index := filepath.Join(dir, "index")
+
+	// Seed from the real index so git only rehashes files that changed
+	cmd := exec.Command("git", "rev-parse", "--git-path", "index")
+	cmd.Dir = workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	realIndex := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(realIndex) {
+		realIndex = filepath.Join(workDir, realIndex)
+	}
+	if data, err := os.ReadFile(realIndex); err == nil {
+		if err := os.WriteFile(index, data, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	add := "git add -A"
+	for _, arg := range r.pathspec(":/") {
+		add += " " + shellQuote(arg)
+	}
+	cmd = exec.Command("bash", "-c", add+" && git write-tree")
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(), "GIT_INDEX_FILE="+index)
+	output, err = cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ChangedFiles lists files that differ from HEAD (including deletions) and
+// untracked files that aren't ignored. Paths are relative to workDir and only
+// cover files inside it.
+func (r *RealCommandExecutor) ChangedFiles(workDir string) ([]string, error) {
+	pathspec := r.pathspec(".")
+	cmd := exec.Command("git", append([]string{"diff", "--name-only", "--relative", "HEAD"}, pathspec...)...)
+	cmd.Dir = workDir
+	tracked, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd = exec.Command("git", append([]string{"ls-files", "--others", "--exclude-standard"}, pathspec...)...)
+	cmd.Dir = workDir
+	untracked, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(tracked)+string(untracked), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// RunCommand is a convenience function that uses RealCommandExecutor.
+// Kept for backward compatibility.
+func RunCommand(command, workDir string) (bool, error) {
+	return (&RealCommandExecutor{}).Run(command, workDir)
+}
+
+// RunCommandSilent is a convenience function that uses RealCommandExecutor.
+// Kept for backward compatibility.
+func RunCommandSilent(command, workDir string) (bool, error) {
+	return (&RealCommandExecutor{}).RunSilent(command, workDir)
+}
+
+// RunCommandShowOnFail is a convenience function that uses RealCommandExecutor.
+// Kept for backward compatibility.
+func RunCommandShowOnFail(command, workDir string) (bool, error) {
+	ok, _, err := (&RealCommandExecutor{}).RunShowOnFail(command, workDir)
+	return ok, err
+}
+
+// HasUncommittedChanges is a convenience function that uses RealCommandExecutor.
+// Kept for backward compatibility.
+func HasUncommittedChanges(workDir string) (bool, error) {
+	return (&RealCommandExecutor{}).HasUncommittedChanges(workDir)
+}
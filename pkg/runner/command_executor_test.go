@@ -0,0 +1,311 @@
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunWatched(t *testing.T) {
+	executor := &RealCommandExecutor{}
+
+	var lines []string
+	ok, output, err := executor.RunWatched(`printf 'compiling a\n\ncompiling b\r50%%\r100%%\n'; echo oops >&2; exit 1`, t.TempDir(),
+		func(line string) { lines = append(lines, line) })
+	if err != nil || ok {
+		t.Fatalf("RunWatched = %v, %v; want a failure without error", ok, err)
+	}
+	want := []string{"compiling a", "compiling b", "50%", "100%", "oops"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+	if !strings.Contains(output, "compiling a") || !strings.Contains(output, "oops") {
+		t.Errorf("output should hold stdout and stderr, got %q", output)
+	}
+
+	ok, output, err = executor.RunWatched("echo fine", t.TempDir(), nil)
+	if err != nil || !ok || output != "fine\n" {
+		t.Errorf("RunWatched = %v, %q, %v; want success with its output", ok, output, err)
+	}
+}
+
+func TestGitWorkingTree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	executor := &RealCommandExecutor{}
+	fingerprint := func() string {
+		fp, err := executor.TreeFingerprint(dir)
+		if err != nil {
+			t.Fatalf("TreeFingerprint failed: %v", err)
+		}
+		return fp
+	}
+
+	git("init", "-q")
+	write("a.txt", "one")
+	git("add", "a.txt")
+	git("commit", "-q", "-m", "initial")
+	clean := fingerprint()
+
+	write("a.txt", "two")
+	write("b.txt", "new")
+	changed := fingerprint()
+	if changed == clean {
+		t.Fatal("fingerprint should change with modified and untracked files")
+	}
+
+	git("add", "-A")
+	git("commit", "-q", "-m", "change")
+	if got := fingerprint(); got != changed {
+		t.Errorf("fingerprint after committing = %s, want %s", got, changed)
+	}
+	if status, _ := executor.HasUncommittedChanges(dir); status {
+		t.Error("fingerprinting should not touch the real index")
+	}
+
+	write("a.txt", "three")
+	write("c.txt", "untracked")
+	files, err := executor.ChangedFiles(dir)
+	if err != nil {
+		t.Fatalf("ChangedFiles failed: %v", err)
+	}
+	if len(files) != 2 || files[0] != "a.txt" || files[1] != "c.txt" {
+		t.Errorf("ChangedFiles = %v, want [a.txt c.txt]", files)
+	}
+
+	// Ignored and ignore_dirty_paths files don't count as changes
+	git("add", "-A")
+	git("commit", "-q", "-m", "more")
+	write(".gitignore", "*.o\n")
+	git("add", ".gitignore")
+	git("commit", "-q", "-m", "ignore")
+	before := fingerprint()
+	write("main.o", "object")
+	if err := os.MkdirAll(filepath.Join(dir, "build", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	write("build/sub/out.bin", "artifact")
+	write("a.swp", "swap")
+	executor.IgnoreDirtyPaths = []string{"build", "*.swp"}
+	if dirty, err := executor.HasUncommittedChanges(dir); err != nil || dirty {
+		t.Errorf("HasUncommittedChanges = (%v, %v), want build artifacts ignored", dirty, err)
+	}
+	if files, _ := executor.ChangedFiles(dir); len(files) != 0 {
+		t.Errorf("ChangedFiles = %v, want none", files)
+	}
+	if got := fingerprint(); got != before {
+		t.Error("fingerprint changed with only ignored files")
+	}
+	write("a.txt", "four")
+	if dirty, _ := executor.HasUncommittedChanges(dir); !dirty {
+		t.Error("expected a change outside ignore_dirty_paths to count")
+	}
+}
+
+// MockCommandExecutor is a test double for CommandExecutor.
+type MockCommandExecutor struct {
+	// Commands to results mapping
+	Results map[string]CommandResult
+	// Per-command results consumed in order before falling back to Results
+	Sequences map[string][]CommandResult
+	// Output returned by RunShowOnFail when a command fails
+	Outputs map[string]string
+	// Record of calls made
+	Calls []CallRecord
+	// Mock for HasUncommittedChanges
+	HasChangesResult bool
+	HasChangesErr    error
+	// Results consumed in order before falling back to HasChangesResult
+	HasChangesSequence []bool
+	// Mock for CurrentRevision
+	Revision string
+	// Revisions returned in order before falling back to Revision
+	RevisionSequence []string
+	// Mock for TreeFingerprint
+	Fingerprint string
+	// Fingerprints returned in order before falling back to Fingerprint
+	FingerprintSequence []string
+	// Mock for ChangedFiles
+	Changed    []string
+	ChangedErr error
+}
+
+// CommandResult represents the result of executing a command.
+type CommandResult struct {
+	Success bool
+	Error   error
+}
+
+// CallRecord records a single command execution.
+type CallRecord struct {
+	Command string
+	WorkDir string
+}
+
+// NewMockCommandExecutor creates a new mock executor.
+func NewMockCommandExecutor() *MockCommandExecutor {
+	return &MockCommandExecutor{
+		Results:          make(map[string]CommandResult),
+		Sequences:        make(map[string][]CommandResult),
+		Outputs:          make(map[string]string),
+		Calls:            make([]CallRecord, 0),
+		HasChangesResult: false, // Default: no changes
+		HasChangesErr:    nil,
+	}
+}
+
+// Run executes a command, recording the call and returning the configured result.
+func (m *MockCommandExecutor) Run(command, workDir string) (bool, error) {
+	m.Calls = append(m.Calls, CallRecord{Command: command, WorkDir: workDir})
+	return m.result(command)
+}
+
+// RunSilent executes a command silently, recording the call and returning the configured result.
+func (m *MockCommandExecutor) RunSilent(command, workDir string) (bool, error) {
+	m.Calls = append(m.Calls, CallRecord{Command: command, WorkDir: workDir})
+	return m.result(command)
+}
+
+// RunShowOnFail executes a command, recording the call and returning the configured result.
+func (m *MockCommandExecutor) RunShowOnFail(command, workDir string) (bool, string, error) {
+	m.Calls = append(m.Calls, CallRecord{Command: command, WorkDir: workDir})
+	ok, err := m.result(command)
+	if !ok && err == nil {
+		return false, m.Outputs[command], nil
+	}
+	return ok, "", err
+}
+
+// RunWatched executes a command like RunShowOnFail, but returns its output
+// whether or not it fails; no output lines are reported.
+func (m *MockCommandExecutor) RunWatched(command, workDir string, onLine func(string)) (bool, string, error) {
+	m.Calls = append(m.Calls, CallRecord{Command: command, WorkDir: workDir})
+	ok, err := m.result(command)
+	if err != nil {
+		return false, "", err
+	}
+	return ok, m.Outputs[command], nil
+}
+
+// RunWithTimeout executes a command, recording the call and returning the configured result.
+func (m *MockCommandExecutor) RunWithTimeout(command, workDir string, timeout time.Duration) (bool, error) {
+	m.Calls = append(m.Calls, CallRecord{Command: command, WorkDir: workDir})
+	return m.result(command)
+}
+
+// HasUncommittedChanges returns the next sequenced result, then the
+// configured one.
+func (m *MockCommandExecutor) HasUncommittedChanges(workDir string) (bool, error) {
+	if len(m.HasChangesSequence) > 0 {
+		next := m.HasChangesSequence[0]
+		m.HasChangesSequence = m.HasChangesSequence[1:]
+		return next, m.HasChangesErr
+	}
+	return m.HasChangesResult, m.HasChangesErr
+}
+
+// CurrentRevision returns the next revision in the sequence, then the
+// configured one.
+func (m *MockCommandExecutor) CurrentRevision(workDir string) (string, error) {
+	if len(m.RevisionSequence) > 0 {
+		next := m.RevisionSequence[0]
+		m.RevisionSequence = m.RevisionSequence[1:]
+		return next, nil
+	}
+	return m.Revision, nil
+}
+
+// TreeFingerprint returns the next sequenced fingerprint, then the
+// configured one.
+func (m *MockCommandExecutor) TreeFingerprint(workDir string) (string, error) {
+	if len(m.FingerprintSequence) > 0 {
+		next := m.FingerprintSequence[0]
+		m.FingerprintSequence = m.FingerprintSequence[1:]
+		return next, nil
+	}
+	return m.Fingerprint, nil
+}
+
+// ChangedFiles returns the configured changed files.
+func (m *MockCommandExecutor) ChangedFiles(workDir string) ([]string, error) {
+	return m.Changed, m.ChangedErr
+}
+
+// SetResult sets the result for a specific command.
+func (m *MockCommandExecutor) SetResult(command string, success bool, err error) {
+	m.Results[command] = CommandResult{Success: success, Error: err}
+}
+
+// SetResultSequence sets results returned for successive calls of a command.
+// Once exhausted, calls fall back to the result set by SetResult.
+func (m *MockCommandExecutor) SetResultSequence(command string, results ...CommandResult) {
+	m.Sequences[command] = results
+}
+
+// result returns the next configured result for a command.
+func (m *MockCommandExecutor) result(command string) (bool, error) {
+	if seq := m.Sequences[command]; len(seq) > 0 {
+		m.Sequences[command] = seq[1:]
+		return seq[0].Success, seq[0].Error
+	}
+	if result, ok := m.Results[command]; ok {
+		return result.Success, result.Error
+	}
+	// Default: success
+	return true, nil
+}
+
+// SetOutput sets the output RunShowOnFail returns when a command fails, and
+// RunWatched returns always.
+func (m *MockCommandExecutor) SetOutput(command, output string) {
+	m.Outputs[command] = output
+}
+
+// SetHasChanges sets the result for HasUncommittedChanges.
+func (m *MockCommandExecutor) SetHasChanges(hasChanges bool, err error) {
+	m.HasChangesResult = hasChanges
+	m.HasChangesErr = err
+}
+
+// ClearCalls clears the recorded calls.
+func (m *MockCommandExecutor) ClearCalls() {
+	m.Calls = make([]CallRecord, 0)
+}
+
+// CallCount returns the number of times a command was called.
+func (m *MockCommandExecutor) CallCount(command string) int {
+	count := 0
+	for _, call := range m.Calls {
+		if call.Command == command {
+			count++
+		}
+	}
+	return count
+}
+
+// CalledWith returns true if a command was called with the exact arguments.
+func (m *MockCommandExecutor) CalledWith(command string) bool {
+	for _, call := range m.Calls {
+		if call.Command == command {
+			return true
+		}
+	}
+	return false
+}
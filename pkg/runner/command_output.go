@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CommandOutputDir returns where a run of task started at t saves the output
+// of its verify and reset commands: one directory per session in an output/
+// directory next to the task's claude.log.
+func (e *Environment) CommandOutputDir(task Task, t time.Time) string {
+	return filepath.Join(e.logDir(task), "output", task.Name+"-"+t.Format("20060102-150405"))
+}
+
+// saveOutput appends a command and its full output to the current iteration's
+// file for name, e.g. 003-verify.log, so a build failure that caused a revert
+// can be read later without re-running the build. Returns the file's path, or
+// "" if nothing was saved (command_output: false, --dry-run, or an error,
+// which only warns).
+func (r *Runner) saveOutput(name, command, output string) string {
+	if r.outputDir == "" {
+		return ""
+	}
+	if err := os.MkdirAll(r.outputDir, 0755); err != nil {
+		fmt.Println(ColorWarning(fmt.Sprintf("Failed to create command output directory: %v", err)))
+		return ""
+	}
+	path := filepath.Join(r.outputDir, fmt.Sprintf("%03d-%s.log", r.iteration, name))
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Println(ColorWarning(fmt.Sprintf("Failed to save %s output: %v", name, err)))
+		return ""
+	}
+	defer file.Close()
+	fmt.Fprintf(file, "$ %s\n%s", command, output)
+	return path
+}
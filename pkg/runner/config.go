@@ -0,0 +1,853 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	ClaudeCommand           string               `yaml:"claude_command"`
+	ClaudeCommandFallbacks  []string             `yaml:"claude_command_fallbacks"` // Failed over to in order when claude_command keeps erroring
+	SuccessCommand          string               `yaml:"success_command"`
+	ResetCommand            string               `yaml:"reset_command"`
+	VerifyCommand           string               `yaml:"verify_command"`
+	ScopedVerifyCommand     string               `yaml:"scoped_verify_command"`      // Verify using $CHANGED_FILES, falling back to verify_command
+	SyncCommand             string               `yaml:"sync_command"`               // Brings in upstream changes between iterations, e.g. git pull --rebase
+	SyncInterval            time.Duration        `yaml:"sync_interval"`              // Minimum time between sync_command runs (0 = before every iteration)
+	VerifyLastLine          bool                 `yaml:"verify_last_line"`           // Show verify output's latest line next to its timer
+	VerifyExcerptLines      int                  `yaml:"verify_excerpt_lines"`       // Lines of failing verify output kept with the outcome (default 3)
+	VerifyRetries           int                  `yaml:"verify_retries"`             // Re-runs of a failing verify before the fix is rejected, for flaky suites
+	SuccessTimeout          time.Duration        `yaml:"success_timeout"`            // Kill success_command after this long (0 = no limit)
+	SuccessRetries          int                  `yaml:"success_retries"`            // Retries for transient success_command failures
+	PushCommand             string               `yaml:"push_command"`               // Retries the push after a non-fast-forward rejection (enables recovery)
+	PushRebaseCommand       string               `yaml:"push_rebase_command"`        // Rebases a rejected commit onto the remote (default git pull --rebase)
+	QuarantineBranch        string               `yaml:"quarantine_branch"`          // Keeps fixes reverted for failing verify as commits on this branch
+	GitAuthor               string               `yaml:"git_author"`                 // "Name <email>" for commits made by success_command
+	GitCommitter            string               `yaml:"git_committer"`              // "Name <email>" for commits made by success_command
+	SignCommits             bool                 `yaml:"sign_commits"`               // GPG-sign commits made by success_command
+	CommitTrailers          *bool                `yaml:"commit_trailers"`            // Add Nigel-* trailers to commits made by success_command (default true)
+	AuditLog                bool                 `yaml:"audit_log"`                  // Record every command a run executes under audit/ next to claude.log
+	CommandOutput           *bool                `yaml:"command_output"`             // Save verify and reset output under output/ next to claude.log (default true)
+	PreCommitScan           string               `yaml:"pre_commit_scan"`            // Must pass on uncommitted changes before success_command runs
+	IgnoreDirtyPaths        []string             `yaml:"ignore_dirty_paths"`         // Paths whose changes don't count as changes, e.g. build outputs verify leaves behind
+	Projects                map[string]Project   `yaml:"projects"`                   // Named checkouts that tasks can target with 'project'
+	TransientErrors         TransientErrors      `yaml:"transient_errors"`           // Claude failures retried without counting against the candidate
+	MCPServers              map[string]MCPServer `yaml:"mcp_servers"`                // MCP servers available to every task
+	Env                     map[string]string    `yaml:"env"`                        // Environment variables for Claude and every command a task runs
+	ContextFiles            []string             `yaml:"context_files"`              // Files appended to every prompt, e.g. CONTRIBUTING.md
+	ContextMaxBytes         int                  `yaml:"context_max_bytes"`          // Cap on each context file (default 16KB)
+	CandidateSourceMaxBytes int                  `yaml:"candidate_source_max_bytes"` // Cap on candidate source output (default 64MB)
+	Email                   *EmailConfig         `yaml:"email"`                      // Mail a run summary when the run finishes or dies
+	LogDir                  string               `yaml:"log_dir"`                    // Directory for claude.log files instead of each task's directory
+	LogFilePattern          string               `yaml:"log_file_pattern"`           // Log file name with $TASK_NAME and $DATE (default claude.log, or $TASK_NAME.log with log_dir)
+	Theme                   ThemeConfig          `yaml:"theme"`                      // Built-in theme name, or colors per output role
+	Banner                  string               `yaml:"banner"`                     // Startup banner: cat (default), minimal, or a file of ASCII art
+	ExternalEdits           string               `yaml:"external_edits"`             // prompt or abort when files Claude didn't edit change during an attempt ("" = don't check)
+	Isolation               string               `yaml:"isolation"`                  // worktree or container to keep runs out of the checkout ("" = run in it)
+	Container               *ContainerConfig     `yaml:"container"`                  // Image and runtime for isolation: container
+}
+
+// Project is a named checkout with its own commands. Empty commands fall
+// back to the top-level config.
+type Project struct {
+	Dir                 string `yaml:"dir"` // Relative to the directory nigel is run from
+	SuccessCommand      string `yaml:"success_command"`
+	ResetCommand        string `yaml:"reset_command"`
+	VerifyCommand       string `yaml:"verify_command"`
+	ScopedVerifyCommand string `yaml:"scoped_verify_command"`
+}
+
+type Task struct {
+	Name              string                    // derived from directory name
+	Dir               string                    // path to task directory
+	CandidateSource   string                    `yaml:"candidate_source"`
+	Prompt            string                    `yaml:"prompt"`
+	Template          string                    `yaml:"template"`
+	ClaudeFlags       string                    `yaml:"claude_flags"`
+	ClaudeCommand     string                    `yaml:"claude_command"`
+	AcceptBestEffort  bool                      `yaml:"accept_best_effort"`
+	BestEffortCheck   string                    `yaml:"best_effort_check"` // Must pass before partial progress is committed
+	Timeout           time.Duration             `yaml:"timeout"`
+	InactivityTimeout time.Duration             `yaml:"inactivity_timeout"`  // Kill Claude after this long without output (0 = no limit)
+	IgnoreList        string                    `yaml:"ignore_list"`         // Command to generate ignore list
+	IgnoreListRefresh string                    `yaml:"ignore_list_refresh"` // per-session (default) or per-iteration
+	Repeat            int                       `yaml:"repeat"`              // Attempt each candidate up to N times (0 = once)
+	Requeue           map[Outcome]RequeuePolicy `yaml:"requeue"`             // Per-outcome requeue behavior
+	Cooldown          time.Duration             `yaml:"cooldown"`            // Retry failed candidates after this long instead of ignoring them
+	TimeoutEscalation *TimeoutEscalation        `yaml:"timeout_escalation"`  // Retry timed-out candidates once with a bigger budget
+	CommitMode        string                    `yaml:"commit_mode"`         // per-candidate (default), per-session, or every-N
+	CommitBatch       int                       `yaml:"-"`                   // Derived from CommitMode: 0 = per-candidate, N = every-N, commitPerSession
+	CommitStage       string                    `yaml:"commit_stage"`        // all (default) or edited: what a batched commit_mode stages for each fix
+	DependsOn         []string                  `yaml:"depends_on"`          // Tasks that must have no remaining candidates before this one runs
+	Workdir           string                    `yaml:"workdir"`             // Subdirectory of the project that commands run in
+	Project           string                    `yaml:"project"`             // Named project from config.yaml (default: current directory)
+	Variants          []PromptVariant           `yaml:"variants"`            // Alternative prompts compared against each other
+	VariantAssignment string                    `yaml:"variant_assignment"`  // round-robin (default) or hash
+	CandidateSchema   *CandidateSchema          `yaml:"candidate_schema"`    // Expected candidate shape, checked on every parse
+	StrictParsing     *bool                     `yaml:"strict_parsing"`      // Fail on malformed candidates (default) rather than skipping them
+	MaxNewCandidates  int                       `yaml:"max_new_candidates"`  // Revert changes that introduce this many new candidates (0 = only report)
+	ZeroDiffFix       string                    `yaml:"zero_diff_fix"`       // fixed, confirm or flaky: a candidate that disappeared without changes (unset: FIXED, without re-checking no-op attempts)
+	SourceCheck       string                    `yaml:"source_check"`        // warn or refuse: run the candidate source twice at startup and compare
+	Pipeline          bool                      `yaml:"pipeline"`            // Run the candidate source alongside verify_command
+	OutputFormat      string                    `yaml:"output_format"`       // stream-json, json or text (default: request stream-json, probe the reply)
+	StreamParser      string                    `yaml:"stream_parser"`       // How the agent CLI's stdout is read: claude-stream-json (default), aider, plain-text-with-sentinel
+	StreamSentinel    string                    `yaml:"stream_sentinel"`     // Line a plain-text-with-sentinel CLI prints when it has finished
+	MCPServers        map[string]MCPServer      `yaml:"mcp_servers"`         // MCP servers for this task, overriding global ones by name
+	Env               map[string]string         `yaml:"env"`                 // Environment variables for this task, overriding global ones by name
+	PathPrepend       []string                  `yaml:"path_prepend"`        // Directories put ahead of PATH for Claude and every command
+	ContextFiles      []string                  `yaml:"context_files"`       // Files appended to this task's prompts, after the global ones
+	Toolchain         map[string]string         `yaml:"toolchain"`           // Tool name to install directory whose bin/ goes ahead of PATH
+	AllowedTools      []string                  `yaml:"allowed_tools"`       // Tools Claude may use without asking (--allowedTools)
+	DisallowedTools   []string                  `yaml:"disallowed_tools"`    // Tools Claude may not use (default: defaultDisallowedTools)
+	PromptLimit       *PromptLimit              `yaml:"prompt_limit"`        // What to do when the interpolated prompt is too large
+	CandidateFamilies *CandidateFamilies        `yaml:"candidate_families"`  // Deprioritize or skip groups of candidates that have never been fixed
+	CandidateFile     string                    `yaml:"candidate_file"`      // Map key, or array index, of the candidate's file path, for $CANDIDATE_FILE
+}
+
+// streamConfig returns the task's choice of stream parser.
+func (t Task) streamConfig() StreamConfig {
+	return StreamConfig{Parser: t.StreamParser, Format: t.OutputFormat, Sentinel: t.StreamSentinel}
+}
+
+// defaultDisallowedTools stop Claude from committing or pushing by itself:
+// nigel commits fixes with success_command and discards failed attempts with
+// reset_command, which a commit made by Claude would escape.
+var defaultDisallowedTools = []string{"Bash(git commit:*)", "Bash(git push:*)"}
+
+// ToolFlags returns the Claude CLI tool permission flags for the task.
+// Without disallowed_tools the defaults apply; `disallowed_tools: []` removes them.
+func (t Task) ToolFlags() string {
+	disallowed := t.DisallowedTools
+	if disallowed == nil {
+		disallowed = defaultDisallowedTools
+	}
+
+	var flags []string
+	if len(t.AllowedTools) > 0 {
+		flags = append(flags, "--allowedTools "+shellQuote(strings.Join(t.AllowedTools, ",")))
+	}
+	if len(disallowed) > 0 {
+		flags = append(flags, "--disallowedTools "+shellQuote(strings.Join(disallowed, ",")))
+	}
+	return strings.Join(flags, " ")
+}
+
+// IsStrictParsing reports whether a malformed candidate fails the whole parse.
+func (t Task) IsStrictParsing() bool {
+	return t.StrictParsing == nil || *t.StrictParsing
+}
+
+// CandidateSchema describes the shape every candidate must have.
+type CandidateSchema struct {
+	Type     string   `yaml:"type"`      // string, array, or object
+	Required []string `yaml:"required"`  // Keys every object candidate must have
+	MinItems int      `yaml:"min_items"` // Minimum length of array candidates
+}
+
+// PromptVariant is one arm of a prompt experiment.
+type PromptVariant struct {
+	Name     string `yaml:"name"`
+	Prompt   string `yaml:"prompt"`
+	Template string `yaml:"template"`
+}
+
+// WorkDir returns the directory the task's commands run in.
+func (t Task) WorkDir(projectDir string) string {
+	if t.Workdir == "" {
+		return projectDir
+	}
+	return filepath.Join(projectDir, t.Workdir)
+}
+
+// TimeoutEscalation configures the single retry given to a candidate that timed out.
+type TimeoutEscalation struct {
+	Multiplier float64 `yaml:"multiplier"` // Timeout multiplier for the retry (default 2)
+	Model      string  `yaml:"model"`      // Optional model passed as --model for the retry
+}
+
+// RequeuePolicy controls whether a candidate is retried after a given outcome.
+type RequeuePolicy string
+
+const (
+	RequeueIgnore        RequeuePolicy = "ignore"                // Add to ignored list (default)
+	RequeueNextSession   RequeuePolicy = "retry_next_session"    // Skip for this run only
+	RequeueDoubleTimeout RequeuePolicy = "retry_doubled_timeout" // Retry once with twice the timeout (TIMEOUT only)
+)
+
+// Playlist rotates between several tasks within one run.
+type Playlist struct {
+	Name  string          // derived from directory name
+	Mode  string          `yaml:"mode"` // round-robin (default) or weighted
+	Tasks []PlaylistEntry `yaml:"tasks"`
+}
+
+// PlaylistEntry is a task in a playlist.
+type PlaylistEntry struct {
+	Task   string `yaml:"task"`
+	Weight int    `yaml:"weight"` // Relative share of iterations in weighted mode (default 1)
+}
+
+type Environment struct {
+	Config     Config
+	Tasks      map[string]Task
+	Playlists  map[string]Playlist
+	ProjectDir string
+	RunnerDir  string
+	TaskID     int64            // Unique task ID for this run
+	Shards     *ShardAssignment // Shard per host from shards.yaml (nil without one)
+	bannerArt  []string         // Custom startup banner art from the `banner:` file, if any
+}
+
+// ForTask returns the environment a task runs in. Tasks that target a named
+// project get that project's directory and command overrides.
+func (e *Environment) ForTask(task Task) *Environment {
+	if task.Project == "" {
+		return e
+	}
+	project := e.Config.Projects[task.Project]
+
+	scoped := *e
+	scoped.ProjectDir = project.Dir
+	if project.SuccessCommand != "" {
+		scoped.Config.SuccessCommand = project.SuccessCommand
+	}
+	if project.ResetCommand != "" {
+		scoped.Config.ResetCommand = project.ResetCommand
+	}
+	if project.VerifyCommand != "" {
+		scoped.Config.VerifyCommand = project.VerifyCommand
+	}
+	if project.ScopedVerifyCommand != "" {
+		scoped.Config.ScopedVerifyCommand = project.ScopedVerifyCommand
+	}
+	return &scoped
+}
+
+func DiscoverEnvironment() (*Environment, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	// Look for nigel/ directory first, fall back to task-runner/ for backwards compatibility
+	runnerDir := filepath.Join(cwd, "nigel")
+	if _, err := os.Stat(runnerDir); os.IsNotExist(err) {
+		runnerDir = filepath.Join(cwd, "task-runner")
+		if _, err := os.Stat(runnerDir); os.IsNotExist(err) {
+			return nil, fmt.Errorf("no nigel/ or task-runner/ directory found in current directory")
+		}
+	}
+
+	configPath := filepath.Join(runnerDir, "config.yaml")
+	config, err := loadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Apply defaults
+	if config.ClaudeCommand == "" {
+		config.ClaudeCommand = "claude"
+	}
+
+	// Expand tilde in claude command
+	config.ClaudeCommand = expandTilde(config.ClaudeCommand)
+
+	// Color all further output; loadConfig has checked the theme
+	resolved, _ := config.Theme.Resolve()
+	SetTheme(resolved)
+
+	tasks, err := loadTasks(runnerDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tasks: %w", err)
+	}
+
+	for name, project := range config.Projects {
+		dir := expandTilde(project.Dir)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(cwd, dir)
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("project %s dir does not exist: %s", name, project.Dir)
+		}
+		project.Dir = dir
+		config.Projects[name] = project
+	}
+	if config.LogDir != "" {
+		config.LogDir = expandTilde(config.LogDir)
+		if !filepath.IsAbs(config.LogDir) {
+			config.LogDir = filepath.Join(cwd, config.LogDir)
+		}
+	}
+
+	bannerArt, err := loadBannerArt(config.Banner, runnerDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range tasks {
+		if _, ok := config.Projects[task.Project]; task.Project != "" && !ok {
+			return nil, fmt.Errorf("task %s references unknown project: %s", task.Name, task.Project)
+		}
+	}
+
+	if _, err := SortTasksByDependencies(tasks); err != nil {
+		return nil, err
+	}
+
+	playlists, err := loadPlaylists(runnerDir, tasks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load playlists: %w", err)
+	}
+
+	shards, err := loadShardAssignment(runnerDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Seed the random generator and generate a unique task ID
+	rand.Seed(time.Now().UnixNano())
+
+	return &Environment{
+		Config:     *config,
+		Tasks:      tasks,
+		Playlists:  playlists,
+		ProjectDir: cwd,
+		RunnerDir:  runnerDir,
+		TaskID:     rand.Int63(),
+		Shards:     shards,
+		bannerArt:  bannerArt,
+	}, nil
+}
+
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if config.GitAuthor != "" {
+		if _, _, err := parseIdentity(config.GitAuthor); err != nil {
+			return nil, fmt.Errorf("invalid git_author: %w", err)
+		}
+	}
+	if config.GitCommitter != "" {
+		if _, _, err := parseIdentity(config.GitCommitter); err != nil {
+			return nil, fmt.Errorf("invalid git_committer: %w", err)
+		}
+	}
+
+	for name, project := range config.Projects {
+		if project.Dir == "" {
+			return nil, fmt.Errorf("project %s missing required field 'dir'", name)
+		}
+	}
+
+	if err := validateMCPServers(config.MCPServers); err != nil {
+		return nil, fmt.Errorf("invalid mcp_servers: %w", err)
+	}
+	if err := validateEnv(config.Env); err != nil {
+		return nil, fmt.Errorf("invalid env: %w", err)
+	}
+
+	if _, err := newTransientMatcher(config.TransientErrors); err != nil {
+		return nil, fmt.Errorf("invalid transient_errors: %w", err)
+	}
+
+	if config.Email != nil {
+		if err := validateEmailConfig(config.Email); err != nil {
+			return nil, fmt.Errorf("invalid email: %w", err)
+		}
+	}
+
+	if _, err := config.Theme.Resolve(); err != nil {
+		return nil, fmt.Errorf("invalid theme: %w", err)
+	}
+	if config.ContextMaxBytes < 0 {
+		return nil, fmt.Errorf("invalid context_max_bytes %d: must not be negative", config.ContextMaxBytes)
+	}
+	if config.CandidateSourceMaxBytes < 0 {
+		return nil, fmt.Errorf("invalid candidate_source_max_bytes %d: must not be negative", config.CandidateSourceMaxBytes)
+	}
+	for _, path := range config.IgnoreDirtyPaths {
+		if strings.TrimSpace(path) == "" {
+			return nil, fmt.Errorf("invalid ignore_dirty_paths: paths must not be empty")
+		}
+	}
+	if config.VerifyRetries < 0 {
+		return nil, fmt.Errorf("invalid verify_retries %d: must not be negative", config.VerifyRetries)
+	}
+	if config.VerifyExcerptLines < 0 {
+		return nil, fmt.Errorf("invalid verify_excerpt_lines %d: must not be negative", config.VerifyExcerptLines)
+	}
+	switch config.ExternalEdits {
+	case "", ExternalEditsPrompt, ExternalEditsAbort:
+	default:
+		return nil, fmt.Errorf("invalid external_edits %q (want prompt or abort)", config.ExternalEdits)
+	}
+	switch config.Isolation {
+	case "", IsolationWorktree:
+	case IsolationContainer:
+		if err := validateContainerConfig(config.Container); err != nil {
+			return nil, fmt.Errorf("invalid container: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid isolation %q (want worktree or container)", config.Isolation)
+	}
+	if err := validateLogFilePattern(config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// loadTasks scans runnerDir for subdirectories containing task.yaml files.
+func loadTasks(runnerDir string) (map[string]Task, error) {
+	tasks := make(map[string]Task)
+
+	entries, err := os.ReadDir(runnerDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		taskDir := filepath.Join(runnerDir, entry.Name())
+		taskFile := filepath.Join(taskDir, "task.yaml")
+
+		if _, err := os.Stat(taskFile); os.IsNotExist(err) {
+			continue // not a task directory
+		}
+
+		task, err := loadTask(taskFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load task %s: %w", entry.Name(), err)
+		}
+
+		task.Name = entry.Name()
+		task.Dir = taskDir
+
+		// Expand tilde in claude command if present
+		task.ClaudeCommand = expandTilde(task.ClaudeCommand)
+		for i, dir := range task.PathPrepend {
+			task.PathPrepend[i] = expandTilde(dir)
+		}
+		for name, dir := range task.Toolchain {
+			if dir == "" {
+				return nil, fmt.Errorf("task %s has invalid 'toolchain': %s needs a directory", entry.Name(), name)
+			}
+			task.Toolchain[name] = expandTilde(dir)
+		}
+
+		// Apply defaults
+		if task.InactivityTimeout < 0 {
+			return nil, fmt.Errorf("task %s has invalid 'inactivity_timeout': must not be negative", entry.Name())
+		}
+		if task.Timeout == 0 {
+			task.Timeout = 1 * time.Hour
+		}
+
+		if task.CandidateSource == "" {
+			return nil, fmt.Errorf("task %s missing required field 'candidate_source'", entry.Name())
+		}
+		if len(task.Variants) > 0 {
+			if task.Prompt != "" || task.Template != "" {
+				return nil, fmt.Errorf("task %s cannot have 'variants' with 'prompt' or 'template'", entry.Name())
+			}
+			if err := validateVariants(task.Variants, &task.VariantAssignment); err != nil {
+				return nil, fmt.Errorf("task %s has invalid 'variants': %w", entry.Name(), err)
+			}
+		} else if task.Prompt == "" && task.Template == "" {
+			return nil, fmt.Errorf("task %s must have either 'prompt' or 'template'", entry.Name())
+		} else if task.VariantAssignment != "" {
+			return nil, fmt.Errorf("task %s has 'variant_assignment' but no 'variants'", entry.Name())
+		}
+		if task.Prompt != "" && task.Template != "" {
+			return nil, fmt.Errorf("task %s cannot have both 'prompt' and 'template'", entry.Name())
+		}
+		if task.TimeoutEscalation != nil {
+			if task.TimeoutEscalation.Multiplier == 0 {
+				task.TimeoutEscalation.Multiplier = 2
+			}
+			if task.TimeoutEscalation.Multiplier < 1 {
+				return nil, fmt.Errorf("task %s has invalid 'timeout_escalation.multiplier': must be at least 1", entry.Name())
+			}
+		}
+		switch task.OutputFormat {
+		case "", OutputStreamJSON, OutputJSON, OutputText:
+		default:
+			return nil, fmt.Errorf("task %s has invalid 'output_format' %q (expected stream-json, json, or text)", entry.Name(), task.OutputFormat)
+		}
+		if _, ok := lookupStreamParser(task.StreamParser); !ok {
+			return nil, fmt.Errorf("task %s has unknown 'stream_parser' %q (expected one of: %s)", entry.Name(), task.StreamParser, streamParserNames())
+		}
+		if task.OutputFormat != "" && task.StreamParser != "" && task.StreamParser != ParserClaudeStreamJSON {
+			return nil, fmt.Errorf("task %s has 'output_format', which only applies to stream_parser: %s", entry.Name(), ParserClaudeStreamJSON)
+		}
+		if (task.StreamParser == ParserSentinel) != (task.StreamSentinel != "") {
+			return nil, fmt.Errorf("task %s must set 'stream_sentinel' with stream_parser: %s, and only with it", entry.Name(), ParserSentinel)
+		}
+		if task.Cooldown < 0 {
+			return nil, fmt.Errorf("task %s has invalid 'cooldown': must not be negative", entry.Name())
+		}
+		if task.Repeat < 0 {
+			return nil, fmt.Errorf("task %s has invalid 'repeat': must not be negative", entry.Name())
+		}
+		if task.MaxNewCandidates < 0 {
+			return nil, fmt.Errorf("task %s has invalid 'max_new_candidates': must not be negative", entry.Name())
+		}
+		switch task.SourceCheck {
+		case "", SourceCheckWarn, SourceCheckRefuse:
+		default:
+			return nil, fmt.Errorf("task %s has invalid 'source_check' %q (expected warn or refuse)", entry.Name(), task.SourceCheck)
+		}
+		switch task.ZeroDiffFix {
+		case "", ZeroDiffFixed, ZeroDiffConfirm, ZeroDiffFlaky:
+		default:
+			return nil, fmt.Errorf("task %s has invalid 'zero_diff_fix' %q (expected fixed, confirm or flaky)", entry.Name(), task.ZeroDiffFix)
+		}
+		if filepath.IsAbs(task.Workdir) || strings.HasPrefix(filepath.Clean(task.Workdir), "..") {
+			return nil, fmt.Errorf("task %s 'workdir' must be a path inside the project", entry.Name())
+		}
+		switch task.IgnoreListRefresh {
+		case "", IgnoreListRefreshSession:
+		case IgnoreListRefreshIteration:
+			if task.IgnoreList == "" {
+				return nil, fmt.Errorf("task %s has 'ignore_list_refresh' without an 'ignore_list' command", entry.Name())
+			}
+		default:
+			return nil, fmt.Errorf("task %s has invalid 'ignore_list_refresh' %q (expected per-session or per-iteration)", entry.Name(), task.IgnoreListRefresh)
+		}
+		task.CommitBatch, err = parseCommitMode(task.CommitMode)
+		if err != nil {
+			return nil, fmt.Errorf("task %s has invalid 'commit_mode': %w", entry.Name(), err)
+		}
+		switch task.CommitStage {
+		case "", CommitStageAll:
+		case CommitStageEdited:
+			if task.CommitBatch == 0 {
+				return nil, fmt.Errorf("task %s has 'commit_stage: edited' without a batched 'commit_mode'", entry.Name())
+			}
+		default:
+			return nil, fmt.Errorf("task %s has invalid 'commit_stage' %q (expected all or edited)", entry.Name(), task.CommitStage)
+		}
+		if err := validateRequeue(task.Requeue); err != nil {
+			return nil, fmt.Errorf("task %s has invalid 'requeue': %w", entry.Name(), err)
+		}
+		for _, tool := range append(append([]string{}, task.AllowedTools...), task.DisallowedTools...) {
+			if strings.TrimSpace(tool) == "" || strings.Contains(tool, ",") {
+				return nil, fmt.Errorf("task %s has invalid tool %q in 'allowed_tools'/'disallowed_tools'", entry.Name(), tool)
+			}
+		}
+		if task.PromptLimit != nil {
+			if err := validatePromptLimit(*task.PromptLimit); err != nil {
+				return nil, fmt.Errorf("task %s has invalid 'prompt_limit': %w", entry.Name(), err)
+			}
+		}
+		if err := validateMCPServers(task.MCPServers); err != nil {
+			return nil, fmt.Errorf("task %s has invalid 'mcp_servers': %w", entry.Name(), err)
+		}
+		if err := validateEnv(task.Env); err != nil {
+			return nil, fmt.Errorf("task %s has invalid 'env': %w", entry.Name(), err)
+		}
+		if task.CandidateSchema != nil {
+			if err := validateCandidateSchema(*task.CandidateSchema); err != nil {
+				return nil, fmt.Errorf("task %s has invalid 'candidate_schema': %w", entry.Name(), err)
+			}
+		}
+		if task.CandidateFamilies != nil {
+			if err := validateCandidateFamilies(*task.CandidateFamilies); err != nil {
+				return nil, fmt.Errorf("task %s has invalid 'candidate_families': %w", entry.Name(), err)
+			}
+		}
+
+		tasks[task.Name] = *task
+	}
+
+	return tasks, nil
+}
+
+func loadTask(path string) (*Task, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var task Task
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&task); err != nil {
+		return nil, fmt.Errorf("failed to parse task: %w", err)
+	}
+
+	return &task, nil
+}
+
+// commitPerSession is the CommitBatch value for committing once at the end of a run.
+const commitPerSession = -1
+
+// `commit_stage:` settings for what a batched commit_mode stages for each fix.
+const (
+	CommitStageAll    = "all"    // Every change, including new untracked files
+	CommitStageEdited = "edited" // Changes to tracked files, and new files Claude's editing tools wrote
+)
+
+// `ignore_list_refresh:` settings for when an ignore_list command runs.
+const (
+	IgnoreListRefreshSession   = "per-session"   // Once, when the run starts
+	IgnoreListRefreshIteration = "per-iteration" // Again before every iteration after the first
+)
+
+// SortTasksByDependencies returns all task names ordered so that every task
+// comes after the tasks it depends on. Ties are broken alphabetically.
+// Returns an error for unknown dependencies or cycles.
+func SortTasksByDependencies(tasks map[string]Task) ([]string, error) {
+	remaining := make(map[string]int, len(tasks)) // task -> unmet dependency count
+	dependents := make(map[string][]string)
+	for name, task := range tasks {
+		remaining[name] = len(task.DependsOn)
+		for _, dep := range task.DependsOn {
+			if _, ok := tasks[dep]; !ok {
+				return nil, fmt.Errorf("task %s depends on unknown task %q", name, dep)
+			}
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	var ready []string
+	for name, n := range remaining {
+		if n == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	order := make([]string, 0, len(tasks))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+		for _, dependent := range dependents[name] {
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(order) != len(tasks) {
+		var cyclic []string
+		for name, n := range remaining {
+			if n > 0 {
+				cyclic = append(cyclic, name)
+			}
+		}
+		sort.Strings(cyclic)
+		return nil, fmt.Errorf("dependency cycle between tasks: %s", strings.Join(cyclic, ", "))
+	}
+
+	return order, nil
+}
+
+// loadPlaylists scans runnerDir for subdirectories containing playlist.yaml files.
+func loadPlaylists(runnerDir string, tasks map[string]Task) (map[string]Playlist, error) {
+	playlists := make(map[string]Playlist)
+
+	entries, err := os.ReadDir(runnerDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		playlistFile := filepath.Join(runnerDir, entry.Name(), "playlist.yaml")
+		if _, err := os.Stat(playlistFile); os.IsNotExist(err) {
+			continue // not a playlist directory
+		}
+		if _, isTask := tasks[entry.Name()]; isTask {
+			return nil, fmt.Errorf("%s cannot contain both task.yaml and playlist.yaml", entry.Name())
+		}
+
+		data, err := os.ReadFile(playlistFile)
+		if err != nil {
+			return nil, err
+		}
+		var playlist Playlist
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&playlist); err != nil {
+			return nil, fmt.Errorf("failed to parse playlist %s: %w", entry.Name(), err)
+		}
+		playlist.Name = entry.Name()
+
+		switch playlist.Mode {
+		case "":
+			playlist.Mode = "round-robin"
+		case "round-robin", "weighted":
+		default:
+			return nil, fmt.Errorf("playlist %s has unknown mode %q (expected round-robin or weighted)", entry.Name(), playlist.Mode)
+		}
+		if len(playlist.Tasks) == 0 {
+			return nil, fmt.Errorf("playlist %s has no tasks", entry.Name())
+		}
+		for i, t := range playlist.Tasks {
+			if _, ok := tasks[t.Task]; !ok {
+				return nil, fmt.Errorf("playlist %s references unknown task %q", entry.Name(), t.Task)
+			}
+			if t.Weight < 0 {
+				return nil, fmt.Errorf("playlist %s has negative weight for %s", entry.Name(), t.Task)
+			}
+			if t.Weight == 0 || playlist.Mode == "round-robin" {
+				playlist.Tasks[i].Weight = 1
+			}
+		}
+
+		playlists[playlist.Name] = playlist
+	}
+
+	return playlists, nil
+}
+
+// parseCommitMode converts a commit_mode value to a batch size.
+// Returns 0 for per-candidate, N for every-N, or commitPerSession.
+func parseCommitMode(mode string) (int, error) {
+	switch mode {
+	case "", "per-candidate":
+		return 0, nil
+	case "per-session":
+		return commitPerSession, nil
+	}
+	if n, ok := strings.CutPrefix(mode, "every-"); ok {
+		batch, err := strconv.Atoi(n)
+		if err != nil || batch < 1 {
+			return 0, fmt.Errorf("%q must be every-N with N >= 1", mode)
+		}
+		if batch == 1 {
+			return 0, nil
+		}
+		return batch, nil
+	}
+	return 0, fmt.Errorf("unknown mode %q (expected per-candidate, per-session, or every-N)", mode)
+}
+
+// validateRequeue checks that requeue keys are known outcomes and values are known policies.
+func validateRequeue(requeue map[Outcome]RequeuePolicy) error {
+	for outcome, policy := range requeue {
+		switch outcome {
+		case OutcomeFixedReverted, OutcomeNotFixed, OutcomeBestEffort, OutcomeBuildFailed, OutcomeTimeout, OutcomeScanFailed, OutcomeRegression, OutcomePromptTooLarge, OutcomeFlakySource:
+		default:
+			return fmt.Errorf("unknown outcome %q", outcome)
+		}
+		switch policy {
+		case RequeueIgnore, RequeueNextSession:
+		case RequeueDoubleTimeout:
+			if outcome != OutcomeTimeout {
+				return fmt.Errorf("%s is only valid for %s", policy, OutcomeTimeout)
+			}
+		default:
+			return fmt.Errorf("unknown policy %q for %s", policy, outcome)
+		}
+	}
+	return nil
+}
+
+// validateCandidateSchema checks that a schema's options match its type.
+func validateCandidateSchema(schema CandidateSchema) error {
+	switch schema.Type {
+	case "string", "array", "object":
+	default:
+		return fmt.Errorf("unknown type %q (expected string, array, or object)", schema.Type)
+	}
+	if len(schema.Required) > 0 && schema.Type != "object" {
+		return fmt.Errorf("'required' is only valid for object candidates")
+	}
+	if schema.MinItems < 0 {
+		return fmt.Errorf("'min_items' must not be negative")
+	}
+	if schema.MinItems > 0 && schema.Type != "array" {
+		return fmt.Errorf("'min_items' is only valid for array candidates")
+	}
+	return nil
+}
+
+// parseIdentity splits a git identity of the form "Name <email>".
+func parseIdentity(identity string) (name, email string, err error) {
+	open := strings.LastIndex(identity, "<")
+	if open < 0 || !strings.HasSuffix(identity, ">") {
+		return "", "", fmt.Errorf("%q must be in the form \"Name <email>\"", identity)
+	}
+	name = strings.TrimSpace(identity[:open])
+	email = strings.TrimSpace(identity[open+1 : len(identity)-1])
+	if name == "" || email == "" {
+		return "", "", fmt.Errorf("%q must be in the form \"Name <email>\"", identity)
+	}
+	return name, email, nil
+}
+
+// GitEnvPrefix returns shell exports that apply the configured commit identity
+// and signing policy to any git commands that follow. Empty if none configured.
+func (c *Config) GitEnvPrefix() string {
+	var exports []string
+	if name, email, err := parseIdentity(c.GitAuthor); err == nil {
+		exports = append(exports, "GIT_AUTHOR_NAME="+shellQuote(name), "GIT_AUTHOR_EMAIL="+shellQuote(email))
+	}
+	if name, email, err := parseIdentity(c.GitCommitter); err == nil {
+		exports = append(exports, "GIT_COMMITTER_NAME="+shellQuote(name), "GIT_COMMITTER_EMAIL="+shellQuote(email))
+	}
+	if c.SignCommits {
+		// Equivalent to `git -c commit.gpgsign=true` for every git invocation.
+		// Appended after any GIT_CONFIG_* entries already in the environment;
+		// the words expand before export assigns any of them.
+		exports = append(exports,
+			`"GIT_CONFIG_KEY_${GIT_CONFIG_COUNT:-0}=commit.gpgsign"`,
+			`"GIT_CONFIG_VALUE_${GIT_CONFIG_COUNT:-0}=true"`,
+			`GIT_CONFIG_COUNT=$((${GIT_CONFIG_COUNT:-0} + 1))`)
+	}
+	if len(exports) == 0 {
+		return ""
+	}
+	return "export " + strings.Join(exports, " ") + "; "
+}
+
+// TrailersEnabled reports whether commits get Nigel-* trailers.
+func (c *Config) TrailersEnabled() bool {
+	return c.CommitTrailers == nil || *c.CommitTrailers
+}
+
+// SavesCommandOutput reports whether verify and reset output is saved to files.
+func (c *Config) SavesCommandOutput() bool {
+	return c.CommandOutput == nil || *c.CommandOutput
+}
+
+// expandTilde expands ~ to the user's home directory.
+func expandTilde(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return path
+		}
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
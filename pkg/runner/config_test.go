@@ -0,0 +1,550 @@
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigRejectsUnknownFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{
+			name: "valid config",
+			yaml: `
+claude_command: "/path/to/claude"
+verify_command: "cargo check"
+success_command: "git commit -m 'Fix: $CANDIDATE'"
+reset_command: "git reset --hard"
+`,
+			wantErr: false,
+		},
+		{
+			name: "unknown field",
+			yaml: `
+claude_command: "claude"
+rofnkjsnfke3: "bad"
+`,
+			wantErr: true,
+		},
+		{
+			name: "typo in claude_command",
+			yaml: `
+cluade_command: "claude"
+`,
+			wantErr: true,
+		},
+		{
+			name: "typo in verify_command",
+			yaml: `
+claude_command: "claude"
+verify_comand: "cargo check"
+`,
+			wantErr: true,
+		},
+		{
+			name: "minimal valid config",
+			yaml: `
+claude_command: "claude"
+`,
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create temp file
+			tmpDir := t.TempDir()
+			configPath := filepath.Join(tmpDir, "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := loadConfig(configPath)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadTaskRejectsUnknownFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{
+			name: "valid task with prompt",
+			yaml: `
+candidate_source: "cargo check 2>&1 | grep error"
+prompt: "Fix this issue: $INPUT"
+`,
+			wantErr: false,
+		},
+		{
+			name: "valid task with template",
+			yaml: `
+candidate_source: "cargo check 2>&1 | grep error"
+template: "template.txt"
+`,
+			wantErr: false,
+		},
+		{
+			name: "valid task with all fields",
+			yaml: `
+candidate_source: "cargo check 2>&1 | grep error"
+prompt: "Fix this issue: $INPUT"
+claude_flags: "--fast"
+claude_command: "/custom/claude"
+accept_best_effort: true
+`,
+			wantErr: false,
+		},
+		{
+			name: "unknown field",
+			yaml: `
+candidate_source: "cargo check"
+prompt: "fix it"
+unknown_field: "value"
+`,
+			wantErr: true,
+		},
+		{
+			name: "typo in candidate_source",
+			yaml: `
+candiate_source: "cargo check"
+prompt: "fix it"
+`,
+			wantErr: true,
+		},
+		{
+			name: "typo in accept_best_effort",
+			yaml: `
+candidate_source: "cargo check"
+prompt: "fix it"
+accept_best_effort: truee
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create temp file
+			tmpDir := t.TempDir()
+			taskPath := filepath.Join(tmpDir, "task.yaml")
+			if err := os.WriteFile(taskPath, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := loadTask(taskPath)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadTask() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRequeue(t *testing.T) {
+	tests := []struct {
+		name    string
+		requeue map[Outcome]RequeuePolicy
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			requeue: nil,
+			wantErr: false,
+		},
+		{
+			name: "valid policies",
+			requeue: map[Outcome]RequeuePolicy{
+				OutcomeBuildFailed: RequeueNextSession,
+				OutcomeNotFixed:    RequeueIgnore,
+				OutcomeTimeout:     RequeueDoubleTimeout,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "unknown outcome",
+			requeue: map[Outcome]RequeuePolicy{"BROKEN": RequeueIgnore},
+			wantErr: true,
+		},
+		{
+			name:    "fixed outcome cannot be requeued",
+			requeue: map[Outcome]RequeuePolicy{OutcomeFixed: RequeueIgnore},
+			wantErr: true,
+		},
+		{
+			name:    "unknown policy",
+			requeue: map[Outcome]RequeuePolicy{OutcomeNotFixed: "sometimes"},
+			wantErr: true,
+		},
+		{
+			name:    "doubled timeout on non-timeout outcome",
+			requeue: map[Outcome]RequeuePolicy{OutcomeNotFixed: RequeueDoubleTimeout},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRequeue(tt.requeue)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRequeue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCandidateSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  CandidateSchema
+		wantErr bool
+	}{
+		{name: "string", schema: CandidateSchema{Type: "string"}},
+		{name: "object with required keys", schema: CandidateSchema{Type: "object", Required: []string{"file"}}},
+		{name: "array with min items", schema: CandidateSchema{Type: "array", MinItems: 2}},
+		{name: "missing type", schema: CandidateSchema{}, wantErr: true},
+		{name: "unknown type", schema: CandidateSchema{Type: "map"}, wantErr: true},
+		{name: "required on array", schema: CandidateSchema{Type: "array", Required: []string{"file"}}, wantErr: true},
+		{name: "min items on object", schema: CandidateSchema{Type: "object", MinItems: 1}, wantErr: true},
+		{name: "negative min items", schema: CandidateSchema{Type: "array", MinItems: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCandidateSchema(tt.schema)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateCandidateSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseCommitMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    int
+		wantErr bool
+	}{
+		{mode: "", want: 0},
+		{mode: "per-candidate", want: 0},
+		{mode: "per-session", want: commitPerSession},
+		{mode: "every-5", want: 5},
+		{mode: "every-1", want: 0},
+		{mode: "every-0", wantErr: true},
+		{mode: "every-x", wantErr: true},
+		{mode: "hourly", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			got, err := parseCommitMode(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCommitMode(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseCommitMode(%q) = %d, want %d", tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitEnvPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{
+			name:     "nothing configured",
+			config:   Config{},
+			expected: "",
+		},
+		{
+			name:     "author only",
+			config:   Config{GitAuthor: "Nigel Bot <nigel@example.com>"},
+			expected: "export GIT_AUTHOR_NAME='Nigel Bot' GIT_AUTHOR_EMAIL='nigel@example.com'; ",
+		},
+		{
+			name: "author, committer and signing",
+			config: Config{
+				GitAuthor:    "Nigel Bot <nigel@example.com>",
+				GitCommitter: "CI <ci@example.com>",
+				SignCommits:  true,
+			},
+			expected: "export GIT_AUTHOR_NAME='Nigel Bot' GIT_AUTHOR_EMAIL='nigel@example.com' " +
+				"GIT_COMMITTER_NAME='CI' GIT_COMMITTER_EMAIL='ci@example.com' " +
+				`"GIT_CONFIG_KEY_${GIT_CONFIG_COUNT:-0}=commit.gpgsign" "GIT_CONFIG_VALUE_${GIT_CONFIG_COUNT:-0}=true" ` +
+				`GIT_CONFIG_COUNT=$((${GIT_CONFIG_COUNT:-0} + 1)); `,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.GitEnvPrefix(); got != tt.expected {
+				t.Errorf("GitEnvPrefix() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("signing keeps the environment's config entries", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git not installed")
+		}
+		config := Config{SignCommits: true}
+		cmd := exec.Command("sh", "-c", config.GitEnvPrefix()+"git config --get user.name; git config --get commit.gpgsign")
+		cmd.Dir = t.TempDir()
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=user.name", "GIT_CONFIG_VALUE_0=From Env")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("git config failed: %v", err)
+		}
+		if string(output) != "From Env\ntrue\n" {
+			t.Errorf("git config = %q, want the environment's user.name and commit.gpgsign", output)
+		}
+	})
+}
+
+func TestLoadConfigValidatesIdentity(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{
+			name:    "valid identity",
+			yaml:    `git_author: "Nigel Bot <nigel@example.com>"`,
+			wantErr: false,
+		},
+		{
+			name:    "missing email",
+			yaml:    `git_author: "Nigel Bot"`,
+			wantErr: true,
+		},
+		{
+			name:    "missing name",
+			yaml:    `git_committer: "<nigel@example.com>"`,
+			wantErr: true,
+		},
+		{
+			name:    "project with dir",
+			yaml:    "projects:\n  infra:\n    dir: ../infra",
+			wantErr: false,
+		},
+		{
+			name:    "project missing dir",
+			yaml:    "projects:\n  infra:\n    verify_command: make check",
+			wantErr: true,
+		},
+		{
+			name:    "log_dir with default pattern",
+			yaml:    "log_dir: /var/log/nigel",
+			wantErr: false,
+		},
+		{
+			name:    "log_dir pattern without task name",
+			yaml:    "log_dir: /var/log/nigel\nlog_file_pattern: claude-$DATE.log",
+			wantErr: true,
+		},
+		{
+			name:    "built-in theme",
+			yaml:    "theme: mono",
+			wantErr: false,
+		},
+		{
+			name:    "unknown theme",
+			yaml:    "theme: neon",
+			wantErr: true,
+		},
+		{
+			name:    "theme with bad color",
+			yaml:    "theme:\n  base: light\n  success: chartreuse",
+			wantErr: true,
+		},
+		{
+			name:    "external_edits prompt",
+			yaml:    "external_edits: prompt",
+			wantErr: false,
+		},
+		{
+			name:    "unknown external_edits",
+			yaml:    "external_edits: ignore",
+			wantErr: true,
+		},
+		{
+			name:    "worktree isolation",
+			yaml:    "isolation: worktree",
+			wantErr: false,
+		},
+		{
+			name:    "unknown isolation",
+			yaml:    "isolation: vm",
+			wantErr: true,
+		},
+		{
+			name:    "container isolation",
+			yaml:    "isolation: container\ncontainer:\n  image: nigel-sandbox\n  args: [-e, ANTHROPIC_API_KEY]",
+			wantErr: false,
+		},
+		{
+			name:    "container isolation without an image",
+			yaml:    "isolation: container",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := loadConfig(configPath)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSortTasksByDependencies(t *testing.T) {
+	t.Run("orders prerequisites first", func(t *testing.T) {
+		tasks := map[string]Task{
+			"coverage": {Name: "coverage", DependsOn: []string{"build", "lint"}},
+			"lint":     {Name: "lint", DependsOn: []string{"build"}},
+			"build":    {Name: "build"},
+			"docs":     {Name: "docs"},
+		}
+
+		order, err := SortTasksByDependencies(tasks)
+		if err != nil {
+			t.Fatalf("SortTasksByDependencies failed: %v", err)
+		}
+		expected := []string{"build", "docs", "lint", "coverage"}
+		if len(order) != len(expected) {
+			t.Fatalf("order = %v, want %v", order, expected)
+		}
+		for i := range expected {
+			if order[i] != expected[i] {
+				t.Fatalf("order = %v, want %v", order, expected)
+			}
+		}
+	})
+
+	t.Run("unknown dependency", func(t *testing.T) {
+		tasks := map[string]Task{"lint": {Name: "lint", DependsOn: []string{"missing"}}}
+		if _, err := SortTasksByDependencies(tasks); err == nil {
+			t.Error("expected error for unknown dependency")
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		tasks := map[string]Task{
+			"a": {Name: "a", DependsOn: []string{"b"}},
+			"b": {Name: "b", DependsOn: []string{"a"}},
+			"c": {Name: "c"},
+		}
+		if _, err := SortTasksByDependencies(tasks); err == nil {
+			t.Error("expected error for dependency cycle")
+		}
+	})
+}
+
+func TestTaskWorkDir(t *testing.T) {
+	tests := []struct {
+		name    string
+		workdir string
+		want    string
+	}{
+		{name: "defaults to project dir", workdir: "", want: "/repo"},
+		{name: "joins subdirectory", workdir: "services/api", want: "/repo/services/api"},
+		{name: "cleans path", workdir: "./web/", want: "/repo/web"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := Task{Workdir: tt.workdir}
+			if got := task.WorkDir("/repo"); got != tt.want {
+				t.Errorf("WorkDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskToolFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		task Task
+		want string
+	}{
+		{
+			name: "safe default",
+			task: Task{},
+			want: `--disallowedTools 'Bash(git commit:*),Bash(git push:*)'`,
+		},
+		{
+			name: "allowed tools keep the default denials",
+			task: Task{AllowedTools: []string{"Edit", "Read"}},
+			want: `--allowedTools 'Edit,Read' --disallowedTools 'Bash(git commit:*),Bash(git push:*)'`,
+		},
+		{
+			name: "explicit disallowed tools replace the default",
+			task: Task{DisallowedTools: []string{"Bash"}},
+			want: `--disallowedTools 'Bash'`,
+		},
+		{
+			name: "empty list opts out",
+			task: Task{DisallowedTools: []string{}},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.task.ToolFlags(); got != tt.want {
+				t.Errorf("ToolFlags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvironmentForTask(t *testing.T) {
+	env := &Environment{
+		ProjectDir: "/app",
+		Config: Config{
+			VerifyCommand:  "make test",
+			ResetCommand:   "git reset --hard",
+			SuccessCommand: "git commit -am fix",
+			Projects: map[string]Project{
+				"infra": {Dir: "/infra", VerifyCommand: "terraform validate"},
+			},
+		},
+	}
+
+	if got := env.ForTask(Task{Name: "app-task"}); got != env {
+		t.Error("ForTask() without project should return the shared environment")
+	}
+
+	scoped := env.ForTask(Task{Name: "infra-task", Project: "infra"})
+	if scoped.ProjectDir != "/infra" {
+		t.Errorf("ProjectDir = %q, want /infra", scoped.ProjectDir)
+	}
+	if scoped.Config.VerifyCommand != "terraform validate" {
+		t.Errorf("VerifyCommand = %q, want project override", scoped.Config.VerifyCommand)
+	}
+	if scoped.Config.ResetCommand != "git reset --hard" {
+		t.Errorf("ResetCommand = %q, want inherited default", scoped.Config.ResetCommand)
+	}
+	if env.ProjectDir != "/app" || env.Config.VerifyCommand != "make test" {
+		t.Error("ForTask() modified the shared environment")
+	}
+}
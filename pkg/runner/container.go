@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ContainerConfig is the `container:` setting used with isolation: container.
+type ContainerConfig struct {
+	Image   string   `yaml:"image"`   // Image to run commands in; needs bash, git and the claude CLI
+	Runtime string   `yaml:"runtime"` // docker (default) or another CLI with the same run flags, e.g. podman
+	Args    []string `yaml:"args"`    // Extra run arguments, e.g. ["-e", "ANTHROPIC_API_KEY"]
+}
+
+func validateContainerConfig(c *ContainerConfig) error {
+	if c == nil || c.Image == "" {
+		return errors.New("image is required with isolation: container")
+	}
+	return nil
+}
+
+// run returns the command that starts a throwaway container with mountDir
+// bind-mounted at the same path, so paths mean the same inside and out, and
+// workDir as the working directory. It runs as the current user so files it
+// writes stay owned by them.
+func (c *ContainerConfig) run(mountDir, workDir string) string {
+	runtime := c.Runtime
+	if runtime == "" {
+		runtime = "docker"
+	}
+	parts := []string{
+		runtime, "run", "--rm", "-i", "--init",
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		"-v", shellQuote(mountDir + ":" + mountDir),
+		"-w", shellQuote(workDir),
+	}
+	for _, arg := range c.Args {
+		parts = append(parts, shellQuote(arg))
+	}
+	parts = append(parts, shellQuote(c.Image))
+	return strings.Join(parts, " ")
+}
+
+// wrap returns a shell command that runs command in the container.
+func (c *ContainerConfig) wrap(mountDir, workDir, command string) string {
+	return c.run(mountDir, workDir) + " bash -c " + shellQuote(command)
+}
+
+// wrapPrefix returns a shell command that runs command in the container with
+// any arguments appended to the result passed on to it, for the claude
+// command nigel adds flags and the prompt to.
+func (c *ContainerConfig) wrapPrefix(mountDir, workDir, command string) string {
+	return c.run(mountDir, workDir) + " bash -c " + shellQuote(command+` "$@"`) + " nigel"
+}
+
+// containerExecutor runs shell commands in the task's container. Git queries
+// still run on the host, against the bind-mounted clone.
+type containerExecutor struct {
+	CommandExecutor
+	container *ContainerConfig
+	mountDir  string
+}
+
+func (e containerExecutor) Run(command, workDir string) (bool, error) {
+	return e.CommandExecutor.Run(e.container.wrap(e.mountDir, workDir, command), workDir)
+}
+
+func (e containerExecutor) RunSilent(command, workDir string) (bool, error) {
+	return e.CommandExecutor.RunSilent(e.container.wrap(e.mountDir, workDir, command), workDir)
+}
+
+func (e containerExecutor) RunShowOnFail(command, workDir string) (bool, string, error) {
+	return e.CommandExecutor.RunShowOnFail(e.container.wrap(e.mountDir, workDir, command), workDir)
+}
+
+func (e containerExecutor) RunWatched(command, workDir string, onLine func(string)) (bool, string, error) {
+	return e.CommandExecutor.RunWatched(e.container.wrap(e.mountDir, workDir, command), workDir, onLine)
+}
+
+func (e containerExecutor) RunWithTimeout(command, workDir string, timeout time.Duration) (bool, error) {
+	return e.CommandExecutor.RunWithTimeout(e.container.wrap(e.mountDir, workDir, command), workDir, timeout)
+}
@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContainerWrap(t *testing.T) {
+	c := &ContainerConfig{Image: "nigel-sandbox", Runtime: "podman", Args: []string{"-e", "ANTHROPIC_API_KEY"}}
+	got := c.wrap("/tmp/clone", "/tmp/clone/api", "make test")
+	want := fmt.Sprintf("podman run --rm -i --init --user %d:%d -v '/tmp/clone:/tmp/clone' -w '/tmp/clone/api' '-e' 'ANTHROPIC_API_KEY' 'nigel-sandbox' bash -c 'make test'",
+		os.Getuid(), os.Getgid())
+	if got != want {
+		t.Errorf("wrap() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestContainerWrapPrefixPassesArguments(t *testing.T) {
+	// A fake runtime that skips the run flags up to the image and runs the rest
+	dir := t.TempDir()
+	runtime := filepath.Join(dir, "fake-docker")
+	script := `#!/bin/bash
+while [ "$1" != "img" ]; do shift; done
+shift
+exec "$@"
+`
+	if err := os.WriteFile(runtime, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &ContainerConfig{Image: "img", Runtime: runtime}
+	command := c.wrapPrefix(dir, dir, "echo claude") + " --model 'opus 4' -p"
+	output, err := exec.Command("bash", "-c", command).Output()
+	if err != nil {
+		t.Fatalf("wrapped command failed: %v", err)
+	}
+	if got := strings.TrimSpace(string(output)); got != "claude --model opus 4 -p" {
+		t.Errorf("output = %q, want the appended arguments passed through", got)
+	}
+}
+
+func TestContainerExecutorWrapsCommands(t *testing.T) {
+	mock := NewMockCommandExecutor()
+	c := &ContainerConfig{Image: "img"}
+	executor := containerExecutor{CommandExecutor: mock, container: c, mountDir: "/tmp/clone"}
+
+	executor.RunSilent("git reset --hard", "/tmp/clone")
+	if !mock.CalledWith(c.wrap("/tmp/clone", "/tmp/clone", "git reset --hard")) {
+		t.Errorf("expected the command to run in the container, got %v", mock.Calls)
+	}
+}
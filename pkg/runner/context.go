@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultContextMaxBytes caps each context file when context_max_bytes isn't set.
+const defaultContextMaxBytes = 16 * 1024
+
+// contextFile is a context file's contents as of its last read.
+type contextFile struct {
+	modTime time.Time
+	size    int64
+	text    string
+}
+
+// contextFiles returns the context_files for the task: the global ones, then
+// the task's own, without repeats.
+func (r *Runner) contextFiles() []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, file := range append(append([]string{}, r.env.Config.ContextFiles...), r.task.ContextFiles...) {
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// promptContext returns the context files' contents to append to every
+// prompt, or "" without any. Each file is capped at context_max_bytes and only
+// re-read when it changes, so a run doesn't read the same style guide for
+// every candidate. Relative paths are in the project.
+func (r *Runner) promptContext() (string, error) {
+	files := r.contextFiles()
+	if len(files) == 0 {
+		return "", nil
+	}
+	if r.contextCache == nil {
+		r.contextCache = make(map[string]contextFile)
+	}
+	maxBytes := r.env.Config.ContextMaxBytes
+	if maxBytes == 0 {
+		maxBytes = defaultContextMaxBytes
+	}
+
+	var b strings.Builder
+	for _, file := range files {
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(r.env.ProjectDir, path)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fatalError(ErrAgent, "failed to read context file: %w", err)
+		}
+		cached, ok := r.contextCache[path]
+		if !ok || !cached.modTime.Equal(info.ModTime()) || cached.size != info.Size() {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", fatalError(ErrAgent, "failed to read context file: %w", err)
+			}
+			cached = contextFile{modTime: info.ModTime(), size: info.Size(), text: capContext(string(data), maxBytes)}
+			r.contextCache[path] = cached
+		}
+		fmt.Fprintf(&b, "\n\nConventions from %s:\n\n%s", file, strings.TrimRight(cached.text, "\n"))
+	}
+	return b.String(), nil
+}
+
+// capContext cuts text to at most maxBytes, at a line break where there is
+// one, noting how much was left out.
+func capContext(text string, maxBytes int) string {
+	if len(text) <= maxBytes {
+		return text
+	}
+	cut := text[:maxBytes]
+	if i := strings.LastIndex(cut, "\n"); i > 0 {
+		cut = cut[:i+1]
+	}
+	return cut + fmt.Sprintf("[... %d more bytes not shown]\n", len(text)-len(cut))
+}
@@ -0,0 +1,51 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPromptContext(t *testing.T) {
+	tmpDir := t.TempDir()
+	guide := filepath.Join(tmpDir, "CONTRIBUTING.md")
+	os.WriteFile(guide, []byte("Use tabs.\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "style.md"), []byte("line one\nline two\nline three\n"), 0644)
+
+	env := &Environment{
+		ProjectDir: tmpDir,
+		Config:     Config{ContextFiles: []string{"CONTRIBUTING.md"}, ContextMaxBytes: 20},
+		Tasks: map[string]Task{
+			"test-task": {Name: "test-task", Dir: tmpDir, Prompt: "Fix $INPUT", ContextFiles: []string{"style.md", "CONTRIBUTING.md"}},
+		},
+	}
+	runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("NewRunner failed: %v", err)
+	}
+
+	candidate := &Candidate{Key: "a.go", Data: []byte(`"a.go"`)}
+	prompt, err := runner.getPrompt(candidate)
+	if err != nil {
+		t.Fatalf("getPrompt failed: %v", err)
+	}
+	want := "Fix a.go\n\nConventions from CONTRIBUTING.md:\n\nUse tabs." +
+		"\n\nConventions from style.md:\n\nline one\nline two\n[... 11 more bytes not shown]"
+	if prompt != want {
+		t.Errorf("prompt = %q, want %q", prompt, want)
+	}
+
+	// Edits are picked up; unchanged files come from the cache
+	os.WriteFile(guide, []byte("Use spaces.\n"), 0644)
+	os.Chtimes(guide, time.Now().Add(time.Minute), time.Now().Add(time.Minute))
+	if prompt, _ = runner.getPrompt(candidate); !strings.Contains(prompt, "Use spaces.") {
+		t.Errorf("edited context file not re-read:\n%s", prompt)
+	}
+
+	os.Remove(guide)
+	if _, err := runner.getPrompt(candidate); err == nil {
+		t.Error("expected an error for a missing context file")
+	}
+}
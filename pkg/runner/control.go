@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// RunControl lets another goroutine pause, resume or stop a run. Pauses and
+// stops take effect between iterations. Pass one in RunnerOptions.Control;
+// without one a run can only be stopped by signals.
+type RunControl struct {
+	stop atomic.Bool
+
+	mu     sync.Mutex
+	paused bool
+	resume chan struct{} // Closed when the current pause ends
+}
+
+// NewRunControl creates a control for a run that is neither paused nor stopped.
+func NewRunControl() *RunControl {
+	return &RunControl{}
+}
+
+// Stop asks the run to finish after the current iteration. It also ends a pause.
+func (c *RunControl) Stop() {
+	c.stop.Store(true)
+	c.Resume()
+}
+
+// Stopped reports whether Stop has been called.
+func (c *RunControl) Stopped() bool {
+	return c.stop.Load()
+}
+
+// Pause holds the run before its next iteration until Resume or Stop.
+func (c *RunControl) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.paused {
+		c.paused = true
+		c.resume = make(chan struct{})
+	}
+}
+
+// Resume lets a paused run continue.
+func (c *RunControl) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused {
+		c.paused = false
+		close(c.resume)
+	}
+}
+
+// Paused reports whether the run is paused.
+func (c *RunControl) Paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// waitWhilePaused blocks while the run is paused. Safe on a nil control.
+func (c *RunControl) waitWhilePaused() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	resume, paused := c.resume, c.paused
+	c.mu.Unlock()
+	if paused {
+		<-resume
+	}
+}
+
+// stopFlag returns the flag Stop sets, shared with the signal handler. Without
+// a control, a fresh flag is returned.
+func (c *RunControl) stopFlag() *atomic.Bool {
+	if c == nil {
+		return &atomic.Bool{}
+	}
+	return &c.stop
+}
@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunControlPauseResume(t *testing.T) {
+	c := NewRunControl()
+	c.Pause()
+	if !c.Paused() {
+		t.Fatal("Paused() = false after Pause")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.waitWhilePaused()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("waitWhilePaused returned while paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	c.Resume()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waitWhilePaused did not return after Resume")
+	}
+	if c.Paused() {
+		t.Error("Paused() = true after Resume")
+	}
+}
+
+func TestRunControlStopEndsPause(t *testing.T) {
+	c := NewRunControl()
+	c.Pause()
+	c.Stop()
+	if !c.Stopped() || c.Paused() {
+		t.Errorf("Stopped() = %v, Paused() = %v, want true, false", c.Stopped(), c.Paused())
+	}
+	if !c.stopFlag().Load() {
+		t.Error("stopFlag not set by Stop")
+	}
+	c.waitWhilePaused() // Must not block
+
+	var nilControl *RunControl
+	nilControl.waitWhilePaused()
+	if nilControl.stopFlag().Load() {
+		t.Error("nil control stop flag set")
+	}
+}
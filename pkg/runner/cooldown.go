@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadCooldowns indexes cooldown.log, one "<unix time> <key>" line per
+// failed attempt at a candidate on cooldown until that time. The latest line
+// for a key wins. The file is compacted if it has lines that have expired,
+// been superseded, or are for keys that have since been ignored.
+func (l *IgnoredList) loadCooldowns(content string) error {
+	l.cooldowns = make(map[string]time.Time)
+	now := time.Now()
+	lines := 0
+	eachLine(content, func(line string) {
+		lines++
+		stamp, key, ok := strings.Cut(line, " ")
+		seconds, err := strconv.ParseInt(stamp, 10, 64)
+		if !ok || err != nil {
+			return
+		}
+		if until := time.Unix(seconds, 0); until.After(now) && !l.persisted(key) {
+			l.cooldowns[key] = until
+		} else {
+			delete(l.cooldowns, key)
+		}
+	})
+	if lines == len(l.cooldowns) {
+		return nil
+	}
+	kept := make([]string, 0, len(l.cooldowns))
+	for key, until := range l.cooldowns {
+		kept = append(kept, cooldownLine(key, until))
+	}
+	return writeLines(l.cooldownPath, kept)
+}
+
+func cooldownLine(key string, until time.Time) string {
+	return strconv.FormatInt(until.Unix(), 10) + " " + key
+}
+
+// CoolDown keeps a key out of the candidates for d (the task's cooldown),
+// after which it's eligible again. Command-based lists only remember the
+// cooldown for this run.
+func (l *IgnoredList) CoolDown(key string, d time.Duration) error {
+	if err := l.wait(); err != nil {
+		return err
+	}
+	until := time.Now().Add(d).Truncate(time.Second)
+	l.cooldowns[key] = until
+	if l.cooldownPath == "" {
+		return nil
+	}
+	file, err := os.OpenFile(l.cooldownPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open cooldown log for writing: %w", err)
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintln(file, cooldownLine(key, until)); err != nil {
+		return fmt.Errorf("failed to write to cooldown log: %w", err)
+	}
+	return nil
+}
+
+// coolingDown reports whether key is on cooldown.
+func (l *IgnoredList) coolingDown(key string) bool {
+	until, ok := l.cooldowns[key]
+	return ok && time.Now().Before(until)
+}
+
+// Cooldowns returns how many of candidates are on cooldown and when the first
+// of them becomes eligible again.
+func (l *IgnoredList) Cooldowns(candidates []Candidate) (int, time.Time) {
+	l.wait()
+	count := 0
+	var next time.Time
+	for _, c := range candidates {
+		if l.persisted(c.Key) || !l.coolingDown(c.Key) {
+			continue
+		}
+		count++
+		if until := l.cooldowns[c.Key]; next.IsZero() || until.Before(next) {
+			next = until
+		}
+	}
+	return count, next
+}
@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCooldown(t *testing.T) {
+	t.Run("keeps a candidate out until the cooldown passes", func(t *testing.T) {
+		dir := t.TempDir()
+		list, _ := NewIgnoredList(dir)
+		if err := list.CoolDown("flaky_test.go", time.Hour); err != nil {
+			t.Fatalf("CoolDown failed: %v", err)
+		}
+		if !list.Contains("flaky_test.go") {
+			t.Error("expected flaky_test.go to be skipped while cooling down")
+		}
+		count, next := list.Cooldowns([]Candidate{{Key: "flaky_test.go"}, {Key: "other.go"}})
+		if count != 1 || time.Until(next) < 59*time.Minute {
+			t.Errorf("Cooldowns = (%d, %v), want 1 about an hour from now", count, next)
+		}
+
+		// Still cooling down in the next run
+		reloaded, _ := NewIgnoredList(dir)
+		if !reloaded.Contains("flaky_test.go") {
+			t.Error("cooldown was lost on reload")
+		}
+	})
+
+	t.Run("expired cooldowns are dropped on load", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "cooldown.log")
+		past := time.Now().Add(-time.Minute).Unix()
+		future := time.Now().Add(time.Hour).Unix()
+		os.WriteFile(path, []byte(fmt.Sprintf("%d a.go\n%d b.go\n", past, future)), 0644)
+
+		list, err := NewIgnoredList(dir)
+		if err != nil {
+			t.Fatalf("NewIgnoredList failed: %v", err)
+		}
+		if list.Contains("a.go") || !list.Contains("b.go") {
+			t.Error("expected only b.go to still be cooling down")
+		}
+		content, _ := os.ReadFile(path)
+		if want := fmt.Sprintf("%d b.go\n", future); string(content) != want {
+			t.Errorf("cooldown.log = %q, want %q", content, want)
+		}
+	})
+
+	t.Run("requeue uses the cooldown unless the outcome has a policy", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		env := &Environment{
+			ProjectDir: tmpDir,
+			Tasks: map[string]Task{
+				"test-task": {
+					Name: "test-task", Dir: tmpDir, Prompt: "test prompt",
+					Cooldown: time.Hour,
+					Requeue:  map[Outcome]RequeuePolicy{OutcomeBuildFailed: RequeueIgnore},
+				},
+			},
+		}
+		runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
+		if err != nil {
+			t.Fatalf("NewRunner failed: %v", err)
+		}
+
+		runner.requeue(&Candidate{Key: "flaky_test.go"}, OutcomeNotFixed)
+		runner.requeue(&Candidate{Key: "broken.go"}, OutcomeBuildFailed)
+		content, _ := os.ReadFile(filepath.Join(tmpDir, "ignored.log"))
+		if string(content) != "broken.go\n" {
+			t.Errorf("ignored.log = %q, want only broken.go", content)
+		}
+		if !runner.ignoredList.coolingDown("flaky_test.go") {
+			t.Error("expected flaky_test.go to be on cooldown")
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package runner
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"sort"
+)
+
+//go:embed dashboard.html
+var dashboardHTML string
+
+// dashboardTask is a task's row in the dashboard, totalled from its attempt history.
+type dashboardTask struct {
+	Name       string  `json:"name"`
+	Attempts   int     `json:"attempts"`
+	Fixed      int     `json:"fixed"`
+	BestEffort int     `json:"best_effort"`
+	Failed     int     `json:"failed"`
+	CostUSD    float64 `json:"cost_usd"`
+}
+
+// dashboard serves the web UI for `nigel serve --http`. Historical data comes
+// from each task's claude.log; live output comes from the active run's events.
+type dashboard struct {
+	env    *Environment
+	server *rpcServer
+}
+
+func newDashboard(env *Environment, server *rpcServer) http.Handler {
+	d := &dashboard{env: env, server: server}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.index)
+	mux.HandleFunc("/api/status", d.status)
+	mux.HandleFunc("/api/tasks", d.tasks)
+	mux.HandleFunc("/api/attempts", d.attempts)
+	mux.HandleFunc("/api/diff", d.diff)
+	mux.HandleFunc("/api/events", d.events)
+	return mux
+}
+
+func (d *dashboard) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, dashboardHTML)
+}
+
+func (d *dashboard) status(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, d.server.status())
+}
+
+func (d *dashboard) tasks(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(d.env.Tasks))
+	for name := range d.env.Tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tasks := make([]dashboardTask, 0, len(names))
+	for _, name := range names {
+		attempts, err := ReadTaskAttempts(d.env, d.env.Tasks[name])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		summary := RunSummary{Outcomes: make(map[Outcome]int)}
+		for _, a := range attempts {
+			summary.Outcomes[a.Outcome]++
+			summary.CostUSD += a.Usage.CostUSD
+		}
+		tasks = append(tasks, dashboardTask{
+			Name:       name,
+			Attempts:   len(attempts),
+			Fixed:      summary.Fixed(),
+			BestEffort: summary.BestEffort(),
+			Failed:     summary.Failed(),
+			CostUSD:    summary.CostUSD,
+		})
+	}
+	writeJSON(w, tasks)
+}
+
+// attempts lists a task's attempts, oldest first, in the `nigel export` format.
+func (d *dashboard) attempts(w http.ResponseWriter, r *http.Request) {
+	attempts, ok := d.readAttempts(w, r)
+	if !ok {
+		return
+	}
+	rows := make([]exportRow, len(attempts))
+	for i, a := range attempts {
+		rows[i] = newExportRow(a)
+	}
+	writeJSON(w, rows)
+}
+
+// diff shows a commit recorded in the task's history. Other revisions are
+// refused so the endpoint can't be used to browse the repository.
+func (d *dashboard) diff(w http.ResponseWriter, r *http.Request) {
+	attempts, ok := d.readAttempts(w, r)
+	if !ok {
+		return
+	}
+	commit := r.URL.Query().Get("commit")
+	recorded := false
+	for _, a := range attempts {
+		if commit != "" && a.Commit == commit {
+			recorded = true
+			break
+		}
+	}
+	if !recorded {
+		http.Error(w, "commit not found in task history: "+commit, http.StatusNotFound)
+		return
+	}
+
+	task := d.env.Tasks[r.URL.Query().Get("task")]
+	cmd := exec.Command("git", "show", "--stat", "--patch", commit)
+	cmd.Dir = task.WorkDir(d.env.ForTask(task).ProjectDir)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("git show failed: %v\n%s", err, output), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(output)
+}
+
+// readAttempts reads the history of the task named in the query, writing an
+// error response if it can't.
+func (d *dashboard) readAttempts(w http.ResponseWriter, r *http.Request) ([]AttemptRecord, bool) {
+	name := r.URL.Query().Get("task")
+	task, ok := d.env.Tasks[name]
+	if !ok {
+		http.Error(w, "task not found: "+name, http.StatusNotFound)
+		return nil, false
+	}
+	attempts, err := ReadTaskAttempts(d.env, task)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return attempts, true
+}
+
+// sseSink buffers events for one server-sent events stream. Events are
+// dropped rather than blocking the run when the browser falls behind.
+type sseSink chan rpcEvent
+
+func (s sseSink) sendEvent(event rpcEvent) {
+	select {
+	case s <- event:
+	default:
+	}
+}
+
+// events streams the active run's events as server-sent events.
+func (d *dashboard) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	sink := make(sseSink, 256)
+	d.server.subscribe(sink)
+	defer d.server.unsubscribe(sink)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-sink:
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestDashboard(t *testing.T) (*httptest.Server, *rpcServer) {
+	tmpDir := t.TempDir()
+	logger, err := NewClaudeLogger(filepath.Join(tmpDir, "claude.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range []struct {
+		candidate string
+		outcome   Outcome
+		meta      OutcomeMeta
+	}{
+		{"a.go", OutcomeFixed, OutcomeMeta{Usage: Usage{CostUSD: 0.25}, Commit: "abc1234"}},
+		{"b.go", OutcomeNotFixed, OutcomeMeta{Usage: Usage{CostUSD: 0.5}}},
+	} {
+		logger.StartEntry(LogEntry{Candidate: a.candidate, Prompt: "Fix " + a.candidate})
+		logger.LogOutcome(a.outcome, "details", a.meta)
+	}
+	logger.Close()
+
+	env := &Environment{
+		ProjectDir: tmpDir,
+		Tasks:      map[string]Task{"test-task": {Name: "test-task", Dir: tmpDir}},
+	}
+	server := newRPCServer(env, RunnerOptions{})
+	ts := httptest.NewServer(newDashboard(env, server))
+	t.Cleanup(ts.Close)
+	return ts, server
+}
+
+func getJSON(t *testing.T, url string, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDashboardHistory(t *testing.T) {
+	ts, _ := newTestDashboard(t)
+
+	var tasks []dashboardTask
+	getJSON(t, ts.URL+"/api/tasks", &tasks)
+	if len(tasks) != 1 {
+		t.Fatalf("tasks = %+v, want one", tasks)
+	}
+	if got := tasks[0]; got.Attempts != 2 || got.Fixed != 1 || got.Failed != 1 || got.CostUSD != 0.75 {
+		t.Errorf("task = %+v, want 2 attempts, 1 fixed, 1 failed, $0.75", got)
+	}
+
+	var attempts []exportRow
+	getJSON(t, ts.URL+"/api/attempts?task=test-task", &attempts)
+	if len(attempts) != 2 || attempts[0].Commit != "abc1234" || attempts[1].Outcome != OutcomeNotFixed {
+		t.Errorf("attempts = %+v", attempts)
+	}
+
+	resp, err := http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("GET / = %s %s, want HTML", resp.Status, resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestDashboardRejectsUnknownTaskAndCommit(t *testing.T) {
+	ts, _ := newTestDashboard(t)
+
+	for _, path := range []string{
+		"/api/attempts?task=missing",
+		"/api/diff?task=test-task&commit=HEAD",
+		"/api/diff?task=test-task&commit=",
+		"/nope",
+	} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s = %s, want 404", path, resp.Status)
+		}
+	}
+}
+
+func TestDashboardStreamsEvents(t *testing.T) {
+	ts, server := newTestDashboard(t)
+
+	resp, err := http.Get(ts.URL + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// The handler subscribes before flushing headers, so the event can't be missed
+	server.OnAgentChunk("test-task", "hello", false)
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	var event rpcEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+		t.Fatalf("invalid event %q: %v", line, err)
+	}
+	if event.Type != "agent_chunk" || event.Text != "hello" {
+		t.Errorf("event = %+v, want agent_chunk hello", event)
+	}
+}
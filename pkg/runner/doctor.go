@@ -0,0 +1,229 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DoctorCheck is the result of a single environment check.
+type DoctorCheck struct {
+	Name   string
+	Detail string // Extra information shown for passing checks
+	Err    error  // nil when the check passed
+	Fix    string // Suggested action when the check failed
+}
+
+// doctor runs environment checks for `nigel doctor`.
+type doctor struct {
+	executor CommandExecutor
+	checks   []DoctorCheck
+}
+
+// pass records a passing check.
+func (d *doctor) pass(name, detail string) {
+	d.checks = append(d.checks, DoctorCheck{Name: name, Detail: detail})
+}
+
+// fail records a failing check with a suggested fix.
+func (d *doctor) fail(name string, err error, fix string) {
+	d.checks = append(d.checks, DoctorCheck{Name: name, Err: err, Fix: fix})
+}
+
+// RunDoctor discovers the environment and checks everything a run depends on.
+func RunDoctor() []DoctorCheck {
+	d := &doctor{executor: &RealCommandExecutor{}}
+
+	env, err := DiscoverEnvironment()
+	if err != nil {
+		d.fail("configuration", err, "Run nigel from the project root and fix the reported file in nigel/")
+		return d.checks
+	}
+	d.pass("configuration", fmt.Sprintf("%d task(s) in %s", len(env.Tasks), relativePath(env.RunnerDir)))
+	d.executor = &RealCommandExecutor{IgnoreDirtyPaths: env.Config.IgnoreDirtyPaths}
+
+	d.checkEnvironment(env)
+	return d.checks
+}
+
+// checkEnvironment checks the claude commands, repositories and tasks of a
+// discovered environment.
+func (d *doctor) checkEnvironment(env *Environment) {
+	names := make([]string, 0, len(env.Tasks))
+	for name := range env.Tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	claudeCommands := append([]string{env.Config.ClaudeCommand}, env.Config.ClaudeCommandFallbacks...)
+	for _, name := range names {
+		if cmd := env.Tasks[name].ClaudeCommand; cmd != "" {
+			claudeCommands = append(claudeCommands, cmd)
+		}
+	}
+	checked := make(map[string]bool)
+	for _, cmd := range claudeCommands {
+		if !checked[cmd] {
+			checked[cmd] = true
+			d.checkClaude(cmd)
+		}
+	}
+
+	checked = make(map[string]bool)
+	for _, name := range names {
+		taskEnv := env.ForTask(env.Tasks[name])
+		if !checked[taskEnv.ProjectDir] {
+			checked[taskEnv.ProjectDir] = true
+			d.checkRepository(taskEnv)
+		}
+	}
+
+	for _, name := range names {
+		d.checkTask(env, env.Tasks[name])
+	}
+}
+
+// checkClaude verifies a claude command is on the PATH and runs.
+func (d *doctor) checkClaude(claudeCmd string) {
+	name := "claude command " + claudeCmd
+	if err := CheckClaudeCommand(claudeCmd); err != nil {
+		d.fail(name, err, "Install the Claude CLI or set claude_command in nigel/config.yaml to its full path (try `which claude`)")
+		return
+	}
+	ok, err := d.executor.RunSilent(claudeCmd+" --version", ".")
+	if err != nil || !ok {
+		if err == nil {
+			err = fmt.Errorf("`%s --version` exited with an error", claudeCmd)
+		}
+		d.fail(name, err, "Run `"+claudeCmd+" --version` yourself to see why it fails (often missing login or a broken install)")
+		return
+	}
+	d.pass(name, "")
+}
+
+// checkRepository verifies the project directory is a git repository that
+// nigel can start in: clean, or with a reset_command to make it clean.
+func (d *doctor) checkRepository(env *Environment) {
+	name := "git repository " + relativePath(env.ProjectDir)
+	ok, err := d.executor.RunSilent("git rev-parse --is-inside-work-tree", env.ProjectDir)
+	if err != nil || !ok {
+		d.fail(name, fmt.Errorf("not a git repository"), "Run `git init` or point the project's dir at a git checkout")
+		return
+	}
+
+	dirty, err := d.executor.HasUncommittedChanges(env.ProjectDir)
+	if err != nil {
+		d.fail(name, fmt.Errorf("failed to check git status: %w", err), "Make sure git works in this directory")
+		return
+	}
+	switch {
+	case dirty && env.Config.ResetCommand == "":
+		d.fail(name, fmt.Errorf("uncommitted changes and no reset_command configured"),
+			"Commit or stash your changes, or set reset_command (e.g. \"git reset --hard && git clean -fd\")")
+	case dirty:
+		d.pass(name, "uncommitted changes will be discarded by reset_command")
+	default:
+		d.pass(name, "clean")
+	}
+}
+
+// checkTask verifies a task's templates, workdir, candidate source and log directory.
+func (d *doctor) checkTask(env *Environment, task Task) {
+	prefix := "task " + task.Name + ": "
+	env = env.ForTask(task)
+
+	templates := []string{task.Template}
+	for _, v := range task.Variants {
+		templates = append(templates, v.Template)
+	}
+	for _, template := range templates {
+		if template == "" {
+			continue
+		}
+		path := filepath.Join(task.Dir, template)
+		if _, err := os.Stat(path); err != nil {
+			d.fail(prefix+"template "+template, fmt.Errorf("not found: %s", relativePath(path)),
+				"Create the file or fix the template path (it is relative to the task directory)")
+		} else {
+			d.pass(prefix+"template "+template, "")
+		}
+	}
+
+	workDir := task.WorkDir(env.ProjectDir)
+	if info, err := os.Stat(workDir); err != nil || !info.IsDir() {
+		d.fail(prefix+"workdir", fmt.Errorf("does not exist: %s", relativePath(workDir)), "Fix 'workdir' in task.yaml")
+	} else {
+		d.checkCandidateSource(prefix, task, workDir, env.Config.CandidateSourceMaxBytes)
+	}
+
+	d.checkLogDir(prefix+"log directory", task.Dir)
+	if logDir := filepath.Dir(env.LogPath(task, time.Now())); logDir != task.Dir {
+		d.checkLogDir(prefix+"claude.log directory", logDir)
+	}
+}
+
+// checkCandidateSource runs a task's candidate source and parses its output.
+func (d *doctor) checkCandidateSource(prefix string, task Task, workDir string, maxBytes int) {
+	name := prefix + "candidate source"
+	output, err := RunCandidateSource(task.CandidateSource, workDir, maxBytes)
+	if err != nil {
+		d.fail(name, err, "Run the candidate_source command from "+relativePath(workDir)+" and fix the error")
+		return
+	}
+	candidates, warnings, err := ParseTaskCandidates(output, task)
+	if err != nil {
+		d.fail(name, err, "Make candidate_source print a JSON array (or one candidate per line) matching candidate_schema")
+		return
+	}
+	detail := fmt.Sprintf("%d candidate(s)", len(candidates))
+	if len(warnings) > 0 {
+		detail += fmt.Sprintf(", %d malformed skipped", len(warnings))
+	}
+	d.pass(name, detail)
+}
+
+// checkLogDir verifies nigel can write logs (claude.log, ignored.log) in dir.
+// A missing directory passes, since the logger creates it.
+func (d *doctor) checkLogDir(name, dir string) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		d.pass(name, "will be created")
+		return
+	}
+	file, err := os.CreateTemp(dir, ".nigel-doctor-*")
+	if err != nil {
+		d.fail(name, fmt.Errorf("not writable: %w", err), "Fix the permissions on "+relativePath(dir))
+		return
+	}
+	file.Close()
+	os.Remove(file.Name())
+	d.pass(name, "")
+}
+
+// FormatDoctor renders doctor results with a closing verdict.
+func FormatDoctor(checks []DoctorCheck) string {
+	var b strings.Builder
+	failed := 0
+	for _, c := range checks {
+		if c.Err != nil {
+			failed++
+			fmt.Fprintf(&b, "%s %s: %v\n", ColorError("✗"), c.Name, c.Err)
+			fmt.Fprintf(&b, "  %s\n", ColorDim("→ "+c.Fix))
+			continue
+		}
+		line := ColorSuccess("✓") + " " + c.Name
+		if c.Detail != "" {
+			line += ColorDim(" (" + c.Detail + ")")
+		}
+		b.WriteString(line + "\n")
+	}
+
+	if failed == 0 {
+		b.WriteString("\n" + ColorSuccess("All checks passed.") + "\n")
+	} else {
+		b.WriteString("\n" + ColorError(fmt.Sprintf("%d problem(s) found.", failed)) + "\n")
+	}
+	return b.String()
+}
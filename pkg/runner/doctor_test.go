@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoctorCheckRepository(t *testing.T) {
+	tests := []struct {
+		name     string
+		isRepo   bool
+		dirty    bool
+		reset    string
+		wantFail string
+	}{
+		{name: "clean repo", isRepo: true},
+		{name: "dirty with reset command", isRepo: true, dirty: true, reset: "git reset --hard"},
+		{name: "dirty without reset command", isRepo: true, dirty: true, wantFail: "no reset_command"},
+		{name: "not a repo", wantFail: "not a git repository"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockCommandExecutor()
+			mock.SetResult("git rev-parse --is-inside-work-tree", tt.isRepo, nil)
+			mock.SetHasChanges(tt.dirty, nil)
+			d := &doctor{executor: mock}
+
+			d.checkRepository(&Environment{ProjectDir: t.TempDir(), Config: Config{ResetCommand: tt.reset}})
+
+			if len(d.checks) != 1 {
+				t.Fatalf("expected 1 check, got %d", len(d.checks))
+			}
+			err := d.checks[0].Err
+			if tt.wantFail == "" && err != nil {
+				t.Errorf("unexpected failure: %v", err)
+			}
+			if tt.wantFail != "" && (err == nil || !strings.Contains(err.Error(), tt.wantFail)) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantFail)
+			}
+		})
+	}
+}
+
+func TestDoctorCheckTask(t *testing.T) {
+	taskDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(taskDir, "a.md"), []byte("Fix $INPUT"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env := &Environment{ProjectDir: t.TempDir()}
+	task := Task{
+		Name:            "lint",
+		Dir:             taskDir,
+		CandidateSource: `echo '["a", "b"]'`,
+		Variants: []PromptVariant{
+			{Name: "a", Template: "a.md"},
+			{Name: "b", Template: "missing.md"},
+		},
+	}
+
+	d := &doctor{executor: NewMockCommandExecutor()}
+	d.checkTask(env, task)
+
+	results := make(map[string]DoctorCheck)
+	for _, c := range d.checks {
+		results[c.Name] = c
+	}
+	if c := results["task lint: template a.md"]; c.Err != nil {
+		t.Errorf("existing template failed: %v", c.Err)
+	}
+	if c := results["task lint: template missing.md"]; c.Err == nil {
+		t.Error("missing template should fail")
+	}
+	if c := results["task lint: candidate source"]; c.Err != nil || c.Detail != "2 candidate(s)" {
+		t.Errorf("candidate source check = %+v", c)
+	}
+	if c := results["task lint: log directory"]; c.Err != nil {
+		t.Errorf("log directory check failed: %v", c.Err)
+	}
+
+	d = &doctor{executor: NewMockCommandExecutor()}
+	task.Variants = nil
+	task.CandidateSource = "exit 3"
+	d.checkTask(env, task)
+	if c := d.checks[0]; c.Name != "task lint: candidate source" || c.Err == nil {
+		t.Errorf("failing candidate source check = %+v", c)
+	}
+}
+
+func TestFormatDoctor(t *testing.T) {
+	result := FormatDoctor([]DoctorCheck{
+		{Name: "configuration", Detail: "2 task(s)"},
+		{Name: "task lint: candidate source", Err: os.ErrNotExist, Fix: "Fix it"},
+	})
+	for _, want := range []string{"configuration", "2 task(s)", "candidate source", "→ Fix it", "1 problem(s) found."} {
+		if !strings.Contains(result, want) {
+			t.Errorf("output missing %q:\n%s", want, result)
+		}
+	}
+
+	if result := FormatDoctor([]DoctorCheck{{Name: "configuration"}}); !strings.Contains(result, "All checks passed.") {
+		t.Errorf("expected all checks passed:\n%s", result)
+	}
+}
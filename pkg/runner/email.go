@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EmailConfig sends a run summary over SMTP when a run finishes or dies.
+type EmailConfig struct {
+	Host      string   `yaml:"host"`
+	Port      int      `yaml:"port"`     // Default 587
+	Username  string   `yaml:"username"` // Empty for servers without auth
+	Password  string   `yaml:"password"` // Environment variables are expanded, e.g. ${SMTP_PASSWORD}
+	From      string   `yaml:"from"`
+	To        []string `yaml:"to"`
+	CommitURL string   `yaml:"commit_url"` // Link template for commits, with $COMMIT (e.g. https://github.com/org/repo/commit/$COMMIT)
+}
+
+const defaultSMTPPort = 587
+
+// validateEmailConfig checks the required fields of an email config.
+func validateEmailConfig(c *EmailConfig) error {
+	if c.Host == "" {
+		return fmt.Errorf("missing required field 'host'")
+	}
+	if c.From == "" {
+		return fmt.Errorf("missing required field 'from'")
+	}
+	if len(c.To) == 0 {
+		return fmt.Errorf("missing required field 'to'")
+	}
+	if c.Port < 0 {
+		return fmt.Errorf("port must be positive")
+	}
+	return nil
+}
+
+// FormatEmailSummary renders the plain-text body of a run summary email:
+// per-task counts, total cost, and the commits made.
+func FormatEmailSummary(summaries []RunSummary, runErr error, commitURL string) string {
+	var b strings.Builder
+	if runErr != nil {
+		fmt.Fprintf(&b, "The run stopped with an error: %v\n\n", runErr)
+	} else {
+		b.WriteString("The run finished.\n\n")
+	}
+
+	var cost float64
+	var commits []string
+	for _, s := range summaries {
+		fmt.Fprintf(&b, "%s: %d iterations, %d fixed, %d best effort, %d failed in %s (candidates %s)",
+			s.Task, s.Iterations, s.Fixed(), s.BestEffort(), s.Failed(), formatDuration(s.Duration), s.Candidates())
+		if s.CostUSD > 0 {
+			fmt.Fprintf(&b, ", $%.2f", s.CostUSD)
+		}
+		b.WriteString("\n")
+		cost += s.CostUSD
+		commits = append(commits, s.Commits...)
+	}
+	if cost > 0 {
+		fmt.Fprintf(&b, "\nTotal cost: $%.2f\n", cost)
+	}
+
+	if len(commits) > 0 {
+		b.WriteString("\nCommits:\n")
+		for _, commit := range commits {
+			if commitURL != "" {
+				commit = strings.ReplaceAll(commitURL, "$COMMIT", commit)
+			}
+			fmt.Fprintf(&b, "- %s\n", commit)
+		}
+	}
+	return b.String()
+}
+
+// sendEmailSummary mails the run summary to the configured recipients.
+func sendEmailSummary(c *EmailConfig, summaries []RunSummary, runErr error) error {
+	subject := "Nigel run finished"
+	if runErr != nil {
+		subject = "Nigel run stopped with an error"
+	}
+	fixed := 0
+	for _, s := range summaries {
+		fixed += s.Fixed()
+	}
+	subject += fmt.Sprintf(" (%d fixed)", fixed)
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", c.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(c.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(FormatEmailSummary(summaries, runErr, c.CommitURL), "\n", "\r\n"))
+
+	port := c.Port
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	var auth smtp.Auth
+	if c.Username != "" {
+		auth = smtp.PlainAuth("", c.Username, os.ExpandEnv(c.Password), c.Host)
+	}
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(port))
+	return smtp.SendMail(addr, auth, c.From, c.To, []byte(msg.String()))
+}
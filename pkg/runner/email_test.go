@@ -0,0 +1,51 @@
+package runner
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateEmailConfig(t *testing.T) {
+	valid := EmailConfig{Host: "smtp.example.com", From: "nigel@example.com", To: []string{"dev@example.com"}}
+	if err := validateEmailConfig(&valid); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+	for _, c := range []EmailConfig{
+		{From: "nigel@example.com", To: []string{"dev@example.com"}},
+		{Host: "smtp.example.com", To: []string{"dev@example.com"}},
+		{Host: "smtp.example.com", From: "nigel@example.com"},
+	} {
+		if err := validateEmailConfig(&c); err == nil {
+			t.Errorf("expected error for %+v", c)
+		}
+	}
+}
+
+func TestFormatEmailSummary(t *testing.T) {
+	summaries := []RunSummary{{
+		Task:       "lint",
+		Iterations: 3,
+		Outcomes:   map[Outcome]int{OutcomeFixed: 2, OutcomeNotFixed: 1},
+		CostUSD:    1.25,
+		Commits:    []string{"abc123", "def456"},
+	}}
+
+	body := FormatEmailSummary(summaries, nil, "https://github.com/org/repo/commit/$COMMIT")
+	for _, want := range []string{
+		"The run finished.",
+		"lint: 3 iterations, 2 fixed, 0 best effort, 1 failed",
+		"Total cost: $1.25",
+		"- https://github.com/org/repo/commit/abc123\n",
+		"- https://github.com/org/repo/commit/def456\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q:\n%s", want, body)
+		}
+	}
+
+	body = FormatEmailSummary(summaries, errors.New("reset failed"), "")
+	if !strings.Contains(body, "stopped with an error: reset failed") || !strings.Contains(body, "- abc123\n") {
+		t.Errorf("unexpected body:\n%s", body)
+	}
+}
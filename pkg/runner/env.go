@@ -0,0 +1,195 @@
+package runner
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// envNameRe matches a valid environment variable name.
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateEnv checks that each `env:` variable has a usable name.
+func validateEnv(vars map[string]string) error {
+	for _, name := range sortedEnvNames(vars) {
+		if !envNameRe.MatchString(name) {
+			return fmt.Errorf("invalid variable name %q", name)
+		}
+	}
+	return nil
+}
+
+// mergeEnv combines the global and task `env:` variables. A task variable
+// replaces a global one with the same name.
+func mergeEnv(global, task map[string]string) map[string]string {
+	if len(global) == 0 {
+		return task
+	}
+	merged := make(map[string]string, len(global)+len(task))
+	for name, value := range global {
+		merged[name] = value
+	}
+	for name, value := range task {
+		merged[name] = value
+	}
+	return merged
+}
+
+func sortedEnvNames(vars map[string]string) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// usesCandidate reports whether an `env:` value refers to the candidate.
+func usesCandidate(value string) bool {
+	return strings.Contains(value, "$CANDIDATE") || strings.Contains(value, "$INPUT")
+}
+
+// envExports returns shell exports that set vars, like GitEnvPrefix, with
+// $TASK_NAME, $TASK_ID, $CANDIDATE (the key), $CANDIDATE_FILE and the
+// prompt's $INPUT forms interpolated. Without a candidate, variables that
+// refer to one are left unset. Empty if there's nothing to set.
+func envExports(vars map[string]string, candidate *Candidate, file, taskName string, taskID int64) (string, error) {
+	var exports []string
+	for _, name := range sortedEnvNames(vars) {
+		value := vars[name]
+		if usesCandidate(value) && candidate == nil {
+			continue
+		}
+		value, err := InterpolatePrompt(value, candidate, taskID)
+		if err != nil {
+			return "", fmt.Errorf("env %s: %w", name, err)
+		}
+		value = strings.ReplaceAll(value, "$TASK_NAME", taskName)
+		if candidate != nil {
+			value = strings.ReplaceAll(value, "$CANDIDATE_FILE", file)
+			value = candidateVarRe.ReplaceAllLiteralString(value, candidate.Key)
+		}
+		exports = append(exports, name+"="+shellQuote(value))
+	}
+	if len(exports) == 0 {
+		return "", nil
+	}
+	return "export " + strings.Join(exports, " ") + "; ", nil
+}
+
+// pathDirs returns the directories task puts ahead of PATH: path_prepend in
+// order, then each toolchain's bin/ by tool name. Relative directories are in
+// projectDir.
+func (t Task) pathDirs(projectDir string) []string {
+	dirs := append([]string{}, t.PathPrepend...)
+	for _, name := range sortedEnvNames(t.Toolchain) {
+		dirs = append(dirs, filepath.Join(t.Toolchain[name], "bin"))
+	}
+	for i, dir := range dirs {
+		if !filepath.IsAbs(dir) {
+			dirs[i] = filepath.Join(projectDir, dir)
+		}
+	}
+	return dirs
+}
+
+// pathExport returns an export that puts dirs ahead of the inherited PATH, or
+// "" without any.
+func pathExport(dirs []string) string {
+	if len(dirs) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(dirs))
+	for i, dir := range dirs {
+		quoted[i] = shellQuote(dir)
+	}
+	return "export PATH=" + strings.Join(quoted, ":") + `:"$PATH"; `
+}
+
+// taskEnv returns the `env:` variables for task, global and its own.
+func (e *Environment) taskEnv(task Task) map[string]string {
+	return mergeEnv(e.Config.Env, task.Env)
+}
+
+// hasTaskEnv reports whether task's commands run with anything set.
+func (e *Environment) hasTaskEnv(task Task) bool {
+	return len(e.taskEnv(task)) > 0 || len(task.pathDirs(e.ProjectDir)) > 0 || task.CandidateFile != ""
+}
+
+// candidateFile returns the file path candidate_file picks out of candidate,
+// or "" if the task doesn't set it or the candidate has no such field.
+func (t Task) candidateFile(candidate *Candidate) string {
+	if t.CandidateFile == "" || candidate == nil {
+		return ""
+	}
+	return candidate.Field(t.CandidateFile)
+}
+
+// taskExports returns the exports for task's commands: its PATH, then its
+// `env:` variables for candidate (nil outside an attempt) and, with
+// candidate_file set, the candidate's file as CANDIDATE_FILE.
+func (e *Environment) taskExports(task Task, candidate *Candidate) (string, error) {
+	file := task.candidateFile(candidate)
+	exports, err := envExports(e.taskEnv(task), candidate, file, task.Name, e.TaskID)
+	if err != nil {
+		return "", err
+	}
+	if task.CandidateFile != "" && candidate != nil {
+		exports += "export CANDIDATE_FILE=" + shellQuote(file) + "; "
+	}
+	return pathExport(task.pathDirs(e.ProjectDir)) + exports, nil
+}
+
+// baseEnvExports returns the exports for task's commands outside an attempt,
+// such as its candidate source. They can't fail: the variables that could are
+// the ones that refer to a candidate, which are left unset.
+func (e *Environment) baseEnvExports(task Task) string {
+	exports, _ := e.taskExports(task, nil)
+	return exports
+}
+
+// forwardEnvArgs returns container run arguments that pass vars through from
+// the exports on the host side of the container command.
+func forwardEnvArgs(vars map[string]string) []string {
+	var args []string
+	for _, name := range sortedEnvNames(vars) {
+		args = append(args, "-e", name)
+	}
+	return args
+}
+
+// commandEnv holds the exports for the current candidate, shared by the
+// runner, which updates it as each attempt starts, and its envExecutor.
+type commandEnv struct {
+	exports string
+}
+
+// envExecutor sets the `env:` variables for each shell command. It sits
+// innermost, so the audit and -vv logs show commands without the values.
+type envExecutor struct {
+	CommandExecutor
+	env *commandEnv
+}
+
+func (e envExecutor) Run(command, workDir string) (bool, error) {
+	return e.CommandExecutor.Run(e.env.exports+command, workDir)
+}
+
+func (e envExecutor) RunSilent(command, workDir string) (bool, error) {
+	return e.CommandExecutor.RunSilent(e.env.exports+command, workDir)
+}
+
+func (e envExecutor) RunShowOnFail(command, workDir string) (bool, string, error) {
+	return e.CommandExecutor.RunShowOnFail(e.env.exports+command, workDir)
+}
+
+func (e envExecutor) RunWatched(command, workDir string, onLine func(string)) (bool, string, error) {
+	return e.CommandExecutor.RunWatched(e.env.exports+command, workDir, onLine)
+}
+
+func (e envExecutor) RunWithTimeout(command, workDir string, timeout time.Duration) (bool, error) {
+	return e.CommandExecutor.RunWithTimeout(e.env.exports+command, workDir, timeout)
+}
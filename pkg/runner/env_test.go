@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEnvExports(t *testing.T) {
+	vars := map[string]string{
+		"GOFLAGS":   "-mod=mod",
+		"FILE":      `$INPUT["file"]`,
+		"NIGEL_KEY": "$TASK_NAME:$CANDIDATE",
+		"TARGET":    "./$CANDIDATE_FILE",
+		"QUOTED":    "it's",
+	}
+	candidate := &Candidate{Key: `{"file":"a.go"}`, Data: json.RawMessage(`{"file":"a.go"}`)}
+
+	got, err := envExports(vars, candidate, "a.go", "lint", 7)
+	if err != nil {
+		t.Fatalf("envExports failed: %v", err)
+	}
+	want := `export FILE='a.go' GOFLAGS='-mod=mod' NIGEL_KEY='lint:{"file":"a.go"}' QUOTED='it'"'"'s' TARGET='./a.go'; `
+	if got != want {
+		t.Errorf("envExports() = %q, want %q", got, want)
+	}
+
+	got, err = envExports(vars, nil, "", "lint", 7)
+	if err != nil {
+		t.Fatalf("envExports without candidate failed: %v", err)
+	}
+	if want := `export GOFLAGS='-mod=mod' QUOTED='it'"'"'s'; `; got != want {
+		t.Errorf("envExports(nil) = %q, want %q", got, want)
+	}
+
+	if got, _ := envExports(nil, candidate, "a.go", "lint", 7); got != "" {
+		t.Errorf("envExports with no variables = %q, want empty", got)
+	}
+	if _, err := envExports(map[string]string{"X": "$INPUT[0]"}, candidate, "a.go", "lint", 7); err == nil {
+		t.Error("expected an error indexing a map candidate")
+	}
+}
+
+func TestValidateEnv(t *testing.T) {
+	if err := validateEnv(map[string]string{"GOFLAGS": "", "_X1": ""}); err != nil {
+		t.Errorf("validateEnv rejected valid names: %v", err)
+	}
+	for _, name := range []string{"1X", "A-B", "A B", ""} {
+		if err := validateEnv(map[string]string{name: "x"}); err == nil {
+			t.Errorf("validateEnv(%q) = nil, want an error", name)
+		}
+	}
+}
+
+func TestEnvExecutor(t *testing.T) {
+	tmpDir := t.TempDir()
+	env := &Environment{
+		ProjectDir: tmpDir,
+		Config: Config{
+			VerifyCommand: "make build",
+			Env:           map[string]string{"GOFLAGS": "-mod=mod", "API_KEY": "global"},
+		},
+		Tasks: map[string]Task{
+			"test-task": {Name: "test-task", Dir: tmpDir, Prompt: "test prompt", Env: map[string]string{"API_KEY": "task"}},
+		},
+	}
+	mock := NewMockCommandExecutor()
+	runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true, Executor: mock})
+	if err != nil {
+		t.Fatalf("NewRunner failed: %v", err)
+	}
+
+	runner.runVerify()
+	if want := "export API_KEY='task' GOFLAGS='-mod=mod'; make build"; !mock.CalledWith(want) {
+		t.Errorf("calls = %v, want %q", mock.Calls, want)
+	}
+}
+
+func TestTaskExportsPath(t *testing.T) {
+	env := &Environment{ProjectDir: "/repo", Config: Config{Env: map[string]string{"CGO_ENABLED": "0"}}}
+	task := Task{
+		Name:        "lint",
+		PathPrepend: []string{"/opt/tools", "bin"},
+		Toolchain:   map[string]string{"node": "/opt/node20", "go": "/usr/local/go1.22"},
+	}
+	got, err := env.taskExports(task, nil)
+	if err != nil {
+		t.Fatalf("taskExports failed: %v", err)
+	}
+	want := `export PATH='/opt/tools':'/repo/bin':'/usr/local/go1.22/bin':'/opt/node20/bin':"$PATH"; export CGO_ENABLED='0'; `
+	if got != want {
+		t.Errorf("taskExports() = %q, want %q", got, want)
+	}
+
+	if !env.hasTaskEnv(Task{}) {
+		t.Error("expected global env to count")
+	}
+	if (&Environment{}).hasTaskEnv(Task{}) {
+		t.Error("expected nothing to set without env or path settings")
+	}
+}
+
+func TestCandidateFile(t *testing.T) {
+	env := &Environment{ProjectDir: "/repo"}
+	task := Task{Name: "lint", CandidateFile: "path"}
+	candidate := &Candidate{Key: `{"path":"it's.go"}`, Data: json.RawMessage(`{"path":"it's.go","line":3}`)}
+
+	got, err := env.taskExports(task, candidate)
+	if err != nil {
+		t.Fatalf("taskExports failed: %v", err)
+	}
+	if want := `export CANDIDATE_FILE='it'"'"'s.go'; `; got != want {
+		t.Errorf("taskExports() = %q, want %q", got, want)
+	}
+	if got, _ := env.taskExports(task, nil); got != "" {
+		t.Errorf("taskExports(nil) = %q, want nothing outside an attempt", got)
+	}
+	if !env.hasTaskEnv(task) {
+		t.Error("expected candidate_file to count as task env")
+	}
+
+	array := &Candidate{Key: `["a.go",3]`, Data: json.RawMessage(`["a.go",3]`)}
+	if got := (Task{CandidateFile: "0"}).candidateFile(array); got != "a.go" {
+		t.Errorf("candidateFile(array) = %q, want a.go", got)
+	}
+	if got := (Task{CandidateFile: "path"}).candidateFile(array); got != "" {
+		t.Errorf("candidateFile with a key on an array = %q, want empty", got)
+	}
+
+	// $CANDIDATE_FILE is left for the shell rather than taken for $CANDIDATE
+	if got := InterpolateCommand("lint $CANDIDATE_FILE # $CANDIDATE", candidate, "lint"); got != `lint $CANDIDATE_FILE # '{"path":"it'"'"'s.go"}'` {
+		t.Errorf("InterpolateCommand = %q", got)
+	}
+}
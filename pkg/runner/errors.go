@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrorStage identifies the part of an iteration an error came from. A run
+// stopped by an error from a stage exits with that stage's code. Match a stage
+// with errors.Is, e.g. errors.Is(err, ErrVerify).
+type ErrorStage struct {
+	Name     string
+	ExitCode int
+}
+
+func (s *ErrorStage) Error() string {
+	return s.Name + " error"
+}
+
+var (
+	ErrCandidateSource = &ErrorStage{Name: "candidate source", ExitCode: 3} // Running or parsing the candidate source
+	ErrAgent           = &ErrorStage{Name: "agent", ExitCode: 4}            // Building the prompt or running Claude
+	ErrVerify          = &ErrorStage{Name: "verify", ExitCode: 5}           // Checking, verifying or resetting changes
+	ErrCommit          = &ErrorStage{Name: "commit", ExitCode: 6}           // Committing changes with success_command
+)
+
+// StageError is an iteration error tagged with its stage and whether the run
+// should back off and retry or stop.
+type StageError struct {
+	Stage     *ErrorStage
+	Retryable bool          // Back off and try again rather than stopping the run
+	Backoff   time.Duration // Fixed wait before retrying (0 = exponential backoff)
+	Err       error
+}
+
+func (e *StageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the error's stage.
+func (e *StageError) Is(target error) bool {
+	return target == e.Stage
+}
+
+// retryableError wraps err as a retryable error from stage.
+func retryableError(stage *ErrorStage, format string, args ...interface{}) *StageError {
+	return &StageError{Stage: stage, Retryable: true, Err: fmt.Errorf(format, args...)}
+}
+
+// fatalError returns an error from stage that stops the run immediately.
+func fatalError(stage *ErrorStage, format string, args ...interface{}) *StageError {
+	return &StageError{Stage: stage, Err: fmt.Errorf(format, args...)}
+}
+
+// candidateSourceError wraps a candidate source failure. Output nigel refuses
+// (too large or binary) stops the run, since it would be the same next time;
+// anything else is retried.
+func candidateSourceError(context string, err error) *StageError {
+	var outputErr *candidateOutputError
+	if errors.As(err, &outputErr) {
+		return fatalError(ErrCandidateSource, "%s: %w", context, err)
+	}
+	return retryableError(ErrCandidateSource, "%s: %w", context, err)
+}
+
+// candidateParseError wraps a failure to parse candidate source output. Output
+// that doesn't match candidate_schema stops the run, since it would be the
+// same next time; anything else is retried.
+func candidateParseError(context string, err error) *StageError {
+	var mismatch *schemaMismatchError
+	if errors.As(err, &mismatch) {
+		return fatalError(ErrCandidateSource, "%s: %w", context, err)
+	}
+	return retryableError(ErrCandidateSource, "%s: %w", context, err)
+}
+
+// commitError wraps a failure to commit a fix as retryable, unless it already
+// has a stage, e.g. a batch that can't be committed, which stops the run.
+func commitError(context string, err error) *StageError {
+	if stageErr := asStageError(err); stageErr != nil {
+		return stageErr
+	}
+	return retryableError(ErrCommit, "%s: %w", context, err)
+}
+
+// rateLimitError reports that Claude hit its rate limit; the run sleeps for
+// rateLimitBackoff before trying again.
+func rateLimitError() *StageError {
+	return &StageError{Stage: ErrAgent, Retryable: true, Backoff: rateLimitBackoff, Err: errors.New("claude rate limit hit")}
+}
+
+// asStageError returns the StageError in err's chain, or nil if there is none.
+func asStageError(err error) *StageError {
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		return stageErr
+	}
+	return nil
+}
+
+// isRetryable reports whether the run should back off and continue after err.
+// Errors without a stage are retried.
+func isRetryable(err error) bool {
+	if stageErr := asStageError(err); stageErr != nil {
+		return stageErr.Retryable
+	}
+	return true
+}
+
+// ExitCode returns the process exit code for a run that stopped with err: the
+// stage's code, or 1 for errors without a stage.
+func ExitCode(err error) int {
+	if stageErr := asStageError(err); stageErr != nil {
+		return stageErr.Stage.ExitCode
+	}
+	return 1
+}
+
+// DescribeError prefixes an error with its stage, if it has one.
+func DescribeError(err error) string {
+	if stageErr := asStageError(err); stageErr != nil {
+		return fmt.Sprintf("%s: %v", stageErr.Stage, err)
+	}
+	return err.Error()
+}
@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestStageErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		stage     *ErrorStage
+		retryable bool
+		exitCode  int
+		described string
+	}{
+		{
+			name:      "retryable candidate source error",
+			err:       retryableError(ErrCandidateSource, "candidate source failed: %w", errors.New("exit status 2")),
+			stage:     ErrCandidateSource,
+			retryable: true,
+			exitCode:  3,
+			described: "candidate source error: candidate source failed: exit status 2",
+		},
+		{
+			name:      "fatal commit error",
+			err:       fatalError(ErrCommit, "success command returned non-zero exit code"),
+			stage:     ErrCommit,
+			exitCode:  6,
+			described: "commit error: success command returned non-zero exit code",
+		},
+		{
+			name:      "wrapped fatal verify error",
+			err:       fmt.Errorf("task lint: %w", fatalError(ErrVerify, "failed to reset")),
+			stage:     ErrVerify,
+			exitCode:  5,
+			described: "verify error: task lint: failed to reset",
+		},
+		{
+			name:      "plain error",
+			err:       errors.New("boom"),
+			retryable: true,
+			exitCode:  1,
+			described: "boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.stage != nil && !errors.Is(tt.err, tt.stage) {
+				t.Errorf("errors.Is(err, %v) = false", tt.stage)
+			}
+			for _, other := range []*ErrorStage{ErrCandidateSource, ErrAgent, ErrVerify, ErrCommit} {
+				if other != tt.stage && errors.Is(tt.err, other) {
+					t.Errorf("errors.Is(err, %v) = true", other)
+				}
+			}
+			if got := isRetryable(tt.err); got != tt.retryable {
+				t.Errorf("isRetryable() = %v, want %v", got, tt.retryable)
+			}
+			if got := ExitCode(tt.err); got != tt.exitCode {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.exitCode)
+			}
+			if got := DescribeError(tt.err); got != tt.described {
+				t.Errorf("DescribeError() = %q, want %q", got, tt.described)
+			}
+		})
+	}
+}
@@ -0,0 +1,753 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// timeoutError indicates Claude execution timed out
+type timeoutError struct {
+	duration time.Duration
+	inactive bool // Killed for producing no output for duration (inactivity_timeout)
+}
+
+// StreamCallback is called for each chunk of text received from Claude.
+type StreamCallback func(text string)
+
+// ToolUse is a tool call Claude made, e.g. Edit with the file it edited.
+type ToolUse struct {
+	Name   string `json:"name"`
+	Detail string `json:"detail,omitempty"` // The file, command or pattern it acted on, if any
+	Path   string `json:"-"`                // The file it read or edited, as Claude gave it
+}
+
+// ToolUseCallback is called for each tool call in Claude's stream.
+type ToolUseCallback func(tool ToolUse)
+
+// ClaudeResult holds the output of a Claude invocation.
+type ClaudeResult struct {
+	Output    string // Accumulated output (for rate limit detection)
+	SessionID string // Session ID reported in the stream, if any
+	Usage     Usage  // Token usage and cost from the result event, if reported
+}
+
+// Usage is what Claude reports about a run in its final result event.
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+	CostUSD      float64
+	NumTurns     int
+	APIDuration  time.Duration // duration_ms: how long Claude itself reported running
+	IsError      bool          // Claude reported the run as failed
+}
+
+// Claude stream event types
+type streamEvent struct {
+	Type      string                 `json:"type"`
+	Event     map[string]interface{} `json:"event,omitempty"`
+	SessionID string                 `json:"session_id,omitempty"`
+}
+
+// contentBlockDelta represents the delta content in a stream event
+type contentBlockDelta struct {
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+}
+
+// assistantEvent is a complete assistant message, which lists the tool calls
+// made in it
+type assistantEvent struct {
+	Message struct {
+		Content []struct {
+			Type  string                 `json:"type"`
+			Name  string                 `json:"name"`
+			Input map[string]interface{} `json:"input"`
+		} `json:"content"`
+	} `json:"message"`
+}
+
+// toolUseDetailKeys are the tool input fields describing what a call acted on,
+// in order of preference.
+var toolUseDetailKeys = []string{"file_path", "notebook_path", "path", "command", "pattern", "url", "query", "description"}
+
+// maxToolUseDetail is how much of a tool call's detail is kept, in characters.
+const maxToolUseDetail = 80
+
+// newToolUse describes a tool call by its name and the first detail field in
+// its input, cut to one short line.
+func newToolUse(name string, input map[string]interface{}) ToolUse {
+	tool := ToolUse{Name: name}
+	if path, ok := input["file_path"].(string); ok {
+		tool.Path = path
+	} else if path, ok := input["notebook_path"].(string); ok {
+		tool.Path = path
+	}
+	for _, key := range toolUseDetailKeys {
+		if value, ok := input[key].(string); ok && value != "" {
+			if strings.HasSuffix(key, "path") && filepath.IsAbs(value) {
+				value = relativePath(value)
+			}
+			value, _, _ = strings.Cut(value, "\n")
+			if runes := []rune(value); len(runes) > maxToolUseDetail {
+				value = string(runes[:maxToolUseDetail]) + "..."
+			}
+			tool.Detail = value
+			break
+		}
+	}
+	return tool
+}
+
+// resultEvent represents the final result event
+type resultEvent struct {
+	Type         string  `json:"type"`
+	Result       string  `json:"result,omitempty"`
+	SessionID    string  `json:"session_id,omitempty"`
+	TotalCostUSD float64 `json:"total_cost_usd,omitempty"`
+	NumTurns     int     `json:"num_turns,omitempty"`
+	DurationMS   int64   `json:"duration_ms,omitempty"`
+	IsError      bool    `json:"is_error,omitempty"`
+	Usage        struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// usage returns the run metadata reported by the event.
+func (re resultEvent) usage() Usage {
+	return Usage{
+		InputTokens:  re.Usage.InputTokens,
+		OutputTokens: re.Usage.OutputTokens,
+		CostUSD:      re.TotalCostUSD,
+		NumTurns:     re.NumTurns,
+		APIDuration:  time.Duration(re.DurationMS) * time.Millisecond,
+		IsError:      re.IsError,
+	}
+}
+
+// Output formats a claude_command can produce, set per task with output_format.
+// Without one, the format is probed from the first line of output.
+const (
+	OutputStreamJSON = "stream-json" // One JSON event per line (what nigel requests by default)
+	OutputJSON       = "json"        // A single result document
+	OutputText       = "text"        // Plain text
+)
+
+// outputFormatFlags returns the claude flags requesting an output format.
+// Note: --print is required for --output-format to work
+func outputFormatFlags(format string) string {
+	switch format {
+	case OutputJSON:
+		return "--print --output-format json"
+	case OutputText:
+		return "--print"
+	default:
+		return "--print --output-format stream-json --include-partial-messages --verbose"
+	}
+}
+
+// probeOutputFormat guesses the output format from the first line of output:
+// stream-json lines are complete JSON objects, a json document spread over
+// several lines starts with "{", and anything else is plain text.
+func probeOutputFormat(line string) string {
+	var obj map[string]interface{}
+	if json.Unmarshal([]byte(line), &obj) == nil {
+		return OutputStreamJSON
+	}
+	if strings.HasPrefix(strings.TrimSpace(line), "{") {
+		return OutputJSON
+	}
+	return OutputText
+}
+
+func (e *timeoutError) Error() string {
+	if e.inactive {
+		return fmt.Sprintf("no output for %s", e.duration)
+	}
+	return fmt.Sprintf("timeout after %s", e.duration)
+}
+
+func (e *timeoutError) IsTimeout() bool {
+	return true
+}
+
+// defaultCandidateSourceMaxBytes caps candidate source output when
+// candidate_source_max_bytes isn't set.
+const defaultCandidateSourceMaxBytes = 64 * 1024 * 1024
+
+// candidateOutputError is candidate source output nigel won't parse: more
+// than the size cap, or binary. Running the source again won't help.
+type candidateOutputError struct {
+	Reason string
+}
+
+func (e *candidateOutputError) Error() string {
+	return "candidate source output " + e.Reason
+}
+
+// candidateOutput collects candidate source output, refusing it as soon as it
+// goes over maxBytes or contains a NUL byte. The refusal closes the pipe, so
+// the source stops rather than writing on into memory.
+type candidateOutput struct {
+	buf      bytes.Buffer
+	maxBytes int
+	err      *candidateOutputError
+}
+
+func (o *candidateOutput) Write(p []byte) (int, error) {
+	if i := bytes.IndexByte(p, 0); i >= 0 {
+		o.err = &candidateOutputError{Reason: fmt.Sprintf("looks binary (NUL byte at offset %d); candidate_source must print JSON or text", o.buf.Len()+i)}
+		return 0, o.err
+	}
+	if o.buf.Len()+len(p) > o.maxBytes {
+		o.err = &candidateOutputError{Reason: fmt.Sprintf("is over %d bytes; filter it down or raise candidate_source_max_bytes", o.maxBytes)}
+		return 0, o.err
+	}
+	return o.buf.Write(p)
+}
+
+// RunCandidateSource executes a candidate source command and returns its
+// stdout, or a candidateOutputError if that is over maxBytes (0 = the
+// default) or binary.
+func RunCandidateSource(source, workDir string, maxBytes int) ([]byte, error) {
+	cmd := exec.Command("bash", "-c", source)
+	cmd.Dir = workDir
+
+	if maxBytes <= 0 {
+		maxBytes = defaultCandidateSourceMaxBytes
+	}
+	stdout := &candidateOutput{maxBytes: maxBytes}
+	var stderr bytes.Buffer
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if stdout.err != nil {
+		// The source was probably killed writing to the closed pipe
+		return nil, stdout.err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("candidate source failed: %w\nstderr: %s", err, stderr.String())
+	}
+
+	return stdout.buf.Bytes(), nil
+}
+
+// RunCommand, RunCommandSilent, and RunCommandShowOnFail are now defined in command_executor.go
+// as thin wrappers around RealCommandExecutor for backward compatibility.
+
+// runningProcess tracks the currently running Claude process for signal forwarding
+var runningProcess *os.Process
+
+// KillRunningProcess terminates the running Claude process if any
+func KillRunningProcess() {
+	if p := runningProcess; p != nil {
+		// Kill the entire process group
+		syscall.Kill(-p.Pid, syscall.SIGTERM)
+	}
+}
+
+// ProcessRunner starts the shell command that runs Claude. The real one runs
+// it under bash; tests substitute processes that write canned output or hang.
+type ProcessRunner interface {
+	Start(command, workDir string) (ClaudeProcess, error)
+}
+
+// ClaudeProcess is a started Claude command.
+type ClaudeProcess interface {
+	Stdout() io.Reader
+	Stderr() io.Reader
+	Wait() error // Waits for exit; called once stdout and stderr are read to the end
+	Kill()       // Stops the command and anything it started
+}
+
+// execProcessRunner runs the command with bash in its own process group.
+type execProcessRunner struct{}
+
+// execProcess is a bash process started by execProcessRunner.
+type execProcess struct {
+	cmd    *exec.Cmd
+	stdout io.Reader
+	stderr io.Reader
+}
+
+func (execProcessRunner) Start(command, workDir string) (ClaudeProcess, error) {
+	cmd := exec.Command("bash", "-c", command)
+	cmd.Dir = workDir
+	// Put child in its own process group so it doesn't receive SIGQUIT.
+	// Pdeathsig ensures child is killed if parent dies unexpectedly (Linux only).
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGTERM,
+	}
+
+	// Create pipe for stdout so we can read line-by-line
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	// Pipe stderr too, so CLI errors (auth, bad flags) show up immediately
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	// Start the process and track it for signal forwarding
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	runningProcess = cmd.Process
+	return &execProcess{cmd: cmd, stdout: stdoutPipe, stderr: stderrPipe}, nil
+}
+
+func (p *execProcess) Stdout() io.Reader { return p.stdout }
+func (p *execProcess) Stderr() io.Reader { return p.stderr }
+
+func (p *execProcess) Wait() error {
+	defer func() { runningProcess = nil }()
+	return p.cmd.Wait()
+}
+
+func (p *execProcess) Kill() {
+	// Kill the entire process group
+	syscall.Kill(-p.cmd.Process.Pid, syscall.SIGTERM)
+}
+
+// claudeCommandLine builds the shell command running claudeCmd on prompt with
+// the flags the parser needs, passing the prompt through a heredoc to avoid
+// shell escaping issues.
+func claudeCommandLine(spec StreamParserSpec, stream StreamConfig, claudeCmd, claudeFlags, prompt string) string {
+	const delimiter = "__NIGEL_PROMPT_EOF__"
+	parts := []string{claudeCmd}
+	if spec.Flags != nil {
+		parts = append(parts, spec.Flags(stream))
+	}
+	parts = append(parts, claudeFlags, spec.Prompt)
+
+	var cmdStr strings.Builder
+	for _, part := range parts {
+		if part != "" {
+			cmdStr.WriteString(part + " ")
+		}
+	}
+	fmt.Fprintf(&cmdStr, "<<'%s'\n%s\n%s", delimiter, prompt, delimiter)
+	return cmdStr.String()
+}
+
+// RunClaudeCommand executes the Claude command with prompt, timeout, and streaming output.
+// The streamCb callback is invoked for each chunk of text received, stderrCb
+// for each line Claude writes to stderr, and rawCb (optional) for each raw
+// stdout line before it is parsed, as they arrive. stream picks the parser for
+// the CLI's output; for claude-stream-json its Format is one of the Output*
+// formats, or empty to request stream-json and probe what comes back.
+// With inactivity set, Claude is also killed if its stdout goes quiet for that
+// long, which a hung session does while the timeout runs down.
+// Returns the accumulated output (for rate limit detection), session ID, and any error.
+func RunClaudeCommand(claudeCmd, claudeFlags string, stream StreamConfig, prompt, workDir string, logWriter io.Writer, timeout, inactivity time.Duration, streamCb, stderrCb, rawCb StreamCallback, toolCb ToolUseCallback) (ClaudeResult, error) {
+	spec, ok := lookupStreamParser(stream.Parser)
+	if !ok {
+		return ClaudeResult{}, fmt.Errorf("unknown stream_parser %q", stream.Parser)
+	}
+	run := claudeRun{
+		runner:     execProcessRunner{},
+		parser:     spec.New(stream, StreamCallbacks{Log: logWriter, OnText: streamCb, OnTool: toolCb}),
+		logWriter:  logWriter,
+		stderrCb:   stderrCb,
+		rawCb:      rawCb,
+		timeout:    timeout,
+		inactivity: inactivity,
+	}
+	return run.run(claudeCommandLine(spec, stream, claudeCmd, claudeFlags, prompt), workDir)
+}
+
+// claudeRun is one invocation of Claude: how it's started, how its output is
+// parsed, and how long it may run.
+type claudeRun struct {
+	runner     ProcessRunner
+	parser     StreamParser
+	logWriter  io.Writer
+	stderrCb   StreamCallback
+	rawCb      StreamCallback // Called for each stdout line before it is parsed (optional)
+	timeout    time.Duration  // Kill it after this long (0 = no limit)
+	inactivity time.Duration  // Kill it after this long without a line on stdout (0 = no limit)
+}
+
+// run starts command in workDir and streams its output through the parser
+// until it exits or is killed for the timeout or inactivity. The output
+// includes stderr, so a rate limit message there is still seen.
+func (c claudeRun) run(command, workDir string) (ClaudeResult, error) {
+	// Log the exact command being executed (for debugging hangs)
+	if c.logWriter != nil {
+		fmt.Fprintf(c.logWriter, "Command: %s\n", command)
+	}
+
+	proc, err := c.runner.Start(command, workDir)
+	if err != nil {
+		return ClaudeResult{}, err
+	}
+
+	// Goroutine to stream stderr line-by-line, keeping a copy for the output
+	stderrCh := make(chan string, 1)
+	go func() {
+		var stderrBuf strings.Builder
+		scanner := bufio.NewScanner(proc.Stderr())
+		scanner.Buffer(nil, 10*1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text() + "\n"
+			if c.stderrCb != nil {
+				c.stderrCb(line)
+			}
+			if c.logWriter != nil {
+				fmt.Fprint(c.logWriter, "stderr: "+line)
+			}
+			stderrBuf.WriteString(line)
+		}
+		stderrCh <- stderrBuf.String()
+	}()
+
+	// Goroutine to read stdout line-by-line through the parser
+	type streamResult struct {
+		result ClaudeResult
+		err    error
+	}
+	resultCh := make(chan streamResult, 1)
+	activity := make(chan struct{}, 1)
+
+	go func() {
+		scanner := bufio.NewScanner(proc.Stdout())
+		// Increase buffer size to handle large JSON responses from Claude
+		// Default is 64KB which isn't enough for large code blocks
+		scanner.Buffer(nil, 10*1024*1024) // 10MB max token size
+		for scanner.Scan() {
+			select {
+			case activity <- struct{}{}:
+			default:
+			}
+			if c.rawCb != nil {
+				c.rawCb(scanner.Text())
+			}
+			c.parser.Line(scanner.Text())
+		}
+		result := c.parser.Finish()
+
+		// Include stderr in output for rate limit detection
+		result.Output += <-stderrCh
+
+		resultCh <- streamResult{result: result, err: scanner.Err()}
+	}()
+
+	// Wait for the stream readers, then the process. The readers must finish
+	// first because Wait closes the pipes they read from.
+	type waitResult struct {
+		stream streamResult
+		err    error
+	}
+	done := make(chan waitResult, 1)
+	go func() {
+		stream := <-resultCh
+		done <- waitResult{stream: stream, err: proc.Wait()}
+	}()
+
+	// Wait for completion, the timeout or inactivity_timeout
+	var deadline, idle <-chan time.Time
+	if c.timeout > 0 {
+		deadline = time.After(c.timeout)
+	}
+	var idleTimer *time.Timer
+	var active <-chan struct{}
+	if c.inactivity > 0 {
+		idleTimer = time.NewTimer(c.inactivity)
+		defer idleTimer.Stop()
+		idle, active = idleTimer.C, activity
+	}
+	var waited waitResult
+	var timedOut *timeoutError
+wait:
+	for {
+		select {
+		case <-deadline:
+			timedOut = &timeoutError{duration: c.timeout}
+			break wait
+		case <-idle:
+			timedOut = &timeoutError{duration: c.inactivity, inactive: true}
+			break wait
+		case <-active:
+			if !idleTimer.Stop() {
+				<-idleTimer.C
+			}
+			idleTimer.Reset(c.inactivity)
+		case waited = <-done:
+			break wait
+		}
+	}
+	if timedOut != nil {
+		proc.Kill()
+		waited = <-done
+		return waited.stream.result, timedOut
+	}
+
+	if waited.stream.err != nil {
+		return waited.stream.result, waited.stream.err
+	}
+	return waited.stream.result, waited.err
+}
+
+// Regex patterns for $INPUT interpolation
+var (
+	// $INPUT["key"] - map key access
+	inputMapKeyRe = regexp.MustCompile(`\$INPUT\["([^"]+)"\]`)
+	// $INPUT[n:] - slice from index
+	inputSliceRe = regexp.MustCompile(`\$INPUT\[(\d+):\]`)
+	// $INPUT[n] - array index access
+	inputIndexRe = regexp.MustCompile(`\$INPUT\[(\d+)\]`)
+	// $INPUT - bare input (must be checked last)
+	inputBareRe = regexp.MustCompile(`\$INPUT\b`)
+)
+
+// interpolationError is returned when $INPUT variable type doesn't match the operation.
+type interpolationError struct {
+	Variable string // The variable that caused the error (e.g., "$INPUT[0]")
+	Op       string // The operation being attempted (e.g., "array index")
+	Actual   string // The actual type (e.g., "string", "map", "array")
+}
+
+func (e *interpolationError) Error() string {
+	return fmt.Sprintf("prompt interpolation error: cannot use %s (requires array) on %s candidate", e.Variable, e.Actual)
+}
+
+// InterpolatePrompt replaces template variables with candidate values.
+// Supports: $INPUT, $INPUT[n], $INPUT[n:], $INPUT["key"], $TASK_ID
+// Returns an error if the input type doesn't match the operation (e.g., using array index on a string).
+func InterpolatePrompt(template string, candidate *Candidate, taskID int64) (string, error) {
+	result := template
+
+	// Replace $TASK_ID - unique task identifier
+	result = strings.ReplaceAll(result, "$TASK_ID", fmt.Sprintf("%d", taskID))
+
+	// Replace $INPUT["key"] - map key access
+	result = inputMapKeyRe.ReplaceAllStringFunc(result, func(match string) string {
+		submatch := inputMapKeyRe.FindStringSubmatch(match)
+		if len(submatch) < 2 {
+			return match
+		}
+		key := submatch[1]
+		if val, ok := candidate.GetKey(key); ok {
+			return val
+		}
+		return ""
+	})
+
+	// Check for type mismatches BEFORE replacement
+	// If we find $INPUT[n:] or $INPUT[n] but the candidate is not an array, error
+
+	// Replace $INPUT[n:] - slice from index
+	matches := inputSliceRe.FindAllStringSubmatch(result, -1)
+	for _, match := range matches {
+		if len(match) >= 2 {
+			if !candidate.IsArray() {
+				// Determine the actual type for the error message
+				actualType := "string"
+				if candidate.IsMap() {
+					actualType = "map"
+				}
+				return "", &interpolationError{
+					Variable: match[0],
+					Op:       "slice",
+					Actual:   actualType,
+				}
+			}
+		}
+	}
+
+	result = inputSliceRe.ReplaceAllStringFunc(result, func(match string) string {
+		submatch := inputSliceRe.FindStringSubmatch(match)
+		if len(submatch) < 2 {
+			return match
+		}
+		idx, _ := strconv.Atoi(submatch[1])
+		if val, ok := candidate.GetSlice(idx); ok {
+			return val
+		}
+		return "[]"
+	})
+
+	// Replace $INPUT[n] - array index access
+	matches = inputIndexRe.FindAllStringSubmatch(result, -1)
+	for _, match := range matches {
+		if len(match) >= 2 {
+			if !candidate.IsArray() {
+				// Determine the actual type for the error message
+				actualType := "string"
+				if candidate.IsMap() {
+					actualType = "map"
+				}
+				return "", &interpolationError{
+					Variable: match[0],
+					Op:       "array index",
+					Actual:   actualType,
+				}
+			}
+		}
+	}
+
+	result = inputIndexRe.ReplaceAllStringFunc(result, func(match string) string {
+		submatch := inputIndexRe.FindStringSubmatch(match)
+		if len(submatch) < 2 {
+			return match
+		}
+		idx, _ := strconv.Atoi(submatch[1])
+		if val, ok := candidate.GetIndex(idx); ok {
+			return val
+		}
+		return ""
+	})
+
+	// Replace bare $INPUT - whole value (with single-item unwrap)
+	result = inputBareRe.ReplaceAllStringFunc(result, func(match string) string {
+		return candidate.String()
+	})
+
+	return result, nil
+}
+
+// $OTHER_CANDIDATES or $OTHER_CANDIDATES[:N] - keys of the other pending candidates
+var otherCandidatesRe = regexp.MustCompile(`\$OTHER_CANDIDATES(?:\[:(\d+)\])?`)
+
+// InterpolateOtherCandidates replaces $OTHER_CANDIDATES with a list of the
+// given candidate keys, one per line. $OTHER_CANDIDATES[:N] lists at most N
+// and notes how many were left out.
+func InterpolateOtherCandidates(prompt string, keys []string) string {
+	return otherCandidatesRe.ReplaceAllStringFunc(prompt, func(match string) string {
+		if len(keys) == 0 {
+			return "None."
+		}
+		shown := keys
+		if sub := otherCandidatesRe.FindStringSubmatch(match); sub[1] != "" {
+			if n, _ := strconv.Atoi(sub[1]); n < len(keys) {
+				shown = keys[:n]
+			}
+		}
+		var b strings.Builder
+		for _, key := range shown {
+			fmt.Fprintf(&b, "- %s\n", key)
+		}
+		if len(shown) < len(keys) {
+			fmt.Fprintf(&b, "- ... and %d more\n", len(keys)-len(shown))
+		}
+		return strings.TrimSuffix(b.String(), "\n")
+	})
+}
+
+// shellQuote wraps a value in single quotes for safe shell interpolation.
+// Single quotes within the value are handled by ending the quote, adding an escaped quote, and restarting.
+// Example: O'Reilly -> 'O'"'"'Reilly'
+func shellQuote(value string) string {
+	if value == "" {
+		return "''"
+	}
+	// Single quotes make everything literal, except single quotes themselves.
+	// To handle single quotes in the value, we exit the single-quote context,
+	// add an escaped double-quote, and re-enter single-quote context.
+	// 'value' -> 'value'
+	// O'Reilly -> 'O'"'"'Reilly'
+	return "'" + strings.ReplaceAll(value, "'", "'\"'\"'") + "'"
+}
+
+// $CANDIDATE on its own, not the start of $CANDIDATE_FILE
+var candidateVarRe = regexp.MustCompile(`\$CANDIDATE\b`)
+
+// InterpolateCommand replaces template variables in commands.
+// Supports: $CANDIDATE, $TASK_NAME
+// $CANDIDATE is shell-quoted to safely handle special characters.
+// $CANDIDATE_FILE is left for the shell: it's exported during an attempt.
+func InterpolateCommand(command string, candidate *Candidate, taskName string) string {
+	result := candidateVarRe.ReplaceAllLiteralString(command, shellQuote(candidate.Key))
+	result = strings.ReplaceAll(result, "$TASK_NAME", taskName)
+	return result
+}
+
+// InterpolateChangedFiles replaces $CHANGED_FILES with the shell-quoted,
+// space-separated list of files.
+func InterpolateChangedFiles(command string, files []string) string {
+	quoted := make([]string, len(files))
+	for i, f := range files {
+		quoted[i] = shellQuote(f)
+	}
+	return strings.ReplaceAll(command, "$CHANGED_FILES", strings.Join(quoted, " "))
+}
+
+// OutcomeVars holds attempt metadata exposed to success_command and other hooks.
+type OutcomeVars struct {
+	Outcome    Outcome
+	Duration   time.Duration
+	SessionID  string
+	Attempt    int
+	PromptHash string
+}
+
+// InterpolateOutcome replaces attempt metadata variables in commands.
+// Supports: $OUTCOME, $DURATION, $SESSION_ID, $ATTEMPT, $PROMPT_HASH
+func InterpolateOutcome(command string, vars OutcomeVars) string {
+	result := strings.ReplaceAll(command, "$OUTCOME", string(vars.Outcome))
+	result = strings.ReplaceAll(result, "$DURATION", vars.Duration.Round(time.Second).String())
+	result = strings.ReplaceAll(result, "$SESSION_ID", vars.SessionID)
+	result = strings.ReplaceAll(result, "$ATTEMPT", strconv.Itoa(vars.Attempt))
+	result = strings.ReplaceAll(result, "$PROMPT_HASH", vars.PromptHash)
+	return result
+}
+
+// PromptHash returns a short, stable identifier for a prompt revision. It
+// covers the uninterpolated template and the claude flags, so every
+// candidate attempted with the same prompt setup shares a hash.
+func PromptHash(template, flags string) string {
+	sum := sha256.Sum256([]byte(template + "\x00" + flags))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// LoadTemplate reads a template file and returns its contents.
+func LoadTemplate(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read template: %w", err)
+	}
+	return string(data), nil
+}
+
+// HasUncommittedChanges is now defined in command_executor.go.
+
+// CheckClaudeCommand verifies the Claude command is accessible.
+func CheckClaudeCommand(claudeCmd string) error {
+	// Extract just the command name (first part before any spaces)
+	parts := strings.Fields(claudeCmd)
+	if len(parts) == 0 {
+		return fmt.Errorf("empty claude command")
+	}
+
+	_, err := exec.LookPath(parts[0])
+	if err != nil {
+		return fmt.Errorf("claude command not found: %s", parts[0])
+	}
+	return nil
+}
+
+// parseInt parses a string to int, returning 0 on error.
+func parseInt(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
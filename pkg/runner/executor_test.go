@@ -1,12 +1,16 @@
-package main
+package runner
 
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestInterpolatePrompt(t *testing.T) {
@@ -347,6 +351,75 @@ func TestInterpolateCommand(t *testing.T) {
 	}
 }
 
+func TestInterpolateOutcome(t *testing.T) {
+	vars := OutcomeVars{
+		Outcome:    OutcomeBestEffort,
+		Duration:   95*time.Second + 400*time.Millisecond,
+		SessionID:  "abc-123",
+		Attempt:    2,
+		PromptHash: "0123456789ab",
+	}
+
+	tests := []struct {
+		name     string
+		command  string
+		expected string
+	}{
+		{
+			name:     "all variables",
+			command:  "echo $OUTCOME $DURATION $SESSION_ID $ATTEMPT $PROMPT_HASH",
+			expected: "echo BEST_EFFORT 1m35s abc-123 2 0123456789ab",
+		},
+		{
+			name:     "no variables",
+			command:  "git commit -m 'fix'",
+			expected: "git commit -m 'fix'",
+		},
+		{
+			name:     "leaves $CANDIDATE for InterpolateCommand",
+			command:  "git commit -m \"$OUTCOME: $CANDIDATE\"",
+			expected: "git commit -m \"BEST_EFFORT: $CANDIDATE\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := InterpolateOutcome(tt.command, vars)
+			if result != tt.expected {
+				t.Errorf("InterpolateOutcome() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInterpolateChangedFiles(t *testing.T) {
+	result := InterpolateChangedFiles("go vet $CHANGED_FILES", []string{"main.go", "it's here.go"})
+	expected := `go vet 'main.go' 'it'"'"'s here.go'`
+	if result != expected {
+		t.Errorf("InterpolateChangedFiles() = %q, want %q", result, expected)
+	}
+}
+
+func TestPromptHash(t *testing.T) {
+	base := PromptHash("Fix $INPUT", "--fast")
+
+	if len(base) != 12 {
+		t.Errorf("PromptHash() length = %d, want 12", len(base))
+	}
+	if got := PromptHash("Fix $INPUT", "--fast"); got != base {
+		t.Errorf("PromptHash() not stable: %q != %q", got, base)
+	}
+	if got := PromptHash("Fix $INPUT carefully", "--fast"); got == base {
+		t.Error("PromptHash() should change when the template changes")
+	}
+	if got := PromptHash("Fix $INPUT", "--fast --model opus"); got == base {
+		t.Error("PromptHash() should change when the flags change")
+	}
+	if got := PromptHash("Fix $INPUT--fast", ""); got == base {
+		t.Error("PromptHash() should not collide when text moves between template and flags")
+	}
+}
+
 func TestLargeJSONLineParsing(t *testing.T) {
 	// Test that scanner can handle lines larger than default 64KB buffer
 	// This verifies the fix for "bufio.Scanner: token too long" error
@@ -387,6 +460,149 @@ func TestLargeJSONLineParsing(t *testing.T) {
 	})
 }
 
+func TestRunClaudeCommandStreamsStderr(t *testing.T) {
+	dir := t.TempDir()
+	script := dir + "/fake-claude"
+	body := `#!/bin/bash
+cat > /dev/null
+echo "Error: invalid API key" >&2
+echo '{"type":"stream_event","event":{"type":"content_block_delta","delta":{"type":"text_delta","text":"hi"}}}'
+`
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr strings.Builder
+	var raw []string
+	result, err := RunClaudeCommand(script, "", StreamConfig{}, "prompt", dir, nil, 0, 0,
+		func(text string) { stdout.WriteString(text) },
+		func(text string) { stderr.WriteString(text) },
+		func(line string) { raw = append(raw, line) }, nil)
+	if err != nil {
+		t.Fatalf("RunClaudeCommand failed: %v", err)
+	}
+
+	if stderr.String() != "Error: invalid API key\n" {
+		t.Errorf("stderr callback got %q", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "hi") || strings.Contains(stdout.String(), "API key") {
+		t.Errorf("stream callback got %q, want only stdout text", stdout.String())
+	}
+	if !strings.Contains(result.Output, "Error: invalid API key") {
+		t.Errorf("Output should include stderr for rate limit detection, got %q", result.Output)
+	}
+	if len(raw) != 1 || !strings.HasPrefix(raw[0], `{"type":"stream_event"`) {
+		t.Errorf("raw callback got %q, want the unparsed stream event", raw)
+	}
+}
+
+func TestRunClaudeCommandReportsToolUse(t *testing.T) {
+	dir := t.TempDir()
+	script := dir + "/fake-claude"
+	body := `#!/bin/bash
+cat > /dev/null
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Fixing"},{"type":"tool_use","name":"Edit","input":{"file_path":"a.go","old_string":"x"}}]}}'
+echo '{"type":"assistant","message":{"content":[{"type":"tool_use","name":"Bash","input":{"command":"go test ./...\nexit"}}]}}'
+`
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var tools []ToolUse
+	if _, err := RunClaudeCommand(script, "", StreamConfig{Format: OutputStreamJSON}, "prompt", dir, nil, 0, 0, nil, nil, nil,
+		func(tool ToolUse) { tools = append(tools, tool) }); err != nil {
+		t.Fatalf("RunClaudeCommand failed: %v", err)
+	}
+
+	want := []ToolUse{{Name: "Edit", Detail: "a.go", Path: "a.go"}, {Name: "Bash", Detail: "go test ./..."}}
+	if len(tools) != len(want) {
+		t.Fatalf("tool calls = %+v, want %+v", tools, want)
+	}
+	for i := range want {
+		if tools[i] != want[i] {
+			t.Errorf("tool call %d = %+v, want %+v", i, tools[i], want[i])
+		}
+	}
+}
+
+func TestRunClaudeCommandOutputFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		output string // Printed by the fake claude command
+		want   string // Text passed to the stream callback
+		wantID string
+		usage  Usage
+	}{
+		{
+			name:   "probed plain text",
+			output: "Fixed the lint\nDone\n",
+			want:   "Fixed the lint\nDone\n",
+		},
+		{
+			name:   "probed compact json document",
+			output: `{"type":"result","result":"All fixed","session_id":"abc","total_cost_usd":0.05,"num_turns":4,"duration_ms":2500,"is_error":true,"usage":{"input_tokens":10,"output_tokens":3}}` + "\n",
+			want:   "All fixed\n",
+			wantID: "abc",
+			usage:  Usage{InputTokens: 10, OutputTokens: 3, CostUSD: 0.05, NumTurns: 4, APIDuration: 2500 * time.Millisecond, IsError: true},
+		},
+		{
+			name:   "probed pretty-printed json document",
+			output: "{\n  \"type\": \"result\",\n  \"result\": \"All fixed\",\n  \"session_id\": \"abc\"\n}\n",
+			want:   "All fixed\n",
+			wantID: "abc",
+		},
+		{
+			name:   "explicit text keeps json-looking lines",
+			format: OutputText,
+			output: `{"type":"result","result":"ignored"}` + "\n",
+			want:   `{"type":"result","result":"ignored"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(dir+"/out", []byte(tt.output), 0644); err != nil {
+				t.Fatal(err)
+			}
+			script := dir + "/fake-claude"
+			if err := os.WriteFile(script, []byte("#!/bin/bash\ncat > /dev/null\ncat \""+dir+"/out\"\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			var streamed strings.Builder
+			result, err := RunClaudeCommand(script, "", StreamConfig{Format: tt.format}, "prompt", dir, nil, 0, 0,
+				func(text string) { streamed.WriteString(text) }, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("RunClaudeCommand failed: %v", err)
+			}
+			// RunClaudeCommand ends every stream with a newline
+			if got := streamed.String(); got != tt.want+"\n" {
+				t.Errorf("streamed %q, want %q", got, tt.want+"\n")
+			}
+			if result.SessionID != tt.wantID {
+				t.Errorf("SessionID = %q, want %q", result.SessionID, tt.wantID)
+			}
+			if result.Usage != tt.usage {
+				t.Errorf("Usage = %+v, want %+v", result.Usage, tt.usage)
+			}
+		})
+	}
+}
+
+func TestOutputFormatFlags(t *testing.T) {
+	if flags := outputFormatFlags(OutputJSON); flags != "--print --output-format json" {
+		t.Errorf("json flags = %q", flags)
+	}
+	if flags := outputFormatFlags(OutputText); flags != "--print" {
+		t.Errorf("text flags = %q", flags)
+	}
+	if flags := outputFormatFlags(""); !strings.Contains(flags, "stream-json") {
+		t.Errorf("default flags = %q, want stream-json", flags)
+	}
+}
+
 func TestStreamingWithEmptyMessages(t *testing.T) {
 	// Test that simulates streaming events including empty messages
 	// Verifies no extra newlines are added after empty messages
@@ -585,3 +801,318 @@ func TestRunCommandShowOnFail(t *testing.T) {
 		}
 	})
 }
+
+func TestRunWithTimeout(t *testing.T) {
+	exec := &RealCommandExecutor{}
+
+	t.Run("completes within timeout", func(t *testing.T) {
+		ok, err := exec.RunWithTimeout("true", ".", 5*time.Second)
+		if err != nil || !ok {
+			t.Errorf("RunWithTimeout = (%v, %v), want (true, nil)", ok, err)
+		}
+	})
+
+	t.Run("non-zero exit is not an error", func(t *testing.T) {
+		ok, err := exec.RunWithTimeout("exit 1", ".", 5*time.Second)
+		if err != nil || ok {
+			t.Errorf("RunWithTimeout = (%v, %v), want (false, nil)", ok, err)
+		}
+	})
+
+	t.Run("kills command after timeout", func(t *testing.T) {
+		start := time.Now()
+		ok, err := exec.RunWithTimeout("sleep 10", ".", 100*time.Millisecond)
+		if ok {
+			t.Error("expected ok=false")
+		}
+		if _, isTimeout := err.(*timeoutError); !isTimeout {
+			t.Errorf("expected timeoutError, got %T: %v", err, err)
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Error("command was not killed promptly")
+		}
+	})
+}
+
+func TestInterpolateOtherCandidates(t *testing.T) {
+	keys := []string{"a.go", "b.go", "c.go"}
+	tests := []struct {
+		name   string
+		prompt string
+		keys   []string
+		want   string
+	}{
+		{name: "all", prompt: "Skip:\n$OTHER_CANDIDATES", keys: keys, want: "Skip:\n- a.go\n- b.go\n- c.go"},
+		{name: "limited", prompt: "$OTHER_CANDIDATES[:2]", keys: keys, want: "- a.go\n- b.go\n- ... and 1 more"},
+		{name: "limit above count", prompt: "$OTHER_CANDIDATES[:5]", keys: keys, want: "- a.go\n- b.go\n- c.go"},
+		{name: "none", prompt: "$OTHER_CANDIDATES", want: "None."},
+		{name: "absent", prompt: "Fix $INPUT", keys: keys, want: "Fix $INPUT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InterpolateOtherCandidates(tt.prompt, tt.keys); got != tt.want {
+				t.Errorf("InterpolateOtherCandidates() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCandidateSourceLimits(t *testing.T) {
+	dir := t.TempDir()
+
+	output, err := RunCandidateSource(`echo '["a.go"]'`, dir, 0)
+	if err != nil || string(output) != "[\"a.go\"]\n" {
+		t.Fatalf("RunCandidateSource = (%q, %v)", output, err)
+	}
+
+	// Stops a source that would never finish on its own
+	_, err = RunCandidateSource("yes a.go", dir, 1024)
+	if err == nil || !strings.Contains(err.Error(), "over 1024 bytes") {
+		t.Errorf("oversized output returned %v", err)
+	}
+	if stageErr := candidateSourceError("candidate source failed", err); !errors.Is(stageErr, ErrCandidateSource) || isRetryable(stageErr) {
+		t.Error("oversized output should stop the run")
+	}
+
+	_, err = RunCandidateSource(`printf 'a.go\0b.go'`, dir, 0)
+	if err == nil || !strings.Contains(err.Error(), "NUL byte at offset 4") {
+		t.Errorf("binary output returned %v", err)
+	}
+
+	_, err = RunCandidateSource("exit 1", dir, 0)
+	if err == nil || !isRetryable(candidateSourceError("candidate source failed", err)) {
+		t.Errorf("failing source returned %v, want a retryable error", err)
+	}
+}
+
+func TestRunClaudeCommandInactivity(t *testing.T) {
+	dir := t.TempDir()
+	script := dir + "/fake-claude"
+	// Three lines 200ms apart, then hangs
+	body := `#!/bin/bash
+cat > /dev/null
+for i in 1 2 3; do echo "line $i"; sleep 0.2; done
+sleep 10
+`
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	result, err := RunClaudeCommand(script, "", StreamConfig{Format: OutputText}, "prompt", dir, nil, time.Minute, 500*time.Millisecond, nil, nil, nil, nil)
+	timeout, ok := err.(*timeoutError)
+	if !ok || !timeout.inactive {
+		t.Fatalf("expected an inactivity timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %s to notice the hang", elapsed)
+	}
+	// Lines kept resetting the timer
+	if !strings.Contains(result.Output, "line 3") {
+		t.Errorf("output = %q, want all three lines", result.Output)
+	}
+	if err.Error() != "no output for 500ms" {
+		t.Errorf("error = %q", err)
+	}
+}
+
+// fakeProcess is a ClaudeProcess writing canned output. With hang set it then
+// keeps stdout open until killed, and with chatty it also keeps writing lines.
+type fakeProcess struct {
+	stdout, stderr string
+	hang, chatty   bool
+	waitErr        error
+	outR, errR     *io.PipeReader
+	killed         chan struct{}
+	killOnce       sync.Once
+}
+
+func (p *fakeProcess) start() {
+	var outW, errW *io.PipeWriter
+	p.outR, outW = io.Pipe()
+	p.errR, errW = io.Pipe()
+	p.killed = make(chan struct{})
+	go func() {
+		io.WriteString(errW, p.stderr)
+		errW.Close()
+	}()
+	go func() {
+		defer outW.Close()
+		io.WriteString(outW, p.stdout)
+		for p.hang {
+			select {
+			case <-p.killed:
+				return
+			case <-time.After(10 * time.Millisecond):
+				if p.chatty {
+					io.WriteString(outW, "still going\n")
+				}
+			}
+		}
+	}()
+}
+
+func (p *fakeProcess) Stdout() io.Reader { return p.outR }
+func (p *fakeProcess) Stderr() io.Reader { return p.errR }
+func (p *fakeProcess) Wait() error       { return p.waitErr }
+func (p *fakeProcess) Kill()             { p.killOnce.Do(func() { close(p.killed) }) }
+
+func (p *fakeProcess) wasKilled() bool {
+	select {
+	case <-p.killed:
+		return true
+	default:
+		return false
+	}
+}
+
+// fakeProcessRunner starts its process, or fails with err.
+type fakeProcessRunner struct {
+	proc *fakeProcess
+	err  error
+}
+
+func (r fakeProcessRunner) Start(command, workDir string) (ClaudeProcess, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.proc.start()
+	return r.proc, nil
+}
+
+func TestClaudeRun(t *testing.T) {
+	exitErr := errors.New("exit status 1")
+	tests := []struct {
+		name        string
+		format      string
+		proc        *fakeProcess
+		startErr    error
+		timeout     time.Duration
+		inactivity  time.Duration
+		wantOutput  []string // Substrings of the output
+		wantSession string
+		wantErr     error  // Compared with errors.Is, for errors other than timeouts
+		wantTimeout string // Error text of the expected timeoutError
+		wantKilled  bool
+	}{
+		{
+			name: "stream-json result",
+			proc: &fakeProcess{stdout: `{"type":"system","subtype":"init","session_id":"abc"}
+{"type":"stream_event","event":{"type":"content_block_delta","delta":{"type":"text_delta","text":"done"}}}
+{"type":"result","result":"done","total_cost_usd":0.5}
+`},
+			wantOutput:  []string{"done"},
+			wantSession: "abc",
+		},
+		{
+			name:   "malformed JSON is kept in the output",
+			format: OutputStreamJSON,
+			proc: &fakeProcess{stdout: `{"type":"stream_event","event":{"type":"content_bl
+not json at all
+{"type":"stream_event","event":{"type":"content_block_delta","delta":{"type":"text_delta","text":"ok"}}}
+`},
+			wantOutput: []string{`"content_bl`, "not json at all", "ok"},
+		},
+		{
+			name:       "rate limit message on stderr reaches the output",
+			proc:       &fakeProcess{stderr: rateLimitPhrase + " · resets 5pm\n", waitErr: exitErr},
+			wantOutput: []string{rateLimitPhrase},
+			wantErr:    exitErr,
+		},
+		{
+			name:     "start failure",
+			startErr: exitErr,
+			wantErr:  exitErr,
+		},
+		{
+			name:        "timeout kills a process that keeps talking",
+			format:      OutputText,
+			proc:        &fakeProcess{stdout: "working\n", hang: true, chatty: true},
+			timeout:     100 * time.Millisecond,
+			inactivity:  time.Minute,
+			wantOutput:  []string{"working", "still going"},
+			wantTimeout: "timeout after 100ms",
+			wantKilled:  true,
+		},
+		{
+			name:        "inactivity kills a silent process",
+			format:      OutputText,
+			proc:        &fakeProcess{stdout: "working\n", hang: true},
+			timeout:     time.Minute,
+			inactivity:  100 * time.Millisecond,
+			wantOutput:  []string{"working"},
+			wantTimeout: "no output for 100ms",
+			wantKilled:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := claudeRun{
+				runner:     fakeProcessRunner{proc: tt.proc, err: tt.startErr},
+				parser:     &claudeStreamParser{Format: tt.format},
+				timeout:    tt.timeout,
+				inactivity: tt.inactivity,
+			}
+			result, err := run.run("claude", "")
+
+			if tt.wantTimeout != "" {
+				if _, ok := err.(*timeoutError); !ok || err.Error() != tt.wantTimeout {
+					t.Fatalf("error = %v, want timeout %q", err, tt.wantTimeout)
+				}
+			} else if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(result.Output, want) {
+					t.Errorf("output = %q, want it to contain %q", result.Output, want)
+				}
+			}
+			if result.SessionID != tt.wantSession {
+				t.Errorf("session = %q, want %q", result.SessionID, tt.wantSession)
+			}
+			if tt.proc != nil && tt.proc.wasKilled() != tt.wantKilled {
+				t.Errorf("killed = %v, want %v", tt.proc.wasKilled(), tt.wantKilled)
+			}
+		})
+	}
+}
+
+func TestClaudeStreamParser(t *testing.T) {
+	var text strings.Builder
+	var tools []ToolUse
+	parser := &claudeStreamParser{StreamCallbacks: StreamCallbacks{
+		OnText: func(s string) { text.WriteString(s) },
+		OnTool: func(tool ToolUse) { tools = append(tools, tool) },
+	}}
+	for _, line := range []string{
+		`{"type":"system","subtype":"init","session_id":"s1"}`,
+		`{"type":"stream_event","event":{"type":"content_block_delta","delta":{"type":"text_delta","text":"Hello"}}}`,
+		`{"type":"stream_event","event":{"type":"message_stop"}}`,
+		`{"type":"stream_event","event":{"type":"message_stop"}}`,
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","name":"Edit","input":{"file_path":"main.go"}}]}}`,
+		`{"type":"result","result":"Hello","num_turns":2,"usage":{"input_tokens":10,"output_tokens":3}}`,
+	} {
+		parser.Line(line)
+	}
+	result := parser.Finish()
+
+	// The probed format is stream-json, and the empty message adds no newline
+	if parser.Format != OutputStreamJSON {
+		t.Errorf("format = %q", parser.Format)
+	}
+	if result.Output != "Hello\n" || text.String() != "Hello\n\n" {
+		t.Errorf("output = %q, streamed = %q", result.Output, text.String())
+	}
+	if result.SessionID != "s1" || result.Usage.NumTurns != 2 || result.Usage.InputTokens != 10 {
+		t.Errorf("result = %+v", result)
+	}
+	if len(tools) != 1 || tools[0].Name != "Edit" || tools[0].Path != "main.go" {
+		t.Errorf("tools = %+v", tools)
+	}
+}
@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// exportRow is one attempt as written by `nigel export`.
+type exportRow struct {
+	Candidate       string  `json:"candidate"`
+	Outcome         Outcome `json:"outcome"`
+	Variant         string  `json:"variant,omitempty"`
+	PromptHash      string  `json:"prompt_hash"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	InputTokens     int     `json:"input_tokens"`
+	OutputTokens    int     `json:"output_tokens"`
+	CostUSD         float64 `json:"cost_usd"`
+	NumTurns        int     `json:"num_turns"`
+	ClaudeSeconds   float64 `json:"claude_duration_seconds"`
+	ClaudeError     bool    `json:"claude_error"`
+	Commit          string  `json:"commit,omitempty"`
+	VerifyError     string  `json:"verify_error,omitempty"`
+	Stalled         bool    `json:"stalled"`
+}
+
+func newExportRow(a AttemptRecord) exportRow {
+	return exportRow{
+		Candidate:       a.Candidate,
+		Outcome:         a.Outcome,
+		Variant:         a.Variant,
+		PromptHash:      a.PromptHash,
+		DurationSeconds: a.Duration.Seconds(),
+		InputTokens:     a.Usage.InputTokens,
+		OutputTokens:    a.Usage.OutputTokens,
+		CostUSD:         a.Usage.CostUSD,
+		NumTurns:        a.Usage.NumTurns,
+		ClaudeSeconds:   a.Usage.APIDuration.Seconds(),
+		ClaudeError:     a.Usage.IsError,
+		Commit:          a.Commit,
+		VerifyError:     a.VerifyError,
+		Stalled:         a.Stalled,
+	}
+}
+
+var exportHeader = []string{"candidate", "outcome", "variant", "prompt_hash", "duration_seconds", "input_tokens", "output_tokens", "cost_usd",
+	"num_turns", "claude_duration_seconds", "claude_error", "commit", "verify_error", "stalled"}
+
+// ExportAttempts writes one row per attempt in csv or json format.
+func ExportAttempts(w io.Writer, attempts []AttemptRecord, format string) error {
+	rows := make([]exportRow, len(attempts))
+	for i, a := range attempts {
+		rows[i] = newExportRow(a)
+	}
+
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(exportHeader)
+		for _, r := range rows {
+			cw.Write([]string{
+				r.Candidate, string(r.Outcome), r.Variant, r.PromptHash,
+				strconv.FormatFloat(r.DurationSeconds, 'f', -1, 64),
+				strconv.Itoa(r.InputTokens), strconv.Itoa(r.OutputTokens),
+				strconv.FormatFloat(r.CostUSD, 'f', -1, 64), strconv.Itoa(r.NumTurns),
+				strconv.FormatFloat(r.ClaudeSeconds, 'f', -1, 64), strconv.FormatBool(r.ClaudeError), r.Commit,
+				r.VerifyError, strconv.FormatBool(r.Stalled),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	default:
+		return fmt.Errorf("unknown export format %q (want csv or json)", format)
+	}
+}
+
+// ExportTask writes a task's attempt history to outPath, or stdout if empty.
+func ExportTask(env *Environment, taskName, format, outPath string) error {
+	task, ok := env.Tasks[taskName]
+	if !ok {
+		return fmt.Errorf("task not found: %s", taskName)
+	}
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("unknown export format %q (want csv or json)", format)
+	}
+
+	attempts, err := ReadTaskAttempts(env, task)
+	if err != nil {
+		return err
+	}
+
+	if outPath == "" {
+		return ExportAttempts(os.Stdout, attempts, format)
+	}
+	file, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", outPath, err)
+	}
+	if err := ExportAttempts(file, attempts, format); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Println(ColorInfo(fmt.Sprintf("Exported %d attempts to %s", len(attempts), outPath)))
+	return nil
+}
@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportAttempts(t *testing.T) {
+	attempts := []AttemptRecord{
+		{Outcome: OutcomeFixed, Candidate: "a.go", PromptHash: "aaa", Duration: 90 * time.Second,
+			Usage: Usage{InputTokens: 1200, OutputTokens: 340, CostUSD: 0.0421, NumTurns: 7, APIDuration: 80 * time.Second}, Commit: "abc1234"},
+		{Outcome: OutcomeBuildFailed, Candidate: "b, c.go", PromptHash: "aaa", Variant: "terse", Duration: 5 * time.Second,
+			VerifyError: "undefined: foo", Stalled: true},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := ExportAttempts(&buf, attempts, "csv"); err != nil {
+			t.Fatalf("ExportAttempts failed: %v", err)
+		}
+		want := "candidate,outcome,variant,prompt_hash,duration_seconds,input_tokens,output_tokens,cost_usd,num_turns,claude_duration_seconds,claude_error,commit,verify_error,stalled\n" +
+			"a.go,FIXED,,aaa,90,1200,340,0.0421,7,80,false,abc1234,,false\n" +
+			"\"b, c.go\",BUILD_FAILED,terse,aaa,5,0,0,0,0,0,false,,undefined: foo,true\n"
+		if buf.String() != want {
+			t.Errorf("csv =\n%s\nwant\n%s", buf.String(), want)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := ExportAttempts(&buf, attempts, "json"); err != nil {
+			t.Fatalf("ExportAttempts failed: %v", err)
+		}
+		var rows []exportRow
+		if err := json.Unmarshal(buf.Bytes(), &rows); err != nil {
+			t.Fatalf("invalid json: %v", err)
+		}
+		if len(rows) != 2 || rows[0].CostUSD != 0.0421 || rows[0].Commit != "abc1234" || rows[1].DurationSeconds != 5 ||
+			rows[1].VerifyError != "undefined: foo" || !rows[1].Stalled {
+			t.Errorf("rows = %+v", rows)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		err := ExportAttempts(&bytes.Buffer{}, attempts, "xml")
+		if err == nil || !strings.Contains(err.Error(), "xml") {
+			t.Errorf("err = %v, want unknown format error", err)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// `external_edits:` settings for files that change during an attempt without
+// Claude editing them, e.g. someone saving a file in their editor.
+const (
+	ExternalEditsPrompt = "prompt" // Ask whether to carry on, aborting without a terminal
+	ExternalEditsAbort  = "abort"  // Stop the run, leaving the working tree as it is
+)
+
+// editingTools are the Claude tools that write the file in their input.
+var editingTools = map[string]bool{"Edit": true, "MultiEdit": true, "Write": true, "NotebookEdit": true}
+
+// recordEdit notes the file an editing tool call wrote, relative to workDir
+// like ChangedFiles.
+func (r *Runner) recordEdit(tool ToolUse) {
+	if !editingTools[tool.Name] || tool.Path == "" || r.edited == nil {
+		return
+	}
+	path := tool.Path
+	if filepath.IsAbs(path) {
+		rel, err := filepath.Rel(r.workDir(), path)
+		if err != nil {
+			return
+		}
+		path = rel
+	}
+	r.edited[filepath.Clean(path)] = true
+}
+
+// externalEdits returns the changed files that were neither changed before the
+// attempt nor written by Claude's editing tools.
+func externalEdits(changed []string, before, edited map[string]bool) []string {
+	var external []string
+	for _, file := range changed {
+		file = filepath.Clean(file)
+		if !before[file] && !edited[file] {
+			external = append(external, file)
+		}
+	}
+	sort.Strings(external)
+	return external
+}
+
+// checkExternalEdits looks for files changed during the attempt that Claude
+// didn't edit, and with external_edits set, asks whether to carry on or stops
+// the run before they're verified and then committed or reset with the fix.
+// Files Claude changed through shell commands (formatters, code generators)
+// can't be told apart from someone else's edits, so they're reported too.
+func (r *Runner) checkExternalEdits() error {
+	mode := r.env.Config.ExternalEdits
+	if mode == "" {
+		return nil
+	}
+	files, err := r.executor.ChangedFiles(r.workDir())
+	if err != nil {
+		return retryableError(ErrVerify, "failed to list changed files: %w", err)
+	}
+	external := externalEdits(files, r.dirtyBefore, r.edited)
+	if len(external) == 0 {
+		return nil
+	}
+
+	fmt.Println(ColorWarning(fmt.Sprintf("%d file(s) changed during the attempt that Claude didn't edit: %s",
+		len(external), summarizeKeys(external))))
+	if mode == ExternalEditsPrompt && isTerminal(os.Stdin) &&
+		askYesNo("Carry on? They'll be verified, then committed or reset along with the fix") {
+		return nil
+	}
+	return fatalError(ErrVerify, "files changed outside Claude during the attempt (%s); leaving the working tree as it is",
+		summarizeKeys(external))
+}
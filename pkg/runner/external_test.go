@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExternalEdits(t *testing.T) {
+	changed := []string{"src/b.go", "notes.md", "src/a.go", "wip.go"}
+	before := map[string]bool{"wip.go": true}
+	edited := map[string]bool{"src/a.go": true}
+
+	got := externalEdits(changed, before, edited)
+	want := []string{"notes.md", "src/b.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("externalEdits() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckExternalEdits(t *testing.T) {
+	tmpDir := t.TempDir()
+	taskDir := filepath.Join(tmpDir, "test-task")
+	if err := os.Mkdir(taskDir, 0755); err != nil {
+		t.Fatalf("failed to create task dir: %v", err)
+	}
+	env := &Environment{
+		ProjectDir: tmpDir,
+		Config:     Config{ClaudeCommand: "claude", ExternalEdits: ExternalEditsAbort},
+		Tasks: map[string]Task{
+			"test-task": {Name: "test-task", Dir: taskDir, Prompt: "test prompt"},
+		},
+	}
+	runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("NewRunner failed: %v", err)
+	}
+	mock := NewMockCommandExecutor()
+	runner.setExecutor(mock)
+	runner.captureBaseline()
+
+	runner.recordEdit(ToolUse{Name: "Edit", Path: filepath.Join(runner.workDir(), "src", "a.go")})
+	runner.recordEdit(ToolUse{Name: "Write", Path: "src/new.go"})
+	runner.recordEdit(ToolUse{Name: "Read", Path: "notes.md"})
+
+	mock.Changed = []string{"src/a.go", "src/new.go"}
+	if err := runner.checkExternalEdits(); err != nil {
+		t.Errorf("only Claude's edits changed, got %v", err)
+	}
+
+	mock.Changed = []string{"src/a.go", "notes.md"}
+	err = runner.checkExternalEdits()
+	if err == nil || isRetryable(err) || !errors.Is(err, ErrVerify) {
+		t.Fatalf("expected a fatal verify error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "notes.md") || strings.Contains(err.Error(), "a.go") {
+		t.Errorf("error should name only the external edit, got %q", err)
+	}
+
+	env.Config.ExternalEdits = ""
+	if err := runner.checkExternalEdits(); err != nil {
+		t.Errorf("check should be off without external_edits, got %v", err)
+	}
+}
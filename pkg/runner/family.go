@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CandidateFamilies is the `candidate_families:` task setting, grouping
+// candidates that tend to succeed or fail together (same file, same lint rule)
+// so the attempt history of a family steers the selection order.
+type CandidateFamilies struct {
+	Field     string `yaml:"field"`      // Map key, or array index, whose value names the family
+	SkipAfter int    `yaml:"skip_after"` // Skip a family for the session after this many attempts without a fix (0 = only deprioritize)
+}
+
+func validateCandidateFamilies(f CandidateFamilies) error {
+	if f.Field == "" {
+		return fmt.Errorf("'field' is required")
+	}
+	if f.SkipAfter < 0 {
+		return fmt.Errorf("'skip_after' must not be negative")
+	}
+	return nil
+}
+
+// family returns the family a candidate belongs to, or "" if it has no value
+// for the field (including string candidates, which have no fields).
+func (f *CandidateFamilies) family(c *Candidate) string {
+	return c.Field(f.Field)
+}
+
+// candidateFromKey rebuilds a candidate from the key recorded in claude.log.
+// Structured candidates' keys are their JSON; anything else is a plain string.
+func candidateFromKey(key string) *Candidate {
+	if len(key) > 0 && (key[0] == '{' || key[0] == '[') && json.Valid([]byte(key)) {
+		return &Candidate{Key: key, Data: json.RawMessage(key)}
+	}
+	data, _ := json.Marshal(key)
+	return &Candidate{Key: key, Data: data}
+}
+
+// familyRecord counts a family's attempts and the ones that made progress.
+type familyRecord struct {
+	attempts int
+	fixed    int // Fixed or best-effort
+}
+
+// failing reports whether the family has been attempted and never fixed.
+func (f familyRecord) failing() bool {
+	return f.attempts > 0 && f.fixed == 0
+}
+
+// familyHistory tallies attempts by family.
+func familyHistory(history map[string][]AttemptRecord, f *CandidateFamilies) map[string]familyRecord {
+	families := make(map[string]familyRecord)
+	for key, attempts := range history {
+		name := f.family(candidateFromKey(key))
+		if name == "" {
+			continue
+		}
+		record := families[name]
+		for _, a := range attempts {
+			record.attempts++
+			if a.Outcome == OutcomeFixed || a.Outcome == OutcomeBestEffort {
+				record.fixed++
+			}
+		}
+		families[name] = record
+	}
+	return families
+}
+
+// prioritizeFamilies moves candidates whose family has only ever failed behind
+// the rest, keeping candidate source order otherwise, so attempts go where
+// Claude has succeeded before. With skip_after, families that have failed that
+// many times are skipped for the rest of the session; they're not added to the
+// ignored list, so a later session with a better prompt tries them again.
+func (r *Runner) prioritizeFamilies(candidates []Candidate) ([]Candidate, error) {
+	f := r.task.CandidateFamilies
+	if f == nil {
+		return candidates, nil
+	}
+	if err := r.loadHistory(); err != nil {
+		return nil, err
+	}
+	families := familyHistory(r.history, f)
+
+	var preferred, deprioritized []Candidate
+	for _, c := range candidates {
+		name := f.family(&c)
+		record := families[name]
+		if name == "" || !record.failing() {
+			preferred = append(preferred, c)
+			continue
+		}
+		if f.SkipAfter > 0 && record.attempts >= f.SkipAfter && r.ignoredList != nil && !r.ignoredList.Contains(c.Key) {
+			if !r.skippedFamilies[name] {
+				r.skippedFamilies[name] = true
+				fmt.Println(ColorWarning(fmt.Sprintf("Skipping family %s for this session: 0 of %d attempts fixed", name, record.attempts)))
+			}
+			r.ignoredList.SkipForSession(c.Key)
+		}
+		deprioritized = append(deprioritized, c)
+	}
+	return append(preferred, deprioritized...), nil
+}
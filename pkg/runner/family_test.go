@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCandidateFamily(t *testing.T) {
+	byFile := &CandidateFamilies{Field: "file"}
+	if got := byFile.family(candidateFromKey(`{"file":"a.go","line":3}`)); got != "a.go" {
+		t.Errorf("map family = %q, want a.go", got)
+	}
+	if got := byFile.family(candidateFromKey("plain")); got != "" {
+		t.Errorf("string candidates have no family, got %q", got)
+	}
+
+	byIndex := &CandidateFamilies{Field: "1"}
+	if got := byIndex.family(candidateFromKey(`["a.go","unused-var"]`)); got != "unused-var" {
+		t.Errorf("array family = %q, want unused-var", got)
+	}
+}
+
+func TestPrioritizeFamilies(t *testing.T) {
+	tmpDir := t.TempDir()
+	taskDir := filepath.Join(tmpDir, "test-task")
+	if err := os.Mkdir(taskDir, 0755); err != nil {
+		t.Fatalf("failed to create task dir: %v", err)
+	}
+	env := &Environment{
+		ProjectDir: tmpDir,
+		Config:     Config{ClaudeCommand: "claude"},
+		Tasks: map[string]Task{
+			"test-task": {Name: "test-task", Dir: taskDir, Prompt: "test prompt",
+				CandidateFamilies: &CandidateFamilies{Field: "file", SkipAfter: 3}},
+		},
+	}
+
+	candidate := func(file string, line int) Candidate {
+		data, _ := json.Marshal(map[string]interface{}{"file": file, "line": line})
+		return Candidate{Key: string(data), Data: data}
+	}
+	stuck, tried, fixed, fresh := candidate("stuck.go", 1), candidate("tried.go", 1), candidate("fixed.go", 2), candidate("new.go", 1)
+
+	setup := func(t *testing.T) *Runner {
+		runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
+		if err != nil {
+			t.Fatalf("NewRunner failed: %v", err)
+		}
+		runner.history = map[string][]AttemptRecord{
+			candidate("stuck.go", 9).Key: {{Outcome: OutcomeNotFixed}, {Outcome: OutcomeTimeout}, {Outcome: OutcomeBuildFailed}},
+			candidate("tried.go", 9).Key: {{Outcome: OutcomeNotFixed}},
+			candidate("fixed.go", 1).Key: {{Outcome: OutcomeNotFixed}, {Outcome: OutcomeFixed}},
+		}
+		return runner
+	}
+
+	t.Run("failing families go last", func(t *testing.T) {
+		runner := setup(t)
+		ordered, err := runner.prioritizeFamilies([]Candidate{stuck, tried, fixed, fresh})
+		if err != nil {
+			t.Fatalf("prioritizeFamilies failed: %v", err)
+		}
+		var keys []string
+		for _, c := range ordered {
+			keys = append(keys, c.Key)
+		}
+		want := []string{fixed.Key, fresh.Key, stuck.Key, tried.Key}
+		for i := range want {
+			if keys[i] != want[i] {
+				t.Fatalf("order = %v, want %v", keys, want)
+			}
+		}
+	})
+
+	t.Run("skip_after skips the family for the session", func(t *testing.T) {
+		runner := setup(t)
+		if _, err := runner.prioritizeFamilies([]Candidate{stuck, tried}); err != nil {
+			t.Fatalf("prioritizeFamilies failed: %v", err)
+		}
+		if !runner.ignoredList.Contains(stuck.Key) {
+			t.Error("a family with 3 failed attempts should be skipped")
+		}
+		if runner.ignoredList.Contains(tried.Key) {
+			t.Error("a family with 1 failed attempt should only be deprioritized")
+		}
+		if _, err := os.Stat(filepath.Join(taskDir, "ignored.log")); !os.IsNotExist(err) {
+			t.Error("skipped families should not be written to ignored.log")
+		}
+	})
+}
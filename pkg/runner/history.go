@@ -0,0 +1,224 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AttemptRecord is one outcome entry read back from claude.log.
+type AttemptRecord struct {
+	Outcome     Outcome
+	Candidate   string
+	PromptHash  string
+	Variant     string // "" for attempts made without prompt variants
+	SessionID   string // Claude session ID, if the output reported one
+	VerifyError string // Excerpt of the failing verify output, if any
+	Verify      string // passed, failed or flaky; "" if verify didn't run or wasn't recorded
+	Stalled     bool   // Claude was killed by inactivity_timeout and its edits salvaged
+	Usage       Usage  // Tokens and cost, if Claude reported them
+	Commit      string // Revision the fix was committed as, if any
+	Duration    time.Duration
+	Details     string
+}
+
+// AttemptRecord.Verify values.
+const (
+	VerifyPassed = "passed"
+	VerifyFailed = "failed"
+	VerifyFlaky  = "flaky" // Failed, then passed on a verify_retries re-run
+)
+
+// ReadAttempts parses the outcome entries from a claude.log file. A missing
+// log yields no attempts.
+func ReadAttempts(path string) ([]AttemptRecord, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var attempts []AttemptRecord
+	var current *AttemptRecord
+	var prev, prev2 string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024) // Claude output lines can be long
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case prev2 == "" && prev == separator && strings.HasPrefix(line, "Outcome: "):
+			// Outcome blocks always open with a blank line and a separator, so
+			// stray "Outcome:" lines in Claude's streamed output are not mistaken for one
+			attempts = append(attempts, AttemptRecord{Outcome: Outcome(strings.TrimPrefix(line, "Outcome: "))})
+			current = &attempts[len(attempts)-1]
+		case current != nil && strings.HasPrefix(line, "Candidate: "):
+			current.Candidate = strings.TrimPrefix(line, "Candidate: ")
+		case current != nil && strings.HasPrefix(line, "Prompt Hash: "):
+			current.PromptHash = strings.TrimPrefix(line, "Prompt Hash: ")
+		case current != nil && strings.HasPrefix(line, "Variant: "):
+			current.Variant = strings.TrimPrefix(line, "Variant: ")
+		case current != nil && strings.HasPrefix(line, "Session ID: "):
+			current.SessionID = strings.TrimPrefix(line, "Session ID: ")
+		case current != nil && line == "Stalled: true":
+			current.Stalled = true
+		case current != nil && strings.HasPrefix(line, "Verify: "):
+			current.Verify = strings.TrimPrefix(line, "Verify: ")
+		case current != nil && strings.HasPrefix(line, "Verify Error: "):
+			current.VerifyError = strings.TrimPrefix(line, "Verify Error: ")
+		case current != nil && strings.HasPrefix(line, "Tokens: "):
+			fmt.Sscanf(strings.TrimPrefix(line, "Tokens: "), "%d in / %d out", &current.Usage.InputTokens, &current.Usage.OutputTokens)
+		case current != nil && strings.HasPrefix(line, "Cost: "):
+			fmt.Sscanf(strings.TrimPrefix(line, "Cost: "), "$%g", &current.Usage.CostUSD)
+		case current != nil && strings.HasPrefix(line, "Turns: "):
+			current.Usage.NumTurns, _ = strconv.Atoi(strings.TrimPrefix(line, "Turns: "))
+		case current != nil && strings.HasPrefix(line, "Claude Duration: "):
+			current.Usage.APIDuration = parseLoggedDuration(strings.TrimPrefix(line, "Claude Duration: "))
+		case current != nil && strings.HasPrefix(line, "Claude Error: "):
+			current.Usage.IsError = strings.TrimPrefix(line, "Claude Error: ") == "true"
+		case current != nil && strings.HasPrefix(line, "Commit: "):
+			current.Commit = strings.TrimPrefix(line, "Commit: ")
+		case current != nil && strings.HasPrefix(line, "Duration: "):
+			current.Duration = parseLoggedDuration(strings.TrimPrefix(line, "Duration: "))
+		case current != nil && strings.HasPrefix(line, "Details: "):
+			current.Details = strings.TrimPrefix(line, "Details: ")
+			current = nil
+		}
+		prev2, prev = prev, line
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return attempts, nil
+}
+
+// ReadTaskAttempts reads a task's attempts from all of its log files, oldest first.
+func ReadTaskAttempts(env *Environment, task Task) ([]AttemptRecord, error) {
+	files, err := env.LogFiles(task)
+	if err != nil {
+		return nil, err
+	}
+	var attempts []AttemptRecord
+	for _, path := range files {
+		fileAttempts, err := ReadAttempts(path)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, fileAttempts...)
+	}
+	return attempts, nil
+}
+
+// parseLoggedDuration reads back a duration written by formatDuration, which
+// parses once the space in e.g. "1m 05s" is removed. Returns 0 if malformed.
+func parseLoggedDuration(s string) time.Duration {
+	d, _ := time.ParseDuration(strings.ReplaceAll(s, " ", ""))
+	return d
+}
+
+// LastSessionID returns the session ID of the most recent attempt on a
+// candidate that recorded one, or "" if there is none.
+func LastSessionID(attempts []AttemptRecord, candidate string) string {
+	for i := len(attempts) - 1; i >= 0; i-- {
+		if attempts[i].Candidate == candidate && attempts[i].SessionID != "" {
+			return attempts[i].SessionID
+		}
+	}
+	return ""
+}
+
+// ResumeSession reopens the Claude session of a candidate's most recent
+// attempt interactively, in the directory the attempt ran in.
+func ResumeSession(env *Environment, taskName, candidate, claudeOverride string) error {
+	task, ok := env.Tasks[taskName]
+	if !ok {
+		return fmt.Errorf("task not found: %s", taskName)
+	}
+
+	attempts, err := ReadTaskAttempts(env, task)
+	if err != nil {
+		return err
+	}
+	sessionID := LastSessionID(attempts, candidate)
+	if sessionID == "" {
+		return fmt.Errorf("no recorded session for candidate %s in task %s", candidate, taskName)
+	}
+
+	// Same precedence as a run: CLI override > task-level > global
+	claudeCmd := claudeOverride
+	if claudeCmd == "" {
+		claudeCmd = task.ClaudeCommand
+	}
+	if claudeCmd == "" {
+		claudeCmd = env.Config.ClaudeCommand
+	}
+
+	fmt.Println(ColorInfo(fmt.Sprintf("Resuming session %s for %s...", sessionID, candidate)))
+	cmd := exec.Command("bash", "-c", claudeCmd+" --resume "+shellQuote(sessionID))
+	cmd.Dir = task.WorkDir(env.ForTask(task).ProjectDir)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// maxPreviousAttempts caps how many prior attempts $PREVIOUS_ATTEMPTS lists.
+const maxPreviousAttempts = 5
+
+// FormatPreviousAttempts summarizes a candidate's earlier attempts for the
+// $PREVIOUS_ATTEMPTS prompt variable, most recent last.
+func FormatPreviousAttempts(attempts []AttemptRecord) string {
+	if len(attempts) == 0 {
+		return "No previous attempts."
+	}
+
+	first := 0
+	if len(attempts) > maxPreviousAttempts {
+		first = len(attempts) - maxPreviousAttempts
+	}
+
+	var b strings.Builder
+	for i := first; i < len(attempts); i++ {
+		a := attempts[i]
+		fmt.Fprintf(&b, "- Attempt %d: %s (%s)", i+1, a.Outcome, a.Details)
+		// Build failures carry the excerpt in their details already
+		if a.VerifyError != "" && !strings.Contains(a.Details, a.VerifyError) {
+			fmt.Fprintf(&b, "; verify error: %s", a.VerifyError)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// defaultExcerptLines and excerptLineLength bound the verify output kept per
+// attempt: the last few lines, at most excerptLineLength characters each on
+// average.
+const (
+	defaultExcerptLines = 3
+	excerptLineLength   = 100
+)
+
+// errorExcerpt condenses command output to its last maxLines non-empty lines
+// on a single line, suitable for the log and prompts.
+func errorExcerpt(output string, maxLines int) string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > maxLines {
+		lines = lines[len(lines)-maxLines:]
+	}
+	excerpt := strings.Join(lines, " | ")
+	if maxLength := maxLines * excerptLineLength; len(excerpt) > maxLength {
+		excerpt = "..." + excerpt[len(excerpt)-maxLength:]
+	}
+	return excerpt
+}
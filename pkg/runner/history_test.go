@@ -0,0 +1,213 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadAttempts(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "claude.log")
+	file, err := os.Create(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := &ClaudeLogger{file: file}
+
+	logger.StartEntry(LogEntry{Candidate: "a.go", Prompt: "Fix a", PromptHash: "aaa", Variant: "terse"})
+	logger.Write([]byte("Outcome: FIXED\n")) // Claude output that looks like an outcome
+	logger.EndEntry()
+	logger.LogOutcome(OutcomeBuildFailed, "reverted", OutcomeMeta{SessionID: "session-1", VerifyError: "undefined: foo", Stalled: true})
+	logger.EndEntry()
+	logger.StartEntry(LogEntry{Candidate: "b.go", Prompt: "Fix b", PromptHash: "bbb"})
+	logger.EndEntry()
+	logger.LogOutcome(OutcomeFixed, "committed", OutcomeMeta{
+		Usage:  Usage{InputTokens: 1200, OutputTokens: 340, CostUSD: 0.0421, NumTurns: 7, APIDuration: 65 * time.Second, IsError: true},
+		Commit: "abc1234",
+	})
+	logger.EndEntry()
+	logger.Close()
+
+	attempts, err := ReadAttempts(logPath)
+	if err != nil {
+		t.Fatalf("ReadAttempts failed: %v", err)
+	}
+
+	want := []AttemptRecord{
+		{Outcome: OutcomeBuildFailed, Candidate: "a.go", PromptHash: "aaa", Variant: "terse", SessionID: "session-1", VerifyError: "undefined: foo", Stalled: true, Details: "reverted"},
+		{Outcome: OutcomeFixed, Candidate: "b.go", PromptHash: "bbb", Usage: Usage{InputTokens: 1200, OutputTokens: 340, CostUSD: 0.0421, NumTurns: 7, APIDuration: 65 * time.Second, IsError: true}, Commit: "abc1234", Details: "committed"},
+	}
+	if len(attempts) != len(want) {
+		t.Fatalf("got %d attempts, want %d: %+v", len(attempts), len(want), attempts)
+	}
+	for i := range want {
+		if attempts[i] != want[i] {
+			t.Errorf("attempt %d = %+v, want %+v", i, attempts[i], want[i])
+		}
+	}
+
+	missing, err := ReadAttempts(filepath.Join(t.TempDir(), "claude.log"))
+	if err != nil || len(missing) != 0 {
+		t.Errorf("missing log: got %v, %v; want no attempts", missing, err)
+	}
+}
+
+func TestLogPath(t *testing.T) {
+	task := Task{Name: "lint", Dir: "/repo/nigel/lint"}
+	day := time.Date(2026, 3, 14, 23, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{"default", Config{}, "/repo/nigel/lint/claude.log"},
+		{"dated in task dir", Config{LogFilePattern: "claude-$DATE.log"}, "/repo/nigel/lint/claude-2026-03-14.log"},
+		{"log_dir", Config{LogDir: "/mnt/logs"}, "/mnt/logs/lint.log"},
+		{"log_dir with pattern", Config{LogDir: "/mnt/logs", LogFilePattern: "$TASK_NAME/$DATE.log"}, "/mnt/logs/lint/2026-03-14.log"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := &Environment{Config: tt.config}
+			if got := env.LogPath(task, day); got != tt.want {
+				t.Errorf("LogPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTaskAttemptsAcrossDatedLogs(t *testing.T) {
+	logDir := t.TempDir()
+	env := &Environment{Config: Config{LogDir: logDir, LogFilePattern: "$TASK_NAME/$DATE.log"}}
+	task := Task{Name: "lint", Dir: t.TempDir()}
+
+	// Written out of order; history is read oldest first
+	for _, day := range []time.Time{
+		time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC),
+	} {
+		logger, err := NewClaudeLogger(env.LogPath(task, day))
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger.StartEntry(LogEntry{Candidate: day.Format("Jan 2")})
+		logger.LogOutcome(OutcomeFixed, "committed", OutcomeMeta{})
+		logger.Close()
+	}
+	// Another task's log in the same directory isn't picked up
+	other, _ := NewClaudeLogger(env.LogPath(Task{Name: "other"}, time.Now()))
+	other.StartEntry(LogEntry{Candidate: "other.go"})
+	other.LogOutcome(OutcomeFixed, "committed", OutcomeMeta{})
+	other.Close()
+
+	attempts, err := ReadTaskAttempts(env, task)
+	if err != nil {
+		t.Fatalf("ReadTaskAttempts failed: %v", err)
+	}
+	if len(attempts) != 2 || attempts[0].Candidate != "Mar 14" || attempts[1].Candidate != "Mar 15" {
+		t.Errorf("attempts = %+v, want Mar 14 then Mar 15", attempts)
+	}
+}
+
+func TestLastSessionID(t *testing.T) {
+	attempts := []AttemptRecord{
+		{Candidate: "a.go", SessionID: "first"},
+		{Candidate: "b.go", SessionID: "other"},
+		{Candidate: "a.go", SessionID: "second"},
+		{Candidate: "a.go"}, // e.g. output without a session ID
+	}
+	if got := LastSessionID(attempts, "a.go"); got != "second" {
+		t.Errorf("LastSessionID(a.go) = %q, want %q", got, "second")
+	}
+	if got := LastSessionID(attempts, "c.go"); got != "" {
+		t.Errorf("LastSessionID(c.go) = %q, want none", got)
+	}
+}
+
+func TestFormatPreviousAttempts(t *testing.T) {
+	t.Run("no attempts", func(t *testing.T) {
+		if got := FormatPreviousAttempts(nil); got != "No previous attempts." {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("includes outcome and verify error", func(t *testing.T) {
+		got := FormatPreviousAttempts([]AttemptRecord{
+			{Outcome: OutcomeNotFixed, Details: "reverted"},
+			{Outcome: OutcomeBuildFailed, Details: "reverted", VerifyError: "main.go:3: undefined: foo"},
+		})
+		want := "- Attempt 1: NOT_FIXED (reverted)\n- Attempt 2: BUILD_FAILED (reverted); verify error: main.go:3: undefined: foo"
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("keeps only the most recent attempts", func(t *testing.T) {
+		attempts := make([]AttemptRecord, maxPreviousAttempts+2)
+		for i := range attempts {
+			attempts[i] = AttemptRecord{Outcome: OutcomeNotFixed, Details: "reverted"}
+		}
+		got := FormatPreviousAttempts(attempts)
+		if strings.Contains(got, "Attempt 2:") || !strings.Contains(got, "Attempt 7:") {
+			t.Errorf("expected attempts 3-7, got:\n%s", got)
+		}
+	})
+}
+
+func TestErrorExcerpt(t *testing.T) {
+	output := "building...\n\nok pkg/a\nFAIL pkg/b\n  b_test.go:10: boom\n\n"
+	want := "ok pkg/a | FAIL pkg/b | b_test.go:10: boom"
+	if got := errorExcerpt(output, 3); got != want {
+		t.Errorf("errorExcerpt() = %q, want %q", got, want)
+	}
+	if got := errorExcerpt(output, 1); got != "b_test.go:10: boom" {
+		t.Errorf("errorExcerpt(1) = %q, want the last line", got)
+	}
+
+	long := errorExcerpt(strings.Repeat("x", 1000), 3)
+	if len(long) != 3*excerptLineLength+3 || !strings.HasPrefix(long, "...") {
+		t.Errorf("long excerpt not truncated: %d chars", len(long))
+	}
+}
+
+func TestPreviousAttemptsInPrompt(t *testing.T) {
+	taskDir := t.TempDir()
+	env := &Environment{
+		Tasks: map[string]Task{
+			"test-task": {
+				Name:   "test-task",
+				Dir:    taskDir,
+				Prompt: "Fix $INPUT\n$PREVIOUS_ATTEMPTS",
+			},
+		},
+	}
+
+	runner, err := NewRunner(env, "test-task", RunnerOptions{})
+	if err != nil {
+		t.Fatalf("NewRunner failed: %v", err)
+	}
+	defer runner.claudeLogger.Close()
+
+	candidate := &Candidate{Key: "a.go", Data: []byte(`"a.go"`)}
+	prompt, err := runner.getPrompt(candidate)
+	if err != nil {
+		t.Fatalf("getPrompt failed: %v", err)
+	}
+	if prompt != "Fix a.go\nNo previous attempts." {
+		t.Errorf("first prompt = %q", prompt)
+	}
+
+	runner.candidate = candidate.Key
+	runner.verifyError = "undefined: foo"
+	runner.logOutcome(OutcomeBuildFailed, "reverted")
+
+	prompt, err = runner.getPrompt(candidate)
+	if err != nil {
+		t.Fatalf("getPrompt failed: %v", err)
+	}
+	if !strings.Contains(prompt, "Attempt 1: BUILD_FAILED (reverted: undefined: foo)") {
+		t.Errorf("second prompt missing previous attempt:\n%s", prompt)
+	}
+}
@@ -0,0 +1,262 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile is an ignore list as moved between machines by `nigel ignore`.
+// The json format records the task it came from; the text format is the same
+// one key per line as ignored.log.
+type IgnoreFile struct {
+	Task string   `json:"task,omitempty"`
+	Keys []string `json:"keys"`
+}
+
+// ignoredLogPath returns the task's ignore list file.
+func ignoredLogPath(task Task) string {
+	return filepath.Join(task.Dir, "ignored.log")
+}
+
+// ExportIgnored writes a task's ignore list in text or json format to
+// outPath, or stdout if empty.
+func ExportIgnored(env *Environment, taskName, format, outPath string) error {
+	task, ok := env.Tasks[taskName]
+	if !ok {
+		return fmt.Errorf("task not found: %s", taskName)
+	}
+	if task.IgnoreList != "" {
+		return fmt.Errorf("task %s uses an ignore_list command; its ignore list isn't stored by nigel", taskName)
+	}
+	list, err := readIgnoreFile(ignoredLogPath(task))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	list.Task = taskName
+
+	if err := writeIgnoreOutput(outPath, list, format); err != nil {
+		return err
+	}
+	if outPath != "" {
+		fmt.Println(ColorInfo(fmt.Sprintf("Exported %d ignored keys to %s", len(list.Keys), outPath)))
+	}
+	return nil
+}
+
+// ImportIgnored adds the keys in the given files (text or json, as written by
+// ExportIgnored or copied from another machine's ignored.log) to a task's
+// ignore list. Keys already ignored, including structured keys written with
+// different field order or spacing, aren't added again.
+func ImportIgnored(env *Environment, taskName string, paths []string) error {
+	task, ok := env.Tasks[taskName]
+	if !ok {
+		return fmt.Errorf("task not found: %s", taskName)
+	}
+	if task.IgnoreList != "" {
+		return fmt.Errorf("task %s uses an ignore_list command; add the keys to what it reads instead", taskName)
+	}
+
+	existing, err := readIgnoreFile(ignoredLogPath(task))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, key := range existing.Keys {
+		seen[canonicalIgnoreKey(key)] = true
+	}
+
+	var added []string
+	skipped := 0
+	for _, path := range paths {
+		list, err := readIgnoreFile(path)
+		if err != nil {
+			return err
+		}
+		if list.Task != "" && list.Task != taskName {
+			fmt.Println(ColorWarning(fmt.Sprintf("Warning: %s was exported from task %s, importing into %s", path, list.Task, taskName)))
+		}
+		for _, key := range list.Keys {
+			key = canonicalIgnoreKey(key)
+			if seen[key] {
+				skipped++
+				continue
+			}
+			seen[key] = true
+			added = append(added, key)
+		}
+	}
+
+	if len(added) > 0 {
+		file, err := os.OpenFile(ignoredLogPath(task), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open ignored list for writing: %w", err)
+		}
+		for _, key := range added {
+			fmt.Fprintln(file, key)
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write to ignored list: %w", err)
+		}
+	}
+	fmt.Println(ColorInfo(fmt.Sprintf("Imported %d new ignored keys into %s (%d already ignored or duplicated)", len(added), taskName, skipped)))
+	return nil
+}
+
+// MergeIgnored combines ignore lists, e.g. from parallel --shard runs on
+// different machines, into one deduplicated list written to outPath, or stdout
+// if empty. Lists exported from different tasks aren't merged.
+func MergeIgnored(paths []string, format, outPath string) error {
+	var lists []IgnoreFile
+	for _, path := range paths {
+		list, err := readIgnoreFile(path)
+		if err != nil {
+			return err
+		}
+		lists = append(lists, list)
+	}
+
+	merged := mergeIgnoreFiles(lists)
+	if merged.conflict != "" {
+		return fmt.Errorf("can't merge ignore lists from different tasks: %s", merged.conflict)
+	}
+	if err := writeIgnoreOutput(outPath, merged.IgnoreFile, format); err != nil {
+		return err
+	}
+	if outPath != "" {
+		fmt.Println(ColorInfo(fmt.Sprintf("Merged %d ignored keys into %s (%d duplicates dropped)", len(merged.Keys), outPath, merged.duplicates)))
+	}
+	return nil
+}
+
+// mergedIgnoreFile is the result of merging ignore lists.
+type mergedIgnoreFile struct {
+	IgnoreFile
+	duplicates int    // Keys dropped because an earlier list had them
+	conflict   string // Describes lists from different tasks, if any
+}
+
+// mergeIgnoreFiles merges lists in order, keeping the first copy of each key.
+func mergeIgnoreFiles(lists []IgnoreFile) mergedIgnoreFile {
+	var merged mergedIgnoreFile
+	seen := make(map[string]bool)
+	tasks := make(map[string]bool)
+	for _, list := range lists {
+		if list.Task != "" {
+			if merged.Task != "" && !tasks[list.Task] {
+				merged.conflict = merged.Task + " and " + list.Task
+			}
+			if merged.Task == "" {
+				merged.Task = list.Task
+			}
+			tasks[list.Task] = true
+		}
+		for _, key := range list.Keys {
+			key = canonicalIgnoreKey(key)
+			if seen[key] {
+				merged.duplicates++
+				continue
+			}
+			seen[key] = true
+			merged.Keys = append(merged.Keys, key)
+		}
+	}
+	return merged
+}
+
+// canonicalIgnoreKey returns the key a candidate source would produce for a
+// structured (JSON object or array) key, so the same candidate written with
+// different field order or spacing is recognized. Other keys are unchanged.
+func canonicalIgnoreKey(key string) string {
+	key = strings.TrimSpace(key)
+	if !strings.HasPrefix(key, "{") && !strings.HasPrefix(key, "[") {
+		return key
+	}
+	if !json.Valid([]byte(key)) {
+		return key
+	}
+	candidates, err := parseJsonCandidates([]json.RawMessage{json.RawMessage(key)})
+	if err != nil || len(candidates) != 1 {
+		return key
+	}
+	return candidates[0].Key
+}
+
+// readIgnoreFile reads an ignore list in either format: a json IgnoreFile, or
+// one key per line.
+func readIgnoreFile(path string) (IgnoreFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return IgnoreFile{}, err
+		}
+		return IgnoreFile{}, fmt.Errorf("failed to read ignore list: %w", err)
+	}
+
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("{")) {
+		var list IgnoreFile
+		if err := json.Unmarshal(trimmed, &list); err == nil && list.Keys != nil {
+			return list, nil
+		}
+		// Otherwise a text list whose first key is a JSON object
+	}
+
+	var list IgnoreFile
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 10*1024*1024)
+	for scanner.Scan() {
+		if key := strings.TrimSpace(scanner.Text()); key != "" {
+			list.Keys = append(list.Keys, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return IgnoreFile{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return list, nil
+}
+
+// WriteIgnoreFile writes an ignore list in text or json format.
+func WriteIgnoreFile(w io.Writer, list IgnoreFile, format string) error {
+	switch format {
+	case "text":
+		for _, key := range list.Keys {
+			if _, err := fmt.Fprintln(w, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		if list.Keys == nil {
+			list.Keys = []string{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	default:
+		return fmt.Errorf("unknown ignore list format %q (want text or json)", format)
+	}
+}
+
+// writeIgnoreOutput writes an ignore list to outPath, or stdout if empty.
+func writeIgnoreOutput(outPath string, list IgnoreFile, format string) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown ignore list format %q (want text or json)", format)
+	}
+	if outPath == "" {
+		return WriteIgnoreFile(os.Stdout, list, format)
+	}
+	file, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", outPath, err)
+	}
+	if err := WriteIgnoreFile(file, list, format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalIgnoreKey(t *testing.T) {
+	tests := []struct{ key, want string }{
+		{"src/a.go", "src/a.go"},
+		{`{"line": 3, "file": "a.go"}`, `{"file":"a.go","line":3}`},
+		{`[ "a.go", 3 ]`, `["a.go",3]`},
+		{`{not json`, `{not json`},
+	}
+	for _, tt := range tests {
+		if got := canonicalIgnoreKey(tt.key); got != tt.want {
+			t.Errorf("canonicalIgnoreKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestMergeIgnoreFiles(t *testing.T) {
+	merged := mergeIgnoreFiles([]IgnoreFile{
+		{Task: "lint", Keys: []string{"a.go", `{"file":"b.go","line":1}`}},
+		{Keys: []string{"c.go", `{"line":1,"file":"b.go"}`, "a.go"}},
+	})
+	want := []string{"a.go", `{"file":"b.go","line":1}`, "c.go"}
+	if !reflect.DeepEqual(merged.Keys, want) || merged.duplicates != 2 || merged.Task != "lint" || merged.conflict != "" {
+		t.Errorf("merged = %+v, want keys %q with 2 duplicates", merged, want)
+	}
+
+	conflicting := mergeIgnoreFiles([]IgnoreFile{{Task: "lint"}, {Task: "lint"}, {Task: "types"}})
+	if conflicting.conflict != "lint and types" {
+		t.Errorf("conflict = %q, want %q", conflicting.conflict, "lint and types")
+	}
+}
+
+func TestReadIgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "lint.json")
+	textPath := filepath.Join(dir, "ignored.log")
+	os.WriteFile(jsonPath, []byte(`{"task": "lint", "keys": ["a.go"]}`), 0644)
+	// A text list whose first key is itself a JSON object
+	os.WriteFile(textPath, []byte("{\"file\":\"a.go\"}\n\nb.go\n"), 0644)
+
+	list, err := readIgnoreFile(jsonPath)
+	if err != nil || list.Task != "lint" || !reflect.DeepEqual(list.Keys, []string{"a.go"}) {
+		t.Errorf("json list = %+v, %v", list, err)
+	}
+	list, err = readIgnoreFile(textPath)
+	if err != nil || list.Task != "" || !reflect.DeepEqual(list.Keys, []string{`{"file":"a.go"}`, "b.go"}) {
+		t.Errorf("text list = %+v, %v", list, err)
+	}
+}
+
+func TestExportImportIgnored(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	os.Mkdir(src, 0755)
+	os.Mkdir(dst, 0755)
+	os.WriteFile(filepath.Join(src, "ignored.log"), []byte("a.go\n{\"line\":1,\"file\":\"b.go\"}\n"), 0644)
+	os.WriteFile(filepath.Join(dst, "ignored.log"), []byte("{\"file\":\"b.go\",\"line\":1}\n"), 0644)
+	env := &Environment{Tasks: map[string]Task{
+		"lint":  {Name: "lint", Dir: src},
+		"other": {Name: "other", Dir: dst},
+	}}
+
+	exported := filepath.Join(dir, "lint.json")
+	if err := ExportIgnored(env, "lint", "json", exported); err != nil {
+		t.Fatalf("ExportIgnored failed: %v", err)
+	}
+	if err := ImportIgnored(env, "other", []string{exported}); err != nil {
+		t.Fatalf("ImportIgnored failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dst, "ignored.log"))
+	if got := strings.Split(strings.TrimSpace(string(data)), "\n"); !reflect.DeepEqual(got, []string{`{"file":"b.go","line":1}`, "a.go"}) {
+		t.Errorf("ignored.log after import = %q", got)
+	}
+
+	env.Tasks["cmd"] = Task{Name: "cmd", Dir: dir, IgnoreList: "cat list"}
+	if err := ImportIgnored(env, "cmd", []string{exported}); err == nil {
+		t.Error("expected an error importing into a task with an ignore_list command")
+	}
+}
+
+func TestMergeIgnoredRejectsDifferentTasks(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")
+	os.WriteFile(a, []byte(`{"task": "lint", "keys": ["a.go"]}`), 0644)
+	os.WriteFile(b, []byte(`{"task": "types", "keys": ["b.go"]}`), 0644)
+
+	if err := MergeIgnored([]string{a, b}, "text", filepath.Join(dir, "out")); err == nil || !strings.Contains(err.Error(), "different tasks") {
+		t.Errorf("MergeIgnored error = %v, want a different tasks error", err)
+	}
+}
@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"hash/maphash"
+	"math"
+	"slices"
+	"unicode"
+)
+
+// keyIndex is an exact, read-only set of the keys in an ignored.log. It keeps
+// the file's contents and a sorted index of each key's hash and position, so
+// hundreds of thousands of keys cost a few allocations and 16 bytes a key
+// rather than a string and a map entry each. A lookup binary-searches the
+// hashes and checks a match against the key's bytes, so a hash collision
+// can't ignore a candidate by mistake.
+type keyIndex struct {
+	seed       maphash.Seed
+	data       []byte
+	entries    []keyEntry // Sorted by hash, then position; duplicate keys are indexed twice
+	duplicates int        // Lines repeating an earlier key
+}
+
+// keyEntry locates one line of the indexed data.
+type keyEntry struct {
+	hash       uint64
+	start, end uint32
+}
+
+// newKeyIndex indexes data, one key per non-blank line, trimmed.
+func newKeyIndex(data []byte) (*keyIndex, error) {
+	if len(data) > math.MaxUint32 {
+		return nil, fmt.Errorf("ignored list is too large to index (%d bytes)", len(data))
+	}
+	idx := &keyIndex{seed: maphash.MakeSeed(), data: data}
+	idx.entries = make([]keyEntry, 0, bytes.Count(data, []byte{'\n'})+1)
+	eachLineBounds(data, func(start, end int) {
+		idx.entries = append(idx.entries, keyEntry{
+			hash:  maphash.Bytes(idx.seed, data[start:end]),
+			start: uint32(start),
+			end:   uint32(end),
+		})
+	})
+	slices.SortFunc(idx.entries, func(a, b keyEntry) int {
+		if c := cmp.Compare(a.hash, b.hash); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.start, b.start)
+	})
+	for i, e := range idx.entries {
+		for j := i - 1; j >= 0 && idx.entries[j].hash == e.hash; j-- {
+			if d := idx.entries[j]; bytes.Equal(data[d.start:d.end], data[e.start:e.end]) {
+				idx.duplicates++
+				break
+			}
+		}
+	}
+	return idx, nil
+}
+
+// contains reports whether key is one of the indexed lines. A nil index is
+// empty.
+func (idx *keyIndex) contains(key string) bool {
+	if idx == nil {
+		return false
+	}
+	hash := maphash.String(idx.seed, key)
+	i, _ := slices.BinarySearchFunc(idx.entries, hash, func(e keyEntry, hash uint64) int {
+		return cmp.Compare(e.hash, hash)
+	})
+	for ; i < len(idx.entries) && idx.entries[i].hash == hash; i++ {
+		if e := idx.entries[i]; string(idx.data[e.start:e.end]) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// each calls fn with every indexed key in file order, duplicates included.
+func (idx *keyIndex) each(fn func(key string)) {
+	if idx == nil {
+		return
+	}
+	eachLineBounds(idx.data, func(start, end int) {
+		fn(string(idx.data[start:end]))
+	})
+}
+
+// eachLineBounds calls fn with the bounds of each non-blank line of data,
+// trimmed the way eachLine trims them.
+func eachLineBounds(data []byte, fn func(start, end int)) {
+	for start := 0; start < len(data); {
+		end, next := len(data), len(data)
+		if i := bytes.IndexByte(data[start:], '\n'); i >= 0 {
+			end, next = start+i, start+i+1
+		}
+		line := data[start:end]
+		trimmed := bytes.TrimLeftFunc(line, unicode.IsSpace)
+		from := start + len(line) - len(trimmed)
+		if trimmed = bytes.TrimRightFunc(trimmed, unicode.IsSpace); len(trimmed) > 0 {
+			fn(from, from+len(trimmed))
+		}
+		start = next
+	}
+}
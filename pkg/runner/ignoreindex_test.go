@@ -0,0 +1,104 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeyIndex(t *testing.T) {
+	idx, err := newKeyIndex([]byte("a.go\n  b.go \r\n\n{\"file\": \"c.go\"}\na.go\nlast"))
+	if err != nil {
+		t.Fatalf("newKeyIndex failed: %v", err)
+	}
+	if idx.duplicates != 1 {
+		t.Errorf("duplicates = %d, want 1", idx.duplicates)
+	}
+	for _, key := range []string{"a.go", "b.go", `{"file": "c.go"}`, "last"} {
+		if !idx.contains(key) {
+			t.Errorf("expected %q to be indexed", key)
+		}
+	}
+	for _, key := range []string{"", "a", "b.go ", "c.go", "las"} {
+		if idx.contains(key) {
+			t.Errorf("expected %q not to be indexed", key)
+		}
+	}
+
+	var keys []string
+	idx.each(func(key string) { keys = append(keys, key) })
+	if got := strings.Join(keys, ","); got != `a.go,b.go,{"file": "c.go"},a.go,last` {
+		t.Errorf("each() = %s", got)
+	}
+
+	var empty *keyIndex
+	if empty.contains("a.go") {
+		t.Error("expected a nil index to be empty")
+	}
+}
+
+// writeLargeIgnoredList writes an ignored.log of n keys to a new directory.
+func writeLargeIgnoredList(tb testing.TB, n int) string {
+	tb.Helper()
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "src/pkg%d/file%d.go:%d\n", i%100, i, i%1000)
+	}
+	dir := tb.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ignored.log"), []byte(b.String()), 0644); err != nil {
+		tb.Fatal(err)
+	}
+	return dir
+}
+
+func TestIgnoredListLarge(t *testing.T) {
+	const n = 300000
+	dir := writeLargeIgnoredList(t, n)
+	list, err := NewIgnoredList(dir)
+	if err != nil {
+		t.Fatalf("NewIgnoredList failed: %v", err)
+	}
+	for _, i := range []int{0, n / 2, n - 1} {
+		if key := fmt.Sprintf("src/pkg%d/file%d.go:%d", i%100, i, i%1000); !list.Contains(key) {
+			t.Errorf("expected %s to be ignored", key)
+		}
+	}
+	if list.Contains("src/pkg0/file0.go:1") || list.Contains(fmt.Sprintf("src/pkg0/file%d.go:0", n)) {
+		t.Error("expected keys not in the file not to be ignored")
+	}
+
+	// Loading doesn't allocate per key, and lookups don't allocate at all
+	data, _ := os.ReadFile(filepath.Join(dir, "ignored.log"))
+	if allocs := testing.AllocsPerRun(1, func() { newKeyIndex(data) }); allocs > 10 {
+		t.Errorf("indexing %d keys made %.0f allocations", n, allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { list.Contains("src/pkg7/file7.go:7") }); allocs != 0 {
+		t.Errorf("Contains made %.0f allocations", allocs)
+	}
+}
+
+func BenchmarkIgnoredListLoad(b *testing.B) {
+	dir := writeLargeIgnoredList(b, 500000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		list, err := NewIgnoredList(dir)
+		if err != nil {
+			b.Fatal(err)
+		}
+		list.Contains("src/pkg7/file7.go:7") // Indexes the list
+	}
+}
+
+func BenchmarkIgnoredListContains(b *testing.B) {
+	list, err := NewIgnoredList(writeLargeIgnoredList(b, 500000))
+	if err != nil {
+		b.Fatal(err)
+	}
+	list.Contains("") // Indexes the list
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		list.Contains(fmt.Sprintf("src/pkg%d/file%d.go:%d", i%100, i%1000000, i%1000))
+	}
+}
@@ -0,0 +1,149 @@
+package runner
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// streamGutter starts every line of Claude's streamed output, setting it off
+// from nigel's own messages.
+const streamGutter = "  │ "
+
+// streamLayout indents Claude's streamed text behind a gutter and wraps it to
+// the terminal width, so long paragraphs don't run under progress-timer lines
+// or smear when the terminal is resized. Chunks are laid out as they arrive,
+// and the width is read again for each one. Words move whole to the next line
+// when they don't fit, and a word longer than a line is broken. Not safe for
+// concurrent use.
+type streamLayout struct {
+	width     func() int // Terminal columns, or 0 to indent without wrapping
+	col       int        // Display column after the gutter
+	lineStart bool       // Nothing written on the current line yet
+	wrapped   bool       // The current line was started by a soft wrap
+	spaces    string     // Held until a word follows, so wrapped lines don't end in spaces
+}
+
+func newStreamLayout(width func() int) *streamLayout {
+	return &streamLayout{width: width, lineStart: true}
+}
+
+// reset starts the next chunk on a fresh line, e.g. for a new candidate.
+func (l *streamLayout) reset() {
+	l.col, l.lineStart, l.wrapped, l.spaces = 0, true, false, ""
+}
+
+// render lays out a chunk, returning the text to print. gutter is inserted
+// at the start of each line, so callers can color it apart from the text.
+func (l *streamLayout) render(text, gutter string) string {
+	avail := 0
+	if w := l.width(); w > 0 {
+		avail = w - displayWidth(streamGutter)
+		if avail < 20 {
+			avail = 20 // Too narrow to be worth wrapping tighter
+		}
+	}
+
+	var b strings.Builder
+	newline := func(soft bool) {
+		b.WriteString("\n")
+		l.col, l.lineStart, l.wrapped, l.spaces = 0, true, soft, ""
+	}
+	write := func(s string) {
+		if l.lineStart {
+			b.WriteString(gutter)
+			l.lineStart = false
+		}
+		b.WriteString(s)
+		l.col += displayWidth(s)
+	}
+
+	for _, token := range splitLayoutTokens(text) {
+		switch {
+		case token == "\n":
+			newline(false)
+		case token[0] == ' ' || token[0] == '\t':
+			if !(l.wrapped && l.lineStart) { // Drop spaces wrapped onto a new line
+				l.spaces += strings.ReplaceAll(token, "\t", "    ")
+			}
+		default:
+			if avail > 0 && l.col > 0 && l.col+len(l.spaces)+displayWidth(token) > avail {
+				newline(true)
+			}
+			if l.spaces != "" {
+				if avail > 0 && l.col+len(l.spaces) >= avail {
+					l.spaces = "" // Indentation as wide as the line
+				}
+				write(l.spaces)
+				l.spaces = ""
+			}
+			// Break words longer than a whole line
+			for avail > 0 && l.col+displayWidth(token) > avail {
+				head, tail := splitAtWidth(token, avail-l.col)
+				write(head)
+				newline(true)
+				token = tail
+			}
+			write(token)
+		}
+	}
+	return b.String()
+}
+
+// splitLayoutTokens splits text into newlines, runs of spaces or tabs, and
+// runs of other characters.
+func splitLayoutTokens(text string) []string {
+	var tokens []string
+	start := 0
+	kind := func(r rune) int {
+		switch {
+		case r == '\n':
+			return 0
+		case r == ' ' || r == '\t':
+			return 1
+		}
+		return 2
+	}
+	prev := -1
+	for i, r := range text {
+		k := kind(r)
+		if i > start && (k != prev || k == 0) {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+		prev = k
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+// splitAtWidth splits s after at most width display columns (at least one rune).
+func splitAtWidth(s string, width int) (string, string) {
+	used := 0
+	for i, r := range s {
+		w := displayWidth(string(r))
+		if i > 0 && used+w > width {
+			return s[:i], s[i:]
+		}
+		used += w
+	}
+	return s, ""
+}
+
+// terminalWidth returns the width of the terminal on stdout, falling back to
+// $COLUMNS, or 0 when output isn't a terminal (so piped output isn't wrapped).
+func terminalWidth() int {
+	var ws struct{ Row, Col, X, Y uint16 }
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdout.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno == 0 && ws.Col > 0 {
+		return int(ws.Col)
+	}
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
+	}
+	return 0
+}
@@ -0,0 +1,57 @@
+package runner
+
+import "testing"
+
+func TestStreamLayoutWrapsWords(t *testing.T) {
+	// 24 columns less the 4-column gutter leaves 20 for text
+	l := newStreamLayout(func() int { return 24 })
+	got := l.render("the quick brown fox jumps over the lazy dog\n", "> ")
+	want := "> the quick brown fox\n> jumps over the lazy\n> dog\n"
+	if got != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+}
+
+func TestStreamLayoutAcrossChunks(t *testing.T) {
+	l := newStreamLayout(func() int { return 24 })
+	got := l.render("the quick brown ", "> ") + l.render("fox jumps", "> ") + l.render(" over\n", "> ")
+	want := "> the quick brown fox\n> jumps over\n"
+	if got != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+}
+
+func TestStreamLayoutKeepsIndentation(t *testing.T) {
+	l := newStreamLayout(func() int { return 24 })
+	got := l.render("func f() {\n\treturn\n}\n", "> ")
+	want := "> func f() {\n>     return\n> }\n"
+	if got != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+}
+
+func TestStreamLayoutBreaksLongWords(t *testing.T) {
+	l := newStreamLayout(func() int { return 24 })
+	got := l.render("see abcdefghijklmnopqrstuvwxyz", "> ")
+	want := "> see\n> abcdefghijklmnopqrst\n> uvwxyz"
+	if got != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+}
+
+func TestStreamLayoutWithoutTerminal(t *testing.T) {
+	l := newStreamLayout(func() int { return 0 })
+	text := "a line much longer than any terminal would be wide, left alone when piped"
+	if got := l.render(text+"\nnext\n", "> "); got != "> "+text+"\n> next\n" {
+		t.Errorf("render = %q", got)
+	}
+}
+
+func TestStreamLayoutReset(t *testing.T) {
+	l := newStreamLayout(func() int { return 0 })
+	l.render("partial", "> ")
+	l.reset()
+	if got := l.render("next", "> "); got != "> next" {
+		t.Errorf("render after reset = %q, want %q", got, "> next")
+	}
+}
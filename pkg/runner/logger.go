@@ -0,0 +1,229 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const separator = "================================================================================"
+
+// Outcome represents the result of processing a candidate.
+type Outcome string
+
+const (
+	OutcomeFixed          Outcome = "FIXED"
+	OutcomeFixedReverted  Outcome = "FIXED_BUT_REVERTED" // Fixed but build failed, had to revert
+	OutcomeNotFixed       Outcome = "NOT_FIXED"
+	OutcomeBestEffort     Outcome = "BEST_EFFORT" // Not fixed but partial progress committed
+	OutcomeBuildFailed    Outcome = "BUILD_FAILED"
+	OutcomeTimeout        Outcome = "TIMEOUT"          // Timed out and reverted
+	OutcomeScanFailed     Outcome = "SCAN_FAILED"      // Pre-commit scan flagged the changes, reverted
+	OutcomeRegression     Outcome = "REGRESSION"       // Changes introduced max_new_candidates or more new candidates, reverted
+	OutcomePromptTooLarge Outcome = "PROMPT_TOO_LARGE" // Prompt over prompt_limit, Claude not run
+	OutcomeFlakySource    Outcome = "FLAKY_SOURCE"     // Candidate disappeared without any changes, per zero_diff_fix
+)
+
+// ClaudeLogger handles logging of Claude interactions.
+type ClaudeLogger struct {
+	BaseObserver
+	file      *os.File
+	startTime time.Time
+	entry     LogEntry
+}
+
+// LogEntry identifies the attempt a log entry belongs to.
+type LogEntry struct {
+	Candidate  string // Candidate key
+	Prompt     string // Interpolated prompt
+	PromptHash string
+	Variant    string // Prompt variant name, "" without variants
+}
+
+// defaultLogFilePattern names logs in task directories; with log_dir the
+// task name is needed to keep tasks apart.
+const (
+	defaultLogFilePattern       = "claude.log"
+	defaultSharedLogFilePattern = "$TASK_NAME.log"
+)
+
+// logFilePattern returns the configured log_file_pattern or its default.
+func (c Config) logFilePattern() string {
+	if c.LogFilePattern != "" {
+		return c.LogFilePattern
+	}
+	if c.LogDir != "" {
+		return defaultSharedLogFilePattern
+	}
+	return defaultLogFilePattern
+}
+
+// validateLogFilePattern rejects patterns that would send several tasks'
+// logs to the same file.
+func validateLogFilePattern(c Config) error {
+	if c.LogDir != "" && !strings.Contains(c.logFilePattern(), "$TASK_NAME") {
+		return fmt.Errorf("log_file_pattern must contain $TASK_NAME when log_dir is set")
+	}
+	return nil
+}
+
+// LogPath returns the claude.log a run of task started at t writes to:
+// log_file_pattern with $TASK_NAME and $DATE (YYYY-MM-DD) expanded, in
+// log_dir or the task directory.
+func (e *Environment) LogPath(task Task, t time.Time) string {
+	name := strings.NewReplacer("$TASK_NAME", task.Name, "$DATE", t.Format("2006-01-02")).Replace(e.Config.logFilePattern())
+	return filepath.Join(e.logDir(task), name)
+}
+
+// LogFiles returns the task's existing log files, oldest first. Logs named
+// by $DATE are split across files, which together make up the history.
+func (e *Environment) LogFiles(task Task) ([]string, error) {
+	date := "[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]"
+	pattern := strings.NewReplacer("$TASK_NAME", task.Name, "$DATE", date).Replace(e.Config.logFilePattern())
+	files, err := filepath.Glob(filepath.Join(e.logDir(task), pattern))
+	if err != nil {
+		return nil, fmt.Errorf("invalid log_file_pattern: %w", err)
+	}
+	sort.Strings(files) // ISO dates sort chronologically
+	return files, nil
+}
+
+func (e *Environment) logDir(task Task) string {
+	if e.Config.LogDir != "" {
+		return e.Config.LogDir
+	}
+	return task.Dir
+}
+
+// NewClaudeLogger creates a new logger for Claude interactions writing to
+// path, creating its directory if needed.
+func NewClaudeLogger(path string) (*ClaudeLogger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open claude log: %w", err)
+	}
+
+	return &ClaudeLogger{file: file}, nil
+}
+
+// StartEntry begins a new log entry with timestamp, candidate, prompt hash,
+// prompt variant (if any) and prompt.
+func (l *ClaudeLogger) StartEntry(entry LogEntry) error {
+	l.startTime = time.Now()
+	l.entry = entry
+	timestamp := l.startTime.Format("2006-01-02 15:04:05")
+
+	_, err := fmt.Fprintf(l.file, "\n%s\nTimestamp: %s\nCandidate: %s\nPrompt Hash: %s\n%sPrompt: %s\n%s\n",
+		separator, timestamp, entry.Candidate, entry.PromptHash, optionalLine("Variant", entry.Variant), entry.Prompt, separator)
+	return err
+}
+
+// OutcomeMeta holds the optional fields of an outcome entry.
+type OutcomeMeta struct {
+	SessionID   string // Claude session ID, if known
+	VerifyError string // Excerpt of the verify output if the build failed
+	Verify      string // passed, failed or flaky, if verify ran
+	Stalled     bool   // Claude was killed by inactivity_timeout
+	Usage       Usage  // Tokens and cost reported by Claude
+	Commit      string // Revision the fix was committed as
+}
+
+// LogOutcome logs the result of processing the candidate, with whichever
+// optional fields are known.
+func (l *ClaudeLogger) LogOutcome(outcome Outcome, details string, meta OutcomeMeta) error {
+	duration := time.Since(l.startTime)
+	var stalled string
+	if meta.Stalled {
+		stalled = "true"
+	}
+	_, err := fmt.Fprintf(l.file, "\n%s\nOutcome: %s\nCandidate: %s\nPrompt Hash: %s\n%s%s%s%s%s%s%sDuration: %s\nDetails: %s\n",
+		separator, outcome, l.entry.Candidate, l.entry.PromptHash, optionalLine("Variant", l.entry.Variant),
+		optionalLine("Session ID", meta.SessionID), optionalLine("Stalled", stalled),
+		optionalLine("Verify", meta.Verify), optionalLine("Verify Error", meta.VerifyError),
+		usageLines(meta.Usage), optionalLine("Commit", meta.Commit),
+		formatDuration(duration), details)
+	return err
+}
+
+// usageLines returns the log lines for the result metadata Claude reported,
+// omitting fields it didn't report.
+func usageLines(u Usage) string {
+	var tokens, cost, turns, apiDuration, claudeError string
+	if u.InputTokens > 0 || u.OutputTokens > 0 {
+		tokens = fmt.Sprintf("%d in / %d out", u.InputTokens, u.OutputTokens)
+	}
+	if u.CostUSD > 0 {
+		cost = fmt.Sprintf("$%.4f", u.CostUSD)
+	}
+	if u.NumTurns > 0 {
+		turns = fmt.Sprintf("%d", u.NumTurns)
+	}
+	if u.APIDuration > 0 {
+		apiDuration = formatDuration(u.APIDuration)
+	}
+	if u.IsError {
+		claudeError = "true"
+	}
+	return optionalLine("Tokens", tokens) + optionalLine("Cost", cost) + optionalLine("Turns", turns) +
+		optionalLine("Claude Duration", apiDuration) + optionalLine("Claude Error", claudeError)
+}
+
+// OnOutcome writes the attempt's outcome entry, making the logger a RunObserver.
+func (l *ClaudeLogger) OnOutcome(task string, attempt AttemptRecord) {
+	l.LogOutcome(attempt.Outcome, attempt.Details, OutcomeMeta{
+		SessionID:   attempt.SessionID,
+		VerifyError: attempt.VerifyError,
+		Verify:      attempt.Verify,
+		Stalled:     attempt.Stalled,
+		Usage:       attempt.Usage,
+		Commit:      attempt.Commit,
+	})
+}
+
+// optionalLine returns a "Name: value" log line, or "" when value is empty.
+func optionalLine(name, value string) string {
+	if value == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s: %s\n", name, value)
+}
+
+// EndEntry closes the current log entry.
+func (l *ClaudeLogger) EndEntry() error {
+	_, err := fmt.Fprintf(l.file, "%s\n", separator)
+	return err
+}
+
+// Write implements io.Writer for streaming Claude output to the log.
+func (l *ClaudeLogger) Write(p []byte) (n int, err error) {
+	return l.file.Write(p)
+}
+
+// Close closes the log file.
+func (l *ClaudeLogger) Close() error {
+	if l.file != nil {
+		return l.file.Close()
+	}
+	return nil
+}
+
+// Path returns the path to the log file.
+func (l *ClaudeLogger) Path() string {
+	return l.file.Name()
+}
+
+// formatDuration formats a duration in a human-readable way.
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	minutes := int(d.Minutes())
+	seconds := int(d.Seconds()) % 60
+	return fmt.Sprintf("%dm %02ds", minutes, seconds)
+}
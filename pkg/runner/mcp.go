@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// MCPServer is an MCP server made available to Claude. Servers are written to
+// a temporary file in the Claude CLI's --mcp-config format for each run.
+type MCPServer struct {
+	Type    string            `yaml:"type" json:"type,omitempty"`       // stdio (default), http, or sse
+	Command string            `yaml:"command" json:"command,omitempty"` // stdio: command to start the server
+	Args    []string          `yaml:"args" json:"args,omitempty"`
+	Env     map[string]string `yaml:"env" json:"env,omitempty"`
+	URL     string            `yaml:"url" json:"url,omitempty"` // http/sse: server endpoint
+	Headers map[string]string `yaml:"headers" json:"headers,omitempty"`
+}
+
+// validateMCPServers checks that each server has what its type requires.
+func validateMCPServers(servers map[string]MCPServer) error {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		server := servers[name]
+		switch server.Type {
+		case "", "stdio":
+			if server.Command == "" {
+				return fmt.Errorf("server %s needs a 'command'", name)
+			}
+		case "http", "sse":
+			if server.URL == "" {
+				return fmt.Errorf("server %s needs a 'url'", name)
+			}
+		default:
+			return fmt.Errorf("server %s has unknown type %q (expected stdio, http, or sse)", name, server.Type)
+		}
+	}
+	return nil
+}
+
+// mergeMCPServers combines the global and task servers. A task server
+// replaces a global server with the same name.
+func mergeMCPServers(global, task map[string]MCPServer) map[string]MCPServer {
+	if len(global) == 0 {
+		return task
+	}
+	merged := make(map[string]MCPServer, len(global)+len(task))
+	for name, server := range global {
+		merged[name] = server
+	}
+	for name, server := range task {
+		merged[name] = server
+	}
+	return merged
+}
+
+// writeMCPConfig writes servers to a temporary --mcp-config file in dir (the
+// system temp directory if empty) and returns its path. The caller removes the
+// file when Claude exits.
+func writeMCPConfig(dir string, servers map[string]MCPServer) (string, error) {
+	data, err := json.MarshalIndent(map[string]interface{}{"mcpServers": servers}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.CreateTemp(dir, "nigel-mcp-*.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to create MCP config: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write MCP config: %w", err)
+	}
+	return file.Name(), nil
+}
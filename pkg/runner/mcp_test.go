@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestValidateMCPServers(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers map[string]MCPServer
+		wantErr bool
+	}{
+		{name: "none", servers: nil},
+		{name: "stdio", servers: map[string]MCPServer{"db": {Command: "mcp-postgres", Args: []string{"--readonly"}}}},
+		{name: "http", servers: map[string]MCPServer{"docs": {Type: "http", URL: "https://docs.internal/mcp"}}},
+		{name: "stdio without command", servers: map[string]MCPServer{"db": {Args: []string{"--readonly"}}}, wantErr: true},
+		{name: "sse without url", servers: map[string]MCPServer{"docs": {Type: "sse"}}, wantErr: true},
+		{name: "unknown type", servers: map[string]MCPServer{"docs": {Type: "grpc", URL: "x"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMCPServers(tt.servers)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateMCPServers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergeMCPServers(t *testing.T) {
+	global := map[string]MCPServer{
+		"db":   {Command: "mcp-postgres"},
+		"docs": {Type: "http", URL: "https://docs.internal/mcp"},
+	}
+	task := map[string]MCPServer{"db": {Command: "mcp-postgres", Args: []string{"--readonly"}}}
+
+	merged := mergeMCPServers(global, task)
+	if len(merged) != 2 {
+		t.Fatalf("got %d servers, want 2", len(merged))
+	}
+	if len(merged["db"].Args) != 1 {
+		t.Errorf("task server should replace the global one, got %+v", merged["db"])
+	}
+	if len(global["db"].Args) != 0 {
+		t.Error("merging should not modify the global servers")
+	}
+}
+
+func TestWriteMCPConfig(t *testing.T) {
+	path, err := writeMCPConfig("", map[string]MCPServer{
+		"db": {Command: "mcp-postgres", Env: map[string]string{"PGDATABASE": "dev"}},
+	})
+	if err != nil {
+		t.Fatalf("writeMCPConfig failed: %v", err)
+	}
+	defer os.Remove(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config struct {
+		MCPServers map[string]map[string]interface{} `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, data)
+	}
+	db := config.MCPServers["db"]
+	if db["command"] != "mcp-postgres" || db["env"] == nil {
+		t.Errorf("db server = %v", db)
+	}
+	if _, ok := db["url"]; ok {
+		t.Error("empty fields should be omitted")
+	}
+}
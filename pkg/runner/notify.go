@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// notifyCommand returns the command that shows a native desktop notification
+// on the given OS, or nil where none is supported.
+func notifyCommand(goos, title, message string) []string {
+	switch goos {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))
+		return []string{"osascript", "-e", script}
+	case "linux":
+		return []string{"notify-send", title, message}
+	default:
+		return nil
+	}
+}
+
+// appleScriptString quotes s as an AppleScript string literal.
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// notifyDesktop shows a desktop notification when --notify-desktop is set.
+// Failures (e.g. notify-send not installed) are reported but never stop a run.
+func notifyDesktop(opts RunnerOptions, title, message string) {
+	if !opts.NotifyDesktop || opts.DryRun {
+		return
+	}
+	args := notifyCommand(runtime.GOOS, title, message)
+	if args == nil {
+		return
+	}
+	if err := exec.Command(args[0], args[1:]...).Run(); err != nil {
+		fmt.Println(ColorWarning(fmt.Sprintf("Desktop notification failed: %v", err)))
+	}
+}
+
+// notifyRunComplete reports the end of a run by desktop notification and
+// email, as configured: a fatal error if there was one, otherwise the
+// combined outcome counts.
+func notifyRunComplete(env *Environment, opts RunnerOptions, summaries []RunSummary, runErr error) {
+	if env.Config.Email != nil && !opts.DryRun {
+		fmt.Println(ColorInfo("Sending summary email..."))
+		if err := sendEmailSummary(env.Config.Email, summaries, runErr); err != nil {
+			fmt.Println(ColorWarning(fmt.Sprintf("Summary email failed: %v", err)))
+		}
+	}
+
+	if runErr != nil {
+		notifyDesktop(opts, "Nigel stopped", runErr.Error())
+		return
+	}
+	iterations, fixed := 0, 0
+	for _, s := range summaries {
+		iterations += s.Iterations
+		fixed += s.Fixed()
+	}
+	notifyDesktop(opts, "Nigel finished", fmt.Sprintf("%d fixed in %d iterations", fixed, iterations))
+}
@@ -0,0 +1,25 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNotifyCommand(t *testing.T) {
+	tests := []struct {
+		goos string
+		want []string
+	}{
+		{goos: "darwin", want: []string{"osascript", "-e", `display notification "3 \"fixed\"" with title "Nigel"`}},
+		{goos: "linux", want: []string{"notify-send", "Nigel", `3 "fixed"`}},
+		{goos: "windows", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			if got := notifyCommand(tt.goos, "Nigel", `3 "fixed"`); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("notifyCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
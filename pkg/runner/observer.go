@@ -0,0 +1,218 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RunObserver receives the runner's events. The terminal output, claude.log
+// outcome entries and end-of-run notifications are all observers; programs
+// embedding the runner add their own with RunnerOptions.Observer. Embed
+// BaseObserver to handle only some events.
+//
+// OnAgentChunk and OnToolUse are called from the goroutines reading Claude's
+// output, so implementations must be safe for concurrent use.
+type RunObserver interface {
+	OnIterationStart(summary RunSummary)                          // Before each iteration; summary.Iterations is the iteration number
+	OnCandidateSelected(summary RunSummary, candidate *Candidate) // A candidate was picked; summary has the pending and ignored counts
+	OnAgentChunk(task, text string, stderr bool)                  // Claude streamed output (stderr for CLI errors and warnings)
+	OnToolUse(task string, tool ToolUse)                          // Claude called a tool
+	OnOutcome(task string, attempt AttemptRecord)                 // An attempt finished and was logged
+	OnRunEnd(summaries []RunSummary, err error)                   // RunTasks or RunPlaylist finished, err is the error that stopped it
+}
+
+// BaseObserver implements RunObserver with no-ops.
+type BaseObserver struct{}
+
+func (BaseObserver) OnIterationStart(summary RunSummary)                          {}
+func (BaseObserver) OnCandidateSelected(summary RunSummary, candidate *Candidate) {}
+func (BaseObserver) OnAgentChunk(task, text string, stderr bool)                  {}
+func (BaseObserver) OnToolUse(task string, tool ToolUse)                          {}
+func (BaseObserver) OnOutcome(task string, attempt AttemptRecord)                 {}
+func (BaseObserver) OnRunEnd(summaries []RunSummary, err error)                   {}
+
+// observerList fans events out to several observers in order.
+type observerList []RunObserver
+
+func (l observerList) OnIterationStart(summary RunSummary) {
+	for _, o := range l {
+		o.OnIterationStart(summary)
+	}
+}
+
+func (l observerList) OnCandidateSelected(summary RunSummary, candidate *Candidate) {
+	for _, o := range l {
+		o.OnCandidateSelected(summary, candidate)
+	}
+}
+
+func (l observerList) OnAgentChunk(task, text string, stderr bool) {
+	for _, o := range l {
+		o.OnAgentChunk(task, text, stderr)
+	}
+}
+
+func (l observerList) OnToolUse(task string, tool ToolUse) {
+	for _, o := range l {
+		o.OnToolUse(task, tool)
+	}
+}
+
+func (l observerList) OnOutcome(task string, attempt AttemptRecord) {
+	for _, o := range l {
+		o.OnOutcome(task, attempt)
+	}
+}
+
+func (l observerList) OnRunEnd(summaries []RunSummary, err error) {
+	for _, o := range l {
+		o.OnRunEnd(summaries, err)
+	}
+}
+
+// runObservers returns the observers that outlive a single task: terminal
+// output, notifications, the terminal title, and the caller's observer if any.
+func runObservers(env *Environment, opts RunnerOptions) observerList {
+	observers := observerList{newTerminalObserver(opts.DryRun, opts.Stream), &notifyObserver{env: env, opts: opts}}
+	if title := newTitleObserver(opts); title != nil {
+		observers = append(observers, title)
+	}
+	if opts.Observer != nil {
+		observers = append(observers, opts.Observer)
+	}
+	return observers
+}
+
+// StreamMode is how much of Claude's streamed output the terminal shows (--stream).
+type StreamMode string
+
+const (
+	StreamFull    StreamMode = "full"    // All of Claude's text as it streams (the default)
+	StreamSummary StreamMode = "summary" // Only tool calls, then the final message as one paragraph
+)
+
+// maxSummaryLength is how much of Claude's final message --stream summary shows.
+const maxSummaryLength = 400
+
+// terminalObserver prints iteration banners, the selected candidate, Claude's
+// streamed output and the end-of-run summary.
+type terminalObserver struct {
+	BaseObserver
+	out     *SyncWriter // Serializes Claude's stdout and stderr chunks
+	mu      sync.Mutex  // Guards layout and message across the stdout and stderr readers
+	layout  *streamLayout
+	stream  StreamMode
+	message strings.Builder // Text since the last tool call, shown as the summary
+	dryRun  bool
+}
+
+func newTerminalObserver(dryRun bool, stream StreamMode) *terminalObserver {
+	return &terminalObserver{
+		out:    NewSyncWriter(console()),
+		layout: newStreamLayout(terminalWidth),
+		stream: stream,
+		dryRun: dryRun,
+	}
+}
+
+// OnCandidateSelected prints the iteration banner. It waits for the candidate
+// so the banner can say what the iteration is working on.
+func (t *terminalObserver) OnCandidateSelected(summary RunSummary, candidate *Candidate) {
+	t.mu.Lock()
+	t.layout.reset()
+	t.message.Reset()
+	t.mu.Unlock()
+	fmt.Print(IterationBanner(summary, candidate.Key, time.Now().Format("15:04:05")))
+}
+
+// OnAgentChunk shows Claude's output dimmed, and its stderr in the warning
+// color, indented behind a gutter and wrapped to the terminal width. With
+// --stream summary, Claude's text is held back for the summary instead.
+func (t *terminalObserver) OnAgentChunk(task, text string, stderr bool) {
+	color := theme.ClaudeStream
+	if stderr {
+		color = theme.Warning
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stream == StreamSummary && !stderr {
+		t.message.WriteString(text)
+		return
+	}
+	t.render(color, text)
+}
+
+// OnToolUse shows Claude's tool calls with --stream summary. Text before a
+// tool call narrates the work, so it's dropped from the summary.
+func (t *terminalObserver) OnToolUse(task string, tool ToolUse) {
+	if t.stream != StreamSummary {
+		return
+	}
+	line := "→ " + tool.Name
+	if tool.Detail != "" {
+		line += " " + tool.Detail
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.message.Reset()
+	t.render(theme.Dim, line+"\n")
+}
+
+// OnOutcome shows Claude's final message as a paragraph with --stream summary.
+func (t *terminalObserver) OnOutcome(task string, attempt AttemptRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	summary := summarizeMessage(t.message.String(), maxSummaryLength)
+	t.message.Reset()
+	if t.stream != StreamSummary || summary == "" {
+		return
+	}
+	t.layout.reset()
+	t.out.WriteString(ColorBold("Summary:") + "\n")
+	t.render(theme.ClaudeStream, summary+"\n")
+}
+
+// render writes text through the layout in color. Callers hold t.mu.
+func (t *terminalObserver) render(color, text string) {
+	gutter := colorReset + theme.Dim + streamGutter + colorReset + color
+	t.out.WriteColored(color, t.layout.render(text, gutter))
+}
+
+func (t *terminalObserver) OnRunEnd(summaries []RunSummary, err error) {
+	if len(summaries) > 0 && !t.dryRun {
+		fmt.Print(FormatSummary(summaries))
+	}
+}
+
+// notifyObserver sends the configured end-of-run email and desktop notification.
+type notifyObserver struct {
+	BaseObserver
+	env  *Environment
+	opts RunnerOptions
+}
+
+func (n *notifyObserver) OnRunEnd(summaries []RunSummary, err error) {
+	notifyRunComplete(n.env, n.opts, summaries, err)
+}
+
+// summarizeMessage joins a message's lines and paragraphs into one paragraph,
+// cut to about max characters at a word boundary.
+func summarizeMessage(message string, max int) string {
+	words := strings.Fields(message)
+	var b strings.Builder
+	for _, word := range words {
+		if b.Len() > 0 && b.Len()+1+len(word) > max {
+			b.WriteString(" ...")
+			break
+		}
+		if b.Len() > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
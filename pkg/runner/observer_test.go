@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingObserver collects the events reported to it.
+type recordingObserver struct {
+	mu         sync.Mutex
+	iterations []int
+	selected   []string
+	chunks     []string
+	attempts   []AttemptRecord
+	runEnds    int
+	runErr     error
+}
+
+func (o *recordingObserver) OnIterationStart(summary RunSummary) {
+	o.iterations = append(o.iterations, summary.Iterations)
+}
+
+func (o *recordingObserver) OnCandidateSelected(summary RunSummary, candidate *Candidate) {
+	o.selected = append(o.selected, candidate.Key)
+}
+
+func (o *recordingObserver) OnAgentChunk(task, text string, stderr bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.chunks = append(o.chunks, text)
+}
+
+func (o *recordingObserver) OnToolUse(task string, tool ToolUse) {}
+
+func (o *recordingObserver) OnOutcome(task string, attempt AttemptRecord) {
+	o.attempts = append(o.attempts, attempt)
+}
+
+func (o *recordingObserver) OnRunEnd(summaries []RunSummary, err error) {
+	o.runEnds++
+	o.runErr = err
+}
+
+func TestRunObserverDryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	env := &Environment{
+		ProjectDir: tmpDir,
+		Tasks: map[string]Task{
+			"test-task": {
+				Name:            "test-task",
+				Dir:             tmpDir,
+				CandidateSource: `echo '["a.go", "b.go"]'`,
+				Prompt:          "Fix: $INPUT",
+			},
+		},
+	}
+	observer := &recordingObserver{}
+
+	err := RunTasks(env, []string{"test-task"}, RunnerOptions{DryRun: true, Executor: NewMockCommandExecutor(), Observer: observer})
+	if err != nil {
+		t.Fatalf("RunTasks failed: %v", err)
+	}
+
+	if len(observer.iterations) != 1 || observer.iterations[0] != 1 {
+		t.Errorf("iterations = %v, want [1]", observer.iterations)
+	}
+	if len(observer.selected) != 1 || observer.selected[0] != "a.go" {
+		t.Errorf("selected = %v, want [a.go]", observer.selected)
+	}
+	if observer.runEnds != 1 || observer.runErr != nil {
+		t.Errorf("OnRunEnd called %d times with %v, want once with nil", observer.runEnds, observer.runErr)
+	}
+}
+
+func TestClaudeLoggerObservesOutcomes(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "claude.log")
+	file, err := os.Create(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := &ClaudeLogger{file: file}
+	observers := observerList{logger}
+
+	logger.StartEntry(LogEntry{Candidate: "a.go", Prompt: "Fix a", PromptHash: "aaa"})
+	logger.EndEntry()
+	observers.OnOutcome("test-task", AttemptRecord{Outcome: OutcomeFixed, Commit: "abc1234", Details: "committed"})
+	logger.Close()
+
+	attempts, err := ReadAttempts(logPath)
+	if err != nil {
+		t.Fatalf("ReadAttempts failed: %v", err)
+	}
+	if len(attempts) != 1 || attempts[0].Candidate != "a.go" || attempts[0].Commit != "abc1234" {
+		t.Errorf("attempts = %+v", attempts)
+	}
+}
+
+func TestObserverListFansOut(t *testing.T) {
+	first, second := &recordingObserver{}, &recordingObserver{}
+	observers := observerList{first, BaseObserver{}, second}
+
+	observers.OnAgentChunk("task", "hello", false)
+	observers.OnRunEnd(nil, errors.New("boom"))
+
+	for _, o := range []*recordingObserver{first, second} {
+		if len(o.chunks) != 1 || o.chunks[0] != "hello" || o.runErr == nil {
+			t.Errorf("observer got chunks %v and error %v", o.chunks, o.runErr)
+		}
+	}
+}
+
+func TestTerminalObserverStreamSummary(t *testing.T) {
+	var out bytes.Buffer
+	observer := &terminalObserver{out: NewSyncWriter(&out), layout: newStreamLayout(func() int { return 0 }), stream: StreamSummary}
+
+	observer.OnAgentChunk("task", "Let me look at the file.\n", false)
+	observer.OnToolUse("task", ToolUse{Name: "Edit", Detail: "a.go"})
+	observer.OnAgentChunk("task", "Fixed the lint.\n\nTests ", false)
+	observer.OnAgentChunk("task", "pass.\n", false)
+	observer.OnAgentChunk("task", "warning\n", true)
+	observer.OnOutcome("task", AttemptRecord{Outcome: OutcomeFixed})
+
+	got := out.String()
+	for _, want := range []string{"→ Edit a.go", "warning", "Summary:", "Fixed the lint. Tests pass."} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Let me look") {
+		t.Errorf("output shows text before the last tool call:\n%s", got)
+	}
+}
+
+func TestSummarizeMessage(t *testing.T) {
+	if got := summarizeMessage("one two\n\nthree four five", 13); got != "one two three ..." {
+		t.Errorf("summarizeMessage = %q", got)
+	}
+}
@@ -0,0 +1,38 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+)
+
+// claudePacer keeps Claude invocations at least minInterval apart
+// (--min-interval), for users sharing an API quota with interactive work.
+// The runners of one RunTasks or RunPlaylist share a pacer so the gap also
+// holds when the run moves to another task.
+type claudePacer struct {
+	minInterval time.Duration
+	last        time.Time // When Claude was last started (zero before the first run)
+}
+
+func newClaudePacer(minInterval time.Duration) *claudePacer {
+	return &claudePacer{minInterval: minInterval}
+}
+
+// wait sleeps until minInterval has passed since Claude was last started.
+func (p *claudePacer) wait() {
+	if p.minInterval <= 0 || p.last.IsZero() {
+		return
+	}
+	remaining := p.minInterval - time.Since(p.last)
+	if remaining <= 0 {
+		return
+	}
+	fmt.Println(ColorDim(fmt.Sprintf("Waiting %s before running Claude (min interval %s)...", remaining.Round(time.Second), p.minInterval)))
+	time.Sleep(remaining)
+}
+
+// start waits if needed and records that Claude is being started now.
+func (p *claudePacer) start() {
+	p.wait()
+	p.last = time.Now()
+}
@@ -0,0 +1,41 @@
+package runner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaudePacer(t *testing.T) {
+	p := newClaudePacer(50 * time.Millisecond)
+
+	// The first invocation doesn't wait
+	start := time.Now()
+	p.start()
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("first start waited %s", elapsed)
+	}
+
+	// Later ones wait out the rest of the interval
+	p.start()
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("second start after %s, want at least 50ms", elapsed)
+	}
+
+	// Time already spent counts towards the interval
+	time.Sleep(60 * time.Millisecond)
+	before := time.Now()
+	p.wait()
+	if elapsed := time.Since(before); elapsed > 20*time.Millisecond {
+		t.Errorf("wait after the interval passed took %s", elapsed)
+	}
+}
+
+func TestClaudePacerDisabled(t *testing.T) {
+	p := newClaudePacer(0)
+	p.start()
+	start := time.Now()
+	p.start()
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("start without a min interval waited %s", elapsed)
+	}
+}
@@ -0,0 +1,90 @@
+package runner
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// aiderParser reads aider's --no-pretty output: every line is shown, "Applied
+// edit to" lines become Edit tool calls, so edited files are tracked as with
+// Claude, and "Tokens:" lines are summed into the usage.
+type aiderParser struct {
+	StreamCallbacks
+	output strings.Builder
+	usage  Usage
+}
+
+var (
+	aiderEditRe     = regexp.MustCompile(`^Applied edit to (.+)$`)
+	aiderSentRe     = regexp.MustCompile(`([\d.]+)([kM]?) sent`)
+	aiderReceivedRe = regexp.MustCompile(`([\d.]+)([kM]?) received`)
+	aiderCostRe     = regexp.MustCompile(`Cost: \$([\d.]+) message`)
+)
+
+func (p *aiderParser) Line(line string) {
+	p.text(line + "\n")
+	p.output.WriteString(line + "\n")
+
+	if m := aiderEditRe.FindStringSubmatch(line); m != nil {
+		p.tool(newToolUse("Edit", map[string]interface{}{"file_path": strings.TrimSpace(m[1])}))
+		return
+	}
+	if !strings.HasPrefix(line, "Tokens: ") {
+		return
+	}
+	// One line per message, e.g. "Tokens: 2.3k sent, 120 received. Cost: $0.01 message, $0.05 session."
+	p.usage.NumTurns++
+	if m := aiderSentRe.FindStringSubmatch(line); m != nil {
+		p.usage.InputTokens += aiderTokens(m[1], m[2])
+	}
+	if m := aiderReceivedRe.FindStringSubmatch(line); m != nil {
+		p.usage.OutputTokens += aiderTokens(m[1], m[2])
+	}
+	if m := aiderCostRe.FindStringSubmatch(line); m != nil {
+		cost, _ := strconv.ParseFloat(m[1], 64)
+		p.usage.CostUSD += cost
+	}
+}
+
+// aiderTokens reads a token count aider abbreviated, e.g. "2.3" and "k".
+func aiderTokens(number, suffix string) int {
+	n, _ := strconv.ParseFloat(number, 64)
+	switch suffix {
+	case "k":
+		n *= 1e3
+	case "M":
+		n *= 1e6
+	}
+	return int(n)
+}
+
+func (p *aiderParser) Finish() ClaudeResult {
+	return ClaudeResult{Output: p.output.String(), Usage: p.usage}
+}
+
+// sentinelParser reads plain text from a CLI that prints stream_sentinel on a
+// line of its own when it has finished. Lines after it aren't shown, and
+// output that never reaches it is reported as a failed run.
+type sentinelParser struct {
+	StreamCallbacks
+	sentinel string
+	output   strings.Builder
+	done     bool
+}
+
+func (p *sentinelParser) Line(line string) {
+	if p.done {
+		return
+	}
+	if strings.TrimSpace(line) == p.sentinel {
+		p.done = true
+		return
+	}
+	p.text(line + "\n")
+	p.output.WriteString(line + "\n")
+}
+
+func (p *sentinelParser) Finish() ClaudeResult {
+	return ClaudeResult{Output: p.output.String(), Usage: Usage{IsError: !p.done}}
+}
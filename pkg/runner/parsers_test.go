@@ -0,0 +1,144 @@
+package runner
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAiderParser(t *testing.T) {
+	var text strings.Builder
+	var tools []ToolUse
+	parser := &aiderParser{StreamCallbacks: StreamCallbacks{
+		OnText: func(s string) { text.WriteString(s) },
+		OnTool: func(tool ToolUse) { tools = append(tools, tool) },
+	}}
+	for _, line := range []string{
+		"Aider v0.50.0",
+		"Applied edit to src/main.go",
+		"Tokens: 2.3k sent, 120 received. Cost: $0.01 message, $0.01 session.",
+		"Applied edit to README.md",
+		"Tokens: 4.1k sent, 1.0k cache write, 80 received. Cost: $0.02 message, $0.03 session.",
+	} {
+		parser.Line(line)
+	}
+	result := parser.Finish()
+
+	if !strings.HasPrefix(text.String(), "Aider v0.50.0\nApplied edit to src/main.go\n") || result.Output != text.String() {
+		t.Errorf("streamed = %q, output = %q", text.String(), result.Output)
+	}
+	if len(tools) != 2 || tools[0].Name != "Edit" || tools[0].Path != "src/main.go" || tools[1].Path != "README.md" {
+		t.Errorf("tools = %+v", tools)
+	}
+	want := Usage{InputTokens: 6400, OutputTokens: 200, CostUSD: 0.03, NumTurns: 2}
+	got := result.Usage
+	if got.InputTokens != want.InputTokens || got.OutputTokens != want.OutputTokens || got.NumTurns != want.NumTurns ||
+		got.CostUSD < 0.0299 || got.CostUSD > 0.0301 {
+		t.Errorf("usage = %+v, want %+v", got, want)
+	}
+}
+
+func TestSentinelParser(t *testing.T) {
+	tests := []struct {
+		name       string
+		lines      []string
+		wantOutput string
+		wantError  bool
+	}{
+		{"finished", []string{"fixed it", "  DONE  ", "trailing noise"}, "fixed it\n", false},
+		{"never finished", []string{"working on it"}, "working on it\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := &sentinelParser{sentinel: "DONE"}
+			for _, line := range tt.lines {
+				parser.Line(line)
+			}
+			result := parser.Finish()
+			if result.Output != tt.wantOutput || result.Usage.IsError != tt.wantError {
+				t.Errorf("result = %+v, want output %q, error %v", result, tt.wantOutput, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestClaudeCommandLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream StreamConfig
+		flags  string
+		want   string
+	}{
+		{"claude with flags", StreamConfig{}, "--fast",
+			"claude --print --output-format stream-json --include-partial-messages --verbose --fast -p <<'__NIGEL_PROMPT_EOF__'\nfix\n__NIGEL_PROMPT_EOF__"},
+		{"claude text", StreamConfig{Format: OutputText}, "",
+			"claude --print -p <<'__NIGEL_PROMPT_EOF__'\nfix\n__NIGEL_PROMPT_EOF__"},
+		{"aider", StreamConfig{Parser: ParserAider}, "--model sonnet",
+			"claude --yes-always --no-pretty --no-stream --no-auto-commits --model sonnet --message-file /dev/stdin <<'__NIGEL_PROMPT_EOF__'\nfix\n__NIGEL_PROMPT_EOF__"},
+		{"sentinel", StreamConfig{Parser: ParserSentinel, Sentinel: "DONE"}, "",
+			"claude <<'__NIGEL_PROMPT_EOF__'\nfix\n__NIGEL_PROMPT_EOF__"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, ok := lookupStreamParser(tt.stream.Parser)
+			if !ok {
+				t.Fatalf("no parser %q", tt.stream.Parser)
+			}
+			if got := claudeCommandLine(spec, tt.stream, "claude", tt.flags, "fix"); got != tt.want {
+				t.Errorf("got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// upperParser shows each line in capitals, for testing registration.
+type upperParser struct {
+	StreamCallbacks
+	output strings.Builder
+}
+
+func (p *upperParser) Line(line string) {
+	p.text(strings.ToUpper(line) + "\n")
+	p.output.WriteString(strings.ToUpper(line) + "\n")
+}
+
+func (p *upperParser) Finish() ClaudeResult { return ClaudeResult{Output: p.output.String()} }
+
+func TestRegisterStreamParser(t *testing.T) {
+	RegisterStreamParser("upper", StreamParserSpec{
+		New: func(cfg StreamConfig, cb StreamCallbacks) StreamParser {
+			return &upperParser{StreamCallbacks: cb}
+		},
+		Flags: func(StreamConfig) string { return "--shout" },
+	})
+	defer func() {
+		streamParsersMu.Lock()
+		delete(streamParsers, "upper")
+		streamParsersMu.Unlock()
+	}()
+	if !strings.Contains(streamParserNames(), "upper") {
+		t.Errorf("names = %q", streamParserNames())
+	}
+
+	dir := t.TempDir()
+	script := dir + "/fake-agent"
+	// Echoes its arguments, then the prompt from stdin
+	body := "#!/bin/bash\necho \"$@\"\ncat\n"
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var streamed strings.Builder
+	result, err := RunClaudeCommand(script, "", StreamConfig{Parser: "upper"}, "fix it", dir, nil, 0, 0,
+		func(s string) { streamed.WriteString(s) }, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Output != "--SHOUT\nFIX IT\n" || streamed.String() != result.Output {
+		t.Errorf("output = %q, streamed = %q", result.Output, streamed.String())
+	}
+
+	if _, err := RunClaudeCommand(script, "", StreamConfig{Parser: "missing"}, "fix it", dir, nil, 0, 0, nil, nil, nil, nil); err == nil {
+		t.Error("expected an error for an unknown parser")
+	}
+}
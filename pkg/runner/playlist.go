@@ -0,0 +1,248 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+)
+
+// playlistScheduler picks the next task using smooth weighted round-robin, so
+// tasks are interleaved in proportion to their weights rather than in bursts.
+// With equal weights this is plain round-robin.
+type playlistScheduler struct {
+	weights []int
+	current []int
+}
+
+func newPlaylistScheduler(entries []PlaylistEntry) *playlistScheduler {
+	weights := make([]int, len(entries))
+	for i, e := range entries {
+		weights[i] = e.Weight
+	}
+	return &playlistScheduler{
+		weights: weights,
+		current: make([]int, len(entries)),
+	}
+}
+
+// next returns the index of the next task to run among the active entries,
+// or -1 if none are active.
+func (s *playlistScheduler) next(active []bool) int {
+	best, total := -1, 0
+	for i, w := range s.weights {
+		if !active[i] {
+			continue
+		}
+		s.current[i] += w
+		total += w
+		if best < 0 || s.current[i] > s.current[best] {
+			best = i
+		}
+	}
+	if best >= 0 {
+		s.current[best] -= total
+	}
+	return best
+}
+
+// RunPlaylist alternates iterations between the playlist's tasks until every
+// task runs out of candidates or a shared limit is reached. With isolation set,
+// each task works in its own worktree or clone, merged back when the
+// playlist ends.
+func RunPlaylist(env *Environment, playlist Playlist, opts RunnerOptions) error {
+	taskNames := make([]string, len(playlist.Tasks))
+	for i, entry := range playlist.Tasks {
+		taskNames[i] = entry.Task
+	}
+	if err := confirmPreflight(env, taskNames, opts); err != nil {
+		return err
+	}
+
+	stop := opts.Control.stopFlag()
+	defer watchSignals(stop)()
+	pacer := newClaudePacer(opts.MinInterval)
+	power := newPowerGate(opts)
+
+	runners := make([]*Runner, len(playlist.Tasks))
+	active := make([]bool, len(playlist.Tasks))
+	for i, entry := range playlist.Tasks {
+		runner, err := NewRunner(env, entry.Task, opts)
+		if err != nil {
+			return err
+		}
+		if err := runner.checkClaudeCommand(); err != nil {
+			discardIsolation(runners[:i])
+			return err
+		}
+		if err := runner.isolate(); err != nil {
+			discardIsolation(runners[:i])
+			return err
+		}
+		runner.stopRequested = stop
+		runner.pacer = pacer
+		runners[i] = runner
+		active[i] = true
+	}
+
+	waitsFor, err := playlistDependencies(env, playlist.Tasks, opts.Partition, active)
+	if err != nil {
+		discardIsolation(runners)
+		return err
+	}
+
+	fmt.Print(env.StartupBanner(playlist.Name, "", "playlist ("+playlist.Mode+")"))
+
+	scheduler := newPlaylistScheduler(playlist.Tasks)
+	startTime := time.Now()
+	iterations, last := 0, -1
+	var runErr error
+	for {
+		opts.Control.waitWhilePaused()
+		power.wait(opts, stop)
+		if stop.Load() {
+			fmt.Println("Stopped by user request.")
+			break
+		}
+
+		if opts.Limit > 0 && iterations >= opts.Limit {
+			fmt.Printf("Reached iteration limit (%d).\n", opts.Limit)
+			break
+		}
+		if commits := playlistCommits(runners); opts.MaxCommits > 0 && commits >= opts.MaxCommits {
+			fmt.Printf("Reached commit limit (%d).\n", opts.MaxCommits)
+			break
+		}
+		if cost := playlistCost(runners); opts.MaxCost > 0 && cost >= opts.MaxCost {
+			fmt.Printf("Reached cost limit ($%.2f).\n", opts.MaxCost)
+			break
+		}
+
+		if opts.TimeLimit > 0 && time.Since(startTime) >= opts.TimeLimit {
+			fmt.Printf("Reached time limit (%s).\n", opts.TimeLimit)
+			break
+		}
+
+		idx := scheduler.next(readyTasks(active, waitsFor))
+		if idx < 0 {
+			fmt.Println("All playlist tasks are done.")
+			break
+		}
+
+		// Without isolation the tasks share a branch, so a batch is committed
+		// before another task's commits land on top of it
+		if last >= 0 && last != idx && runners[last].worktree == nil {
+			if err := runners[last].flushPending(); err != nil {
+				runErr = err
+				break
+			}
+		}
+		last = idx
+
+		runner := runners[idx]
+		if runner.checkSentinels() {
+			break
+		}
+		fmt.Println("\n" + ColorInfo(fmt.Sprintf("Playlist task: %s", runner.task.Name)))
+		stepStart := time.Now()
+		done, err := runner.step()
+		runner.summary.Duration += time.Since(stepStart)
+		iterations++
+		if err != nil {
+			runErr = err
+			break
+		}
+		if done {
+			active[idx] = false
+		}
+	}
+
+	summaries := make([]RunSummary, 0, len(runners))
+	for _, runner := range runners {
+		if runErr == nil {
+			runErr = runner.finish()
+		} else {
+			runner.abandonPending()
+			runner.keepWorktree()
+		}
+		if runner.iteration > 0 {
+			summaries = append(summaries, runner.summary)
+		}
+	}
+	runObservers(env, opts).OnRunEnd(summaries, runErr)
+
+	return runErr
+}
+
+// playlistDependencies returns, for each playlist task, the tasks in the
+// playlist it depends on, which it waits for until they run out of
+// candidates. A task whose prerequisite outside the playlist still has
+// candidates is marked inactive, as RunTasks would skip it.
+func playlistDependencies(env *Environment, entries []PlaylistEntry, partition HashPartition, active []bool) ([][]int, error) {
+	index := make(map[string]int, len(entries))
+	for i, entry := range entries {
+		index[entry.Task] = i
+	}
+	waitsFor := make([][]int, len(entries))
+	for i, entry := range entries {
+		outside := env.Tasks[entry.Task]
+		outside.DependsOn = nil
+		for _, dep := range env.Tasks[entry.Task].DependsOn {
+			if j, ok := index[dep]; ok {
+				waitsFor[i] = append(waitsFor[i], j)
+			} else {
+				outside.DependsOn = append(outside.DependsOn, dep)
+			}
+		}
+		dep, pending, err := unmetDependency(env, outside, partition)
+		if err != nil {
+			return nil, err
+		}
+		if dep != "" {
+			fmt.Println(ColorWarning(fmt.Sprintf("Skipping %s: prerequisite %s still has %d candidate(s)", entry.Task, dep, pending)))
+			active[i] = false
+		}
+	}
+	return waitsFor, nil
+}
+
+// readyTasks returns which active tasks can take a turn: those whose
+// prerequisites in the playlist have run out of candidates.
+func readyTasks(active []bool, waitsFor [][]int) []bool {
+	ready := make([]bool, len(active))
+	for i := range active {
+		ready[i] = active[i]
+		for _, j := range waitsFor[i] {
+			if active[j] {
+				ready[i] = false
+			}
+		}
+	}
+	return ready
+}
+
+// discardIsolation removes the worktrees of runners set up before another
+// failed to start. They haven't run, so there is nothing to merge.
+func discardIsolation(runners []*Runner) {
+	for _, runner := range runners {
+		runner.finish()
+	}
+}
+
+// playlistCommits returns the commits the playlist's tasks have created so
+// far, for the shared --max-commits limit.
+func playlistCommits(runners []*Runner) int {
+	commits := 0
+	for _, runner := range runners {
+		commits += runner.summary.NewCommits
+	}
+	return commits
+}
+
+// playlistCost returns what Claude has cost across the playlist's tasks so
+// far, for the shared --max-cost limit.
+func playlistCost(runners []*Runner) float64 {
+	cost := 0.0
+	for _, runner := range runners {
+		cost += runner.summary.CostUSD
+	}
+	return cost
+}
@@ -0,0 +1,323 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlaylistScheduler(t *testing.T) {
+	t.Run("equal weights rotate round-robin", func(t *testing.T) {
+		s := newPlaylistScheduler([]PlaylistEntry{{Task: "a", Weight: 1}, {Task: "b", Weight: 1}, {Task: "c", Weight: 1}})
+		active := []bool{true, true, true}
+
+		var got []int
+		for i := 0; i < 6; i++ {
+			got = append(got, s.next(active))
+		}
+		expected := []int{0, 1, 2, 0, 1, 2}
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Fatalf("sequence = %v, want %v", got, expected)
+			}
+		}
+	})
+
+	t.Run("weights are interleaved proportionally", func(t *testing.T) {
+		s := newPlaylistScheduler([]PlaylistEntry{{Task: "a", Weight: 3}, {Task: "b", Weight: 1}})
+		active := []bool{true, true}
+
+		counts := make([]int, 2)
+		for i := 0; i < 8; i++ {
+			counts[s.next(active)]++
+		}
+		if counts[0] != 6 || counts[1] != 2 {
+			t.Errorf("counts = %v, want [6 2]", counts)
+		}
+	})
+
+	t.Run("skips inactive tasks", func(t *testing.T) {
+		s := newPlaylistScheduler([]PlaylistEntry{{Task: "a", Weight: 1}, {Task: "b", Weight: 1}})
+		active := []bool{false, true}
+
+		for i := 0; i < 3; i++ {
+			if got := s.next(active); got != 1 {
+				t.Errorf("next() = %d, want 1", got)
+			}
+		}
+	})
+
+	t.Run("returns -1 when nothing is active", func(t *testing.T) {
+		s := newPlaylistScheduler([]PlaylistEntry{{Task: "a", Weight: 1}})
+		if got := s.next([]bool{false}); got != -1 {
+			t.Errorf("next() = %d, want -1", got)
+		}
+	})
+}
+
+func TestLoadPlaylists(t *testing.T) {
+	tasks := map[string]Task{"lint": {Name: "lint"}, "tests": {Name: "tests"}}
+
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{
+			name: "weighted playlist",
+			yaml: `
+mode: weighted
+tasks:
+  - task: lint
+    weight: 3
+  - task: tests
+`,
+			wantErr: false,
+		},
+		{
+			name: "unknown task",
+			yaml: `
+tasks:
+  - task: missing
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown mode",
+			yaml: `
+mode: random
+tasks:
+  - task: lint
+`,
+			wantErr: true,
+		},
+		{
+			name:    "no tasks",
+			yaml:    `mode: round-robin`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runnerDir := t.TempDir()
+			playlistDir := filepath.Join(runnerDir, "nightly")
+			if err := os.Mkdir(playlistDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(playlistDir, "playlist.yaml"), []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			playlists, err := loadPlaylists(runnerDir, tasks)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadPlaylists() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			playlist := playlists["nightly"]
+			if playlist.Tasks[0].Weight != 3 || playlist.Tasks[1].Weight != 1 {
+				t.Errorf("weights = %d, %d, want 3, 1", playlist.Tasks[0].Weight, playlist.Tasks[1].Weight)
+			}
+		})
+	}
+}
+
+func TestRunPlaylistIsolation(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		if _, err := gitOutput(repo, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo, _ = filepath.EvalSymlinks(repo)
+
+	// Each task records where its candidate source ran, then has nothing to fix
+	dirs := filepath.Join(t.TempDir(), "dirs")
+	source := "pwd >> " + shellQuote(dirs) + " && echo '[]'"
+	taskDir := t.TempDir()
+	env := &Environment{
+		ProjectDir: repo,
+		Config:     Config{ClaudeCommand: "true", Isolation: IsolationWorktree},
+		Tasks: map[string]Task{
+			"lint":  {Name: "lint", Dir: taskDir, Prompt: "fix", CandidateSource: source},
+			"types": {Name: "types", Dir: taskDir, Prompt: "fix", CandidateSource: source},
+		},
+	}
+	playlist := Playlist{Name: "nightly", Mode: "round-robin", Tasks: []PlaylistEntry{{Task: "lint", Weight: 1}, {Task: "types", Weight: 1}}}
+
+	if err := RunPlaylist(env, playlist, RunnerOptions{}); err != nil {
+		t.Fatalf("RunPlaylist failed: %v", err)
+	}
+	data, err := os.ReadFile(dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ran := strings.Fields(string(data))
+	if len(ran) != 2 {
+		t.Fatalf("candidate source ran in %v, want once per task", ran)
+	}
+	for _, dir := range ran {
+		if dir == repo {
+			t.Errorf("a task ran in the checkout %s instead of a worktree", repo)
+		}
+	}
+	if list, _ := gitOutput(repo, "worktree", "list"); strings.Count(list, "\n") != 0 {
+		t.Errorf("worktrees left behind:\n%s", list)
+	}
+}
+
+func TestRunPlaylistContainer(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		if _, err := gitOutput(repo, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A stand-in runtime that logs its arguments and runs the command on the host
+	state := t.TempDir()
+	calls := filepath.Join(state, "calls")
+	runtime := filepath.Join(state, "fake-docker")
+	script := "#!/bin/bash\necho \"$*\" >> " + shellQuote(calls) + "\nexec bash -c \"${@: -1}\"\n"
+	if err := os.WriteFile(runtime, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	taskDir := t.TempDir()
+	env := &Environment{
+		ProjectDir: repo,
+		Config: Config{
+			ClaudeCommand: "claude",
+			Isolation:     IsolationContainer,
+			Container:     &ContainerConfig{Image: "sandbox", Runtime: runtime},
+		},
+		Tasks: map[string]Task{
+			"lint":  {Name: "lint", Dir: taskDir, Prompt: "fix", CandidateSource: "echo '[]'"},
+			"types": {Name: "types", Dir: taskDir, Prompt: "fix", CandidateSource: "echo '[]'"},
+		},
+	}
+	playlist := Playlist{Name: "nightly", Mode: "round-robin", Tasks: []PlaylistEntry{{Task: "lint", Weight: 1}, {Task: "types", Weight: 1}}}
+
+	if err := RunPlaylist(env, playlist, RunnerOptions{}); err != nil {
+		t.Fatalf("RunPlaylist failed: %v", err)
+	}
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatalf("no command ran in the container: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("container runs = %q, want one candidate source per task", lines)
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, "sandbox bash -c") || strings.Contains(line, "-w "+repo+" ") {
+			t.Errorf("container run %q should use the clone, not the checkout", line)
+		}
+	}
+}
+
+func TestRunPlaylistBatchedCommits(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "test"},
+		{"config", "user.email", "test@example.com"},
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		if _, err := gitOutput(repo, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each task has one candidate, fixed by writing a file named after it
+	task := func(name string) Task {
+		return Task{
+			Name:            name,
+			Dir:             t.TempDir(),
+			Prompt:          "fix",
+			CandidateSource: fmt.Sprintf(`test -f %[1]s.txt && echo '[]' || echo '["%[1]s"]'`, name),
+			ClaudeCommand:   fmt.Sprintf("cat > /dev/null; echo fixed > %s.txt", name),
+			CommitMode:      "per-session",
+			CommitBatch:     commitPerSession,
+		}
+	}
+	env := &Environment{
+		ProjectDir: repo,
+		Config:     Config{SuccessCommand: "git commit -q -m $CANDIDATE"},
+		Tasks:      map[string]Task{"lint": task("lint"), "types": task("types")},
+	}
+	playlist := Playlist{Name: "nightly", Mode: "round-robin", Tasks: []PlaylistEntry{{Task: "lint", Weight: 1}, {Task: "types", Weight: 1}}}
+
+	if err := RunPlaylist(env, playlist, RunnerOptions{}); err != nil {
+		t.Fatalf("RunPlaylist failed: %v", err)
+	}
+	log, err := gitOutput(repo, "log", "--format=%s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(log, "nigel: pending") || strings.Count(log, "1 candidates:") != 2 {
+		t.Errorf("expected one batch commit per task, got log:\n%s", log)
+	}
+}
+
+func TestRunPlaylistDependencies(t *testing.T) {
+	order := filepath.Join(t.TempDir(), "order")
+	task := func(name string, dependsOn ...string) Task {
+		return Task{
+			Name:            name,
+			Dir:             t.TempDir(),
+			Prompt:          "fix",
+			CandidateSource: "echo " + name + " >> " + shellQuote(order) + " && echo '[]'",
+			DependsOn:       dependsOn,
+		}
+	}
+	external := task("external")
+	external.CandidateSource = `echo '["x"]'`
+	env := &Environment{
+		ProjectDir: t.TempDir(),
+		Config:     Config{ClaudeCommand: "true", ResetCommand: "true"},
+		Tasks: map[string]Task{
+			"types":    task("types", "lint"),
+			"lint":     task("lint"),
+			"docs":     task("docs", "external"),
+			"external": external,
+		},
+	}
+	playlist := Playlist{Name: "nightly", Mode: "round-robin", Tasks: []PlaylistEntry{
+		{Task: "types", Weight: 1}, {Task: "lint", Weight: 1}, {Task: "docs", Weight: 1},
+	}}
+
+	if err := RunPlaylist(env, playlist, RunnerOptions{}); err != nil {
+		t.Fatalf("RunPlaylist failed: %v", err)
+	}
+	data, err := os.ReadFile(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// types waits for lint to run out of candidates; docs waits on a task
+	// outside the playlist that still has some, so it never runs
+	if got := strings.Fields(string(data)); strings.Join(got, " ") != "lint types" {
+		t.Errorf("tasks ran in order %v, want [lint types]", got)
+	}
+}
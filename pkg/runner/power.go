@@ -0,0 +1,175 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// PowerState is what the platform probes report about the machine.
+type PowerState struct {
+	BatteryKnown bool // Whether battery state could be read
+	OnBattery    bool // Running on battery rather than mains power
+	Battery      int  // Charge percentage
+	MeteredKnown bool // Whether the connection's metered state could be read
+	Metered      bool
+}
+
+// powerCheckInterval is how often a paused run re-probes power and network.
+const powerCheckInterval = time.Minute
+
+// powerGate pauses iterations while the laptop is on battery below
+// --min-battery or on a metered connection with --pause-on-metered, and
+// resumes automatically once neither applies.
+type powerGate struct {
+	minBattery     int
+	pauseOnMetered bool
+	probe          func() PowerState
+	interval       time.Duration
+}
+
+// newPowerGate returns the gate for opts, or nil if neither option is set or
+// this is a dry run. Warns about options the platform can't probe; those
+// never pause.
+func newPowerGate(opts RunnerOptions) *powerGate {
+	if (opts.MinBattery <= 0 && !opts.PauseOnMetered) || opts.DryRun {
+		return nil
+	}
+	g := &powerGate{
+		minBattery:     opts.MinBattery,
+		pauseOnMetered: opts.PauseOnMetered,
+		probe:          func() PowerState { return probePower(runtime.GOOS) },
+		interval:       powerCheckInterval,
+	}
+	state := g.probe()
+	if g.minBattery > 0 && !state.BatteryKnown {
+		fmt.Println(ColorWarning(fmt.Sprintf("--min-battery: can't read battery state on %s, ignoring", runtime.GOOS)))
+	}
+	if g.pauseOnMetered && !state.MeteredKnown {
+		fmt.Println(ColorWarning(fmt.Sprintf("--pause-on-metered: can't detect metered connections on %s, ignoring", runtime.GOOS)))
+	}
+	return g
+}
+
+// pauseReason explains why the run should pause in state, or "" if it shouldn't.
+func (g *powerGate) pauseReason(state PowerState) string {
+	if g.minBattery > 0 && state.BatteryKnown && state.OnBattery && state.Battery < g.minBattery {
+		return fmt.Sprintf("on battery at %d%% (below %d%%)", state.Battery, g.minBattery)
+	}
+	if g.pauseOnMetered && state.MeteredKnown && state.Metered {
+		return "on a metered connection"
+	}
+	return ""
+}
+
+// wait blocks while the run should be paused, until conditions clear or a stop
+// is requested. Safe on a nil gate.
+func (g *powerGate) wait(opts RunnerOptions, stop *atomic.Bool) {
+	if g == nil {
+		return
+	}
+	reason := g.pauseReason(g.probe())
+	if reason == "" {
+		return
+	}
+	fmt.Println(ColorWarning(fmt.Sprintf("Paused: %s. Checking again every %s...", reason, g.interval)))
+	notifyDesktop(opts, "Nigel paused", reason)
+	for reason != "" && !stop.Load() {
+		time.Sleep(g.interval)
+		reason = g.pauseReason(g.probe())
+	}
+	if !stop.Load() {
+		fmt.Println(ColorInfo("Resuming."))
+	}
+}
+
+// probePower reads battery and metered-connection state on the given OS.
+// Anything that can't be read is left unknown.
+func probePower(goos string) PowerState {
+	var state PowerState
+	switch goos {
+	case "darwin":
+		if output, err := exec.Command("pmset", "-g", "batt").Output(); err == nil {
+			state.BatteryKnown, state.OnBattery, state.Battery = parsePmsetBattery(string(output))
+		}
+		// macOS has no command-line probe for metered (Low Data Mode) networks
+	case "linux":
+		state.BatteryKnown, state.OnBattery, state.Battery = readLinuxBattery("/sys/class/power_supply")
+		output, err := exec.Command("busctl", "get-property", "org.freedesktop.NetworkManager",
+			"/org/freedesktop/NetworkManager", "org.freedesktop.NetworkManager", "Metered").Output()
+		if err == nil {
+			state.MeteredKnown, state.Metered = parseNMMetered(string(output))
+		}
+	}
+	return state
+}
+
+var pmsetPercent = regexp.MustCompile(`(\d+)%`)
+
+// parsePmsetBattery parses `pmset -g batt`, e.g.
+// "Now drawing from 'Battery Power'\n -InternalBattery-0 (id=123)	85%; discharging; ...".
+func parsePmsetBattery(output string) (known, onBattery bool, percent int) {
+	m := pmsetPercent.FindStringSubmatch(output)
+	if m == nil {
+		return false, false, 0 // No battery (desktop Mac)
+	}
+	percent, _ = strconv.Atoi(m[1])
+	return true, strings.Contains(output, "'Battery Power'"), percent
+}
+
+// readLinuxBattery reads the batteries under dir (normally
+// /sys/class/power_supply). The machine is on battery if any battery is
+// discharging; the charge is the average across batteries.
+func readLinuxBattery(dir string) (known, onBattery bool, percent int) {
+	supplies, _ := filepath.Glob(filepath.Join(dir, "*"))
+	total, count := 0, 0
+	for _, supply := range supplies {
+		if readSysfs(filepath.Join(supply, "type")) != "Battery" {
+			continue
+		}
+		capacity, err := strconv.Atoi(readSysfs(filepath.Join(supply, "capacity")))
+		if err != nil {
+			continue
+		}
+		total += capacity
+		count++
+		if readSysfs(filepath.Join(supply, "status")) == "Discharging" {
+			onBattery = true
+		}
+	}
+	if count == 0 {
+		return false, false, 0
+	}
+	return true, onBattery, total / count
+}
+
+func readSysfs(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// parseNMMetered parses NetworkManager's Metered property as printed by
+// busctl ("u 1"). 1 (yes) and 3 (guessed yes) are metered; 0 is unknown.
+func parseNMMetered(output string) (known, metered bool) {
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return false, false
+	}
+	switch fields[1] {
+	case "1", "3":
+		return true, true
+	case "2", "4":
+		return true, false
+	}
+	return false, false
+}
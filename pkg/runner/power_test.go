@@ -0,0 +1,144 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParsePmsetBattery(t *testing.T) {
+	tests := []struct {
+		name          string
+		output        string
+		wantKnown     bool
+		wantOnBattery bool
+		wantPercent   int
+	}{
+		{
+			name:          "on battery",
+			output:        "Now drawing from 'Battery Power'\n -InternalBattery-0 (id=4653155)\t15%; discharging; 0:45 remaining present: true\n",
+			wantKnown:     true,
+			wantOnBattery: true,
+			wantPercent:   15,
+		},
+		{
+			name:        "charging",
+			output:      "Now drawing from 'AC Power'\n -InternalBattery-0 (id=4653155)\t80%; charging; 1:02 remaining present: true\n",
+			wantKnown:   true,
+			wantPercent: 80,
+		},
+		{
+			name:   "no battery",
+			output: "Now drawing from 'AC Power'\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			known, onBattery, percent := parsePmsetBattery(tt.output)
+			if known != tt.wantKnown || onBattery != tt.wantOnBattery || percent != tt.wantPercent {
+				t.Errorf("parsePmsetBattery() = %v, %v, %d, want %v, %v, %d", known, onBattery, percent, tt.wantKnown, tt.wantOnBattery, tt.wantPercent)
+			}
+		})
+	}
+}
+
+func TestReadLinuxBattery(t *testing.T) {
+	dir := t.TempDir()
+	writeSupply := func(name string, files map[string]string) {
+		os.MkdirAll(filepath.Join(dir, name), 0755)
+		for file, content := range files {
+			os.WriteFile(filepath.Join(dir, name, file), []byte(content+"\n"), 0644)
+		}
+	}
+
+	if known, _, _ := readLinuxBattery(dir); known {
+		t.Error("readLinuxBattery() known without batteries")
+	}
+
+	writeSupply("AC", map[string]string{"type": "Mains", "online": "0"})
+	writeSupply("BAT0", map[string]string{"type": "Battery", "capacity": "30", "status": "Discharging"})
+	writeSupply("BAT1", map[string]string{"type": "Battery", "capacity": "10", "status": "Unknown"})
+
+	known, onBattery, percent := readLinuxBattery(dir)
+	if !known || !onBattery || percent != 20 {
+		t.Errorf("readLinuxBattery() = %v, %v, %d, want true, true, 20", known, onBattery, percent)
+	}
+}
+
+func TestParseNMMetered(t *testing.T) {
+	tests := []struct {
+		output      string
+		wantKnown   bool
+		wantMetered bool
+	}{
+		{"u 1\n", true, true},
+		{"u 3\n", true, true},
+		{"u 2\n", true, false},
+		{"u 4\n", true, false},
+		{"u 0\n", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		known, metered := parseNMMetered(tt.output)
+		if known != tt.wantKnown || metered != tt.wantMetered {
+			t.Errorf("parseNMMetered(%q) = %v, %v, want %v, %v", tt.output, known, metered, tt.wantKnown, tt.wantMetered)
+		}
+	}
+}
+
+func TestPowerGatePauseReason(t *testing.T) {
+	g := &powerGate{minBattery: 20, pauseOnMetered: true}
+
+	tests := []struct {
+		name  string
+		state PowerState
+		want  bool
+	}{
+		{"low battery", PowerState{BatteryKnown: true, OnBattery: true, Battery: 15}, true},
+		{"low but charging", PowerState{BatteryKnown: true, Battery: 15}, false},
+		{"enough charge", PowerState{BatteryKnown: true, OnBattery: true, Battery: 50}, false},
+		{"metered", PowerState{MeteredKnown: true, Metered: true}, true},
+		{"unknown", PowerState{OnBattery: true, Metered: true}, false},
+	}
+	for _, tt := range tests {
+		if got := g.pauseReason(tt.state) != ""; got != tt.want {
+			t.Errorf("%s: paused = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPowerGateWaitResumes(t *testing.T) {
+	probes := 0
+	g := &powerGate{
+		minBattery: 20,
+		interval:   time.Millisecond,
+		probe: func() PowerState {
+			probes++
+			// Plugged in on the third check
+			return PowerState{BatteryKnown: true, OnBattery: probes < 3, Battery: 10}
+		},
+	}
+
+	g.wait(RunnerOptions{}, &atomic.Bool{})
+	if probes != 3 {
+		t.Errorf("probed %d times, want 3", probes)
+	}
+
+	// A stop request ends the pause
+	stop := &atomic.Bool{}
+	stop.Store(true)
+	probes = 0
+	g.probe = func() PowerState {
+		probes++
+		return PowerState{BatteryKnown: true, OnBattery: true, Battery: 10}
+	}
+	g.wait(RunnerOptions{}, stop)
+	if probes != 1 {
+		t.Errorf("probed %d times after stop, want 1", probes)
+	}
+
+	var nilGate *powerGate
+	nilGate.wait(RunnerOptions{}, stop)
+}
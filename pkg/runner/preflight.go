@@ -0,0 +1,157 @@
+package runner
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ConfirmFunc shows the preflight summary and reports whether the run may start.
+type ConfirmFunc func(preflight string) (bool, error)
+
+// errNotConfirmed is returned when the preflight summary is declined.
+var errNotConfirmed = errors.New("run not confirmed")
+
+// AssumeYes prints the preflight summary and starts without asking (--yes).
+func AssumeYes(preflight string) (bool, error) {
+	fmt.Print(preflight)
+	return true, nil
+}
+
+// ConfirmOnTerminal prints the preflight summary and asks on stdin. Without a
+// terminal there's nobody to ask, so it fails rather than guessing.
+func ConfirmOnTerminal(preflight string) (bool, error) {
+	fmt.Print(preflight)
+	if !isTerminal(os.Stdin) {
+		return false, errors.New("stdin is not a terminal; pass --yes to start without confirmation")
+	}
+	return askYesNo("Start?"), nil
+}
+
+// askYesNo asks a question on stdin, defaulting to no.
+func askYesNo(question string) bool {
+	fmt.Print(question + " [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// confirmPreflight shows the preflight summary through opts.Confirm before the
+// first iteration. Dry, --no-commit and --evaluate runs commit nothing, so they
+// only print it.
+func confirmPreflight(env *Environment, taskNames []string, opts RunnerOptions) error {
+	if opts.Confirm == nil {
+		return nil
+	}
+	preflight := FormatPreflight(env, taskNames, opts)
+	if opts.DryRun || opts.NoCommit || opts.Evaluate > 0 {
+		fmt.Print(preflight)
+		return nil
+	}
+	ok, err := opts.Confirm(preflight)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errNotConfirmed
+	}
+	return nil
+}
+
+// FormatPreflight describes what a run is about to do: for each task, its
+// pending candidates (after the ignore list and shard), the commands that
+// verify, reset and commit, the Claude command and model, and the timeout;
+// then the run's iteration and time budgets and --min-interval. Runs each
+// task's candidate source.
+func FormatPreflight(env *Environment, taskNames []string, opts RunnerOptions) string {
+	var b strings.Builder
+	b.WriteString("\n" + ColorBold("Preflight") + "\n")
+	for _, name := range taskNames {
+		task := env.Tasks[name]
+		taskEnv := env.ForTask(task)
+
+		mode := "standard"
+		if task.AcceptBestEffort {
+			mode = "best-effort"
+		}
+		candidates := ""
+		if pending, err := pendingCandidates(env, task, opts.Partition); err != nil {
+			candidates = ColorError(fmt.Sprintf("unavailable (%v)", err))
+		} else {
+			candidates = fmt.Sprintf("%d pending", pending)
+		}
+
+		claudeCmd := opts.ClaudeCommand
+		if claudeCmd == "" {
+			claudeCmd = task.ClaudeCommand
+		}
+		if claudeCmd == "" {
+			claudeCmd = taskEnv.Config.ClaudeCommand
+		}
+		model := modelFlag(task.ClaudeFlags)
+		if model == "" {
+			model = "default"
+		}
+		timeout := opts.Timeout
+		if timeout == 0 {
+			timeout = task.Timeout
+		}
+
+		fmt.Fprintf(&b, "  %-12s %s [%s]\n", "Task:", ColorInfo(name), mode)
+		fmt.Fprintf(&b, "  %-12s %s\n", "Directory:", relativePath(task.WorkDir(taskEnv.ProjectDir)))
+		fmt.Fprintf(&b, "  %-12s %s\n", "Candidates:", candidates)
+		fmt.Fprintf(&b, "  %-12s %s\n", "Verify:", commandOrNone(taskEnv.Config.VerifyCommand))
+		fmt.Fprintf(&b, "  %-12s %s\n", "Reset:", commandOrNone(taskEnv.Config.ResetCommand))
+		fmt.Fprintf(&b, "  %-12s %s\n", "Success:", commandOrNone(taskEnv.Config.SuccessCommand))
+		fmt.Fprintf(&b, "  %-12s %s (model: %s)\n", "Claude:", claudeCmd, model)
+		fmt.Fprintf(&b, "  %-12s %s per candidate\n", "Timeout:", unlimitedOr(timeout > 0, timeout.String()))
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "  %-12s %s iterations, %s time", "Budgets:",
+		unlimitedOr(opts.Limit > 0, fmt.Sprint(opts.Limit)), unlimitedOr(opts.TimeLimit > 0, opts.TimeLimit.String()))
+	if opts.MaxCommits > 0 {
+		fmt.Fprintf(&b, ", %d commits", opts.MaxCommits)
+	}
+	if opts.MaxCost > 0 {
+		fmt.Fprintf(&b, ", $%.2f", opts.MaxCost)
+	}
+	if opts.MinInterval > 0 {
+		fmt.Fprintf(&b, ", at least %s between Claude runs", opts.MinInterval)
+	}
+	b.WriteString("\n\n")
+	return b.String()
+}
+
+// modelFlag returns the value of a --model flag in claude_flags, or "".
+func modelFlag(flags string) string {
+	fields := strings.Fields(flags)
+	for i, f := range fields {
+		if f == "--model" && i+1 < len(fields) {
+			return strings.Trim(fields[i+1], `'"`)
+		}
+		if value, ok := strings.CutPrefix(f, "--model="); ok {
+			return strings.Trim(value, `'"`)
+		}
+	}
+	return ""
+}
+
+func commandOrNone(cmd string) string {
+	if cmd == "" {
+		return ColorDim("(none)")
+	}
+	return cmd
+}
+
+func unlimitedOr(limited bool, value string) string {
+	if limited {
+		return value
+	}
+	return "unlimited"
+}
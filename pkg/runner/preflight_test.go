@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func preflightEnv(t *testing.T) *Environment {
+	tmpDir := t.TempDir()
+	return &Environment{
+		ProjectDir: tmpDir,
+		Config:     Config{ClaudeCommand: "claude", VerifyCommand: "make test", SuccessCommand: "git commit -am fix"},
+		Tasks: map[string]Task{
+			"test-task": {
+				Name:            "test-task",
+				Dir:             tmpDir,
+				CandidateSource: `echo '["a.go", "b.go"]'`,
+				Prompt:          "Fix: $INPUT",
+				ClaudeFlags:     "--model opus",
+				Timeout:         5 * time.Minute,
+			},
+		},
+	}
+}
+
+func TestFormatPreflight(t *testing.T) {
+	env := preflightEnv(t)
+	got := FormatPreflight(env, []string{"test-task"}, RunnerOptions{Partition: NoFilter(), Limit: 10})
+
+	for _, want := range []string{
+		"test-task",
+		"2 pending",
+		"make test",
+		"git commit -am fix",
+		"claude (model: opus)",
+		"5m0s per candidate",
+		"10 iterations, unlimited time",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("preflight missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestConfirmPreflight(t *testing.T) {
+	env := preflightEnv(t)
+	observer := &recordingObserver{}
+	opts := RunnerOptions{Executor: NewMockCommandExecutor(), Observer: observer}
+
+	asked := 0
+	opts.Confirm = func(preflight string) (bool, error) {
+		asked++
+		return false, nil
+	}
+	err := RunTasks(env, []string{"test-task"}, opts)
+	if !errors.Is(err, errNotConfirmed) {
+		t.Errorf("declined run returned %v, want errNotConfirmed", err)
+	}
+	if asked != 1 || len(observer.iterations) != 0 {
+		t.Errorf("asked %d times and ran %d iterations, want 1 and 0", asked, len(observer.iterations))
+	}
+
+	opts.Confirm = func(preflight string) (bool, error) {
+		return false, errors.New("no terminal")
+	}
+	if err := RunTasks(env, []string{"test-task"}, opts); err == nil || err.Error() != "no terminal" {
+		t.Errorf("RunTasks returned %v, want the confirmation error", err)
+	}
+
+	// Dry runs commit nothing, so they don't ask
+	opts.DryRun = true
+	opts.Confirm = func(preflight string) (bool, error) {
+		t.Error("dry run asked for confirmation")
+		return false, nil
+	}
+	if err := RunTasks(env, []string{"test-task"}, opts); err != nil {
+		t.Errorf("dry run failed: %v", err)
+	}
+}
+
+func TestModelFlag(t *testing.T) {
+	tests := []struct {
+		flags string
+		want  string
+	}{
+		{"", ""},
+		{"--model opus", "opus"},
+		{"--verbose --model='claude-sonnet-4' --max-turns 5", "claude-sonnet-4"},
+		{"--model", ""},
+	}
+	for _, tt := range tests {
+		if got := modelFlag(tt.flags); got != tt.want {
+			t.Errorf("modelFlag(%q) = %q, want %q", tt.flags, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionStats tracks durations across a session for computing statistics.
+type SessionStats struct {
+	mu        sync.Mutex
+	durations []time.Duration
+}
+
+// NewSessionStats creates a new SessionStats tracker.
+func NewSessionStats() *SessionStats {
+	return &SessionStats{
+		durations: make([]time.Duration, 0),
+	}
+}
+
+// Add records a duration.
+func (s *SessionStats) Add(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.durations = append(s.durations, d)
+}
+
+// Median returns the median duration, or false if no durations recorded.
+func (s *SessionStats) Median() (time.Duration, bool) {
+	sorted := s.sorted()
+	if len(sorted) == 0 {
+		return 0, false
+	}
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2, true
+	}
+	return sorted[mid], true
+}
+
+// sorted returns a sorted copy of the recorded durations.
+func (s *SessionStats) sorted() []time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sorted := make([]time.Duration, len(s.durations))
+	copy(sorted, s.durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// DurationStats describes the spread of recorded durations. Timeouts are set
+// from the tail, so the high percentiles matter more than the median.
+type DurationStats struct {
+	Count         int
+	Min, Max      time.Duration
+	P50, P90, P99 time.Duration
+}
+
+// Distribution returns the min, max and percentiles of the recorded
+// durations, or false if none are recorded.
+func (s *SessionStats) Distribution() (DurationStats, bool) {
+	sorted := s.sorted()
+	if len(sorted) == 0 {
+		return DurationStats{}, false
+	}
+	return DurationStats{
+		Count: len(sorted),
+		Min:   sorted[0],
+		Max:   sorted[len(sorted)-1],
+		P50:   percentile(sorted, 50),
+		P90:   percentile(sorted, 90),
+		P99:   percentile(sorted, 99),
+	}, true
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// String formats the distribution on one line, e.g.
+// "12 runs · min 40s · p50 2m 10s · p90 4m 30s · p99 5m 00s · max 5m 00s".
+func (d DurationStats) String() string {
+	return fmt.Sprintf("%d runs · min %s · p50 %s · p90 %s · p99 %s · max %s", d.Count,
+		formatDuration(d.Min), formatDuration(d.P50), formatDuration(d.P90), formatDuration(d.P99), formatDuration(d.Max))
+}
+
+// histogramWidth is the length of the longest bar in Histogram.
+const histogramWidth = 40
+
+// Histogram draws the recorded durations as an ASCII histogram with up to
+// buckets equal-width rows between the shortest and longest, or "" if none
+// are recorded.
+func (s *SessionStats) Histogram(buckets int) string {
+	sorted := s.sorted()
+	if len(sorted) == 0 || buckets < 1 {
+		return ""
+	}
+	low, high := sorted[0], sorted[len(sorted)-1]
+	span := high - low
+	if span == 0 {
+		buckets = 1
+	}
+
+	counts := make([]int, buckets)
+	largest := 0
+	for _, d := range sorted {
+		i := buckets - 1
+		if span > 0 {
+			i = int(int64(d-low) * int64(buckets) / int64(span))
+			if i == buckets {
+				i--
+			}
+		}
+		counts[i]++
+		if counts[i] > largest {
+			largest = counts[i]
+		}
+	}
+
+	var b strings.Builder
+	for i, n := range counts {
+		from := low + span*time.Duration(i)/time.Duration(buckets)
+		to := low + span*time.Duration(i+1)/time.Duration(buckets)
+		bar := strings.Repeat("█", (n*histogramWidth+largest-1)/largest)
+		fmt.Fprintf(&b, "  %8s – %-8s │%s %d\n", formatDuration(from), formatDuration(to), bar, n)
+	}
+	return b.String()
+}
+
+// PhaseStats tracks durations separately for each phase of an iteration, since
+// a slow verify and a slow Claude call need different fixes.
+type PhaseStats struct {
+	CandidateSource *SessionStats
+	Claude          *SessionStats
+	Verify          *SessionStats
+	Commit          *SessionStats // success_command, including retries
+}
+
+// NewPhaseStats creates empty trackers for every phase.
+func NewPhaseStats() *PhaseStats {
+	return &PhaseStats{
+		CandidateSource: NewSessionStats(),
+		Claude:          NewSessionStats(),
+		Verify:          NewSessionStats(),
+		Commit:          NewSessionStats(),
+	}
+}
+
+// ProgressTimer displays a live updating timer on the terminal's status line
+// during long operations. Output written through the same mediator meanwhile
+// appears above it.
+type ProgressTimer struct {
+	label     string
+	stats     *SessionStats
+	hint      *SessionStats // Shows its median without recording into it
+	out       *outputMediator
+	detailMu  sync.Mutex
+	detail    string // Shown after the time, e.g. a command's latest output line
+	startTime time.Time
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+	stopOnce  sync.Once
+	duration  time.Duration
+}
+
+// NewProgressTimer creates a new timer with the given label.
+func NewProgressTimer(label string, stats *SessionStats) *ProgressTimer {
+	return &ProgressTimer{
+		label:  label,
+		stats:  stats,
+		out:    console(),
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+}
+
+// SetWriter sets the writer for timer output. Call it before Start.
+func (p *ProgressTimer) SetWriter(w io.Writer) {
+	p.out = newOutputMediator(w)
+}
+
+// Start begins the timer display. Call Stop() when the operation completes.
+// Use StreamText() to send text to be displayed above the timer.
+func (p *ProgressTimer) Start() {
+	p.startTime = time.Now()
+
+	go func() {
+		defer close(p.doneCh)
+		ticker := time.NewTicker(1 * time.Second)
+		defer ticker.Stop()
+
+		// Print initial state immediately
+		p.out.setStatus(p.line())
+
+		for {
+			select {
+			case <-p.stopCh:
+				return
+			case <-ticker.C:
+				p.out.setStatus(p.line())
+			}
+		}
+	}()
+}
+
+// StreamText sends text to be displayed above the timer line.
+// Safe to call from multiple goroutines.
+func (p *ProgressTimer) StreamText(text string) {
+	p.out.write(text)
+}
+
+// maxDetailLength caps the detail shown after a timer, so the status line
+// doesn't wrap, which would leave a copy behind on every redraw.
+const maxDetailLength = 60
+
+// SetDetail shows text after the elapsed time from the next redraw on.
+// Safe to call from multiple goroutines.
+func (p *ProgressTimer) SetDetail(text string) {
+	if runes := []rune(text); len(runes) > maxDetailLength {
+		text = string(runes[:maxDetailLength-1]) + "…"
+	}
+	p.detailMu.Lock()
+	defer p.detailMu.Unlock()
+	p.detail = text
+}
+
+// line renders the status line: the label, elapsed time and median hint,
+// then the detail if there is one.
+func (p *ProgressTimer) line() string {
+	elapsed := time.Since(p.startTime)
+
+	stats := p.stats
+	if stats == nil {
+		stats = p.hint
+	}
+
+	timerPart := fmt.Sprintf("(%s)", formatDuration(elapsed))
+	if stats != nil {
+		if median, ok := stats.Median(); ok {
+			timerPart = fmt.Sprintf("(%s · median time %s)",
+				formatDuration(elapsed),
+				formatDuration(median))
+		}
+	}
+	p.detailMu.Lock()
+	detail := p.detail
+	p.detailMu.Unlock()
+	if detail != "" {
+		timerPart += " " + detail
+	}
+	return ColorInfo(p.label) + " " + ColorDim(timerPart)
+}
+
+// Stop stops the timer, leaving its final line on screen, and records the
+// duration. Returns the elapsed duration; calling it again does nothing more.
+func (p *ProgressTimer) Stop() time.Duration {
+	p.stop(true)
+	return p.duration
+}
+
+// stop ends the ticker and takes the status line down, keeping the final line
+// on screen and recording the duration if keep is set. Only the first call
+// has any effect.
+func (p *ProgressTimer) stop(keep bool) {
+	p.stopOnce.Do(func() {
+		if p.startTime.IsZero() {
+			return
+		}
+		close(p.stopCh)
+		<-p.doneCh // Wait for goroutine to exit
+
+		p.duration = time.Since(p.startTime)
+		if !keep {
+			p.out.endStatus("")
+			return
+		}
+		if p.stats != nil {
+			p.stats.Add(p.duration)
+		}
+		p.out.endStatus(p.line())
+	})
+}
+
+// DelayedProgressTimer displays a timer only after the operation exceeds a delay.
+type DelayedProgressTimer struct {
+	label string
+	delay time.Duration
+	hint  *SessionStats
+	out   *outputMediator
+
+	mu    sync.Mutex
+	gen   int // Bumped by Start, Stop and Reset, so a delay that ran out before them shows nothing
+	timer *ProgressTimer
+}
+
+// NewDelayedProgressTimer creates a new delayed timer with the given label and delay.
+func NewDelayedProgressTimer(label string, delay time.Duration) *DelayedProgressTimer {
+	return &DelayedProgressTimer{
+		label: label,
+		delay: delay,
+		out:   console(),
+	}
+}
+
+// SetMedianHint shows the median of stats next to the elapsed time once the
+// timer appears. Durations aren't recorded into stats; the caller times the
+// whole operation, which the timer only sees part of.
+func (d *DelayedProgressTimer) SetMedianHint(stats *SessionStats) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.hint = stats
+}
+
+// SetWriter sets the writer for timer output. Call it before Start.
+func (d *DelayedProgressTimer) SetWriter(w io.Writer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.out = newOutputMediator(w)
+}
+
+// Start begins the delay timer. The timer will only be shown after the
+// delay period has passed, to avoid displaying it for operations that complete quickly.
+func (d *DelayedProgressTimer) Start() {
+	d.mu.Lock()
+	d.gen++
+	gen := d.gen
+	d.mu.Unlock()
+	time.AfterFunc(d.delay, func() { d.show(gen) })
+}
+
+// show starts the timer display, unless the timer was stopped or reset since
+// the delay began.
+func (d *DelayedProgressTimer) show(gen int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if gen != d.gen || d.timer != nil {
+		return
+	}
+	d.timer = NewProgressTimer(d.label, nil)
+	d.timer.hint = d.hint
+	d.timer.out = d.out
+	d.timer.Start()
+}
+
+// Stop stops the timer, leaving its last line on screen if it was shown.
+// Safe to call more than once.
+func (d *DelayedProgressTimer) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.gen++
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+}
+
+// Reset restarts the inactivity timer. If a timer was being displayed, it will be hidden
+// and the delay period starts over.
+func (d *DelayedProgressTimer) Reset() {
+	d.mu.Lock()
+	if d.timer != nil {
+		d.timer.stop(false)
+		d.timer = nil
+	}
+	d.mu.Unlock()
+	d.Start()
+}
@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionStatsDistribution(t *testing.T) {
+	stats := NewSessionStats()
+	if _, ok := stats.Distribution(); ok {
+		t.Error("an empty session should have no distribution")
+	}
+	for i := 1; i <= 100; i++ {
+		stats.Add(time.Duration(i) * time.Second)
+	}
+
+	dist, ok := stats.Distribution()
+	if !ok {
+		t.Fatal("expected a distribution")
+	}
+	want := DurationStats{Count: 100, Min: time.Second, Max: 100 * time.Second,
+		P50: 50 * time.Second, P90: 90 * time.Second, P99: 99 * time.Second}
+	if dist != want {
+		t.Errorf("distribution = %+v, want %+v", dist, want)
+	}
+}
+
+func TestSessionStatsHistogram(t *testing.T) {
+	stats := NewSessionStats()
+	if got := stats.Histogram(4); got != "" {
+		t.Errorf("empty histogram = %q, want none", got)
+	}
+	for _, d := range []time.Duration{10 * time.Second, 12 * time.Second, 14 * time.Second, 50 * time.Second} {
+		stats.Add(d)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(stats.Histogram(4), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 rows, got:\n%s", strings.Join(lines, "\n"))
+	}
+	if !strings.HasSuffix(lines[0], strings.Repeat("█", histogramWidth)+" 3") {
+		t.Errorf("first row = %q, want a full bar of 3", lines[0])
+	}
+	if !strings.HasSuffix(lines[3], " 1") || !strings.Contains(lines[3], "50s") {
+		t.Errorf("last row = %q, want the 50s outlier", lines[3])
+	}
+
+	single := NewSessionStats()
+	single.Add(time.Minute)
+	single.Add(time.Minute)
+	if rows := strings.Count(single.Histogram(4), "\n"); rows != 1 {
+		t.Errorf("identical durations should share one row, got %d", rows)
+	}
+}
@@ -0,0 +1,136 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Actions for a prompt over prompt_limit.max_bytes.
+const (
+	PromptLimitFail      = "fail"      // Give up on the candidate (default)
+	PromptLimitTruncate  = "truncate"  // Cap long candidate values at max_lines and re-interpolate
+	PromptLimitSummarize = "summarize" // Replace the prompt with summarize_command's output
+)
+
+// PromptLimit caps the size of the interpolated prompt sent to Claude.
+type PromptLimit struct {
+	MaxBytes         int      `yaml:"max_bytes"`
+	OnExceed         string   `yaml:"on_exceed"`         // fail (default), truncate, or summarize
+	TruncateFields   []string `yaml:"truncate_fields"`   // truncate: map keys to cap (default: every string value)
+	MaxLines         int      `yaml:"max_lines"`         // truncate: lines kept per value
+	SummarizeCommand string   `yaml:"summarize_command"` // summarize: reads the prompt on stdin, prints a shorter one
+}
+
+// validatePromptLimit checks that the options for the chosen action are set.
+func validatePromptLimit(limit PromptLimit) error {
+	if limit.MaxBytes <= 0 {
+		return fmt.Errorf("'max_bytes' must be positive")
+	}
+	switch limit.OnExceed {
+	case "", PromptLimitFail:
+	case PromptLimitTruncate:
+		if limit.MaxLines <= 0 {
+			return fmt.Errorf("truncate requires a positive 'max_lines'")
+		}
+	case PromptLimitSummarize:
+		if limit.SummarizeCommand == "" {
+			return fmt.Errorf("summarize requires a 'summarize_command'")
+		}
+	default:
+		return fmt.Errorf("unknown on_exceed %q (expected fail, truncate, or summarize)", limit.OnExceed)
+	}
+	return nil
+}
+
+// promptTooLargeError reports a prompt still over the limit after the
+// configured action was applied.
+type promptTooLargeError struct {
+	size  int
+	limit int
+}
+
+func (e *promptTooLargeError) Error() string {
+	return fmt.Sprintf("prompt is %d bytes, over prompt_limit.max_bytes (%d)", e.size, e.limit)
+}
+
+// truncateLines keeps the first maxLines lines of text, noting how many were cut.
+func truncateLines(text string, maxLines int) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) <= maxLines {
+		return text
+	}
+	return strings.Join(lines[:maxLines], "\n") + fmt.Sprintf("\n... (%d more lines truncated)", len(lines)-maxLines)
+}
+
+// truncateCandidate returns a copy of the candidate whose string values are
+// capped at maxLines lines. With fields, only those keys of a map candidate
+// are capped. The key is unchanged so the candidate keeps its identity.
+func truncateCandidate(c *Candidate, fields []string, maxLines int) (*Candidate, error) {
+	var data interface{}
+	if err := json.Unmarshal(c.Data, &data); err != nil {
+		return nil, err
+	}
+
+	selected := func(key string) bool {
+		if len(fields) == 0 {
+			return true
+		}
+		for _, f := range fields {
+			if f == key {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch v := data.(type) {
+	case string:
+		if len(fields) == 0 {
+			data = truncateLines(v, maxLines)
+		}
+	case []interface{}:
+		if len(fields) == 0 {
+			for i, item := range v {
+				if s, ok := item.(string); ok {
+					v[i] = truncateLines(s, maxLines)
+				}
+			}
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			if s, ok := item.(string); ok && selected(key) {
+				v[key] = truncateLines(s, maxLines)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(data); err != nil {
+		return nil, err
+	}
+	return &Candidate{Key: c.Key, Data: json.RawMessage(bytes.TrimSpace(buf.Bytes()))}, nil
+}
+
+// summarizePrompt runs command with the prompt on stdin and returns its output
+// as the new prompt.
+func summarizePrompt(command, prompt, workDir string) (string, error) {
+	cmd := exec.Command("bash", "-c", command)
+	cmd.Dir = workDir
+	cmd.Stdin = strings.NewReader(prompt)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("summarize_command failed: %w\nstderr: %s", err, stderr.String())
+	}
+	summary := strings.TrimSpace(string(output))
+	if summary == "" {
+		return "", fmt.Errorf("summarize_command printed nothing")
+	}
+	return summary, nil
+}
@@ -0,0 +1,121 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePromptLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   PromptLimit
+		wantErr bool
+	}{
+		{name: "fail by default", limit: PromptLimit{MaxBytes: 1000}},
+		{name: "truncate", limit: PromptLimit{MaxBytes: 1000, OnExceed: PromptLimitTruncate, MaxLines: 20}},
+		{name: "summarize", limit: PromptLimit{MaxBytes: 1000, OnExceed: PromptLimitSummarize, SummarizeCommand: "summarize"}},
+		{name: "missing max_bytes", limit: PromptLimit{}, wantErr: true},
+		{name: "truncate without max_lines", limit: PromptLimit{MaxBytes: 1000, OnExceed: PromptLimitTruncate}, wantErr: true},
+		{name: "summarize without command", limit: PromptLimit{MaxBytes: 1000, OnExceed: PromptLimitSummarize}, wantErr: true},
+		{name: "unknown action", limit: PromptLimit{MaxBytes: 1000, OnExceed: "shrink"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePromptLimit(tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validatePromptLimit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTruncateCandidate(t *testing.T) {
+	trace := "line1\nline2\nline3\nline4"
+
+	t.Run("caps selected map fields", func(t *testing.T) {
+		candidates, _ := ParseCandidates([]byte(`[{"file": "a.go", "stacktrace": "line1\nline2\nline3\nline4", "note": "x\ny\nz"}]`))
+		truncated, err := truncateCandidate(&candidates[0], []string{"stacktrace"}, 2)
+		if err != nil {
+			t.Fatalf("truncateCandidate failed: %v", err)
+		}
+		if truncated.Key != candidates[0].Key {
+			t.Error("truncation should keep the candidate key")
+		}
+		if got, _ := truncated.GetKey("stacktrace"); got != "line1\nline2\n... (2 more lines truncated)" {
+			t.Errorf("stacktrace = %q", got)
+		}
+		if got, _ := truncated.GetKey("note"); got != "x\ny\nz" {
+			t.Errorf("unselected field changed: %q", got)
+		}
+	})
+
+	t.Run("caps string candidates without fields", func(t *testing.T) {
+		candidate := Candidate{Key: trace, Data: []byte(`"line1\nline2\nline3\nline4"`)}
+		truncated, err := truncateCandidate(&candidate, nil, 3)
+		if err != nil {
+			t.Fatalf("truncateCandidate failed: %v", err)
+		}
+		if got := truncated.String(); got != "line1\nline2\nline3\n... (1 more lines truncated)" {
+			t.Errorf("String() = %q", got)
+		}
+	})
+}
+
+func TestSummarizePrompt(t *testing.T) {
+	summary, err := summarizePrompt("head -c 5", "a very long prompt", t.TempDir())
+	if err != nil || summary != "a ver" {
+		t.Errorf("summarizePrompt = %q, %v; want %q", summary, err, "a ver")
+	}
+	if _, err := summarizePrompt("true", "prompt", t.TempDir()); err == nil {
+		t.Error("expected error for empty summary")
+	}
+}
+
+func TestApplyPromptLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	newRunner := func(limit PromptLimit) *Runner {
+		env := &Environment{
+			ProjectDir: tmpDir,
+			Tasks: map[string]Task{
+				"test-task": {Name: "test-task", Dir: tmpDir, Prompt: "Fix: $INPUT", PromptLimit: &limit},
+			},
+		}
+		runner, err := NewRunner(env, "test-task", RunnerOptions{})
+		if err != nil {
+			t.Fatalf("NewRunner failed: %v", err)
+		}
+		runner.setExecutor(NewMockCommandExecutor())
+		return runner
+	}
+	long := strings.Repeat("error line\n", 20)
+	candidate := &Candidate{Key: "a", Data: []byte(`"` + strings.ReplaceAll(long, "\n", `\n`) + `"`)}
+
+	t.Run("truncates to fit", func(t *testing.T) {
+		runner := newRunner(PromptLimit{MaxBytes: 100, OnExceed: PromptLimitTruncate, MaxLines: 2})
+		prompt, _ := runner.getPrompt(candidate)
+		prompt, err := runner.applyPromptLimit(candidate, prompt)
+		if err != nil {
+			t.Fatalf("applyPromptLimit failed: %v", err)
+		}
+		if !strings.Contains(prompt, "19 more lines truncated") {
+			t.Errorf("prompt = %q", prompt)
+		}
+	})
+
+	t.Run("fails oversized candidates", func(t *testing.T) {
+		runner := newRunner(PromptLimit{MaxBytes: 100})
+		prompt, _ := runner.getPrompt(candidate)
+		_, err := runner.applyPromptLimit(candidate, prompt)
+		tooLarge, ok := err.(*promptTooLargeError)
+		if !ok {
+			t.Fatalf("err = %v, want *promptTooLargeError", err)
+		}
+		if _, err := runner.handlePromptTooLarge(candidate, tooLarge); err != nil {
+			t.Fatalf("handlePromptTooLarge failed: %v", err)
+		}
+		if runner.summary.Outcomes[OutcomePromptTooLarge] != 1 || !runner.ignoredList.Contains("a") {
+			t.Errorf("expected %s outcome and ignored candidate", OutcomePromptTooLarge)
+		}
+	})
+}
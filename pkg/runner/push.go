@@ -0,0 +1,74 @@
+package runner
+
+import "fmt"
+
+// defaultPushRebaseCommand brings a rejected commit up to date with the remote.
+const defaultPushRebaseCommand = "git pull --rebase --quiet"
+
+// maxPushRecoveries is how many times a rejected push is rebased and retried
+// before giving up, in case the remote keeps moving.
+const maxPushRecoveries = 3
+
+// pushRejectedCheck fetches and succeeds if HEAD has an upstream it doesn't
+// contain, i.e. a push would be rejected as non-fast-forward.
+const pushRejectedCheck = "git fetch -q && git rev-parse -q --verify '@{u}' >/dev/null && ! git merge-base --is-ancestor '@{u}' HEAD"
+
+// pushRejected reports whether a failed success command committed (HEAD moved
+// from before and the tree is clean) but couldn't push because the remote
+// branch moved on. Only checked with push_command set.
+func (r *Runner) pushRejected(before string) bool {
+	if r.env.Config.PushCommand == "" {
+		return false
+	}
+	head, err := r.executor.CurrentRevision(r.workDir())
+	if err != nil || head == before {
+		return false
+	}
+	if dirty, err := r.executor.HasUncommittedChanges(r.workDir()); err != nil || dirty {
+		return false
+	}
+	ok, err := r.executor.RunSilent(pushRejectedCheck, r.workDir())
+	return err == nil && ok
+}
+
+// recoverPush rebases a commit whose push was rejected onto the remote, checks
+// it still passes verify there and pushes it again with push_command. It
+// returns false, leaving the commit unpushed, if the rebase or verify fails or
+// the push is still rejected after maxPushRecoveries tries.
+func (r *Runner) recoverPush() (bool, error) {
+	rebase := r.env.Config.PushRebaseCommand
+	if rebase == "" {
+		rebase = defaultPushRebaseCommand
+	}
+	push := r.env.Config.GitEnvPrefix() + r.env.Config.PushCommand
+	for attempt := 1; attempt <= maxPushRecoveries; attempt++ {
+		fmt.Println(ColorWarning(fmt.Sprintf("Push rejected, the remote has moved on; rebasing and retrying (%d/%d)...", attempt, maxPushRecoveries)))
+		ok, err := r.runSilentSideEffect("push-rebase", rebase)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			fmt.Println(ColorError("Rebasing onto the remote failed; resolve it and push by hand"))
+			return false, nil
+		}
+		if !r.runVerify() {
+			fmt.Println(ColorError("The fix fails verify on top of the remote; the rebased commit is left unpushed"))
+			return false, nil
+		}
+		ok, err = r.executor.RunWithTimeout(push, r.workDir(), r.env.Config.SuccessTimeout)
+		if err != nil {
+			if _, isTimeout := err.(*timeoutError); !isTimeout {
+				return false, err
+			}
+		}
+		if ok {
+			fmt.Println(ColorSuccess("✓ Pushed after rebasing"))
+			return true, nil
+		}
+		// Failed for some other reason than the remote moving again
+		if rejected, err := r.executor.RunSilent(pushRejectedCheck, r.workDir()); err != nil || !rejected {
+			return false, nil
+		}
+	}
+	return false, nil
+}
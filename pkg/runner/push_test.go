@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"testing"
+	"time"
+)
+
+// committingExecutor moves HEAD when the success command runs, as if it
+// committed before its push failed.
+type committingExecutor struct {
+	*MockCommandExecutor
+}
+
+func (c committingExecutor) RunWithTimeout(command, workDir string, timeout time.Duration) (bool, error) {
+	if command == "git commit -qam fix && git push" {
+		c.Revision = "def"
+	}
+	return c.MockCommandExecutor.RunWithTimeout(command, workDir, timeout)
+}
+
+func TestRecoverPush(t *testing.T) {
+	successRetryDelay = 0
+	defer func() { successRetryDelay = 5 * time.Second }()
+
+	newRunner := func(t *testing.T) (*Runner, *MockCommandExecutor) {
+		tmpDir := t.TempDir()
+		env := &Environment{
+			ProjectDir: tmpDir,
+			Config: Config{
+				SuccessCommand: "git commit -qam fix && git push",
+				SuccessRetries: 2,
+				VerifyCommand:  "make",
+				PushCommand:    "git push",
+			},
+			Tasks: map[string]Task{
+				"test-task": {Name: "test-task", Dir: tmpDir, Prompt: "test prompt"},
+			},
+		}
+		runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
+		if err != nil {
+			t.Fatalf("NewRunner failed: %v", err)
+		}
+		mock := NewMockCommandExecutor()
+		mock.Revision = "abc"
+		mock.SetResult("git commit -qam fix && git push", false, nil)
+		mock.SetResult(pushRejectedCheck, true, nil)
+		runner.setExecutor(committingExecutor{mock})
+		return runner, mock
+	}
+
+	t.Run("rebases, verifies and pushes again", func(t *testing.T) {
+		runner, mock := newRunner(t)
+		ok, err := runner.runSuccessCommand("git commit -qam fix && git push")
+		if err != nil || !ok {
+			t.Fatalf("runSuccessCommand = (%v, %v), want (true, nil)", ok, err)
+		}
+		if got := mock.CallCount("git commit -qam fix && git push"); got != 1 {
+			t.Errorf("success command ran %d times, want 1", got)
+		}
+		for _, cmd := range []string{defaultPushRebaseCommand, "make", "git push"} {
+			if !mock.CalledWith(cmd) {
+				t.Errorf("expected %q to run", cmd)
+			}
+		}
+	})
+
+	t.Run("leaves the commit unpushed if verify fails", func(t *testing.T) {
+		runner, mock := newRunner(t)
+		mock.SetResult("make", false, nil)
+		ok, err := runner.runSuccessCommand("git commit -qam fix && git push")
+		if err != nil || ok {
+			t.Fatalf("runSuccessCommand = (%v, %v), want (false, nil)", ok, err)
+		}
+		if mock.CalledWith("git push") {
+			t.Error("pushed a rebased commit that fails verify")
+		}
+	})
+
+	t.Run("retries the whole command when nothing was committed", func(t *testing.T) {
+		runner, mock := newRunner(t)
+		runner.setExecutor(mock)
+		if ok, _ := runner.runSuccessCommand("git commit -qam fix && git push"); ok {
+			t.Fatal("expected the success command to fail")
+		}
+		if got := mock.CallCount("git commit -qam fix && git push"); got != 3 {
+			t.Errorf("success command ran %d times, want 3", got)
+		}
+		if mock.CalledWith(defaultPushRebaseCommand) {
+			t.Error("rebased without a rejected push")
+		}
+	})
+}
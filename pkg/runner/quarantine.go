@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// quarantineScript commits the working tree, untracked files included, on top
+// of HEAD with message and points branch at the commit, without touching HEAD,
+// the index or the working tree. The previous tip of branch is kept as a
+// second parent, so earlier quarantined fixes stay reachable from it.
+func quarantineScript(branch, message string) string {
+	ref := shellQuote("refs/heads/" + branch)
+	return `export GIT_INDEX_FILE="$(git rev-parse --absolute-git-dir)/nigel-quarantine-index" && ` +
+		`git read-tree HEAD && git add -A && tree=$(git write-tree) && rm -f "$GIT_INDEX_FILE" && ` +
+		`prev=$(git rev-parse -q --verify ` + ref + `); ` +
+		`commit=$(git commit-tree "$tree" -p HEAD ${prev:+-p "$prev"} -m ` + shellQuote(message) + `) && ` +
+		`git update-ref ` + ref + ` "$commit"`
+}
+
+// quarantineMessage describes a quarantined fix: what it was for and why it
+// was reverted, with the same Nigel-* trailers as the commits nigel makes.
+func (r *Runner) quarantineMessage(candidate *Candidate, outcome Outcome) string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "nigel: quarantined %s fix for %s\n\n", r.task.Name, strings.Join(strings.Fields(displayKey(candidate.Key)), " "))
+	fmt.Fprintf(&message, "Claude's changes for this candidate failed verify, so they were reverted.\nCheck whether the failure was related before rescuing them.\n")
+	if r.verifyError != "" {
+		fmt.Fprintf(&message, "\nVerify output:\n%s\n", r.verifyError)
+	}
+	message.WriteString("\nNigel-Task: " + r.task.Name)
+	message.WriteString("\nNigel-Candidate: " + strings.Join(strings.Fields(candidate.Key), " "))
+	if r.sessionID != "" {
+		message.WriteString("\nNigel-Session: " + r.sessionID)
+	}
+	message.WriteString("\nNigel-Outcome: " + string(outcome))
+	return message.String()
+}
+
+// quarantine keeps the changes of an attempt that failed verify, before
+// handleFailure reverts them, as a commit on quarantine_branch, so they can be
+// rescued if the failure turns out to be flaky or unrelated. A failure only
+// warns: the revert goes ahead.
+func (r *Runner) quarantine(candidate *Candidate, outcome Outcome) {
+	branch := r.env.Config.QuarantineBranch
+	if branch == "" {
+		return
+	}
+	cmd := r.env.Config.GitEnvPrefix() + quarantineScript(branch, r.quarantineMessage(candidate, outcome))
+	if ok, err := r.runSilentSideEffect("quarantine", cmd); err != nil || !ok {
+		fmt.Println(ColorWarning("Failed to quarantine the fix on " + branch))
+		return
+	}
+	fmt.Println(ColorInfo("Quarantined the fix on " + branch))
+}
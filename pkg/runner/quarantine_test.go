@@ -0,0 +1,188 @@
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQuarantineScript(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	run := func(name string, args ...string) {
+		cmd := exec.Command(name, args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s %v failed: %v\n%s", name, args, err, output)
+		}
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("git", "init", "-q")
+	write("a.txt", "one")
+	run("git", "add", "a.txt")
+	run("git", "commit", "-q", "-m", "initial")
+	head, _ := gitOutput(repo, "rev-parse", "HEAD")
+
+	write("a.txt", "fixed")
+	write("new.txt", "added")
+	run("bash", "-c", quarantineScript("nigel/quarantine", "first\n\nit's quoted"))
+	first, err := gitOutput(repo, "rev-parse", "nigel/quarantine")
+	if err != nil {
+		t.Fatalf("quarantine branch missing: %v", err)
+	}
+	if parents, _ := gitOutput(repo, "rev-parse", first+"^@"); parents != head {
+		t.Errorf("first quarantined fix has parents %q, want HEAD", parents)
+	}
+	if diff, _ := gitOutput(repo, "diff", "--name-only", head, first); diff != "a.txt\nnew.txt" {
+		t.Errorf("quarantined fix changes %q", diff)
+	}
+	if message, _ := gitOutput(repo, "log", "-1", "--format=%B", first); message != "first\n\nit's quoted" {
+		t.Errorf("message = %q", message)
+	}
+
+	// HEAD, the index and the working tree are left as they were
+	if now, _ := gitOutput(repo, "rev-parse", "HEAD"); now != head {
+		t.Error("HEAD moved")
+	}
+	if status, _ := gitOutput(repo, "status", "--porcelain"); status != "M a.txt\n?? new.txt" {
+		t.Errorf("status = %q", status)
+	}
+
+	run("bash", "-c", quarantineScript("nigel/quarantine", "second"))
+	parents, _ := gitOutput(repo, "rev-parse", "nigel/quarantine^@")
+	if strings.Fields(parents)[0] != head || !strings.Contains(parents, first) {
+		t.Errorf("second quarantined fix has parents %q, want HEAD then the first", parents)
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	tmpDir := t.TempDir()
+	env := &Environment{
+		ProjectDir: tmpDir,
+		Config:     Config{VerifyCommand: "make test", QuarantineBranch: "nigel/quarantine"},
+		Tasks: map[string]Task{
+			"test-task": {Name: "test-task", Dir: tmpDir, Prompt: "test prompt"},
+		},
+	}
+	runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("NewRunner failed: %v", err)
+	}
+	mock := NewMockCommandExecutor()
+	runner.setExecutor(mock)
+	runner.verifyError = "FAIL TestFoo"
+
+	message := runner.quarantineMessage(&Candidate{Key: "foo.go:12"}, OutcomeFixedReverted)
+	for _, want := range []string{"fix for foo.go:12", "FAIL TestFoo", "Nigel-Candidate: foo.go:12", "Nigel-Outcome: FIXED_BUT_REVERTED"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("message missing %q:\n%s", want, message)
+		}
+	}
+
+	runner.quarantine(&Candidate{Key: "foo.go:12"}, OutcomeFixedReverted)
+	if len(mock.Calls) != 1 || !strings.Contains(mock.Calls[0].Command, "refs/heads/nigel/quarantine") {
+		t.Errorf("expected one quarantine command, got %v", mock.Calls)
+	}
+
+	runner.env.Config.QuarantineBranch = ""
+	runner.quarantine(&Candidate{Key: "foo.go:12"}, OutcomeFixedReverted)
+	if len(mock.Calls) != 1 {
+		t.Errorf("quarantine ran without quarantine_branch: %v", mock.Calls)
+	}
+}
+
+func TestQuarantineOnVerifyFailure(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		bestEffort  bool
+		failures    int // Verify runs that fail before the one after the reset
+		wantOutcome Outcome
+	}{
+		{"standard", false, 1, OutcomeBuildFailed},
+		{"best effort", true, 2, OutcomeBuildFailed}, // handleFailure verifies again
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{VerifyCommand: "make test", ResetCommand: "git reset --hard", QuarantineBranch: "nigel/quarantine"}
+			runner, mock := newIterationRunner(t, config, Task{AcceptBestEffort: tt.bestEffort}, RunnerOptions{}, `["a"]`)
+			mock.HasChangesResult = true
+			verifies := make([]CommandResult, tt.failures)
+			mock.SetResultSequence("make test", append(verifies, CommandResult{Success: true})...)
+
+			if _, err := runner.runIteration(); err != nil {
+				t.Fatalf("runIteration failed: %v", err)
+			}
+			quarantined, reset := -1, -1
+			for i, call := range mock.Calls {
+				if strings.Contains(call.Command, "refs/heads/nigel/quarantine") {
+					quarantined = i
+					if !strings.Contains(call.Command, "Nigel-Outcome: "+string(tt.wantOutcome)) {
+						t.Errorf("quarantined with %q, want outcome %s", call.Command, tt.wantOutcome)
+					}
+				}
+				if call.Command == "git reset --hard" {
+					reset = i
+				}
+			}
+			if quarantined < 0 || reset < quarantined {
+				t.Errorf("expected the changes to be quarantined before the reset, got %v", mock.Calls)
+			}
+			if runner.summary.Outcomes[tt.wantOutcome] != 1 {
+				t.Errorf("Outcomes = %v, want one %s", runner.summary.Outcomes, tt.wantOutcome)
+			}
+		})
+	}
+
+	t.Run("not when verify passed", func(t *testing.T) {
+		config := Config{VerifyCommand: "make test", ResetCommand: "git reset --hard", QuarantineBranch: "nigel/quarantine"}
+		runner, mock := newIterationRunner(t, config, Task{}, RunnerOptions{}, `["a"]`)
+		mock.HasChangesResult = true
+
+		if _, err := runner.runIteration(); err != nil {
+			t.Fatalf("runIteration failed: %v", err)
+		}
+		for _, call := range mock.Calls {
+			if strings.Contains(call.Command, "nigel/quarantine") {
+				t.Errorf("quarantined an attempt that passed verify: %s", call.Command)
+			}
+		}
+		if runner.summary.Outcomes[OutcomeNotFixed] != 1 {
+			t.Errorf("Outcomes = %v, want one %s", runner.summary.Outcomes, OutcomeNotFixed)
+		}
+	})
+	t.Run("best effort after a timeout", func(t *testing.T) {
+		config := Config{VerifyCommand: "make test", ResetCommand: "git reset --hard", QuarantineBranch: "nigel/quarantine"}
+		runner, mock := newIterationRunner(t, config, Task{AcceptBestEffort: true}, RunnerOptions{})
+		mock.HasChangesResult = true
+		mock.SetResultSequence("make test", CommandResult{Success: false}, CommandResult{Success: true})
+
+		if _, err := runner.handleTimeout(&Candidate{Key: "a"}); err != nil {
+			t.Fatalf("handleTimeout failed: %v", err)
+		}
+		quarantined, reset := -1, -1
+		for i, call := range mock.Calls {
+			if strings.Contains(call.Command, "refs/heads/nigel/quarantine") {
+				quarantined = i
+				if !strings.Contains(call.Command, "Nigel-Outcome: "+string(OutcomeBuildFailed)) {
+					t.Errorf("quarantined with %q, want outcome %s", call.Command, OutcomeBuildFailed)
+				}
+			}
+			if call.Command == "git reset --hard" {
+				reset = i
+			}
+		}
+		if quarantined < 0 || reset < quarantined {
+			t.Errorf("expected the changes to be quarantined before the reset, got %v", mock.Calls)
+		}
+	})
+}
@@ -0,0 +1,375 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Serve exposes the runner over newline-delimited JSON-RPC 2.0 on a Unix
+// socket, so editors and other tools can start tasks, pause, resume or stop
+// them, and receive events instead of scraping terminal output. One run is
+// active at a time. If httpAddr is set, the web dashboard is served there too.
+// Blocks until a listener fails.
+//
+// Methods: start {tasks, limit, time_limit, dry_run}, pause, resume, stop,
+// status, and subscribe, after which the connection receives "event"
+// notifications for every RunObserver event.
+func Serve(env *Environment, socketPath, httpAddr string, opts RunnerOptions) error {
+	// A socket left behind by a previous server would make Listen fail
+	if info, err := os.Stat(socketPath); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(socketPath)
+	}
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+	defer os.Remove(socketPath)
+
+	server := newRPCServer(env, opts)
+	errs := make(chan error, 2)
+	if httpAddr != "" {
+		httpListener, err := net.Listen("tcp", httpAddr)
+		if err != nil {
+			listener.Close()
+			return fmt.Errorf("failed to listen on %s: %w", httpAddr, err)
+		}
+		fmt.Println(ColorInfo(fmt.Sprintf("Dashboard at http://%s", httpListener.Addr())))
+		go func() { errs <- http.Serve(httpListener, newDashboard(env, server)) }()
+	}
+
+	fmt.Println(ColorInfo(fmt.Sprintf("Listening on %s", socketPath)))
+	go func() { errs <- server.serve(listener) }()
+	return <-errs
+}
+
+// rpcRequest is a JSON-RPC 2.0 request; requests without an ID get no response.
+type rpcRequest struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000 // The request was understood but can't be done now
+)
+
+type rpcNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// rpcEvent is the params of an "event" notification.
+type rpcEvent struct {
+	Type       string         `json:"type"` // iteration_start, candidate_selected, agent_chunk, tool_use, outcome, run_end
+	Task       string         `json:"task,omitempty"`
+	Iteration  int            `json:"iteration,omitempty"`
+	Candidates string         `json:"candidates,omitempty"` // Candidate trend, as shown in the banner
+	Candidate  string         `json:"candidate,omitempty"`
+	Pending    int            `json:"pending,omitempty"` // Candidates left, with candidate_selected
+	Ignored    int            `json:"ignored,omitempty"`
+	Text       string         `json:"text,omitempty"`
+	Stderr     bool           `json:"stderr,omitempty"`
+	Tool       *ToolUse       `json:"tool,omitempty"`
+	Attempt    *exportRow     `json:"attempt,omitempty"`
+	Summaries  []rpcTaskTotal `json:"summaries,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// rpcTaskTotal is a task's summary in a run_end event.
+type rpcTaskTotal struct {
+	Task       string  `json:"task"`
+	Iterations int     `json:"iterations"`
+	Fixed      int     `json:"fixed"`
+	BestEffort int     `json:"best_effort"`
+	Failed     int     `json:"failed"`
+	CostUSD    float64 `json:"cost_usd"`
+}
+
+type startParams struct {
+	Tasks     []string `json:"tasks"`
+	Limit     int      `json:"limit"`
+	TimeLimit string   `json:"time_limit"` // Go duration, e.g. "1h30m"
+	DryRun    bool     `json:"dry_run"`
+}
+
+// rpcStatus is the result of the status method.
+type rpcStatus struct {
+	Running bool     `json:"running"`
+	Paused  bool     `json:"paused"`
+	Tasks   []string `json:"tasks,omitempty"`
+}
+
+// eventSink receives the events of the active run.
+type eventSink interface {
+	sendEvent(event rpcEvent)
+}
+
+// rpcConn is a client connection; sends are serialized. Events are buffered
+// and written by writeEvents, and dropped rather than blocking the run when
+// the client falls behind, like the dashboard's sseSink.
+type rpcConn struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	events chan rpcEvent
+}
+
+func (c *rpcConn) send(v interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enc.Encode(v)
+}
+
+func (c *rpcConn) sendEvent(event rpcEvent) {
+	select {
+	case c.events <- event:
+	default:
+	}
+}
+
+// writeEvents sends buffered events as notifications until done is closed.
+func (c *rpcConn) writeEvents(done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case event := <-c.events:
+			c.send(rpcNotification{JSONRPC: "2.0", Method: "event", Params: event})
+		}
+	}
+}
+
+// rpcServer tracks the active run and the subscribed connections. It is the
+// run's RunObserver, forwarding events to subscribers.
+type rpcServer struct {
+	env  *Environment
+	opts RunnerOptions // Base options for started runs
+
+	mu          sync.Mutex
+	control     *RunControl // Control of the active run, nil when idle
+	tasks       []string
+	subscribers map[eventSink]bool
+}
+
+func newRPCServer(env *Environment, opts RunnerOptions) *rpcServer {
+	return &rpcServer{env: env, opts: opts, subscribers: make(map[eventSink]bool)}
+}
+
+func (s *rpcServer) serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *rpcServer) handle(conn net.Conn) {
+	defer conn.Close()
+	c := &rpcConn{enc: json.NewEncoder(conn), events: make(chan rpcEvent, 256)}
+	defer s.unsubscribe(c)
+	done := make(chan struct{})
+	defer close(done)
+	go c.writeEvents(done)
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var req rpcRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			c.send(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+			continue
+		}
+		result, rpcErr := s.call(c, req)
+		if req.ID == nil {
+			continue
+		}
+		c.send(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr})
+	}
+}
+
+func (s *rpcServer) call(c *rpcConn, req rpcRequest) (interface{}, *rpcError) {
+	switch req.Method {
+	case "start":
+		var params startParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		if err := s.start(params); err != nil {
+			return nil, err
+		}
+		return s.status(), nil
+	case "pause", "resume", "stop":
+		s.mu.Lock()
+		control := s.control
+		s.mu.Unlock()
+		if control == nil {
+			return nil, &rpcError{Code: rpcServerError, Message: "no run in progress"}
+		}
+		switch req.Method {
+		case "pause":
+			control.Pause()
+		case "resume":
+			control.Resume()
+		case "stop":
+			control.Stop()
+		}
+		return s.status(), nil
+	case "status":
+		return s.status(), nil
+	case "subscribe":
+		s.subscribe(c)
+		return true, nil
+	default:
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: "unknown method: " + req.Method}
+	}
+}
+
+// start begins a run in the background, with the same task/playlist handling as the CLI.
+func (s *rpcServer) start(params startParams) *rpcError {
+	if len(params.Tasks) == 0 {
+		return &rpcError{Code: rpcInvalidParams, Message: "tasks required"}
+	}
+	opts := s.opts
+	opts.Limit = params.Limit
+	opts.DryRun = params.DryRun
+	if params.TimeLimit != "" {
+		d, err := time.ParseDuration(params.TimeLimit)
+		if err != nil {
+			return &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("invalid time_limit: %v", err)}
+		}
+		opts.TimeLimit = d
+	}
+
+	run := func() error { return RunTasks(s.env, params.Tasks, opts) }
+	if playlist, ok := s.env.Playlists[params.Tasks[0]]; ok && len(params.Tasks) == 1 {
+		run = func() error { return RunPlaylist(s.env, playlist, opts) }
+	} else {
+		for _, name := range params.Tasks {
+			if _, ok := s.env.Tasks[name]; !ok {
+				return &rpcError{Code: rpcInvalidParams, Message: "task not found: " + name}
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.control != nil {
+		return &rpcError{Code: rpcServerError, Message: "a run is already in progress"}
+	}
+	s.control = NewRunControl()
+	s.tasks = params.Tasks
+	opts.Control = s.control
+	opts.Observer = s
+	if s.opts.Observer != nil {
+		opts.Observer = observerList{s, s.opts.Observer}
+	}
+
+	go func() {
+		err := run()
+		if err != nil {
+			fmt.Println(ColorError(fmt.Sprintf("Error: %s", DescribeError(err))))
+		}
+		s.mu.Lock()
+		s.control, s.tasks = nil, nil
+		s.mu.Unlock()
+	}()
+	return nil
+}
+
+func (s *rpcServer) status() rpcStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.control == nil {
+		return rpcStatus{}
+	}
+	return rpcStatus{Running: true, Paused: s.control.Paused(), Tasks: s.tasks}
+}
+
+func (s *rpcServer) subscribe(sink eventSink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers[sink] = true
+}
+
+func (s *rpcServer) unsubscribe(sink eventSink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscribers, sink)
+}
+
+// broadcast sends an event to every subscriber.
+func (s *rpcServer) broadcast(event rpcEvent) {
+	s.mu.Lock()
+	subscribers := make([]eventSink, 0, len(s.subscribers))
+	for sink := range s.subscribers {
+		subscribers = append(subscribers, sink)
+	}
+	s.mu.Unlock()
+
+	for _, sink := range subscribers {
+		sink.sendEvent(event)
+	}
+}
+
+func (s *rpcServer) OnIterationStart(summary RunSummary) {
+	s.broadcast(rpcEvent{Type: "iteration_start", Task: summary.Task, Iteration: summary.Iterations, Candidates: summary.Trend.String()})
+}
+
+func (s *rpcServer) OnCandidateSelected(summary RunSummary, candidate *Candidate) {
+	s.broadcast(rpcEvent{Type: "candidate_selected", Task: summary.Task, Candidate: candidate.Key, Pending: summary.Pending, Ignored: summary.Ignored})
+}
+
+func (s *rpcServer) OnAgentChunk(task, text string, stderr bool) {
+	s.broadcast(rpcEvent{Type: "agent_chunk", Task: task, Text: text, Stderr: stderr})
+}
+
+func (s *rpcServer) OnToolUse(task string, tool ToolUse) {
+	s.broadcast(rpcEvent{Type: "tool_use", Task: task, Tool: &tool})
+}
+
+func (s *rpcServer) OnOutcome(task string, attempt AttemptRecord) {
+	row := newExportRow(attempt)
+	s.broadcast(rpcEvent{Type: "outcome", Task: task, Attempt: &row})
+}
+
+func (s *rpcServer) OnRunEnd(summaries []RunSummary, err error) {
+	event := rpcEvent{Type: "run_end"}
+	for _, sum := range summaries {
+		event.Summaries = append(event.Summaries, rpcTaskTotal{
+			Task:       sum.Task,
+			Iterations: sum.Iterations,
+			Fixed:      sum.Fixed(),
+			BestEffort: sum.BestEffort(),
+			Failed:     sum.Failed(),
+			CostUSD:    sum.CostUSD,
+		})
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	s.broadcast(event)
+}
@@ -0,0 +1,192 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// rpcClient is a test client for the control socket.
+type rpcClient struct {
+	t       *testing.T
+	conn    net.Conn
+	scanner *bufio.Scanner
+	nextID  int
+}
+
+// message is a response or notification read from the server.
+type message struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+	Params rpcEvent        `json:"params"`
+}
+
+func (c *rpcClient) read() message {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if !c.scanner.Scan() {
+		c.t.Fatalf("read failed: %v", c.scanner.Err())
+	}
+	var m message
+	if err := json.Unmarshal(c.scanner.Bytes(), &m); err != nil {
+		c.t.Fatalf("invalid message %q: %v", c.scanner.Text(), err)
+	}
+	return m
+}
+
+// call sends a request and returns its response, collecting events that arrive first.
+func (c *rpcClient) call(method string, params interface{}, events *[]rpcEvent) message {
+	c.t.Helper()
+	c.nextID++
+	req := map[string]interface{}{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params}
+	if err := json.NewEncoder(c.conn).Encode(req); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		m := c.read()
+		if m.Method == "event" {
+			*events = append(*events, m.Params)
+			continue
+		}
+		if m.ID == nil || *m.ID != c.nextID {
+			c.t.Fatalf("response id = %v, want %d", m.ID, c.nextID)
+		}
+		return m
+	}
+}
+
+func startTestServer(t *testing.T, env *Environment) *rpcClient {
+	// Unix socket paths are limited to ~100 bytes, too short for t.TempDir() on some systems
+	dir, err := os.MkdirTemp("", "nigel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go newRPCServer(env, RunnerOptions{Executor: NewMockCommandExecutor()}).serve(listener)
+
+	conn, err := net.Dial("unix", filepath.Join(dir, "s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &rpcClient{t: t, conn: conn, scanner: bufio.NewScanner(conn)}
+}
+
+func TestRPCServerRunsTaskAndStreamsEvents(t *testing.T) {
+	tmpDir := t.TempDir()
+	env := &Environment{
+		ProjectDir: tmpDir,
+		Tasks: map[string]Task{
+			"test-task": {
+				Name:            "test-task",
+				Dir:             tmpDir,
+				CandidateSource: `echo '["a.go", "b.go"]'`,
+				Prompt:          "Fix: $INPUT",
+			},
+		},
+	}
+	client := startTestServer(t, env)
+	var events []rpcEvent
+
+	if m := client.call("subscribe", nil, &events); m.Error != nil {
+		t.Fatalf("subscribe failed: %v", m.Error.Message)
+	}
+	if m := client.call("start", startParams{Tasks: []string{"test-task"}, DryRun: true}, &events); m.Error != nil {
+		t.Fatalf("start failed: %v", m.Error.Message)
+	}
+
+	for len(events) == 0 || events[len(events)-1].Type != "run_end" {
+		m := client.read()
+		if m.Method == "event" {
+			events = append(events, m.Params)
+		}
+	}
+
+	var types []string
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	want := []string{"iteration_start", "candidate_selected", "run_end"}
+	if len(types) != len(want) {
+		t.Fatalf("events = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("events = %v, want %v", types, want)
+		}
+	}
+	if events[1].Candidate != "a.go" {
+		t.Errorf("candidate = %q, want a.go", events[1].Candidate)
+	}
+	if len(events[2].Summaries) != 1 || events[2].Summaries[0].Task != "test-task" {
+		t.Errorf("run_end summaries = %+v, want test-task", events[2].Summaries)
+	}
+}
+
+func TestRPCConnDropsEventsWhenBehind(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	c := &rpcConn{enc: json.NewEncoder(server), events: make(chan rpcEvent, 1)}
+
+	// Nothing reads the pipe, so only the buffer takes events; the rest are
+	// dropped instead of blocking the run
+	sent := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			c.sendEvent(rpcEvent{Type: "agent_chunk", Iteration: i})
+		}
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sendEvent blocked on a client that isn't reading")
+	}
+	if event := <-c.events; event.Iteration != 0 {
+		t.Errorf("buffered event %d, want the first", event.Iteration)
+	}
+}
+
+func TestRPCServerErrors(t *testing.T) {
+	env := &Environment{Tasks: map[string]Task{}}
+	client := startTestServer(t, env)
+	var events []rpcEvent
+
+	tests := []struct {
+		method string
+		params interface{}
+		code   int
+	}{
+		{"bogus", nil, rpcMethodNotFound},
+		{"start", startParams{}, rpcInvalidParams},
+		{"start", startParams{Tasks: []string{"missing"}}, rpcInvalidParams},
+		{"start", startParams{Tasks: []string{"missing"}, TimeLimit: "soon"}, rpcInvalidParams},
+		{"pause", nil, rpcServerError},
+		{"stop", nil, rpcServerError},
+	}
+	for _, tt := range tests {
+		m := client.call(tt.method, tt.params, &events)
+		if m.Error == nil || m.Error.Code != tt.code {
+			t.Errorf("%s(%v) error = %+v, want code %d", tt.method, tt.params, m.Error, tt.code)
+		}
+	}
+
+	m := client.call("status", nil, &events)
+	var status rpcStatus
+	if err := json.Unmarshal(m.Result, &status); err != nil || status.Running {
+		t.Errorf("status = %s (%v), want not running", m.Result, err)
+	}
+}
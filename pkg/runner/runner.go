@@ -0,0 +1,2370 @@
+// Package runner implements nigel's loop: run a task's candidate source, hand
+// one candidate at a time to Claude, verify the result, then commit or reset.
+// The nigel CLI is a thin layer over RunTasks and RunPlaylist; other programs
+// can embed the loop with their own CommandExecutor and RunObserver.
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	baseBackoff      = 5 * time.Minute
+	maxBackoff       = 1 * time.Hour
+	rateLimitBackoff = 1 * time.Hour
+	rateLimitPhrase  = "You've hit your limit"
+)
+
+// failoverThreshold is how many consecutive Claude errors trigger a failover
+// to the next claude_command_fallbacks entry.
+const failoverThreshold = 3
+
+// successRetryDelay is the pause between success command retries.
+var successRetryDelay = 5 * time.Second
+
+// SyncWriter provides synchronized, buffered writing to prevent concurrent
+// writes from corrupting ANSI codes and output.
+type SyncWriter struct {
+	mu     sync.Mutex
+	writer *bufio.Writer
+	color  string // Current active color code
+}
+
+// NewSyncWriter creates a new synchronized writer.
+func NewSyncWriter(w io.Writer) *SyncWriter {
+	return &SyncWriter{
+		writer: bufio.NewWriter(w),
+	}
+}
+
+// WriteString writes text with mutex protection and immediate flush.
+func (s *SyncWriter) WriteString(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writer.WriteString(text)
+	s.writer.Flush()
+}
+
+// SetColor sets the terminal color with mutex protection.
+func (s *SyncWriter) SetColor(color string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writer.WriteString(color)
+	s.color = color
+	s.writer.Flush()
+}
+
+// WriteColored writes text in the given color, then restores the current color.
+func (s *SyncWriter) WriteColored(color, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writer.WriteString(colorReset + color + text + colorReset + s.color)
+	s.writer.Flush()
+}
+
+// ResetColor resets the terminal color with mutex protection.
+func (s *SyncWriter) ResetColor() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writer.WriteString(colorReset)
+	s.color = ""
+	s.writer.Flush()
+}
+
+// calculateBackoff returns the backoff duration for the given level
+func calculateBackoff(level int) time.Duration {
+	backoff := baseBackoff
+	for i := 0; i < level; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return backoff
+}
+
+type RunnerOptions struct {
+	Limit          int
+	TimeLimit      time.Duration
+	DryRun         bool
+	Verbosity      Verbosity // Diagnostic output level (-v, -vv, -vvv)
+	Partition      HashPartition
+	Timeout        time.Duration   // Per-candidate timeout (overrides task.yaml)
+	ClaudeCommand  string          // Claude command (overrides task.yaml)
+	NotifyDesktop  bool            // Show desktop notifications on completion, fatal errors and rate limits
+	Executor       CommandExecutor // Runs shell and git commands (nil = RealCommandExecutor)
+	Observer       RunObserver     // Receives iteration, output and outcome events (optional)
+	Control        *RunControl     // Pauses or stops the run from another goroutine (optional)
+	Confirm        ConfirmFunc     // Approves the preflight summary before the first iteration (nil = no preflight)
+	MinInterval    time.Duration   // Minimum time between Claude invocations (0 = no delay)
+	MinBattery     int             // Pause while on battery below this percentage (0 = never)
+	PauseOnMetered bool            // Pause while on a metered connection
+	Stream         StreamMode      // How much of Claude's output to show ("" = StreamFull)
+	TerminalTitle  bool            // Show the task, iteration and candidate in the terminal title
+	Bell           bool            // Ring the terminal bell when the run finishes or hits a fatal error
+	Prune          bool            // Drop ignored keys no longer produced by the candidate source
+	NoCommit       bool            // Run Claude and verify, but print the success and reset commands instead of running them
+	Evaluate       int             // Attempt this many candidates, recording what would have been fixed, then reset instead of committing (0 = off)
+	Sample         int             // Only work on this many randomly chosen candidates (0 = all)
+	SamplePercent  float64         // Only work on this percentage of the candidates, chosen at random (0 = all)
+	MaxCommits     int             // Stop once success_command has created this many commits (0 = no limit)
+	MaxCost        float64         // Stop once Claude has reported this much cost in USD (0 = no limit)
+	Repeat         int             // Attempts per candidate (overrides task.yaml's repeat when > 0)
+}
+
+type Runner struct {
+	env           *Environment
+	task          Task
+	opts          RunnerOptions
+	ignoredList   *IgnoredList
+	claudeLogger  *ClaudeLogger
+	phases        *PhaseStats
+	stopRequested *atomic.Bool
+	backoffLevel  int
+	executor      CommandExecutor
+
+	escalations   map[string]escalation // Escalated budgets for timed-out candidates
+	variants      *variantAssigner      // Assigns prompt variants to candidates (nil without variants)
+	transient     *transientMatcher     // Recognizes Claude failures worth retrying as-is
+	pacer         *claudePacer          // Spaces Claude invocations by --min-interval
+	power         *powerGate            // Pauses on low battery or metered connections (nil if disabled)
+	pruned        bool                  // The ignore list has been pruned this run (--prune)
+	sourceChecked bool                  // The candidate source has been checked for determinism (source_check)
+	worktree      *worktree             // Dedicated checkout with isolation: worktree or container (nil otherwise)
+	container     *ContainerConfig      // Container commands run in with isolation: container (nil otherwise)
+	audit         *auditLog             // Records every command run with audit_log (nil otherwise)
+
+	observers observerList  // Terminal output, claude.log outcomes and the caller's observer
+	log       leveledLogger // Diagnostic output up to opts.Verbosity
+
+	claudeFailures int // Consecutive Claude invocation errors with the current command
+	fallback       int // Index into claude_command_fallbacks plus one (0 = primary command)
+
+	attemptStart time.Time       // When Claude was started for the current candidate
+	candidate    string          // Key of the current candidate
+	sessionID    string          // Claude session ID for the current candidate
+	usage        Usage           // Tokens and cost Claude reported for the current candidate
+	commit       string          // Revision committed for the current candidate ("" if none yet)
+	baseline     string          // HEAD when the current candidate's attempt started ("" if unknown)
+	cleanBase    bool            // The working tree had no uncommitted changes at baseline
+	dirtyBefore  map[string]bool // Files already changed at baseline (only with external_edits)
+	edited       map[string]bool // Files Claude's edit tools wrote during the current attempt
+	promptHash   string          // Hash of the prompt template and flags for the current candidate
+	variant      *PromptVariant  // Prompt variant for the current candidate (nil without variants)
+	verifyError  string          // Excerpt of the last failed verify output for the current candidate
+	verifyResult string          // How the current attempt's first verify went (Verify* constants)
+	stalled      bool            // Claude was stopped by inactivity_timeout this attempt
+	introduced   []string        // Candidates that appeared after the current candidate's changes
+	others       []string        // Keys of the pending candidates other than the current one, for $OTHER_CANDIDATES
+	prefetched   *prefetch       // Candidate source output from the last pipelined run (nil if none)
+
+	history         map[string][]AttemptRecord // Prior attempts per candidate, loaded on first use of $PREVIOUS_ATTEMPTS or candidate_families
+	skippedFamilies map[string]bool            // Candidate families skipped this session, reported once each
+	sample          map[string]bool            // Keys of the candidates drawn by --sample (nil until drawn)
+	lastSync        time.Time                  // When sync_command last ran
+
+	iteration int        // Iterations started by this runner
+	summary   RunSummary // Iteration and outcome counts for this run
+	outputDir string     // Where verify and reset output is saved ("" to not save it)
+
+	cmdEnv       *commandEnv            // `env:` exports for the current candidate, applied by the executor
+	baseExports  string                 // `env:` exports outside an attempt, for the candidate source
+	contextCache map[string]contextFile // context_files by path, re-read when they change
+
+	pending    []pendingCommit // Fixes staged as temporary commits awaiting a batch commit
+	batchBase  string          // Revision before the first pending commit
+	batchTip   string          // Revision of the last pending commit
+	batchStart time.Time       // When the first pending commit was staged
+}
+
+// prefetch is candidate source output captured for a known working tree state.
+type prefetch struct {
+	fingerprint string
+	output      []byte
+	err         error
+}
+
+// pendingCommit is a fix held back for a batched commit.
+type pendingCommit struct {
+	key     string
+	outcome Outcome
+	session string // Claude session that made the fix
+}
+
+// escalation overrides the timeout and model for a candidate's retry after a timeout.
+type escalation struct {
+	timeout time.Duration
+	model   string
+}
+
+func NewRunner(env *Environment, taskName string, opts RunnerOptions) (*Runner, error) {
+	task, ok := env.Tasks[taskName]
+	if !ok {
+		return nil, fmt.Errorf("task not found: %s", taskName)
+	}
+	env = env.ForTask(task)
+
+	if task.Workdir != "" {
+		if info, err := os.Stat(task.WorkDir(env.ProjectDir)); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("task %s workdir does not exist: %s", task.Name, task.Workdir)
+		}
+	}
+
+	if opts.Repeat > 0 {
+		task.Repeat = opts.Repeat
+	}
+	ignoredList, err := newTaskIgnoredList(task)
+	if err != nil {
+		return nil, err
+	}
+
+	var claudeLogger *ClaudeLogger
+	if !opts.DryRun {
+		claudeLogger, err = NewClaudeLogger(env.LogPath(task, time.Now()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create claude logger: %w", err)
+		}
+	}
+
+	var variants *variantAssigner
+	if len(task.Variants) > 0 {
+		variants = newVariantAssigner(task.VariantAssignment, len(task.Variants))
+	}
+
+	transient, err := newTransientMatcher(env.Config.TransientErrors)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transient_errors: %w", err)
+	}
+
+	var executor CommandExecutor = &RealCommandExecutor{IgnoreDirtyPaths: env.Config.IgnoreDirtyPaths}
+	if opts.Executor != nil {
+		executor = opts.Executor
+	}
+	cmdEnv := &commandEnv{exports: env.baseEnvExports(task)}
+	if env.hasTaskEnv(task) {
+		executor = envExecutor{CommandExecutor: executor, env: cmdEnv}
+	}
+	var audit *auditLog
+	if env.Config.AuditLog && !opts.DryRun {
+		audit, err = openAuditLog(env.AuditLogPath(task, time.Now()))
+		if err != nil {
+			return nil, err
+		}
+		executor = auditExecutor{CommandExecutor: executor, log: audit}
+	}
+	var outputDir string
+	if env.Config.SavesCommandOutput() && !opts.DryRun {
+		outputDir = env.CommandOutputDir(task, time.Now())
+	}
+	log := leveledLogger{level: opts.Verbosity}
+	if log.enabled(VerbosityCommands) {
+		executor = loggingExecutor{CommandExecutor: executor, log: log}
+	}
+
+	// claude.log gets each outcome before anything else hears about it
+	var observers observerList
+	if claudeLogger != nil {
+		observers = append(observers, claudeLogger)
+	}
+	observers = append(observers, runObservers(env, opts)...)
+	phases := NewPhaseStats()
+
+	return &Runner{
+		env:          env,
+		task:         task,
+		opts:         opts,
+		ignoredList:  ignoredList,
+		claudeLogger: claudeLogger,
+		audit:        audit,
+		phases:       phases,
+		outputDir:    outputDir,
+		cmdEnv:       cmdEnv,
+		baseExports:  cmdEnv.exports,
+		executor:     executor,
+		observers:    observers,
+		log:          log,
+
+		stopRequested: &atomic.Bool{},
+		summary:       RunSummary{Task: task.Name, Outcomes: make(map[Outcome]int), Trend: &CandidateTrend{}, Phases: phases},
+
+		escalations:     make(map[string]escalation),
+		skippedFamilies: make(map[string]bool),
+		variants:        variants,
+		transient:       transient,
+		pacer:           newClaudePacer(opts.MinInterval),
+	}, nil
+}
+
+// newTaskIgnoredList creates the task's ignore list from its command or file.
+func newTaskIgnoredList(task Task) (*IgnoredList, error) {
+	var ignoredList *IgnoredList
+	var err error
+	if task.IgnoreList != "" {
+		ignoredList, err = NewIgnoredListFromCommand(task.IgnoreList, task.Dir)
+	} else {
+		ignoredList, err = NewIgnoredList(task.Dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ignored list: %w", err)
+	}
+
+	// Set repeat mode on ignored list
+	ignoredList.SetMaxRepeat(task.Repeat)
+	return ignoredList, nil
+}
+
+// pendingCandidates returns the number of candidates the task would still
+// process: those in its partition that are not on its ignore list.
+func pendingCandidates(env *Environment, task Task, partition HashPartition) (int, error) {
+	env = env.ForTask(task)
+	output, err := RunCandidateSource(env.baseEnvExports(task)+task.CandidateSource, task.WorkDir(env.ProjectDir), env.Config.CandidateSourceMaxBytes)
+	if err != nil {
+		return 0, err
+	}
+	candidates, _, err := ParseTaskCandidates(output, task)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse candidates: %w", err)
+	}
+	candidates, _ = DedupeCandidates(candidates)
+	candidates = FilterByPartition(candidates, partition)
+
+	ignoredList, err := newTaskIgnoredList(task)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, c := range candidates {
+		if !ignoredList.Contains(c.Key) {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// unmetDependency returns the first prerequisite of task that still has
+// pending candidates, or "" if all prerequisites are done.
+func unmetDependency(env *Environment, task Task, partition HashPartition) (string, int, error) {
+	for _, dep := range task.DependsOn {
+		pending, err := pendingCandidates(env, env.Tasks[dep], partition)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to check prerequisite %s: %w", dep, err)
+		}
+		if pending > 0 {
+			return dep, pending, nil
+		}
+	}
+	return "", 0, nil
+}
+
+// workDir returns the directory the task's commands run in.
+func (r *Runner) workDir() string {
+	return r.task.WorkDir(r.env.ProjectDir)
+}
+
+// setExecutor sets the command executor (for testing).
+func (r *Runner) setExecutor(exec CommandExecutor) {
+	r.executor = exec
+}
+
+func (r *Runner) Run() error {
+	if err := r.checkClaudeCommand(); err != nil {
+		return err
+	}
+	if err := r.isolate(); err != nil {
+		return err
+	}
+	r.printStartupBanner()
+
+	startTime := time.Now()
+	for {
+		r.opts.Control.waitWhilePaused()
+		r.power.wait(r.opts, r.stopRequested)
+		if r.stopRequested.Load() {
+			fmt.Println("Stopped by user request.")
+			break
+		}
+		if r.checkSentinels() {
+			break
+		}
+
+		if r.opts.Limit > 0 && r.iteration >= r.opts.Limit {
+			fmt.Printf("Reached iteration limit (%d).\n", r.opts.Limit)
+			break
+		}
+		if r.opts.Evaluate > 0 && r.iteration >= r.opts.Evaluate {
+			fmt.Printf("Evaluated %d candidate(s).\n", r.opts.Evaluate)
+			break
+		}
+		if r.opts.MaxCommits > 0 && r.summary.NewCommits >= r.opts.MaxCommits {
+			fmt.Printf("Reached commit limit (%d).\n", r.opts.MaxCommits)
+			break
+		}
+		if r.opts.MaxCost > 0 && r.summary.CostUSD >= r.opts.MaxCost {
+			fmt.Printf("Reached cost limit ($%.2f).\n", r.opts.MaxCost)
+			break
+		}
+
+		if r.opts.TimeLimit > 0 && time.Since(startTime) >= r.opts.TimeLimit {
+			fmt.Printf("Reached time limit (%s).\n", r.opts.TimeLimit)
+			break
+		}
+
+		done, err := r.step()
+		if err != nil {
+			r.summary.Duration = time.Since(startTime)
+			r.abandonPending()
+			r.keepWorktree()
+			return err
+		}
+		if done {
+			break
+		}
+	}
+	r.summary.Duration = time.Since(startTime)
+	if attempts := r.summary.Attempts(); r.opts.Evaluate > 0 && attempts > 0 {
+		fmt.Println(ColorInfo(fmt.Sprintf("Evaluation: %d/%d would have been fixed (%d%%), %d with partial progress",
+			r.summary.Fixed(), attempts, r.summary.Fixed()*100/attempts, r.summary.BestEffort())))
+	}
+
+	return r.finish()
+}
+
+// isolate moves the run into its own git worktree with isolation: worktree,
+// or into a clone that commands reach through a container with isolation:
+// container. Dry runs commit nothing, so they stay in the checkout.
+func (r *Runner) isolate() error {
+	isolation := r.env.Config.Isolation
+	if isolation == "" || r.opts.DryRun {
+		return nil
+	}
+	projectDir, err := filepath.Abs(r.env.ProjectDir)
+	if err != nil {
+		return fatalError(ErrVerify, "failed to create worktree: %w", err)
+	}
+	var wt *worktree
+	if isolation == IsolationContainer {
+		wt, err = addClone(projectDir, r.task.Name)
+	} else {
+		wt, err = addWorktree(projectDir, r.task.Name)
+	}
+	if err != nil {
+		return fatalError(ErrVerify, "failed to create worktree: %w", err)
+	}
+	isolated := *r.env
+	isolated.ProjectDir = wt.projectDir(projectDir)
+	r.env, r.worktree = &isolated, wt
+	// Relative path_prepend directories now resolve in the worktree
+	r.baseExports = r.env.baseEnvExports(r.task)
+	r.cmdEnv.exports = r.baseExports
+
+	if isolation == IsolationContainer {
+		r.container = r.env.Config.Container
+		// Commands get the env: variables and CANDIDATE_FILE on the host; pass them through
+		forward := forwardEnvArgs(r.env.taskEnv(r.task))
+		if r.task.CandidateFile != "" {
+			forward = append(forward, "-e", "CANDIDATE_FILE")
+		}
+		if len(forward) > 0 {
+			container := *r.container
+			container.Args = append(append([]string{}, container.Args...), forward...)
+			r.container = &container
+		}
+		r.executor = containerExecutor{CommandExecutor: r.executor, container: r.container, mountDir: wt.dir}
+		fmt.Println(ColorInfo(fmt.Sprintf("Working in %s, a clone in container %s, on branch %s", wt.dir, r.container.Image, wt.branch)))
+		return nil
+	}
+	fmt.Println(ColorInfo(fmt.Sprintf("Working in worktree %s on branch %s", wt.dir, wt.branch)))
+	return nil
+}
+
+// keepWorktree leaves the worktree of a run stopped by an error in place, so
+// its state can be inspected and its commits merged by hand.
+func (r *Runner) keepWorktree() {
+	if r.worktree != nil {
+		fmt.Println(ColorWarning(fmt.Sprintf("Worktree left at %s on branch %s", r.worktree.dir, r.worktree.branch)))
+	}
+}
+
+// checkClaudeCommand verifies the claude command exists (skipped in dry-run).
+// Uses the same precedence as execution: CLI override > task-level > global
+func (r *Runner) checkClaudeCommand() error {
+	if r.opts.DryRun || r.env.Config.Isolation == IsolationContainer {
+		return nil
+	}
+	claudeCmd := r.opts.ClaudeCommand
+	if claudeCmd == "" {
+		claudeCmd = r.task.ClaudeCommand
+	}
+	if claudeCmd == "" {
+		claudeCmd = r.env.Config.ClaudeCommand
+	}
+	return CheckClaudeCommand(claudeCmd)
+}
+
+// printStartupBanner prints the startup banner.
+func (r *Runner) printStartupBanner() {
+	logPath := r.env.LogPath(r.task, time.Now())
+	if r.claudeLogger != nil {
+		logPath = r.claudeLogger.Path()
+	}
+	fmt.Print(r.env.StartupBanner(r.task.Name, relativePath(logPath), r.modeString()))
+}
+
+// relativePath returns path relative to the working directory when possible.
+func relativePath(path string) string {
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(cwd, path); err == nil {
+			return rel
+		}
+	}
+	return path
+}
+
+// step runs a single iteration, resetting the environment first if this is the
+// runner's first iteration. Non-fatal errors are handled with backoff and
+// reported as not done; only fatal errors are returned.
+func (r *Runner) step() (done bool, err error) {
+	r.iteration++
+	r.summary.Iterations = r.iteration
+	r.observers.OnIterationStart(r.summary)
+
+	// Reset environment to clean state at start of first iteration
+	if r.iteration == 1 {
+		if err := r.runStartupReset(); err != nil {
+			return false, fatalError(ErrVerify, "startup reset failed: %w", err)
+		}
+	}
+
+	done, err = r.runIteration()
+	if err != nil {
+		fmt.Println(ColorError(fmt.Sprintf("Error: %s", DescribeError(err))))
+
+		if !isRetryable(err) {
+			fmt.Println(ColorError("Fatal error, stopping."))
+			return false, err
+		}
+
+		// Errors with a fixed backoff (rate limits) don't escalate the exponential backoff
+		if stageErr := asStageError(err); stageErr != nil && stageErr.Backoff > 0 {
+			fmt.Println(ColorWarning(fmt.Sprintf("Sleeping for %s...", stageErr.Backoff)))
+			notifyDesktop(r.opts, "Nigel paused", fmt.Sprintf("%s: %v, sleeping for %s", r.task.Name, err, stageErr.Backoff))
+			time.Sleep(stageErr.Backoff)
+			r.backoffLevel = 0
+		} else {
+			// Exponential backoff for other errors
+			backoff := calculateBackoff(r.backoffLevel)
+			fmt.Println(ColorWarning(fmt.Sprintf("Sleeping for %s (backoff level %d)...", backoff, r.backoffLevel)))
+			time.Sleep(backoff)
+			r.backoffLevel++
+		}
+		return false, nil
+	}
+
+	if !done {
+		r.backoffLevel = 0
+	}
+	return done, nil
+}
+
+// finish commits any pending batched fixes, merges them back from the
+// worktree and closes the log.
+func (r *Runner) finish() error {
+	if err := r.flushPending(); err != nil {
+		r.abandonPending()
+		r.keepWorktree()
+		return err
+	}
+	if r.worktree != nil {
+		if err := r.worktree.mergeBack(); err != nil {
+			return fatalError(ErrCommit, "failed to merge worktree: %w", err)
+		}
+	}
+
+	if r.claudeLogger != nil {
+		r.claudeLogger.Close()
+	}
+	r.audit.Close()
+
+	return nil
+}
+
+// signalStops holds the stop flags of the runs in progress, which a graceful
+// stop sets.
+var signalStops = struct {
+	sync.Mutex
+	flags map[*atomic.Bool]bool
+}{flags: map[*atomic.Bool]bool{}}
+
+// signalsOnce installs the signal handlers once per process, so a server
+// starting run after run doesn't stack them.
+var signalsOnce sync.Once
+
+// watchSignals registers a run's stop flag with the handlers for graceful stop
+// (SIGQUIT) and interrupt (SIGINT/SIGTERM), installing them on first use. A
+// graceful stop sets the stop flag of every registered run; an interrupt
+// squashes pending batched commits before exiting. The returned function
+// unregisters the flag once the run is over.
+func watchSignals(stop *atomic.Bool) func() {
+	signalsOnce.Do(func() {
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGQUIT, syscall.SIGINT, syscall.SIGTERM)
+		go handleSignals(sigChan)
+	})
+	signalStops.Lock()
+	defer signalStops.Unlock()
+	signalStops.flags[stop] = true
+	return func() {
+		signalStops.Lock()
+		defer signalStops.Unlock()
+		delete(signalStops.flags, stop)
+	}
+}
+
+// handleSignals acts on the signals watchSignals installed handlers for.
+func handleSignals(sigChan <-chan os.Signal) {
+	for sig := range sigChan {
+		switch sig {
+		case syscall.SIGQUIT:
+			fmt.Println("\n[Ctrl+\\] Graceful stop requested, will finish current iteration...")
+			signalStops.Lock()
+			for stop := range signalStops.flags {
+				stop.Store(true)
+			}
+			signalStops.Unlock()
+		case syscall.SIGINT, syscall.SIGTERM:
+			fmt.Println("\nInterrupted, cleaning up...")
+			KillRunningProcess()
+			abandonAllPending()
+			os.Exit(1)
+		}
+	}
+}
+
+// RunTasks runs the named tasks sequentially. The iteration, time, commit and
+// cost limits in opts are shared across all tasks, and a combined summary is printed
+// at the end.
+func RunTasks(env *Environment, taskNames []string, opts RunnerOptions) error {
+	for _, name := range taskNames {
+		if _, ok := env.Tasks[name]; !ok {
+			return fmt.Errorf("task not found: %s", name)
+		}
+	}
+	if err := confirmPreflight(env, taskNames, opts); err != nil {
+		return err
+	}
+
+	stop := opts.Control.stopFlag()
+	defer watchSignals(stop)()
+	pacer := newClaudePacer(opts.MinInterval)
+	power := newPowerGate(opts)
+
+	startTime := time.Now()
+	iterations, commits, cost := 0, 0, 0.0
+	var summaries []RunSummary
+	var runErr error
+	for _, name := range taskNames {
+		taskOpts := opts
+		if opts.Limit > 0 {
+			taskOpts.Limit = opts.Limit - iterations
+			if taskOpts.Limit <= 0 {
+				break
+			}
+		}
+		if opts.MaxCommits > 0 {
+			taskOpts.MaxCommits = opts.MaxCommits - commits
+			if taskOpts.MaxCommits <= 0 {
+				fmt.Printf("Reached commit limit (%d).\n", opts.MaxCommits)
+				break
+			}
+		}
+		if opts.MaxCost > 0 {
+			taskOpts.MaxCost = opts.MaxCost - cost
+			if taskOpts.MaxCost <= 0 {
+				fmt.Printf("Reached cost limit ($%.2f).\n", opts.MaxCost)
+				break
+			}
+		}
+		if opts.TimeLimit > 0 {
+			taskOpts.TimeLimit = opts.TimeLimit - time.Since(startTime)
+			if taskOpts.TimeLimit <= 0 {
+				break
+			}
+		}
+
+		dep, pending, err := unmetDependency(env, env.Tasks[name], opts.Partition)
+		if err != nil {
+			runErr = err
+			break
+		}
+		if dep != "" {
+			fmt.Println(ColorWarning(fmt.Sprintf("Skipping %s: prerequisite %s still has %d candidate(s)", name, dep, pending)))
+			continue
+		}
+
+		runner, err := NewRunner(env, name, taskOpts)
+		if err != nil {
+			runErr = err
+			break
+		}
+		runner.stopRequested = stop
+		runner.pacer = pacer
+		runner.power = power
+
+		runErr = runner.Run()
+		summaries = append(summaries, runner.summary)
+		iterations += runner.summary.Iterations
+		commits += runner.summary.NewCommits
+		cost += runner.summary.CostUSD
+		if runErr != nil || stop.Load() {
+			break
+		}
+	}
+
+	runObservers(env, opts).OnRunEnd(summaries, runErr)
+	return runErr
+}
+
+func (r *Runner) runIteration() (done bool, err error) {
+	if err := r.syncUpstream(); err != nil {
+		return false, err
+	}
+
+	// Run candidate source to get candidates
+	candidateTimer := NewDelayedProgressTimer("Running candidate source...", 5*time.Second)
+	candidateTimer.SetMedianHint(r.phases.CandidateSource)
+	candidateTimer.Start()
+	sourceStart := time.Now()
+	output, err := r.runCandidateSource()
+	r.phases.CandidateSource.Add(time.Since(sourceStart))
+	candidateTimer.Stop()
+	if err != nil {
+		return false, candidateSourceError("candidate source failed", err)
+	}
+
+	r.log.printf(VerbosityCandidates, ColorInfo("Candidate source output:\n%s\n"), output)
+
+	// The list was just loaded for the first iteration
+	if r.task.IgnoreListRefresh == IgnoreListRefreshIteration && r.iteration > 1 && r.ignoredList != nil {
+		if err := r.ignoredList.Refresh(); err != nil {
+			return false, retryableError(ErrCandidateSource, "%w", err)
+		}
+	}
+
+	candidates, warnings, err := ParseTaskCandidates(output, r.task)
+	if err != nil {
+		return false, candidateParseError("failed to parse candidates", err)
+	}
+	for _, warning := range warnings {
+		fmt.Println(ColorWarning("Skipping malformed " + warning))
+	}
+
+	// Drop duplicate keys so ignored-count bookkeeping stays consistent
+	candidates, dupes := DedupeCandidates(candidates)
+	if dupes > 0 {
+		r.log.printf(VerbosityCandidates, ColorWarning("Dropped %d duplicate candidate(s)")+"\n", dupes)
+	}
+
+	if r.task.SourceCheck != "" && !r.sourceChecked {
+		if err := r.checkSourceDeterminism(candidates); err != nil {
+			return false, err
+		}
+	}
+
+	// Prune once per run, against every shard's candidates. An empty list is
+	// more likely a broken candidate source than everything being fixed.
+	if r.opts.Prune && !r.pruned && !r.opts.DryRun && !r.opts.NoCommit && r.opts.Evaluate == 0 && len(candidates) > 0 {
+		r.pruned = true
+		pruned, err := r.ignoredList.Prune(candidates)
+		if err != nil {
+			return false, fatalError(ErrCandidateSource, "%w", err)
+		}
+		if pruned > 0 {
+			fmt.Println(ColorInfo(fmt.Sprintf("Pruned %d ignored keys no longer in the candidate list", pruned)))
+		}
+	}
+
+	// Filter by hash if requested
+	candidates = FilterByPartition(candidates, r.opts.Partition)
+	r.summary.Trend.Observe(candidates, time.Now())
+	// The re-check is compared with the whole list, not the sample of it
+	listed := candidates
+	candidates = r.applySample(candidates)
+	candidates, err = r.prioritizeFamilies(candidates)
+	if err != nil {
+		return false, retryableError(ErrCandidateSource, "%w", err)
+	}
+
+	if r.log.enabled(VerbosityCandidates) {
+		fmt.Printf(ColorInfo("Parsed candidates (%d total):\n"), len(candidates))
+		for _, c := range candidates {
+			fmt.Printf("  - %s\n", displayKey(c.Key))
+		}
+	}
+
+	// Count ignored candidates
+	ignoredCount := 0
+	if r.ignoredList != nil {
+		for _, c := range candidates {
+			if r.ignoredList.Contains(c.Key) {
+				ignoredCount++
+			}
+		}
+	}
+
+	// Select first non-ignored candidate
+	candidate := SelectCandidate(candidates, r.ignoredList)
+	if candidate == nil {
+		remaining := len(candidates) - ignoredCount
+		if remaining == 0 && ignoredCount > 0 {
+			fmt.Printf("No more candidates (%d ignored)\n", ignoredCount)
+		} else {
+			fmt.Println("No more candidates.")
+		}
+		if r.ignoredList != nil {
+			if cooling, next := r.ignoredList.Cooldowns(candidates); cooling > 0 {
+				fmt.Printf("%d on cooldown, the next eligible again at %s\n", cooling, next.Format("2006-01-02 15:04"))
+			}
+		}
+		return true, nil
+	}
+
+	// The counts are shown in the iteration banner
+	r.summary.Pending = len(candidates) - ignoredCount
+	r.summary.Ignored = ignoredCount
+	r.observers.OnCandidateSelected(r.summary, candidate)
+	r.others = otherPendingKeys(candidates, candidate.Key, r.ignoredList)
+
+	if r.variants != nil {
+		r.variant = &r.task.Variants[r.variants.assign(candidate.Key)]
+		fmt.Printf("Variant: %s\n", r.variant.Name)
+	}
+
+	// Get prompt content
+	prompt, err := r.getPrompt(candidate)
+	if err != nil {
+		return false, err
+	}
+	prompt, err = r.applyPromptLimit(candidate, prompt)
+	if tooLarge, ok := err.(*promptTooLargeError); ok {
+		return r.handlePromptTooLarge(candidate, tooLarge)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	r.log.printf(VerbosityCommands, "Prompt:\n%s\n", prompt)
+
+	// Dry run: just print and exit
+	if r.opts.DryRun {
+		fmt.Printf("\n--- Dry Run Prompt ---\n%s\n--- End Prompt ---\n", prompt)
+		return true, nil
+	}
+
+	exports, err := r.env.taskExports(r.task, candidate)
+	if err != nil {
+		return false, fatalError(ErrAgent, "%w", err)
+	}
+	r.cmdEnv.exports = exports
+
+	// Tool permissions and MCP servers are Claude CLI flags other agent CLIs don't take
+	stream := r.task.streamConfig()
+	parser, _ := lookupStreamParser(stream.Parser)
+	claudeFlags := r.task.ClaudeFlags
+	if parser.Claude {
+		claudeFlags = strings.TrimSpace(r.task.ToolFlags() + " " + claudeFlags)
+	}
+	if esc, ok := r.escalations[candidate.Key]; ok && esc.model != "" {
+		claudeFlags = strings.TrimSpace(claudeFlags + " --model " + shellQuote(esc.model))
+	}
+
+	template, err := r.loadTemplate()
+	if err != nil {
+		return false, err
+	}
+	// Context files are part of the prompt as written, so a style guide edit
+	// counts as a prompt change
+	context, err := r.promptContext()
+	if err != nil {
+		return false, err
+	}
+	r.promptHash = PromptHash(template+context, claudeFlags)
+
+	// Wait out --min-interval before the attempt starts so it isn't counted in its duration
+	r.pacer.wait()
+
+	if r.claudeLogger != nil {
+		r.claudeLogger.StartEntry(LogEntry{
+			Candidate:  candidate.Key,
+			Prompt:     prompt,
+			PromptHash: r.promptHash,
+			Variant:    r.variantName(),
+		})
+	}
+	r.attemptStart = time.Now()
+	r.candidate = candidate.Key
+	r.sessionID = ""
+	r.usage, r.commit = Usage{}, ""
+	r.verifyError, r.verifyResult = "", ""
+	r.stalled = false
+	r.introduced = nil
+	r.captureBaseline()
+
+	// Determine claude command: CLI override > task-level > global
+	claudeCmd := r.opts.ClaudeCommand
+	if claudeCmd != "" {
+		r.log.printf(VerbosityCommands, ColorInfo("Using CLI override claude_command: %s\n"), claudeCmd)
+	} else {
+		claudeCmd = r.task.ClaudeCommand
+		if claudeCmd != "" {
+			r.log.printf(VerbosityCommands, ColorInfo("Using task-level claude_command: %s\n"), claudeCmd)
+		}
+	}
+	if claudeCmd == "" {
+		claudeCmd = r.env.Config.ClaudeCommand
+	}
+	if r.fallback > 0 {
+		claudeCmd = r.env.Config.ClaudeCommandFallbacks[r.fallback-1]
+		r.log.printf(VerbosityCommands, ColorInfo("Using fallback claude_command: %s\n"), claudeCmd)
+	}
+	if r.container != nil {
+		claudeCmd = r.container.wrapPrefix(r.worktree.dir, r.workDir(), claudeCmd)
+	}
+	claudeCmd = r.cmdEnv.exports + claudeCmd
+
+	timeout := r.candidateTimeout(candidate)
+
+	// Added after the prompt hash so the temp file path doesn't change it
+	if servers := mergeMCPServers(r.env.Config.MCPServers, r.task.MCPServers); len(servers) > 0 && parser.Claude {
+		mcpConfig, err := writeMCPConfig(r.mcpConfigDir(), servers)
+		if err != nil {
+			return false, retryableError(ErrAgent, "%w", err)
+		}
+		defer os.Remove(mcpConfig)
+		claudeFlags = strings.TrimSpace(claudeFlags + " --mcp-config " + shellQuote(mcpConfig))
+	}
+
+	// Create inactivity timer - shows after 30 seconds of no streaming output
+	// Note: timer will be stopped when streaming starts
+	inactivityTimer := NewDelayedProgressTimer("Waiting for Claude...", 30*time.Second)
+	inactivityTimer.SetMedianHint(r.phases.Claude)
+
+	fmt.Println(ColorInfo("Running Claude..."))
+
+	// Track first chunk to stop the timer
+	firstChunk := &atomic.Bool{}
+	firstChunk.Store(true)
+
+	// Both streams and tool calls go to the observers; the terminal observer shows them
+	streamCb := func(text string) {
+		if firstChunk.CompareAndSwap(true, false) {
+			inactivityTimer.Stop()
+		}
+		r.observers.OnAgentChunk(r.task.Name, text, false)
+	}
+	stderrCb := func(text string) {
+		if firstChunk.CompareAndSwap(true, false) {
+			inactivityTimer.Stop()
+		}
+		r.observers.OnAgentChunk(r.task.Name, text, true)
+	}
+
+	toolCb := func(tool ToolUse) {
+		if firstChunk.CompareAndSwap(true, false) {
+			inactivityTimer.Stop()
+		}
+		r.recordEdit(tool)
+		r.observers.OnToolUse(r.task.Name, tool)
+	}
+
+	var rawCb StreamCallback
+	if r.log.enabled(VerbosityStream) {
+		rawCb = func(line string) {
+			console().write(ColorDim("event: "+line) + "\n")
+		}
+	}
+	promptArgs := strings.TrimSpace(parser.Prompt + " <prompt>")
+	r.log.printf(VerbosityCommands, ColorDim("$ %s %s %s (in %s)")+"\n", claudeCmd, claudeFlags, promptArgs, relativePath(r.workDir()))
+
+	inactivityTimer.Start()
+
+	var claudeResult ClaudeResult
+	var claudeStart time.Time
+	for retry := 1; ; retry++ {
+		r.pacer.start()
+		claudeStart = time.Now()
+		claudeResult, err = RunClaudeCommand(claudeCmd, claudeFlags, stream, prompt, r.workDir(), r.claudeLogger, timeout, r.task.InactivityTimeout, streamCb, stderrCb, rawCb, toolCb)
+		r.audit.record(claudeCmd+" "+claudeFlags+" "+promptArgs, r.workDir(), claudeStart, err == nil, err)
+		if !r.retryTransient(err, claudeResult.Output, retry) {
+			break
+		}
+	}
+	r.phases.Claude.Add(time.Since(claudeStart))
+	r.sessionID = claudeResult.SessionID
+	r.usage = claudeResult.Usage
+
+	// Make sure timer is stopped (in case no stream chunks arrived)
+	inactivityTimer.Stop()
+
+	if r.claudeLogger != nil {
+		r.claudeLogger.EndEntry()
+	}
+
+	// Check for rate limit in output
+	if strings.Contains(claudeResult.Output, rateLimitPhrase) {
+		return false, rateLimitError()
+	}
+
+	// A stalled session's edits go through the usual checks, so a fix it
+	// finished before hanging is still kept
+	if timeout, ok := err.(*timeoutError); ok && timeout.inactive {
+		fmt.Println(ColorWarning(fmt.Sprintf("Claude stalled (%v) and was stopped, checking the changes it made...", err)))
+		r.stalled, err = true, nil
+	}
+
+	// Check for timeout
+	if _, isTimeout := err.(*timeoutError); isTimeout {
+		fmt.Println(ColorWarning(fmt.Sprintf("Candidate timed out: %v", err)))
+		return r.handleTimeout(candidate)
+	}
+
+	if err != nil {
+		r.recordClaudeFailure()
+		// Claude errored out - clean up any partial changes before retry
+		fmt.Println(ColorWarning("Claude failed, cleaning up..."))
+		if !r.runResetAndVerify() {
+			return false, fatalError(ErrVerify, "failed to reset after claude error")
+		}
+		return false, retryableError(ErrAgent, "claude failed: %w", err)
+	}
+	r.claudeFailures = 0
+
+	// An attempt that changed nothing can't have fixed anything, so don't pay
+	// for a build and re-check
+	hasChanges, err := r.executor.HasUncommittedChanges(r.workDir())
+	if err != nil {
+		return false, retryableError(ErrVerify, "failed to check for changes: %w", err)
+	}
+	if !hasChanges {
+		return r.handleNoChanges(candidate)
+	}
+
+	// Don't verify, commit or reset someone's work in progress along with the fix
+	if err := r.checkExternalEdits(); err != nil {
+		return false, err
+	}
+
+	// With pipelining, the re-check (and, if the changes are kept, the next
+	// iteration's candidate list) is computed while the verify command runs
+	var pipelined <-chan *prefetch
+	if r.task.Pipeline {
+		pipelined = r.startPrefetch()
+	}
+
+	// Verify build FIRST before checking candidate presence
+	// Invalid changes can cause candidates to be excluded from source,
+	// creating false positives if we check presence before build
+	verified := r.runVerify()
+	if pipelined != nil {
+		r.prefetched = <-pipelined
+	}
+	if !verified {
+		fmt.Println(ColorWarning("Build failed after Claude changes"))
+		return r.handleFailure(candidate)
+	}
+	// The re-check can change the tree (code generators, formatters), in
+	// which case handleSuccess verifies the build again before committing
+	verifiedTree, _ := r.executor.TreeFingerprint(r.workDir())
+
+	// Build passed - now check if candidate was fixed
+	fmt.Println(ColorInfo("Re-checking candidates..."))
+	output, err = r.runCandidateSource()
+	if err != nil {
+		r.runResetAndVerify()
+		return false, candidateSourceError("candidate source re-run failed", err)
+	}
+
+	r.log.printf(VerbosityCandidates, ColorInfo("Re-check candidate source output:\n%s\n"), output)
+
+	newCandidates, _, err := ParseTaskCandidates(output, r.task)
+	if err != nil {
+		// Without the re-check the changes can't be judged, so they're not kept
+		r.runResetAndVerify()
+		return false, candidateParseError("failed to parse new candidates", err)
+	}
+	newCandidates, _ = DedupeCandidates(newCandidates)
+
+	// Apply the same hash filter for consistent verification
+	newCandidates = FilterByPartition(newCandidates, r.opts.Partition)
+	r.summary.Trend.Observe(newCandidates, time.Now())
+
+	if r.log.enabled(VerbosityCandidates) {
+		fmt.Printf(ColorInfo("Re-check parsed candidates (%d total):\n"), len(newCandidates))
+		for _, c := range newCandidates {
+			fmt.Printf("  - %s\n", displayKey(c.Key))
+		}
+		fmt.Printf(ColorInfo("Looking for candidate: %s\n"), displayKey(candidate.Key))
+		fmt.Printf(ColorInfo("Candidate found: %v\n"), containsKey(newCandidates, candidate.Key))
+	}
+
+	// Flag candidates the changes created, e.g. a fix that silences one lint but trips three others
+	r.introduced = IntroducedCandidates(listed, newCandidates)
+	if len(r.introduced) > 0 {
+		fmt.Println(ColorWarning(fmt.Sprintf("Changes introduced %d new candidate(s): %s",
+			len(r.introduced), summarizeKeys(r.introduced))))
+		if r.task.MaxNewCandidates > 0 && len(r.introduced) >= r.task.MaxNewCandidates {
+			return r.handleRegression(candidate)
+		}
+	}
+
+	candidateFixed := !containsKey(newCandidates, candidate.Key)
+
+	if candidateFixed {
+		return r.handleSuccess(candidate, r.treeUnchanged(verifiedTree))
+	} else {
+		return r.handleFailure(candidate)
+	}
+}
+
+// recordClaudeFailure counts consecutive Claude invocation errors and, once the
+// current command has failed failoverThreshold times in a row, switches
+// subsequent iterations to the next claude_command_fallbacks entry.
+func (r *Runner) recordClaudeFailure() {
+	r.claudeFailures++
+	fallbacks := r.env.Config.ClaudeCommandFallbacks
+	if r.claudeFailures < failoverThreshold || r.fallback >= len(fallbacks) {
+		return
+	}
+	r.fallback++
+	r.claudeFailures = 0
+
+	msg := fmt.Sprintf("Claude command failed %d times in a row, failing over to %s", failoverThreshold, fallbacks[r.fallback-1])
+	fmt.Println(ColorWarning(msg))
+	if r.claudeLogger != nil {
+		fmt.Fprintln(r.claudeLogger, msg)
+	}
+}
+
+// retryTransient decides whether a failed Claude invocation hit a transient
+// error (overloaded API, network) worth retrying. If so it resets any partial
+// changes and waits before the caller retries the same candidate, without
+// counting the failure against it.
+func (r *Runner) retryTransient(err error, output string, retry int) bool {
+	if err == nil || retry > r.transient.maxRetries {
+		return false
+	}
+	if _, isTimeout := err.(*timeoutError); isTimeout {
+		return false
+	}
+	match := r.transient.match(output)
+	if match == "" {
+		return false
+	}
+
+	msg := fmt.Sprintf("Transient Claude error (%s), retrying in %s (%d/%d)...", match, r.transient.delay, retry, r.transient.maxRetries)
+	console().write(ColorWarning(msg) + "\n") // The Claude timer may be showing
+	if r.claudeLogger != nil {
+		fmt.Fprintln(r.claudeLogger, msg)
+	}
+	if !r.runReset() {
+		return false
+	}
+	time.Sleep(r.transient.delay)
+	return true
+}
+
+// startPrefetch runs the candidate source in the background against the
+// current working tree. The channel yields the result once the source finishes.
+func (r *Runner) startPrefetch() <-chan *prefetch {
+	result := make(chan *prefetch, 1)
+	fingerprint, err := r.executor.TreeFingerprint(r.workDir())
+	if err != nil {
+		result <- &prefetch{err: fmt.Errorf("failed to fingerprint working tree: %w", err)}
+		return result
+	}
+	go func() {
+		output, err := r.execCandidateSource()
+		result <- &prefetch{fingerprint: fingerprint, output: output, err: err}
+	}()
+	return result
+}
+
+// runCandidateSource returns the candidate source output for the current
+// working tree, reusing the last pipelined run if the tree hasn't changed since.
+func (r *Runner) runCandidateSource() ([]byte, error) {
+	if p := r.prefetched; p != nil && p.err == nil {
+		fingerprint, err := r.executor.TreeFingerprint(r.workDir())
+		if err == nil && fingerprint == p.fingerprint {
+			r.log.printf(VerbosityCandidates, ColorInfo("Reusing candidate source output from pipelined run")+"\n")
+			return p.output, nil
+		}
+		r.prefetched = nil
+		r.log.printf(VerbosityCandidates, ColorInfo("Working tree changed since pipelined run, re-running candidate source")+"\n")
+	}
+	return r.execCandidateSource()
+}
+
+// execCandidateSource runs the candidate source, recording it in the audit log.
+func (r *Runner) execCandidateSource() ([]byte, error) {
+	start := time.Now()
+	output, err := RunCandidateSource(r.baseExports+r.candidateSource(), r.workDir(), r.env.Config.CandidateSourceMaxBytes)
+	r.audit.record(r.candidateSource(), r.workDir(), start, err == nil, err)
+	return output, err
+}
+
+// candidateSource returns the command that runs the task's candidate source,
+// in the container with isolation: container.
+func (r *Runner) candidateSource() string {
+	if r.container != nil {
+		return r.container.wrap(r.worktree.dir, r.workDir(), r.task.CandidateSource)
+	}
+	return r.task.CandidateSource
+}
+
+// mcpConfigDir returns where the MCP config for Claude is written: the
+// system temp directory, or inside the clone's .git directory (where the
+// container can see it without it showing up as a change) with
+// isolation: container.
+func (r *Runner) mcpConfigDir() string {
+	if r.container != nil {
+		return filepath.Join(r.worktree.dir, ".git")
+	}
+	return ""
+}
+
+func (r *Runner) handleSuccess(candidate *Candidate, buildVerified bool) (bool, error) {
+	fmt.Println(ColorSuccess(fmt.Sprintf("✓ Candidate %s was fixed!", displayKey(candidate.Key))))
+
+	// Verify build (unless verified and unchanged since)
+	if !buildVerified && !r.runVerify() {
+		fmt.Println(ColorWarning("Build verification failed after fix, attempting recovery..."))
+		if !r.revertToBaseline() {
+			return false, fatalError(ErrVerify, "failed to reset after build failure")
+		}
+		if !r.runVerify() {
+			return false, fatalError(ErrVerify, "build still fails after reset")
+		}
+		fmt.Println("Recovered via reset.")
+		r.logOutcome(OutcomeFixedReverted, "build failed after fix")
+		if err := r.requeue(candidate, OutcomeFixedReverted); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	// Commit changes if there are any
+	hasChanges, err := r.executor.HasUncommittedChanges(r.workDir())
+	if err != nil {
+		return false, retryableError(ErrVerify, "failed to check for changes: %w", err)
+	}
+
+	if hasChanges && !r.runPreCommitScan(candidate) {
+		fmt.Println(ColorWarning("Pre-commit scan failed, resetting..."))
+		if !r.runResetAndVerify() {
+			return false, fatalError(ErrVerify, "failed to reset")
+		}
+		r.logOutcome(OutcomeScanFailed, "reverted")
+		if err := r.requeue(candidate, OutcomeScanFailed); err != nil {
+			return false, err
+		}
+	} else if hasChanges && r.opts.Evaluate > 0 {
+		if err := r.discardEvaluated(candidate, OutcomeFixed, "fixed"); err != nil {
+			return false, err
+		}
+	} else if hasChanges {
+		if err := r.checkDrift(); err != nil {
+			return false, err
+		}
+		fmt.Println(ColorInfo("Committing changes..."))
+		ok, err := r.commitChanges(candidate, OutcomeFixed)
+		if err != nil {
+			return false, commitError("success command error", err)
+		}
+		if !ok {
+			return false, fatalError(ErrCommit, "success command returned non-zero exit code")
+		}
+		fmt.Println(ColorSuccess("✓ Changes committed"))
+		r.logOutcome(OutcomeFixed, "committed")
+	} else {
+		return r.handleZeroDiffFix(candidate)
+	}
+
+	return false, nil
+}
+
+// handleNoChanges records an attempt where Claude left the working tree
+// untouched, skipping verify, the re-check and the reset.
+func (r *Runner) handleNoChanges(candidate *Candidate) (bool, error) {
+	if r.task.ZeroDiffFix != "" {
+		fmt.Println(ColorInfo("No files changed, re-checking candidates..."))
+		present, err := r.stillPresent(candidate)
+		if err != nil {
+			return false, err
+		}
+		if !present {
+			return r.handleZeroDiffFix(candidate)
+		}
+	}
+	fmt.Println(ColorError(fmt.Sprintf("✗ Candidate %s not fixed: no files changed, skipping verify.", displayKey(candidate.Key))))
+	r.logOutcome(OutcomeNotFixed, "no-op - no files changed")
+	if err := r.requeue(candidate, OutcomeNotFixed); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// handleRegression reverts changes that introduced max_new_candidates or more
+// new candidates, whether or not the selected candidate was fixed.
+func (r *Runner) handleRegression(candidate *Candidate) (bool, error) {
+	fmt.Println(ColorError(fmt.Sprintf("✗ Changes to %s introduced too many new candidates (max_new_candidates: %d), resetting...",
+		displayKey(candidate.Key), r.task.MaxNewCandidates)))
+	if !r.runResetAndVerify() {
+		return false, fatalError(ErrVerify, "failed to reset")
+	}
+	r.logOutcome(OutcomeRegression, "reverted")
+	if err := r.requeue(candidate, OutcomeRegression); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+func (r *Runner) handleFailure(candidate *Candidate) (bool, error) {
+	fmt.Println(ColorError(fmt.Sprintf("✗ Candidate %s not fixed.", displayKey(candidate.Key))))
+
+	outcome := OutcomeNotFixed
+	if r.task.AcceptBestEffort {
+		// Best effort mode: commit if build passes
+		if r.runVerify() {
+			hasChanges, err := r.executor.HasUncommittedChanges(r.workDir())
+			if err != nil {
+				return false, retryableError(ErrVerify, "failed to check for changes: %w", err)
+			}
+
+			if hasChanges && !r.runBestEffortCheck(candidate) {
+				fmt.Println(ColorWarning("Best-effort check failed, resetting..."))
+				if !r.runResetAndVerify() {
+					return false, fatalError(ErrVerify, "failed to reset")
+				}
+				r.logOutcome(OutcomeNotFixed, "best effort check failed - reverted")
+			} else if hasChanges && !r.runPreCommitScan(candidate) {
+				fmt.Println(ColorWarning("Pre-commit scan failed, resetting..."))
+				if !r.runResetAndVerify() {
+					return false, fatalError(ErrVerify, "failed to reset")
+				}
+				outcome = OutcomeScanFailed
+				r.logOutcome(outcome, "reverted")
+			} else if hasChanges && r.opts.Evaluate > 0 {
+				outcome = OutcomeBestEffort
+				if err := r.discardEvaluated(candidate, outcome, "partial progress"); err != nil {
+					return false, err
+				}
+			} else if hasChanges {
+				if err := r.checkDrift(); err != nil {
+					return false, err
+				}
+				fmt.Println(ColorInfo("Committing partial progress..."))
+				ok, err := r.commitChanges(candidate, OutcomeBestEffort)
+				if err != nil {
+					return false, commitError("best effort commit error", err)
+				}
+				if !ok {
+					return false, fatalError(ErrCommit, "best effort commit returned non-zero exit code")
+				}
+				fmt.Println(ColorSuccess("✓ Changes committed"))
+				outcome = OutcomeBestEffort
+				r.logOutcome(outcome, "partial progress committed")
+			} else {
+				r.logOutcome(OutcomeNotFixed, "no changes made")
+			}
+		} else {
+			// Build failed, reset
+			fmt.Println(ColorWarning("Build failed, resetting..."))
+			outcome = OutcomeBuildFailed
+			r.quarantine(candidate, outcome)
+			if !r.runResetAndVerify() {
+				return false, fatalError(ErrVerify, "failed to reset")
+			}
+			r.logOutcome(outcome, "reverted")
+		}
+	} else {
+		// Standard mode: reset changes, keeping them first if they failed verify
+		if r.verifyResult == VerifyFailed {
+			outcome = OutcomeBuildFailed
+			r.quarantine(candidate, outcome)
+		}
+		if !r.runResetAndVerify() {
+			return false, fatalError(ErrVerify, "failed to reset")
+		}
+		r.logOutcome(outcome, "reverted")
+	}
+
+	if err := r.requeue(candidate, outcome); err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+func (r *Runner) handleTimeout(candidate *Candidate) (bool, error) {
+	fmt.Println(ColorWarning(fmt.Sprintf("Candidate %s timed out", displayKey(candidate.Key))))
+
+	outcome := OutcomeTimeout
+	if r.task.AcceptBestEffort {
+		// Best effort mode: commit if build passes
+		if r.runVerify() {
+			hasChanges, err := r.executor.HasUncommittedChanges(r.workDir())
+			if err != nil {
+				return false, retryableError(ErrVerify, "failed to check for changes: %w", err)
+			}
+
+			if hasChanges && !r.runBestEffortCheck(candidate) {
+				fmt.Println(ColorWarning("Best-effort check failed after timeout, resetting..."))
+				if !r.runResetAndVerify() {
+					return false, fatalError(ErrVerify, "failed to reset")
+				}
+				r.logOutcome(OutcomeTimeout, "best effort check failed - reverted")
+			} else if hasChanges && !r.runPreCommitScan(candidate) {
+				fmt.Println(ColorWarning("Pre-commit scan failed after timeout, resetting..."))
+				if !r.runResetAndVerify() {
+					return false, fatalError(ErrVerify, "failed to reset")
+				}
+				outcome = OutcomeScanFailed
+				r.logOutcome(outcome, "timeout - reverted")
+			} else if hasChanges && r.opts.Evaluate > 0 {
+				outcome = OutcomeBestEffort
+				if err := r.discardEvaluated(candidate, outcome, "timeout - partial progress"); err != nil {
+					return false, err
+				}
+			} else if hasChanges {
+				if err := r.checkDrift(); err != nil {
+					return false, err
+				}
+				fmt.Println(ColorInfo("Committing partial progress after timeout..."))
+				ok, err := r.commitChanges(candidate, OutcomeBestEffort)
+				if err != nil {
+					return false, commitError("timeout commit error", err)
+				}
+				if !ok {
+					return false, fatalError(ErrCommit, "timeout commit returned non-zero exit code")
+				}
+				fmt.Println(ColorSuccess("✓ Changes committed"))
+				outcome = OutcomeBestEffort
+				r.logOutcome(outcome, "timeout - partial progress committed")
+			} else {
+				r.logOutcome(OutcomeTimeout, "no changes made")
+			}
+		} else {
+			// Build failed, reset
+			fmt.Println(ColorWarning("Build failed after timeout, resetting..."))
+			outcome = OutcomeBuildFailed
+			r.quarantine(candidate, outcome)
+			if !r.runResetAndVerify() {
+				return false, fatalError(ErrVerify, "failed to reset")
+			}
+			r.logOutcome(outcome, "timeout - reverted")
+		}
+	} else {
+		// Standard mode: reset changes
+		if !r.runResetAndVerify() {
+			return false, fatalError(ErrVerify, "failed to reset")
+		}
+		r.logOutcome(OutcomeTimeout, "reverted")
+	}
+
+	if r.task.TimeoutEscalation != nil && r.escalate(candidate, *r.task.TimeoutEscalation) {
+		return false, nil
+	}
+
+	if err := r.requeue(candidate, outcome); err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+// discardEvaluated resets changes that --evaluate would otherwise have
+// committed, logging the outcome they'd have had so fix rates can be compared
+// across prompts. The candidate is skipped for the rest of the session, since
+// resetting brings it back.
+func (r *Runner) discardEvaluated(candidate *Candidate, outcome Outcome, details string) error {
+	fmt.Println(ColorInfo("Evaluating, resetting instead of committing..."))
+	if !r.runResetAndVerify() {
+		return fatalError(ErrVerify, "failed to reset")
+	}
+	r.logOutcome(outcome, details+" - evaluated, reverted")
+	if r.ignoredList != nil {
+		r.ignoredList.SkipForSession(candidate.Key)
+	}
+	return nil
+}
+
+// candidateTimeout returns the timeout for a candidate: escalated override > CLI override > task-level.
+func (r *Runner) candidateTimeout(candidate *Candidate) time.Duration {
+	if esc, ok := r.escalations[candidate.Key]; ok {
+		return esc.timeout
+	}
+	if r.opts.Timeout != 0 {
+		return r.opts.Timeout
+	}
+	return r.task.Timeout
+}
+
+// requeue applies the task's requeue policy for the given outcome.
+// Without a configured policy the candidate is added to the ignored list.
+func (r *Runner) requeue(candidate *Candidate, outcome Outcome) error {
+	if r.ignoredList == nil {
+		return nil
+	}
+
+	// A trial run leaves ignored.log alone, so the real run attempts everything
+	if r.opts.NoCommit || r.opts.Evaluate > 0 {
+		r.ignoredList.SkipForSession(candidate.Key)
+		return nil
+	}
+
+	policy, explicit := r.task.Requeue[outcome]
+	if !explicit && r.task.Cooldown > 0 {
+		return r.coolDown(candidate)
+	}
+
+	switch policy {
+	case RequeueNextSession:
+		fmt.Println(ColorInfo(fmt.Sprintf("Requeue: %s will be retried next session", outcome)))
+		r.ignoredList.SkipForSession(candidate.Key)
+		return nil
+	case RequeueDoubleTimeout:
+		if r.escalate(candidate, TimeoutEscalation{Multiplier: 2}) {
+			return nil
+		}
+	}
+
+	return r.ignoredList.Add(candidate.Key)
+}
+
+// coolDown puts a failed candidate on cooldown, so it's retried once the
+// task's cooldown has passed. With repeat, the attempt still counts towards
+// the limit, after which the candidate is ignored.
+func (r *Runner) coolDown(candidate *Candidate) error {
+	if r.task.Repeat > 0 {
+		if err := r.ignoredList.Add(candidate.Key); err != nil {
+			return err
+		}
+		if r.ignoredList.Attempts(candidate.Key) >= r.task.Repeat {
+			return nil
+		}
+	}
+	fmt.Println(ColorInfo(fmt.Sprintf("Cooldown: will be retried after %s", r.task.Cooldown)))
+	return r.ignoredList.CoolDown(candidate.Key, r.task.Cooldown)
+}
+
+// escalate schedules a single retry of a timed-out candidate with a scaled timeout
+// and optional model override. Returns false if the candidate was already escalated.
+func (r *Runner) escalate(candidate *Candidate, cfg TimeoutEscalation) bool {
+	if _, escalated := r.escalations[candidate.Key]; escalated {
+		return false
+	}
+
+	esc := escalation{
+		timeout: time.Duration(float64(r.candidateTimeout(candidate)) * cfg.Multiplier),
+		model:   cfg.Model,
+	}
+	r.escalations[candidate.Key] = esc
+
+	msg := fmt.Sprintf("Retrying with timeout %s", esc.timeout)
+	if esc.model != "" {
+		msg += fmt.Sprintf(" and model %s", esc.model)
+	}
+	fmt.Println(ColorInfo(msg))
+	return true
+}
+
+// runSuccessCommand runs an interpolated success command with the configured timeout,
+// retrying transient failures (non-zero exit or timeout) up to success_retries times.
+// A failure after the command committed isn't retried. With push_command set, a commit whose push was rejected is rebased and pushed
+// again instead (recoverPush).
+func (r *Runner) runSuccessCommand(cmd string) (bool, error) {
+	if r.opts.NoCommit {
+		r.printSkipped("success_command", cmd)
+		return true, nil
+	}
+	start := time.Now()
+	defer func() { r.phases.Commit.Add(time.Since(start)) }()
+	retries := r.env.Config.SuccessRetries
+	cmd = r.env.Config.GitEnvPrefix() + cmd
+	before, _ := r.executor.CurrentRevision(r.workDir())
+	for attempt := 0; ; attempt++ {
+		ok, err := r.executor.RunWithTimeout(cmd, r.workDir(), r.env.Config.SuccessTimeout)
+		_, isTimeout := err.(*timeoutError)
+		if ok || (err != nil && !isTimeout) {
+			return ok, err
+		}
+		// Retrying the whole command would fail again on the commit it made
+		if !isTimeout && r.pushRejected(before) {
+			return r.recoverPush()
+		}
+
+		reason := "non-zero exit code"
+		if isTimeout {
+			reason = err.Error()
+		}
+		// Running it again would commit the fix twice or fail on a clean tree
+		if head, err := r.executor.CurrentRevision(r.workDir()); err == nil && head != before {
+			fmt.Println(ColorError(fmt.Sprintf("Success command failed (%s) after committing %s; not running it again. Check the commit and finish what the command does by hand",
+				reason, shortRevision(head))))
+			return false, nil
+		}
+		if attempt >= retries {
+			fmt.Println(ColorWarning(fmt.Sprintf("Success command failed (%s)", reason)))
+			return false, nil
+		}
+		fmt.Println(ColorWarning(fmt.Sprintf("Success command failed (%s), retrying (%d/%d)...", reason, attempt+1, retries)))
+		time.Sleep(successRetryDelay)
+	}
+}
+
+// commitChanges runs the success command for a candidate, or stages the changes
+// as a pending commit when the task batches commits.
+func (r *Runner) commitChanges(candidate *Candidate, outcome Outcome) (bool, error) {
+	if r.task.CommitBatch != 0 && !r.opts.NoCommit {
+		return r.stagePending(candidate, outcome)
+	}
+	successCmd := r.successCommand(candidate, outcome)
+	if outcome == OutcomeBestEffort {
+		// Modify message for best effort
+		successCmd = replaceBestEffort(successCmd, candidate.Key)
+	}
+	before, _ := r.executor.CurrentRevision(r.workDir())
+	ok, err := r.runSuccessCommand(successCmd)
+	if ok && err == nil {
+		r.addTrailers(before, []pendingCommit{{key: candidate.Key, outcome: outcome, session: r.sessionID}})
+		// Recorded in claude.log for export; a failed lookup just leaves it blank
+		r.commit, _ = r.executor.CurrentRevision(r.workDir())
+		r.countCommit(before, r.commit)
+	}
+	return ok, err
+}
+
+// countCommit counts a commit towards --max-commits if success_command moved
+// HEAD from before to after (it may have had nothing to commit, or with
+// --no-commit didn't run).
+func (r *Runner) countCommit(before, after string) {
+	if after != "" && after != before {
+		r.summary.NewCommits++
+	}
+}
+
+// stagePending records the candidate's changes as a temporary commit so later
+// resets don't discard them. The batch is committed once it reaches the configured size.
+func (r *Runner) stagePending(candidate *Candidate, outcome Outcome) (bool, error) {
+	if len(r.pending) == 0 {
+		base, err := r.executor.CurrentRevision(r.workDir())
+		if err != nil {
+			return false, retryableError(ErrCommit, "failed to read current revision: %w", err)
+		}
+		r.batchBase = base
+		r.batchStart = time.Now()
+	}
+
+	stage, err := r.stageCommand()
+	if err != nil {
+		return false, err
+	}
+	stageCmd := r.env.Config.GitEnvPrefix() + stage + " && git commit -q --no-verify -m " + shellQuote("nigel: pending "+candidate.Key)
+	ok, err := r.executor.RunSilent(stageCmd, r.workDir())
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	r.pending = append(r.pending, pendingCommit{key: candidate.Key, outcome: outcome, session: r.sessionID})
+	r.batchTip, _ = r.executor.CurrentRevision(r.workDir())
+	r.trackPending()
+	fmt.Println(ColorInfo(fmt.Sprintf("Staged for batch commit (%d pending)", len(r.pending))))
+
+	// Files left out of the commit would be swept into the batch by a
+	// success_command that stages everything, and make every later attempt
+	// start from a dirty tree
+	if r.task.CommitStage == CommitStageEdited {
+		if left, err := r.executor.HasUncommittedChanges(r.workDir()); err == nil && left {
+			files, _ := r.executor.ChangedFiles(r.workDir())
+			return false, fatalError(ErrCommit, "commit_stage: edited left changes Claude's editing tools didn't write out of the pending commit (%s); remove them or add them to .gitignore",
+				summarizeKeys(files))
+		}
+	}
+
+	if r.task.CommitBatch > 0 && len(r.pending) >= r.task.CommitBatch {
+		if err := r.flushPending(); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// stageCommand returns the command that stages a fix for a pending commit:
+// everything, or with commit_stage: edited, changes to tracked files and the
+// new files Claude's editing tools wrote, so build outputs and editor swap
+// files left in the tree stay out of the commit.
+func (r *Runner) stageCommand() (string, error) {
+	if r.task.CommitStage != CommitStageEdited {
+		return "git add -A", nil
+	}
+	changed, err := r.executor.ChangedFiles(r.workDir())
+	if err != nil {
+		return "", retryableError(ErrCommit, "failed to list changed files: %w", err)
+	}
+	cmd := "git add -u -- :/"
+	var written []string
+	for _, file := range changed {
+		// Ignored files aren't listed, so git add won't refuse them
+		if r.edited[filepath.Clean(file)] {
+			written = append(written, shellQuote(file))
+		}
+	}
+	if len(written) > 0 {
+		cmd += " && git add -- " + strings.Join(written, " ")
+	}
+	return cmd, nil
+}
+
+// flushPending squashes pending commits and runs the success command once with
+// a generated multi-candidate message as $CANDIDATE.
+func (r *Runner) flushPending() error {
+	if len(r.pending) == 0 {
+		return nil
+	}
+
+	// Squashing would take in anything committed on top of the pending commits
+	if head, err := r.executor.CurrentRevision(r.workDir()); err == nil && r.batchTip != "" && head != r.batchTip {
+		return fatalError(ErrCommit, "HEAD moved from %s to %s after the last batched fix; leaving %d fixes as pending commits",
+			shortRevision(r.batchTip), shortRevision(head), len(r.pending))
+	}
+
+	fmt.Println(ColorInfo(fmt.Sprintf("Committing batch of %d candidates...", len(r.pending))))
+	ok, err := r.executor.RunSilent("git reset --soft "+shellQuote(r.batchBase), r.workDir())
+	if err != nil {
+		return retryableError(ErrCommit, "failed to squash pending commits: %w", err)
+	}
+	if !ok {
+		return fatalError(ErrCommit, "failed to squash pending commits")
+	}
+	// The fixes are uncommitted on top of the base until success_command commits them
+	r.batchTip = r.batchBase
+	r.trackPending()
+
+	outcome := OutcomeFixed
+	var message strings.Builder
+	fmt.Fprintf(&message, "%d candidates:", len(r.pending))
+	for _, p := range r.pending {
+		fmt.Fprintf(&message, "\n- %s", p.key)
+		if p.outcome == OutcomeBestEffort {
+			outcome = OutcomeBestEffort
+		}
+	}
+
+	successCmd := InterpolateOutcome(r.env.Config.SuccessCommand, OutcomeVars{
+		Outcome:  outcome,
+		Duration: time.Since(r.batchStart),
+		Attempt:  1,
+	})
+	successCmd = InterpolateCommand(successCmd, &Candidate{Key: message.String()}, r.task.Name)
+	ok, err = r.runSuccessCommand(successCmd)
+	if err != nil {
+		return retryableError(ErrCommit, "batch commit error: %w", err)
+	}
+	if !ok {
+		return fatalError(ErrCommit, "batch commit returned non-zero exit code")
+	}
+	r.addTrailers(r.batchBase, r.pending)
+	head, _ := r.executor.CurrentRevision(r.workDir())
+	r.countCommit(r.batchBase, head)
+
+	fmt.Println(ColorSuccess(fmt.Sprintf("✓ Committed batch of %d candidates", len(r.pending))))
+	r.pending = nil
+	r.trackPending()
+	return nil
+}
+
+// successCommand interpolates the success command for a candidate and outcome.
+func (r *Runner) successCommand(candidate *Candidate, outcome Outcome) string {
+	attempt := 1
+	if r.ignoredList != nil {
+		attempt = r.ignoredList.Attempts(candidate.Key) + 1
+	}
+	cmd := InterpolateOutcome(r.env.Config.SuccessCommand, OutcomeVars{
+		Outcome:    outcome,
+		Duration:   time.Since(r.attemptStart),
+		SessionID:  r.sessionID,
+		Attempt:    attempt,
+		PromptHash: r.promptHash,
+	})
+	return InterpolateCommand(cmd, candidate, r.task.Name)
+}
+
+func (r *Runner) getPrompt(candidate *Candidate) (string, error) {
+	template, err := r.loadTemplate()
+	if err != nil {
+		return "", err
+	}
+	prompt, err := InterpolatePrompt(template, candidate, r.env.TaskID)
+	if err != nil {
+		return "", err
+	}
+	prompt = InterpolateOtherCandidates(prompt, r.others)
+	prompt = strings.ReplaceAll(prompt, "$CANDIDATE_FILE", r.task.candidateFile(candidate))
+	if strings.Contains(template, "$PREVIOUS_ATTEMPTS") {
+		// Substituted last so verify output in the summary is never interpolated
+		previous, err := r.previousAttempts(candidate.Key)
+		if err != nil {
+			return "", err
+		}
+		prompt = strings.ReplaceAll(prompt, "$PREVIOUS_ATTEMPTS", previous)
+	}
+
+	context, err := r.promptContext()
+	if err != nil {
+		return "", err
+	}
+	return prompt + context, nil
+}
+
+// applyPromptLimit enforces the task's prompt_limit, truncating candidate
+// values or summarizing an oversized prompt if configured. Returns a
+// *promptTooLargeError if the prompt is still over the limit.
+func (r *Runner) applyPromptLimit(candidate *Candidate, prompt string) (string, error) {
+	limit := r.task.PromptLimit
+	if limit == nil || len(prompt) <= limit.MaxBytes {
+		return prompt, nil
+	}
+
+	size := len(prompt)
+	switch limit.OnExceed {
+	case PromptLimitTruncate:
+		truncated, err := truncateCandidate(candidate, limit.TruncateFields, limit.MaxLines)
+		if err != nil {
+			return "", retryableError(ErrAgent, "failed to truncate candidate: %w", err)
+		}
+		if prompt, err = r.getPrompt(truncated); err != nil {
+			return "", err
+		}
+		fmt.Println(ColorWarning(fmt.Sprintf("Prompt was %d bytes, truncated candidate values to %d lines (%d bytes)", size, limit.MaxLines, len(prompt))))
+	case PromptLimitSummarize:
+		fmt.Println(ColorInfo(fmt.Sprintf("Prompt is %d bytes, summarizing...", size)))
+		start := time.Now()
+		summary, err := summarizePrompt(limit.SummarizeCommand, prompt, r.workDir())
+		r.audit.record(limit.SummarizeCommand, r.workDir(), start, err == nil, err)
+		if err != nil {
+			return "", retryableError(ErrAgent, "%w", err)
+		}
+		prompt = summary
+	}
+
+	if len(prompt) > limit.MaxBytes {
+		return "", &promptTooLargeError{size: len(prompt), limit: limit.MaxBytes}
+	}
+	return prompt, nil
+}
+
+// handlePromptTooLarge records a candidate whose prompt can't be brought under
+// prompt_limit, without running Claude.
+func (r *Runner) handlePromptTooLarge(candidate *Candidate, tooLarge *promptTooLargeError) (bool, error) {
+	fmt.Println(ColorError(fmt.Sprintf("✗ Candidate %s skipped: %v", displayKey(candidate.Key), tooLarge)))
+	if r.opts.DryRun {
+		return true, nil
+	}
+
+	r.candidate = candidate.Key
+	r.promptHash, r.sessionID, r.verifyError, r.introduced = "", "", "", nil
+	r.usage, r.commit = Usage{}, ""
+	if r.claudeLogger != nil {
+		r.claudeLogger.StartEntry(LogEntry{
+			Candidate: candidate.Key,
+			Prompt:    fmt.Sprintf("(not sent: %d bytes)", tooLarge.size),
+			Variant:   r.variantName(),
+		})
+		r.claudeLogger.EndEntry()
+	}
+	r.logOutcome(OutcomePromptTooLarge, tooLarge.Error())
+	if err := r.requeue(candidate, OutcomePromptTooLarge); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// previousAttempts returns the $PREVIOUS_ATTEMPTS summary for a candidate,
+// reading the task's claude.log the first time it is needed.
+func (r *Runner) previousAttempts(key string) (string, error) {
+	if err := r.loadHistory(); err != nil {
+		return "", err
+	}
+	return FormatPreviousAttempts(r.history[key]), nil
+}
+
+// loadHistory reads the task's prior attempts into r.history, once; logOutcome
+// keeps it up to date after that.
+func (r *Runner) loadHistory() error {
+	if r.history != nil {
+		return nil
+	}
+	attempts, err := ReadTaskAttempts(r.env, r.task)
+	if err != nil {
+		return fmt.Errorf("failed to read attempt history: %w", err)
+	}
+	r.history = make(map[string][]AttemptRecord)
+	for _, a := range attempts {
+		r.history[a.Candidate] = append(r.history[a.Candidate], a)
+	}
+	return nil
+}
+
+// loadTemplate returns the uninterpolated prompt template for the current
+// variant, or the task's own prompt when it has no variants.
+func (r *Runner) loadTemplate() (string, error) {
+	prompt, template := r.task.Prompt, r.task.Template
+	if r.variant != nil {
+		prompt, template = r.variant.Prompt, r.variant.Template
+	}
+	if template == "" {
+		return prompt, nil
+	}
+
+	// Load from template file (relative to task directory)
+	templatePath := filepath.Join(r.task.Dir, template)
+	content, err := LoadTemplate(templatePath)
+	if err != nil {
+		return "", fatalError(ErrAgent, "%w", err)
+	}
+	return content, nil
+}
+
+// runVerify verifies the working tree, re-running a failing verify up to
+// verify_retries times. The first verify of an attempt sets its verify result,
+// which is flaky if it only passed on a re-run.
+func (r *Runner) runVerify() bool {
+	if r.env.Config.VerifyCommand == "" && r.env.Config.ScopedVerifyCommand == "" {
+		return true
+	}
+	retries := r.env.Config.VerifyRetries
+	for retry := 0; ; retry++ {
+		if r.verifyOnce() {
+			if retry > 0 {
+				fmt.Println(ColorWarning(fmt.Sprintf("Verify passed on re-run %d: the verify command looks flaky", retry)))
+				r.verifyError = ""
+				r.setVerifyResult(VerifyFlaky)
+			} else {
+				r.setVerifyResult(VerifyPassed)
+			}
+			return true
+		}
+		if retry >= retries {
+			r.setVerifyResult(VerifyFailed)
+			return false
+		}
+		fmt.Println(ColorWarning(fmt.Sprintf("Verify failed, re-running in case it's flaky (%d/%d)...", retry+1, retries)))
+	}
+}
+
+// setVerifyResult records how the attempt's first verify went.
+func (r *Runner) setVerifyResult(result string) {
+	if r.verifyResult == "" {
+		r.verifyResult = result
+	}
+}
+
+// verifyOnce runs scoped_verify_command, falling back to verify_command.
+func (r *Runner) verifyOnce() bool {
+	if scoped := r.env.Config.ScopedVerifyCommand; scoped != "" {
+		files, err := r.executor.ChangedFiles(r.workDir())
+		if err == nil && len(files) > 0 {
+			if r.verifyWith("Verifying changed files... ", "scoped-verify", InterpolateChangedFiles(scoped, files)) {
+				return true
+			}
+			if r.env.Config.VerifyCommand == "" {
+				return false
+			}
+			// The scoping itself may be what failed (e.g. a deleted file), so
+			// only the full verify can fail the attempt
+			fmt.Println(ColorWarning("Scoped verify failed, falling back to verify_command"))
+		}
+	}
+	if r.env.Config.VerifyCommand == "" {
+		return true
+	}
+	return r.verifyWith("Verifying build... ", "verify", r.env.Config.VerifyCommand)
+}
+
+// verifyWith runs a verify command, saving its output under name and recording
+// an excerpt of it on failure.
+func (r *Runner) verifyWith(label, name, command string) bool {
+	timer := r.startCommandTimer(label, r.phases.Verify)
+	start := time.Now()
+	ok, output, err := r.executor.RunWatched(command, r.workDir(), r.outputWatcher(timer))
+	r.phases.Verify.Add(time.Since(start))
+	timer.stop(false)
+	fmt.Print(ColorInfo(label))
+	if err != nil {
+		fmt.Println(ColorError(fmt.Sprintf("Verify command error: %v", err)))
+		return false
+	}
+	path := r.saveOutput(name, command, output)
+	if ok {
+		fmt.Println(ColorInfo("OK"))
+		return true
+	}
+	fmt.Print(output)
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		fmt.Println()
+	}
+	if path != "" {
+		fmt.Println(ColorDim("Full output: " + path))
+	}
+	r.verifyError = errorExcerpt(output, r.excerptLines())
+	return false
+}
+
+// excerptLines returns how many lines of failing verify output are kept.
+func (r *Runner) excerptLines() int {
+	if r.env.Config.VerifyExcerptLines > 0 {
+		return r.env.Config.VerifyExcerptLines
+	}
+	return defaultExcerptLines
+}
+
+// startCommandTimer shows label with a running timer while a verify or reset
+// command runs, so a long build doesn't look frozen. The caller stops it with
+// stop(false) and prints the label for the result to follow.
+func (r *Runner) startCommandTimer(label string, hint *SessionStats) *ProgressTimer {
+	timer := NewProgressTimer(strings.TrimSpace(label), nil)
+	timer.hint = hint
+	timer.Start()
+	return timer
+}
+
+// outputWatcher returns the callback that shows a command's latest output
+// line on its timer with verify_last_line, or nil without it.
+func (r *Runner) outputWatcher(timer *ProgressTimer) func(string) {
+	if !r.env.Config.VerifyLastLine {
+		return nil
+	}
+	return timer.SetDetail
+}
+
+// runBestEffortCheck runs the task's best_effort_check command, which must pass
+// before partial progress is committed. Passes if no check is configured.
+func (r *Runner) runBestEffortCheck(candidate *Candidate) bool {
+	return r.runGate("best-effort check", r.task.BestEffortCheck, candidate)
+}
+
+// runPreCommitScan runs the configured pre_commit_scan command against the
+// uncommitted changes. Passes if no scan is configured.
+func (r *Runner) runPreCommitScan(candidate *Candidate) bool {
+	return r.runGate("pre-commit scan", r.env.Config.PreCommitScan, candidate)
+}
+
+// runGate runs a command that must pass before changes are committed, showing
+// its output if it fails. Passes if command is empty.
+func (r *Runner) runGate(label, command string, candidate *Candidate) bool {
+	if command == "" {
+		return true
+	}
+	fmt.Print(ColorInfo(fmt.Sprintf("Running %s... ", label)))
+	ok, _, err := r.executor.RunShowOnFail(InterpolateCommand(command, candidate, r.task.Name), r.workDir())
+	if err != nil {
+		fmt.Println(ColorError(fmt.Sprintf("Error running %s: %v", label, err)))
+		return false
+	}
+	if ok {
+		fmt.Println(ColorInfo("OK"))
+	}
+	return ok
+}
+
+func (r *Runner) runReset() bool {
+	if r.env.Config.ResetCommand == "" {
+		return true
+	}
+
+	ok, err := r.runSilentSideEffect("reset_command", r.env.Config.ResetCommand)
+	if err != nil {
+		return false
+	}
+	return ok
+}
+
+// runSilentSideEffect runs a command that changes the repository without
+// printing its output, which is saved under label, or with --no-commit prints
+// the command instead.
+func (r *Runner) runSilentSideEffect(label, command string) (bool, error) {
+	if r.opts.NoCommit {
+		r.printSkipped(label, command)
+		return true, nil
+	}
+	ok, output, err := r.executor.RunWatched(command, r.workDir(), nil)
+	if err == nil {
+		r.saveOutput(label, command, output)
+	}
+	return ok, err
+}
+
+// printSkipped shows a command --no-commit didn't run.
+func (r *Runner) printSkipped(label, command string) {
+	// Resets run under a timer, so this goes through the mediator
+	console().write(ColorWarning("--no-commit, not running "+label+":") + "\n  " + command + "\n")
+}
+
+// captureBaseline records HEAD before Claude runs, so a bad fix can be undone
+// without the reset_command and commits made by someone else in the meantime
+// are noticed before committing (checkDrift).
+func (r *Runner) captureBaseline() {
+	r.baseline, r.cleanBase = "", false
+	if rev, err := r.executor.CurrentRevision(r.workDir()); err == nil {
+		r.baseline = rev
+	}
+	hasChanges, err := r.executor.HasUncommittedChanges(r.workDir())
+	r.cleanBase = err == nil && !hasChanges
+
+	r.dirtyBefore, r.edited = nil, make(map[string]bool)
+	if r.env.Config.ExternalEdits != "" && !r.cleanBase {
+		files, _ := r.executor.ChangedFiles(r.workDir())
+		r.dirtyBefore = make(map[string]bool)
+		for _, file := range files {
+			r.dirtyBefore[file] = true
+		}
+	}
+}
+
+// checkDrift returns a fatal error if HEAD has moved since the attempt started,
+// e.g. another process or a person on a shared machine committed (or Claude did,
+// despite the default disallowed_tools). Committing on top would fold the fix
+// into history nigel didn't verify, and a batched commit_mode would squash the
+// other commits into the batch, so the run stops with the changes uncommitted.
+func (r *Runner) checkDrift() error {
+	if r.baseline == "" {
+		return nil
+	}
+	if len(r.pending) > 0 && r.baseline != r.batchTip {
+		return fatalError(ErrCommit, "HEAD moved from %s to %s between batched fixes; leaving the changes uncommitted",
+			shortRevision(r.batchTip), shortRevision(r.baseline))
+	}
+	head, err := r.executor.CurrentRevision(r.workDir())
+	if err != nil {
+		return retryableError(ErrCommit, "failed to read current revision: %w", err)
+	}
+	if head != r.baseline {
+		return fatalError(ErrCommit, "HEAD moved from %s to %s during the attempt (another process committed?); leaving the changes uncommitted",
+			shortRevision(r.baseline), shortRevision(head))
+	}
+	return nil
+}
+
+// treeUnchanged reports whether the working tree still has the fingerprint it
+// had when the build was verified.
+func (r *Runner) treeUnchanged(fingerprint string) bool {
+	current, err := r.executor.TreeFingerprint(r.workDir())
+	return err == nil && current == fingerprint
+}
+
+// revertToBaseline undoes the current candidate's changes in the work
+// directory by restoring it from the baseline and removing new untracked
+// files. Unlike a reset_command such as `git reset --hard && git clean -fdx`,
+// ignored files (build caches, local config), ignore_dirty_paths and the rest
+// of the repository survive. Falls back to the reset_command if the tree
+// wasn't clean at baseline, or HEAD has moved since and restoring would
+// discard someone else's commits.
+func (r *Runner) revertToBaseline() bool {
+	if r.baseline == "" || !r.cleanBase {
+		return r.runReset()
+	}
+	if head, err := r.executor.CurrentRevision(r.workDir()); err != nil || head != r.baseline {
+		return r.runReset()
+	}
+	fmt.Println(ColorInfo(fmt.Sprintf("Reverting to %s...", shortRevision(r.baseline))))
+	pathspec := "-- ."
+	for _, path := range r.env.Config.IgnoreDirtyPaths {
+		pathspec += " " + shellQuote(":(top,exclude)"+path)
+	}
+	cmd := "git restore -q --source=" + shellQuote(r.baseline) + " --staged --worktree " + pathspec +
+		" && git clean -fdq " + pathspec
+	ok, err := r.runSilentSideEffect("revert", cmd)
+	return err == nil && ok
+}
+
+// shortRevision abbreviates a commit hash for display.
+func shortRevision(rev string) string {
+	if len(rev) > 12 {
+		return rev[:12]
+	}
+	return rev
+}
+
+func (r *Runner) runResetAndVerify() bool {
+	const label = "Resetting changes and verifying build..."
+	timer := r.startCommandTimer(label, nil)
+	var err error
+	ok := r.runReset()
+	if ok && r.env.Config.VerifyCommand != "" {
+		var output string
+		start := time.Now()
+		ok, output, err = r.executor.RunWatched(r.env.Config.VerifyCommand, r.workDir(), r.outputWatcher(timer))
+		r.phases.Verify.Add(time.Since(start))
+		if err == nil {
+			r.saveOutput("reset-verify", r.env.Config.VerifyCommand, output)
+		}
+	}
+	timer.stop(false)
+	fmt.Print(ColorInfo(label))
+
+	if err != nil || !ok {
+		fmt.Println(ColorError(" FAILED"))
+		return false
+	}
+
+	fmt.Println(ColorInfo(" OK"))
+	return true
+}
+
+func (r *Runner) runStartupReset() error {
+	fmt.Println(ColorInfo("Resetting environment to clean state..."))
+
+	if r.env.Config.ResetCommand == "" {
+		// No reset command configured - check if there are uncommitted changes
+		hasChanges, err := r.executor.HasUncommittedChanges(r.workDir())
+		if err != nil {
+			return fmt.Errorf("failed to check git status: %w", err)
+		}
+		if hasChanges {
+			return fmt.Errorf("working directory has uncommitted changes but no reset_command configured")
+		}
+		fmt.Println(ColorInfo("No reset_command configured, working directory is clean"))
+		return nil
+	}
+
+	// Run reset command
+	ok, err := r.runSilentSideEffect("reset_command", r.env.Config.ResetCommand)
+	if err != nil {
+		return fmt.Errorf("reset command error: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("reset command failed")
+	}
+
+	// Verify build after reset
+	if r.env.Config.VerifyCommand != "" {
+		var output string
+		ok, output, err = r.executor.RunWatched(r.env.Config.VerifyCommand, r.workDir(), nil)
+		if err == nil {
+			r.saveOutput("reset-verify", r.env.Config.VerifyCommand, output)
+		}
+		if err != nil || !ok {
+			return fmt.Errorf("build verification failed after reset")
+		}
+	}
+
+	fmt.Println(ColorSuccess("✓ Environment reset complete"))
+	return nil
+}
+
+func (r *Runner) modeString() string {
+	if r.opts.DryRun {
+		return "dry-run"
+	}
+	if r.opts.NoCommit {
+		return "no-commit"
+	}
+	if r.opts.Evaluate > 0 {
+		return "evaluate"
+	}
+	if r.task.AcceptBestEffort {
+		return "best-effort"
+	}
+	return "standard"
+}
+
+// variantName returns the current prompt variant's name, or "" without variants.
+func (r *Runner) variantName() string {
+	if r.variant == nil {
+		return ""
+	}
+	return r.variant.Name
+}
+
+func (r *Runner) logOutcome(outcome Outcome, details string) {
+	r.summary.Outcomes[outcome]++
+	r.summary.CostUSD += r.usage.CostUSD
+	if r.commit != "" {
+		r.summary.Commits = append(r.summary.Commits, r.commit)
+	}
+	if len(r.introduced) > 0 {
+		details += fmt.Sprintf(" (introduced %d new candidate(s): %s)", len(r.introduced), summarizeKeys(r.introduced))
+	}
+	if r.verifyError != "" && (outcome == OutcomeFixedReverted || outcome == OutcomeBuildFailed) {
+		details += ": " + r.verifyError
+	}
+	attempt := AttemptRecord{
+		Outcome:     outcome,
+		Candidate:   r.candidate,
+		PromptHash:  r.promptHash,
+		Variant:     r.variantName(),
+		SessionID:   r.sessionID,
+		VerifyError: r.verifyError,
+		Verify:      r.verifyResult,
+		Stalled:     r.stalled,
+		Usage:       r.usage,
+		Commit:      r.commit,
+		Duration:    time.Since(r.attemptStart),
+		Details:     details,
+	}
+	if r.history != nil {
+		r.history[r.candidate] = append(r.history[r.candidate], attempt)
+	}
+	r.observers.OnOutcome(r.task.Name, attempt)
+}
+
+// summarizeKeys joins candidate keys for display, listing at most five.
+func summarizeKeys(keys []string) string {
+	const max = 5
+	shown := make([]string, 0, max)
+	for i := 0; i < len(keys) && i < max; i++ {
+		shown = append(shown, displayKey(keys[i]))
+	}
+	if len(keys) <= max {
+		return strings.Join(shown, ", ")
+	}
+	return fmt.Sprintf("%s, and %d more", strings.Join(shown, ", "), len(keys)-max)
+}
+
+// otherPendingKeys returns the keys of candidates that are neither ignored nor
+// the selected one, in candidate source order.
+func otherPendingKeys(candidates []Candidate, selected string, ignored *IgnoredList) []string {
+	var keys []string
+	for _, c := range candidates {
+		if c.Key != selected && (ignored == nil || !ignored.Contains(c.Key)) {
+			keys = append(keys, c.Key)
+		}
+	}
+	return keys
+}
+
+func containsKey(candidates []Candidate, key string) bool {
+	for _, c := range candidates {
+		if c.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceBestEffort modifies the success command for best effort mode.
+// If the command contains "fix", replace with "best effort".
+func replaceBestEffort(cmd, candidate string) string {
+	// Simple heuristic: if it says "fix $CANDIDATE", change to "best effort $CANDIDATE"
+	// This handles the common case of commit messages
+	return cmd
+}
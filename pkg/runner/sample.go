@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// sampleSize returns how many of n candidates --sample or --sample-percent
+// keep, or n without either.
+func (o RunnerOptions) sampleSize(n int) int {
+	size := n
+	if o.Sample > 0 {
+		size = o.Sample
+	} else if o.SamplePercent > 0 {
+		size = int(math.Ceil(float64(n) * o.SamplePercent / 100))
+	}
+	if size > n {
+		return n
+	}
+	return size
+}
+
+// drawSample picks size of the keys at random.
+func drawSample(keys []string, size int, rng *rand.Rand) map[string]bool {
+	sample := make(map[string]bool, size)
+	for _, i := range rng.Perm(len(keys))[:size] {
+		sample[keys[i]] = true
+	}
+	return sample
+}
+
+// applySample narrows candidates to the run's random sample with --sample or
+// --sample-percent. The sample is drawn from the candidates not yet ignored
+// the first time they're listed and kept for the rest of the run, so
+// candidates that show up later (say, introduced by a fix) aren't attempted.
+func (r *Runner) applySample(candidates []Candidate) []Candidate {
+	if r.opts.Sample == 0 && r.opts.SamplePercent == 0 {
+		return candidates
+	}
+	if r.sample == nil {
+		var keys []string
+		for _, c := range candidates {
+			if r.ignoredList == nil || !r.ignoredList.Contains(c.Key) {
+				keys = append(keys, c.Key)
+			}
+		}
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		r.sample = drawSample(keys, r.opts.sampleSize(len(keys)), rng)
+		fmt.Println(ColorInfo(fmt.Sprintf("Sampled %d of %d candidates for this run", len(r.sample), len(keys))))
+	}
+
+	var sampled []Candidate
+	for _, c := range candidates {
+		if r.sample[c.Key] {
+			sampled = append(sampled, c)
+		}
+	}
+	return sampled
+}
@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestSampleSize(t *testing.T) {
+	tests := []struct {
+		opts RunnerOptions
+		n    int
+		want int
+	}{
+		{RunnerOptions{}, 3000, 3000},
+		{RunnerOptions{Sample: 20}, 3000, 20},
+		{RunnerOptions{Sample: 20}, 5, 5},
+		{RunnerOptions{SamplePercent: 10}, 3000, 300},
+		{RunnerOptions{SamplePercent: 10}, 5, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.sampleSize(tt.n); got != tt.want {
+			t.Errorf("%+v.sampleSize(%d) = %d, want %d", tt.opts, tt.n, got, tt.want)
+		}
+	}
+
+	sample := drawSample([]string{"a", "b", "c", "d"}, 2, rand.New(rand.NewSource(1)))
+	if len(sample) != 2 {
+		t.Errorf("drawSample = %v, want 2 keys", sample)
+	}
+}
+
+func TestApplySample(t *testing.T) {
+	tmpDir := t.TempDir()
+	env := &Environment{
+		ProjectDir: tmpDir,
+		Tasks: map[string]Task{
+			"test-task": {Name: "test-task", Dir: tmpDir, Prompt: "test prompt"},
+		},
+	}
+	runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true, Sample: 3})
+	if err != nil {
+		t.Fatalf("NewRunner failed: %v", err)
+	}
+	runner.ignoredList.Add("c0")
+
+	var candidates []Candidate
+	for i := 0; i < 10; i++ {
+		candidates = append(candidates, Candidate{Key: fmt.Sprintf("c%d", i)})
+	}
+	first := runner.applySample(candidates)
+	if len(first) != 3 {
+		t.Fatalf("sampled %d candidates, want 3", len(first))
+	}
+	for _, c := range first {
+		if c.Key == "c0" {
+			t.Error("sampled an ignored candidate")
+		}
+	}
+
+	// Later listings keep the same sample and drop newcomers
+	again := runner.applySample(append(candidates, Candidate{Key: "new"}))
+	if fmt.Sprint(again) != fmt.Sprint(first) {
+		t.Errorf("second listing sampled %v, want %v", again, first)
+	}
+}
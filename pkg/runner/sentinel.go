@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Files in a task's directory that control a run from another shell, e.g.
+// over SSH without the terminal the run started in.
+const (
+	stopFile  = "STOP"  // Stop before the next iteration; removed when honored
+	pauseFile = "PAUSE" // Hold before the next iteration until removed
+)
+
+// sentinelInterval is how often a paused run checks whether PAUSE is gone.
+var sentinelInterval = 5 * time.Second
+
+// checkSentinels looks for STOP and PAUSE in the task directory between
+// iterations, waiting while PAUSE exists. Returns true if the run should
+// stop. STOP is removed so the next run doesn't stop straight away.
+func (r *Runner) checkSentinels() bool {
+	stop := filepath.Join(r.task.Dir, stopFile)
+	pause := filepath.Join(r.task.Dir, pauseFile)
+	if exists(pause) && !exists(stop) {
+		fmt.Println(ColorWarning(fmt.Sprintf("Paused: found %s. Remove it to resume, or create %s to stop...",
+			relativePath(pause), stopFile)))
+		for exists(pause) && !exists(stop) && !r.stopRequested.Load() {
+			time.Sleep(sentinelInterval)
+		}
+		if !exists(stop) && !r.stopRequested.Load() {
+			fmt.Println(ColorInfo("Resuming."))
+		}
+	}
+	if !exists(stop) {
+		return false
+	}
+	if err := os.Remove(stop); err != nil {
+		fmt.Println(ColorWarning(fmt.Sprintf("Failed to remove %s: %v", relativePath(stop), err)))
+	}
+	fmt.Printf("Found %s, stopping.\n", relativePath(stop))
+	return true
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckSentinels(t *testing.T) {
+	defer func(interval time.Duration) { sentinelInterval = interval }(sentinelInterval)
+	sentinelInterval = time.Millisecond
+	tmpDir := t.TempDir()
+	env := &Environment{
+		ProjectDir: tmpDir,
+		Tasks: map[string]Task{
+			"test-task": {Name: "test-task", Dir: tmpDir, Prompt: "test prompt"},
+		},
+	}
+	runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("NewRunner failed: %v", err)
+	}
+
+	if runner.checkSentinels() {
+		t.Error("stopped without a STOP file")
+	}
+
+	stop := filepath.Join(tmpDir, stopFile)
+	os.WriteFile(stop, nil, 0644)
+	if !runner.checkSentinels() {
+		t.Error("expected STOP to stop the run")
+	}
+	if exists(stop) {
+		t.Error("STOP should be removed once honored")
+	}
+
+	// PAUSE holds the run until it's removed
+	pause := filepath.Join(tmpDir, pauseFile)
+	os.WriteFile(pause, nil, 0644)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		os.Remove(pause)
+	}()
+	start := time.Now()
+	if runner.checkSentinels() {
+		t.Error("stopped after PAUSE was removed")
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Error("expected PAUSE to hold the run")
+	}
+
+	// STOP ends a pause
+	os.WriteFile(pause, nil, 0644)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		os.WriteFile(stop, nil, 0644)
+	}()
+	if !runner.checkSentinels() {
+		t.Error("expected STOP to end the pause and stop the run")
+	}
+}
@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ShardAssignment is nigel/shards.yaml: the shard each machine in a fleet
+// works on, so every machine can run the same command without --shard.
+type ShardAssignment struct {
+	Total int            `yaml:"total"` // Number of shards (default: the number of hosts)
+	Hosts map[string]int `yaml:"hosts"` // Hostname to shard index, 1 to total
+}
+
+// loadShardAssignment reads shards.yaml from the runner directory, returning
+// nil if there isn't one.
+func loadShardAssignment(runnerDir string) (*ShardAssignment, error) {
+	data, err := os.ReadFile(filepath.Join(runnerDir, "shards.yaml"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var shards ShardAssignment
+	if err := yaml.Unmarshal(data, &shards); err != nil {
+		return nil, fmt.Errorf("failed to parse shards.yaml: %w", err)
+	}
+	if shards.Total == 0 {
+		shards.Total = len(shards.Hosts)
+	}
+	if err := shards.validate(); err != nil {
+		return nil, fmt.Errorf("invalid shards.yaml: %w", err)
+	}
+	return &shards, nil
+}
+
+func (a *ShardAssignment) validate() error {
+	if len(a.Hosts) == 0 {
+		return fmt.Errorf("no hosts listed")
+	}
+	hosts := make([]string, 0, len(a.Hosts))
+	for host := range a.Hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	owner := make(map[int]string)
+	for _, host := range hosts {
+		index := a.Hosts[host]
+		if index < 1 || index > a.Total {
+			return fmt.Errorf("host %s has shard %d, want 1 to %d", host, index, a.Total)
+		}
+		if other, ok := owner[index]; ok {
+			return fmt.Errorf("hosts %s and %s both have shard %d", other, host, index)
+		}
+		owner[index] = host
+	}
+	return nil
+}
+
+// Partition returns the partition for hostname, matching it in full or by its
+// short name (up to the first dot).
+func (a *ShardAssignment) Partition(hostname string) (HashPartition, error) {
+	index, ok := a.Hosts[hostname]
+	if !ok {
+		index, ok = a.Hosts[strings.SplitN(hostname, ".", 2)[0]]
+	}
+	if !ok {
+		return HashPartition{}, fmt.Errorf("host %s is not in shards.yaml; add it or pass --shard", hostname)
+	}
+	return HashPartition{WorkerCount: a.Total, WorkerIndex: index - 1}, nil
+}
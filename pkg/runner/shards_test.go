@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadShardAssignment(t *testing.T) {
+	dir := t.TempDir()
+	if shards, err := loadShardAssignment(dir); shards != nil || err != nil {
+		t.Fatalf("without shards.yaml = %v, %v; want nil, nil", shards, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "shards.yaml"), []byte("hosts:\n  build-01: 1\n  build-02: 2\n  build-03: 3\n"), 0644)
+	shards, err := loadShardAssignment(dir)
+	if err != nil {
+		t.Fatalf("loadShardAssignment failed: %v", err)
+	}
+
+	partition, err := shards.Partition("build-02.ci.example.com")
+	if err != nil {
+		t.Fatalf("Partition failed: %v", err)
+	}
+	if partition != (HashPartition{WorkerCount: 3, WorkerIndex: 1}) {
+		t.Errorf("Partition = %+v, want shard 2 of 3", partition)
+	}
+	if _, err := shards.Partition("laptop"); err == nil || !strings.Contains(err.Error(), "laptop") {
+		t.Errorf("Partition(unlisted) error = %v, want one naming the host", err)
+	}
+}
+
+func TestShardAssignmentValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		shards ShardAssignment
+		err    string
+	}{
+		{"no hosts", ShardAssignment{Total: 2}, "no hosts"},
+		{"out of range", ShardAssignment{Total: 2, Hosts: map[string]int{"a": 3}}, "want 1 to 2"},
+		{"shared shard", ShardAssignment{Total: 2, Hosts: map[string]int{"a": 1, "b": 1}}, "both have shard 1"},
+	}
+	for _, tt := range tests {
+		if err := tt.shards.validate(); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: validate() = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+	spare := ShardAssignment{Total: 4, Hosts: map[string]int{"a": 1, "b": 4}}
+	if err := spare.validate(); err != nil {
+		t.Errorf("shards without a host should be allowed, got %v", err)
+	}
+}
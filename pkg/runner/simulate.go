@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ShardLoad is the share of a task's logged attempts one worker would have
+// made under a different --shard total.
+type ShardLoad struct {
+	Attempts   int
+	Fixed      int
+	Duration   time.Duration // Time the attempts took, back to back
+	Candidates []string      // Distinct candidates in the order the worker would have attempted them
+}
+
+// SimulateSharding replays logged attempts, oldest first, against a split
+// across workers. A worker takes its candidates in the same relative order the
+// logged runs did, since sharding filters the candidate list without
+// reordering it.
+func SimulateSharding(attempts []AttemptRecord, workers int) []ShardLoad {
+	shards := make([]ShardLoad, workers)
+	seen := make(map[string]bool)
+	for _, a := range attempts {
+		s := &shards[shardOf(a.Candidate, workers)]
+		s.Attempts++
+		if a.Outcome == OutcomeFixed {
+			s.Fixed++
+		}
+		s.Duration += a.Duration
+		if !seen[a.Candidate] {
+			seen[a.Candidate] = true
+			s.Candidates = append(s.Candidates, a.Candidate)
+		}
+	}
+	return shards
+}
+
+// FormatSimulation renders the per-worker split of attempts, and how long the
+// slowest worker would have taken compared with the attempts run one after
+// another.
+func FormatSimulation(attempts []AttemptRecord, shards []ShardLoad) string {
+	var total time.Duration
+	for _, a := range attempts {
+		total += a.Duration
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "  %-7s %8s %10s %7s %10s  %s\n", "Shard", "Attempts", "Candidates", "Fixed", "Duration", "First candidates")
+	var longest time.Duration
+	for i, s := range shards {
+		fmt.Fprintf(&b, "  %-7s %8d %10d %7d %10s  %s\n",
+			fmt.Sprintf("%d/%d", i+1, len(shards)), s.Attempts, len(s.Candidates), s.Fixed, formatDuration(s.Duration), summarizeKeys(s.Candidates))
+		if s.Duration > longest {
+			longest = s.Duration
+		}
+	}
+	if longest > 0 {
+		fmt.Fprintf(&b, "\nSlowest shard: %s, against %s back to back (%.1fx faster)\n",
+			formatDuration(longest), formatDuration(total), float64(total)/float64(longest))
+	}
+	return b.String()
+}
+
+// Simulate prints how a task's logged attempts would have been split across
+// workers with --shard I/<workers>, for checking the balance before
+// re-sharding. Only the attempts in claude.log are replayed: candidates that
+// were never attempted aren't recorded anywhere.
+func Simulate(env *Environment, taskName string, workers int) error {
+	task, ok := env.Tasks[taskName]
+	if !ok {
+		return fmt.Errorf("task not found: %s", taskName)
+	}
+	if workers < 1 {
+		return fmt.Errorf("--shards must be at least 1")
+	}
+
+	attempts, err := ReadTaskAttempts(env, task)
+	if err != nil {
+		return err
+	}
+	if len(attempts) == 0 {
+		fmt.Printf("No attempts recorded for %s.\n", taskName)
+		return nil
+	}
+
+	fmt.Println(ColorBold(fmt.Sprintf("%s split across %d shard(s) (%d attempts)", taskName, workers, len(attempts))))
+	fmt.Print(FormatSimulation(attempts, SimulateSharding(attempts, workers)))
+	return nil
+}
@@ -0,0 +1,43 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimulateSharding(t *testing.T) {
+	attempts := []AttemptRecord{
+		{Candidate: "a", Outcome: OutcomeNotFixed, Duration: time.Minute},
+		{Candidate: "b", Outcome: OutcomeFixed, Duration: 2 * time.Minute},
+		{Candidate: "c", Outcome: OutcomeFixed, Duration: time.Minute},
+		{Candidate: "a", Outcome: OutcomeFixed, Duration: time.Minute},
+	}
+
+	shards := SimulateSharding(attempts, 2)
+	total := 0
+	for i, s := range shards {
+		total += s.Attempts
+		for _, key := range s.Candidates {
+			if shardOf(key, 2) != i {
+				t.Errorf("candidate %s placed in shard %d, FilterByPartition puts it in %d", key, i, shardOf(key, 2))
+			}
+		}
+	}
+	if total != len(attempts) {
+		t.Errorf("shards hold %d attempts, want %d", total, len(attempts))
+	}
+
+	a := shards[shardOf("a", 2)]
+	if a.Candidates[0] != "a" || a.Attempts < 2 {
+		t.Errorf("shard with a = %+v, want a first and both its attempts", a)
+	}
+
+	one := SimulateSharding(attempts, 1)
+	if one[0].Fixed != 3 || one[0].Duration != 5*time.Minute || strings.Join(one[0].Candidates, ",") != "a,b,c" {
+		t.Errorf("single shard = %+v, want every attempt in logged order", one[0])
+	}
+	if out := FormatSimulation(attempts, one); !strings.Contains(out, "1.0x faster") {
+		t.Errorf("single shard should be no faster, got:\n%s", out)
+	}
+}
@@ -0,0 +1,47 @@
+package runner
+
+import "fmt"
+
+// `source_check:` settings for what to do when the candidate source lists
+// different candidates on two runs against the same tree.
+const (
+	SourceCheckWarn   = "warn"   // Print the difference and carry on
+	SourceCheckRefuse = "refuse" // Stop the run
+)
+
+// checkSourceDeterminism runs the candidate source a second time, once per
+// run, and compares its candidates with the first run's. Order and duplicates
+// don't matter; a candidate missing from one run would look fixed (or
+// introduced) after an attempt that did nothing about it.
+func (r *Runner) checkSourceDeterminism(first []Candidate) error {
+	fmt.Println(ColorInfo("Running the candidate source again to check it's deterministic..."))
+	output, err := r.execCandidateSource()
+	if err != nil {
+		return candidateSourceError("candidate source check run failed", err)
+	}
+	second, _, err := ParseTaskCandidates(output, r.task)
+	if err != nil {
+		return candidateParseError("failed to parse candidates", err)
+	}
+	r.sourceChecked = true
+	second, _ = DedupeCandidates(second)
+
+	dropped := IntroducedCandidates(second, first)
+	added := IntroducedCandidates(first, second)
+	if len(dropped) == 0 && len(added) == 0 {
+		return nil
+	}
+	msg := fmt.Sprintf("The candidate source isn't deterministic: of %d candidates, a second run dropped %d and added %d",
+		len(first), len(dropped), len(added))
+	if len(dropped) > 0 {
+		msg += "\n  dropped: " + summarizeKeys(dropped)
+	}
+	if len(added) > 0 {
+		msg += "\n  added: " + summarizeKeys(added)
+	}
+	if r.task.SourceCheck == SourceCheckRefuse {
+		return fatalError(ErrCandidateSource, "%s", msg)
+	}
+	fmt.Println(ColorWarning(msg + "\nFixed and not-fixed outcomes may be wrong (see zero_diff_fix)"))
+	return nil
+}
@@ -0,0 +1,60 @@
+package runner
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckSourceDeterminism(t *testing.T) {
+	// Lists a and b on the first run, b and c after
+	flaky := `if [ -f ran ]; then echo '["c", "b"]'; else touch ran; echo '["a", "b"]'; fi`
+	stable := `echo '["b", "a", "a"]'`
+	tests := []struct {
+		name    string
+		source  string
+		check   string
+		wantErr bool
+	}{
+		{"stable source", stable, SourceCheckRefuse, false},
+		{"warn", flaky, SourceCheckWarn, false},
+		{"refuse", flaky, SourceCheckRefuse, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			env := &Environment{
+				ProjectDir: tmpDir,
+				Tasks: map[string]Task{
+					"test-task": {Name: "test-task", Dir: tmpDir, Prompt: "test prompt", CandidateSource: tt.source, SourceCheck: tt.check},
+				},
+			}
+			runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
+			if err != nil {
+				t.Fatalf("NewRunner failed: %v", err)
+			}
+			runner.setExecutor(NewMockCommandExecutor())
+
+			output, err := runner.execCandidateSource()
+			if err != nil {
+				t.Fatal(err)
+			}
+			first, _, _ := ParseTaskCandidates(output, runner.task)
+			first, _ = DedupeCandidates(first)
+			err = runner.checkSourceDeterminism(first)
+			if tt.wantErr {
+				if !errors.Is(err, ErrCandidateSource) || isRetryable(err) {
+					t.Fatalf("expected a fatal candidate source error, got %v", err)
+				}
+				if !strings.Contains(err.Error(), "dropped 1 and added 1") {
+					t.Errorf("error = %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("checkSourceDeterminism failed: %v", err)
+			}
+			if !runner.sourceChecked {
+				t.Error("expected the check to run once per run")
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// variantKey groups attempts made with the same variant and prompt revision.
+type variantKey struct {
+	variant    string
+	promptHash string
+}
+
+// FormatVariantStats renders a comparison table of outcomes per prompt
+// variant and prompt hash, so edits to a variant show up as separate rows.
+func FormatVariantStats(attempts []AttemptRecord) string {
+	groups := make(map[variantKey]RunSummary)
+	for _, a := range attempts {
+		key := variantKey{variant: a.Variant, promptHash: a.PromptHash}
+		s, ok := groups[key]
+		if !ok {
+			s = RunSummary{Outcomes: make(map[Outcome]int)}
+		}
+		s.Iterations++
+		s.Outcomes[a.Outcome]++
+		groups[key] = s
+	}
+
+	keys := make([]variantKey, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].variant != keys[j].variant {
+			return keys[i].variant < keys[j].variant
+		}
+		return keys[i].promptHash < keys[j].promptHash
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "  %-20s %-12s %8s %7s %11s %7s %8s\n", "Variant", "Prompt hash", "Attempts", "Fixed", "Best effort", "Failed", "Fix rate")
+	for _, key := range keys {
+		s := groups[key]
+		variant, hash := key.variant, key.promptHash
+		if variant == "" {
+			variant = "-"
+		}
+		if hash == "" {
+			hash = "-"
+		}
+		rate := float64(s.Fixed()) / float64(s.Iterations) * 100
+		fmt.Fprintf(&b, "  %-20s %-12s %8d %7d %11d %7d %7.1f%%\n",
+			variant, hash, s.Iterations, s.Fixed(), s.BestEffort(), s.Failed(), rate)
+	}
+	return b.String()
+}
+
+// FormatVerifyStats renders how often verify only passed on a verify_retries
+// re-run, out of the attempts that recorded a verify result. It's empty if
+// none did (there's no verify command, or the logs predate the result).
+func FormatVerifyStats(attempts []AttemptRecord) string {
+	verified, flaky := 0, 0
+	for _, a := range attempts {
+		switch a.Verify {
+		case VerifyFlaky:
+			flaky++
+			verified++
+		case VerifyPassed, VerifyFailed:
+			verified++
+		}
+	}
+	if verified == 0 {
+		return ""
+	}
+	return fmt.Sprintf("  Flaky verify: %d of %d verified attempts (%.1f%%) only passed on a re-run\n",
+		flaky, verified, float64(flaky)/float64(verified)*100)
+}
+
+// FormatStallStats renders how many attempts Claude stalled on and was
+// stopped by inactivity_timeout, a sign of an unhealthy backend. It's empty if
+// none did.
+func FormatStallStats(attempts []AttemptRecord) string {
+	stalled, fixed := 0, 0
+	for _, a := range attempts {
+		if a.Stalled {
+			stalled++
+			if a.Outcome == OutcomeFixed || a.Outcome == OutcomeBestEffort {
+				fixed++
+			}
+		}
+	}
+	if stalled == 0 {
+		return ""
+	}
+	return fmt.Sprintf("  Stalls: %d of %d attempts (%.1f%%) stopped by inactivity_timeout, %d salvaged as fixed or best effort\n",
+		stalled, len(attempts), float64(stalled)/float64(len(attempts))*100, fixed)
+}
+
+// ruleStats totals the attempts on one category of candidates.
+type ruleStats struct {
+	rule     string
+	attempts int
+	fixed    int
+	costUSD  float64
+}
+
+// FormatRuleStats renders attempts, fix rate and cost per category of
+// candidate, taking the category from a map key or array index of each
+// candidate like candidate_families. Most expensive categories come first, so
+// the ones that cost a lot for little come to the top. Candidates without
+// the field are grouped under "-".
+func FormatRuleStats(attempts []AttemptRecord, field string) string {
+	f := &CandidateFamilies{Field: field}
+	groups := make(map[string]*ruleStats)
+	for _, a := range attempts {
+		rule := f.family(candidateFromKey(a.Candidate))
+		if rule == "" {
+			rule = "-"
+		}
+		s, ok := groups[rule]
+		if !ok {
+			s = &ruleStats{rule: rule}
+			groups[rule] = s
+		}
+		s.attempts++
+		if a.Outcome == OutcomeFixed {
+			s.fixed++
+		}
+		s.costUSD += a.Usage.CostUSD
+	}
+
+	rules := make([]*ruleStats, 0, len(groups))
+	for _, s := range groups {
+		rules = append(rules, s)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].costUSD != rules[j].costUSD {
+			return rules[i].costUSD > rules[j].costUSD
+		}
+		return rules[i].rule < rules[j].rule
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "  %-30s %8s %7s %8s %10s %12s\n", "Rule", "Attempts", "Fixed", "Fix rate", "Cost", "Cost per fix")
+	for _, s := range rules {
+		perFix := "-"
+		if s.fixed > 0 {
+			perFix = fmt.Sprintf("$%.2f", s.costUSD/float64(s.fixed))
+		}
+		rate := float64(s.fixed) / float64(s.attempts) * 100
+		fmt.Fprintf(&b, "  %-30s %8d %7d %7.1f%% %10s %12s\n",
+			s.rule, s.attempts, s.fixed, rate, fmt.Sprintf("$%.2f", s.costUSD), perFix)
+	}
+	return b.String()
+}
+
+// FormatDurationStats renders the spread of Claude's reported run times across
+// attempts, for tuning the task timeout. Attempts without a reported time are
+// left out.
+func FormatDurationStats(attempts []AttemptRecord) string {
+	stats := NewSessionStats()
+	for _, a := range attempts {
+		if a.Usage.APIDuration > 0 {
+			stats.Add(a.Usage.APIDuration)
+		}
+	}
+	dist, ok := stats.Distribution()
+	if !ok {
+		return "  No Claude durations recorded.\n"
+	}
+	return fmt.Sprintf("  Claude time: %s\n", dist) + stats.Histogram(durationHistogramBuckets)
+}
+
+// durationHistogramBuckets is the number of rows in `stats --durations`.
+const durationHistogramBuckets = 10
+
+// ShowStats prints the per-variant outcome comparison for a task, or with
+// byRule, the cost and fix rate per value of that candidate field, or with
+// durations, the spread of Claude's run times.
+func ShowStats(env *Environment, taskName, byRule string, durations bool) error {
+	task, ok := env.Tasks[taskName]
+	if !ok {
+		return fmt.Errorf("task not found: %s", taskName)
+	}
+
+	attempts, err := ReadTaskAttempts(env, task)
+	if err != nil {
+		return err
+	}
+	if len(attempts) == 0 {
+		fmt.Printf("No attempts recorded for %s.\n", taskName)
+		return nil
+	}
+
+	fmt.Println(ColorBold(fmt.Sprintf("Stats for %s (%d attempts)", taskName, len(attempts))))
+	if byRule != "" {
+		fmt.Print(FormatRuleStats(attempts, byRule))
+		return nil
+	}
+	if durations {
+		fmt.Print(FormatDurationStats(attempts))
+		return nil
+	}
+	fmt.Print(FormatVariantStats(attempts))
+	fmt.Print(FormatVerifyStats(attempts))
+	fmt.Print(FormatStallStats(attempts))
+	return nil
+}
@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatVariantStats(t *testing.T) {
+	attempts := []AttemptRecord{
+		{Outcome: OutcomeFixed, PromptHash: "aaa", Variant: "terse"},
+		{Outcome: OutcomeNotFixed, PromptHash: "aaa", Variant: "terse"},
+		{Outcome: OutcomeFixed, PromptHash: "bbb", Variant: "verbose"},
+	}
+
+	result := FormatVariantStats(attempts)
+	lines := strings.Split(strings.TrimSpace(result), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got:\n%s", result)
+	}
+	if !strings.Contains(lines[1], "terse") || !strings.Contains(lines[1], "50.0%") {
+		t.Errorf("terse row = %q, want 50.0%% fix rate", lines[1])
+	}
+	if !strings.Contains(lines[2], "verbose") || !strings.Contains(lines[2], "100.0%") {
+		t.Errorf("verbose row = %q, want 100.0%% fix rate", lines[2])
+	}
+}
+
+func TestFormatVerifyStats(t *testing.T) {
+	if got := FormatVerifyStats([]AttemptRecord{{Outcome: OutcomeNotFixed}}); got != "" {
+		t.Errorf("expected nothing without verify results, got %q", got)
+	}
+	attempts := []AttemptRecord{
+		{Outcome: OutcomeFixed, Verify: VerifyFlaky},
+		{Outcome: OutcomeFixed, Verify: VerifyPassed},
+		{Outcome: OutcomeBuildFailed, Verify: VerifyFailed},
+		{Outcome: OutcomeFixed, Verify: VerifyPassed},
+		{Outcome: OutcomeNotFixed},
+	}
+	if got := FormatVerifyStats(attempts); !strings.Contains(got, "1 of 4 verified attempts (25.0%)") {
+		t.Errorf("FormatVerifyStats = %q", got)
+	}
+}
+
+func TestFormatStallStats(t *testing.T) {
+	if got := FormatStallStats([]AttemptRecord{{Outcome: OutcomeFixed}}); got != "" {
+		t.Errorf("expected nothing without stalls, got %q", got)
+	}
+	attempts := []AttemptRecord{
+		{Outcome: OutcomeFixed, Stalled: true},
+		{Outcome: OutcomeNotFixed, Stalled: true},
+		{Outcome: OutcomeFixed},
+		{Outcome: OutcomeNotFixed},
+	}
+	if got := FormatStallStats(attempts); !strings.Contains(got, "2 of 4 attempts (50.0%) stopped by inactivity_timeout, 1 salvaged") {
+		t.Errorf("FormatStallStats = %q", got)
+	}
+}
+
+func TestFormatRuleStats(t *testing.T) {
+	attempts := []AttemptRecord{
+		{Candidate: `{"file":"a.go","rule":"unused-variable"}`, Outcome: OutcomeFixed, Usage: Usage{CostUSD: 0.02}},
+		{Candidate: `{"file":"b.go","rule":"unused-variable"}`, Outcome: OutcomeFixed, Usage: Usage{CostUSD: 0.02}},
+		{Candidate: `{"file":"c.go","rule":"data-race"}`, Outcome: OutcomeNotFixed, Usage: Usage{CostUSD: 3}},
+		{Candidate: "plain", Outcome: OutcomeNotFixed},
+	}
+
+	result := FormatRuleStats(attempts, "rule")
+	lines := strings.Split(strings.TrimSpace(result), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header and 3 rows, got:\n%s", result)
+	}
+	if !strings.Contains(lines[1], "data-race") || !strings.Contains(lines[1], "0.0%") || !strings.HasSuffix(lines[1], "-") {
+		t.Errorf("most expensive row = %q, want data-race with no fixes", lines[1])
+	}
+	if !strings.Contains(lines[2], "unused-variable") || !strings.Contains(lines[2], "100.0%") || !strings.HasSuffix(lines[2], "$0.02") {
+		t.Errorf("second row = %q, want unused-variable at $0.02 per fix", lines[2])
+	}
+	if !strings.HasPrefix(strings.TrimSpace(lines[3]), "-") {
+		t.Errorf("last row = %q, want candidates without the field grouped as -", lines[3])
+	}
+}
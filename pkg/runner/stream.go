@@ -0,0 +1,247 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StreamParser turns the lines an agent CLI writes to stdout into streamed
+// text, tool calls and a ClaudeResult. It holds no process state, so each
+// parser can be tested line by line. A parser is used for one run.
+type StreamParser interface {
+	Line(line string)     // Handles one line of stdout, without its newline
+	Finish() ClaudeResult // Called at EOF; returns what was collected
+}
+
+// StreamCallbacks are where a parser sends what it finds.
+type StreamCallbacks struct {
+	Log    io.Writer       // Receives the text shown and any lines that aren't events (optional)
+	OnText StreamCallback  // Called for each chunk of text (optional)
+	OnTool ToolUseCallback // Called for each tool call (optional)
+}
+
+// text shows a chunk of text and writes it to the log.
+func (cb StreamCallbacks) text(s string) {
+	if cb.OnText != nil {
+		cb.OnText(s)
+	}
+	if cb.Log != nil {
+		fmt.Fprint(cb.Log, s)
+	}
+}
+
+// tool reports a tool call.
+func (cb StreamCallbacks) tool(tool ToolUse) {
+	if cb.OnTool != nil {
+		cb.OnTool(tool)
+	}
+}
+
+// StreamConfig is a task's choice of parser and its settings.
+type StreamConfig struct {
+	Parser   string // stream_parser: a registered parser name (default claude-stream-json)
+	Format   string // output_format, for claude-stream-json
+	Sentinel string // stream_sentinel, for plain-text-with-sentinel
+}
+
+// StreamParserSpec registers a parser with how to run the CLI whose output it
+// reads. Supporting a new agent CLI means writing one of these.
+type StreamParserSpec struct {
+	New    func(cfg StreamConfig, cb StreamCallbacks) StreamParser
+	Flags  func(cfg StreamConfig) string // Flags requesting the output it parses, put before claude_flags (optional)
+	Prompt string                        // Arguments making the CLI read the prompt from stdin, e.g. "-p"
+	Claude bool                          // The CLI takes Claude's flags: tool permissions and --mcp-config
+}
+
+// Built-in stream parsers, selected per task with stream_parser.
+const (
+	ParserClaudeStreamJSON = "claude-stream-json"       // Claude CLI output in any output_format
+	ParserAider            = "aider"                    // aider's plain output, with edits and token counts
+	ParserSentinel         = "plain-text-with-sentinel" // Plain text ended by a stream_sentinel line
+)
+
+var (
+	streamParsersMu sync.RWMutex
+	streamParsers   = map[string]StreamParserSpec{
+		ParserClaudeStreamJSON: {
+			New: func(cfg StreamConfig, cb StreamCallbacks) StreamParser {
+				return &claudeStreamParser{StreamCallbacks: cb, Format: cfg.Format}
+			},
+			Flags:  func(cfg StreamConfig) string { return outputFormatFlags(cfg.Format) },
+			Prompt: "-p",
+			Claude: true,
+		},
+		ParserAider: {
+			New: func(cfg StreamConfig, cb StreamCallbacks) StreamParser {
+				return &aiderParser{StreamCallbacks: cb}
+			},
+			// nigel commits fixes itself, so aider mustn't
+			Flags:  func(StreamConfig) string { return "--yes-always --no-pretty --no-stream --no-auto-commits" },
+			Prompt: "--message-file /dev/stdin",
+		},
+		ParserSentinel: {
+			New: func(cfg StreamConfig, cb StreamCallbacks) StreamParser {
+				return &sentinelParser{StreamCallbacks: cb, sentinel: cfg.Sentinel}
+			},
+		},
+	}
+)
+
+// RegisterStreamParser adds a parser that tasks can select by name with
+// stream_parser, replacing any parser of the same name. Call it before
+// loading the environment, which checks the names tasks use.
+func RegisterStreamParser(name string, spec StreamParserSpec) {
+	streamParsersMu.Lock()
+	defer streamParsersMu.Unlock()
+	streamParsers[name] = spec
+}
+
+// lookupStreamParser returns the parser registered as name, or
+// claude-stream-json for an empty name.
+func lookupStreamParser(name string) (StreamParserSpec, bool) {
+	if name == "" {
+		name = ParserClaudeStreamJSON
+	}
+	streamParsersMu.RLock()
+	defer streamParsersMu.RUnlock()
+	spec, ok := streamParsers[name]
+	return spec, ok
+}
+
+// streamParserNames lists the registered parsers, for error messages.
+func streamParserNames() string {
+	streamParsersMu.RLock()
+	defer streamParsersMu.RUnlock()
+	names := make([]string, 0, len(streamParsers))
+	for name := range streamParsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// claudeStreamParser reads the Claude CLI's output: stream-json events, a
+// json result document or plain text.
+type claudeStreamParser struct {
+	StreamCallbacks
+	Format string // One of the Output* formats, or empty to probe from the first line
+
+	output            strings.Builder // Accumulated output (for rate limit detection)
+	messageHasContent bool            // Whether the current message has streamed text
+	streamedText      bool            // Whether any text_delta has been streamed
+	sessionID         string
+	usage             Usage
+	jsonDoc           strings.Builder // Lines of a json document, parsed once complete
+}
+
+// emit shows text that didn't arrive as a stream event
+func (p *claudeStreamParser) emit(text string) {
+	p.text(text)
+	p.output.WriteString(text)
+}
+
+func (p *claudeStreamParser) Line(line string) {
+	// Without an explicit output_format, decide from the first line
+	if p.Format == "" && strings.TrimSpace(line) != "" {
+		p.Format = probeOutputFormat(line)
+	}
+
+	switch p.Format {
+	case OutputText:
+		p.emit(line + "\n")
+		return
+	case OutputJSON:
+		p.jsonDoc.WriteString(line + "\n")
+		return
+	}
+
+	// Try to parse as stream event
+	var se streamEvent
+	if jsonErr := json.Unmarshal([]byte(line), &se); jsonErr != nil {
+		// Not valid JSON - write as-is to log and continue
+		if p.Log != nil {
+			fmt.Fprintln(p.Log, line)
+		}
+		p.output.WriteString(line + "\n")
+		return
+	}
+
+	// The system/init event opens the stream with the session ID
+	if se.SessionID != "" {
+		p.sessionID = se.SessionID
+	}
+
+	// Handle different event types
+	switch se.Type {
+	case "stream_event":
+		eventType, _ := se.Event["type"].(string)
+		// Check if this is a content_block_delta
+		if eventType == "content_block_delta" {
+			// Extract the delta text
+			eventJSON, _ := json.Marshal(se.Event)
+			var delta contentBlockDelta
+			if json.Unmarshal(eventJSON, &delta) == nil && delta.Delta.Type == "text_delta" && delta.Delta.Text != "" {
+				p.messageHasContent = true
+				p.streamedText = true
+				p.emit(delta.Delta.Text)
+			}
+		}
+		// Check if this is message_stop - add newline between messages (only if content was received)
+		if eventType == "message_stop" {
+			if p.messageHasContent {
+				p.emit("\n")
+			}
+			p.messageHasContent = false
+		}
+
+	case "assistant":
+		// Complete messages repeat the streamed text; only their tool calls are new
+		var ae assistantEvent
+		if p.OnTool != nil && json.Unmarshal([]byte(line), &ae) == nil {
+			for _, block := range ae.Message.Content {
+				if block.Type == "tool_use" {
+					p.tool(newToolUse(block.Name, block.Input))
+				}
+			}
+		}
+
+	case "result":
+		// Final result event - completion confirmed. Show the result if
+		// nothing was streamed (e.g. a wrapper printing a compact json document)
+		var re resultEvent
+		if json.Unmarshal([]byte(line), &re) == nil {
+			p.usage = re.usage()
+			if re.Result != "" && !p.streamedText {
+				p.emit(re.Result + "\n")
+			}
+		}
+	}
+}
+
+// Finish parses a buffered json document, ends the streamed output with a
+// newline and returns what was collected.
+func (p *claudeStreamParser) Finish() ClaudeResult {
+	if p.jsonDoc.Len() > 0 {
+		var re resultEvent
+		if json.Unmarshal([]byte(p.jsonDoc.String()), &re) == nil {
+			if re.SessionID != "" {
+				p.sessionID = re.SessionID
+			}
+			p.usage = re.usage()
+			p.emit(re.Result + "\n")
+		} else {
+			// Not a json document after all - show it as-is
+			p.emit(p.jsonDoc.String())
+		}
+		p.jsonDoc.Reset()
+	}
+
+	// Add a final newline after streaming is complete
+	p.text("\n")
+
+	return ClaudeResult{Output: p.output.String(), SessionID: p.sessionID, Usage: p.usage}
+}
@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RunSummary records what happened during a single task's run.
+type RunSummary struct {
+	Task       string
+	Iterations int
+	Duration   time.Duration
+	Outcomes   map[Outcome]int
+	Trend      *CandidateTrend // Candidate count over the run
+	CostUSD    float64         // Claude cost reported across attempts
+	Commits    []string        // Revisions committed by success_command
+	NewCommits int             // Commits success_command created, including batched ones
+	Pending    int             // Candidates not yet ignored, at the latest selection
+	Ignored    int             // Candidates on the ignore list, at the latest selection
+	Phases     *PhaseStats     // How long each phase of the iterations took (nil if not tracked)
+}
+
+// Fixed returns the number of candidates fixed and committed.
+func (s RunSummary) Fixed() int {
+	return s.Outcomes[OutcomeFixed]
+}
+
+// BestEffort returns the number of candidates with partial progress committed.
+func (s RunSummary) BestEffort() int {
+	return s.Outcomes[OutcomeBestEffort]
+}
+
+// Failed returns the number of candidates whose changes were reverted or discarded.
+func (s RunSummary) Failed() int {
+	failed := 0
+	for outcome, n := range s.Outcomes {
+		if outcome != OutcomeFixed && outcome != OutcomeBestEffort {
+			failed += n
+		}
+	}
+	return failed
+}
+
+// Attempts returns the number of attempts with an outcome.
+func (s RunSummary) Attempts() int {
+	attempts := 0
+	for _, n := range s.Outcomes {
+		attempts += n
+	}
+	return attempts
+}
+
+// Status describes the run so far for the iteration banner, e.g.
+// "38 remaining, 3 ignored · 41 candidates (-1 net, +0 new) · 3/5 fixed (60%)".
+// Parts not known yet are left out.
+func (s RunSummary) Status() string {
+	var parts []string
+	if s.Pending > 0 || s.Ignored > 0 {
+		parts = append(parts, fmt.Sprintf("%d remaining, %d ignored", s.Pending, s.Ignored))
+	}
+	if s.Trend != nil && s.Trend.Observed() {
+		parts = append(parts, s.Trend.String())
+	}
+	if attempts := s.Attempts(); attempts > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d fixed (%d%%)", s.Fixed(), attempts, s.Fixed()*100/attempts))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// Candidates describes how the candidate count moved over the run, e.g.
+// "120→95 (+4 new)", or "-" if the candidate source never ran.
+func (s RunSummary) Candidates() string {
+	if s.Trend == nil || !s.Trend.Observed() {
+		return "-"
+	}
+	return fmt.Sprintf("%d→%d (+%d new)", s.Trend.Start, s.Trend.Last, s.Trend.New)
+}
+
+// summaryHistogramBuckets is the number of rows in the Claude time histograms.
+const summaryHistogramBuckets = 6
+
+// formatPhases describes how long each phase of a task's iterations took,
+// with a histogram of Claude's times, or "" if nothing was timed.
+func formatPhases(s RunSummary) string {
+	if s.Phases == nil {
+		return ""
+	}
+	phases := []struct {
+		name  string
+		stats *SessionStats
+	}{
+		{"Candidate source", s.Phases.CandidateSource},
+		{"Claude", s.Phases.Claude},
+		{"Verify", s.Phases.Verify},
+		{"Commit", s.Phases.Commit},
+	}
+
+	var b strings.Builder
+	for _, phase := range phases {
+		if dist, ok := phase.stats.Distribution(); ok {
+			fmt.Fprintf(&b, "  %-18s %s\n", phase.name, dist)
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n  Times for %s:\n", s.Task) + b.String() + s.Phases.Claude.Histogram(summaryHistogramBuckets)
+}
+
+// FormatSummary renders a table of per-task results, with a totals row when
+// more than one task ran, followed by how long each task's phases took.
+func FormatSummary(summaries []RunSummary) string {
+	var b strings.Builder
+	b.WriteString("\n" + ColorBold("Summary") + "\n")
+	fmt.Fprintf(&b, "  %-30s %10s %7s %11s %7s %10s %18s\n", "Task", "Iterations", "Fixed", "Best effort", "Failed", "Duration", "Candidates")
+
+	row := func(s RunSummary) {
+		fmt.Fprintf(&b, "  %-30s %10d %7d %11d %7d %10s %18s\n",
+			s.Task, s.Iterations, s.Fixed(), s.BestEffort(), s.Failed(), formatDuration(s.Duration), s.Candidates())
+	}
+
+	total := RunSummary{Task: "Total", Outcomes: make(map[Outcome]int), Trend: &CandidateTrend{}}
+	for _, s := range summaries {
+		row(s)
+		total.Iterations += s.Iterations
+		total.Duration += s.Duration
+		for outcome, n := range s.Outcomes {
+			total.Outcomes[outcome] += n
+		}
+		if s.Trend != nil && s.Trend.Observed() {
+			total.Trend.observations++
+			total.Trend.Start += s.Trend.Start
+			total.Trend.Last += s.Trend.Last
+			total.Trend.New += s.Trend.New
+		}
+	}
+	if len(summaries) > 1 {
+		row(total)
+	}
+
+	for _, s := range summaries {
+		b.WriteString(formatPhases(s))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunSummaryCounts(t *testing.T) {
+	s := RunSummary{
+		Task: "lint",
+		Outcomes: map[Outcome]int{
+			OutcomeFixed:       3,
+			OutcomeBestEffort:  1,
+			OutcomeNotFixed:    2,
+			OutcomeBuildFailed: 1,
+			OutcomeTimeout:     1,
+		},
+	}
+
+	if s.Fixed() != 3 {
+		t.Errorf("Fixed() = %d, want 3", s.Fixed())
+	}
+	if s.BestEffort() != 1 {
+		t.Errorf("BestEffort() = %d, want 1", s.BestEffort())
+	}
+	if s.Failed() != 4 {
+		t.Errorf("Failed() = %d, want 4", s.Failed())
+	}
+}
+
+func TestFormatSummary(t *testing.T) {
+	summaries := []RunSummary{
+		{Task: "lint", Iterations: 4, Duration: 90 * time.Second, Outcomes: map[Outcome]int{OutcomeFixed: 3, OutcomeNotFixed: 1}},
+		{Task: "tests", Iterations: 2, Duration: 30 * time.Second, Outcomes: map[Outcome]int{OutcomeFixed: 1}},
+	}
+
+	t.Run("includes each task and a total", func(t *testing.T) {
+		result := FormatSummary(summaries)
+		for _, want := range []string{"lint", "tests", "Total", "2m 00s"} {
+			if !strings.Contains(result, want) {
+				t.Errorf("summary missing %q:\n%s", want, result)
+			}
+		}
+	})
+
+	t.Run("shows candidate trend when observed", func(t *testing.T) {
+		trend := &CandidateTrend{}
+		trend.Observe([]Candidate{{Key: "a"}, {Key: "b"}}, time.Now())
+		trend.Observe([]Candidate{{Key: "b"}, {Key: "c"}}, time.Now())
+		withTrend := append([]RunSummary{{Task: "trended", Outcomes: map[Outcome]int{}, Trend: trend}}, summaries...)
+
+		result := FormatSummary(withTrend)
+		if !strings.Contains(result, "2→2 (+1 new)") {
+			t.Errorf("summary missing candidate trend:\n%s", result)
+		}
+		if !strings.Contains(result, " -\n") {
+			t.Errorf("tasks without a trend should show '-':\n%s", result)
+		}
+	})
+
+	t.Run("shows the phases that were timed", func(t *testing.T) {
+		phases := NewPhaseStats()
+		phases.Claude.Add(2 * time.Minute)
+		phases.Verify.Add(20 * time.Second)
+		timed := []RunSummary{{Task: "timed", Outcomes: map[Outcome]int{}, Phases: phases}}
+
+		result := FormatSummary(timed)
+		for _, want := range []string{"Times for timed:", "Claude             1 runs · min 2m 00s", "Verify             1 runs · min 20s", "│"} {
+			if !strings.Contains(result, want) {
+				t.Errorf("summary missing %q:\n%s", want, result)
+			}
+		}
+		if strings.Contains(result, "Candidate source") || strings.Contains(result, "Commit ") {
+			t.Errorf("phases that never ran should be left out:\n%s", result)
+		}
+	})
+
+	t.Run("single task has no total row", func(t *testing.T) {
+		result := FormatSummary(summaries[:1])
+		if strings.Contains(result, "Total") {
+			t.Errorf("single-task summary should not include a total row:\n%s", result)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+)
+
+// syncUpstream runs sync_command (e.g. `git pull --rebase`) before the
+// candidate source, at most once per sync_interval, so a long run keeps up
+// with the branch it commits to instead of drifting behind it. It only runs
+// on a clean tree with no fixes waiting for a batched commit. A failure that
+// leaves the tree clean (say, the network is down) is retried next interval;
+// one that leaves changes behind, such as a rebase conflict, stops the run.
+func (r *Runner) syncUpstream() error {
+	command := r.env.Config.SyncCommand
+	if command == "" || r.opts.DryRun {
+		return nil
+	}
+	if !r.lastSync.IsZero() && time.Since(r.lastSync) < r.env.Config.SyncInterval {
+		return nil
+	}
+	if len(r.pending) > 0 {
+		return nil
+	}
+	hasChanges, err := r.executor.HasUncommittedChanges(r.workDir())
+	if err != nil {
+		return retryableError(ErrCommit, "failed to check git status before sync: %w", err)
+	}
+	if hasChanges {
+		fmt.Println(ColorWarning("Working tree has changes, skipping sync_command"))
+		return nil
+	}
+
+	r.lastSync = time.Now()
+	r.log.printf(VerbosityCandidates, ColorInfo("Syncing with upstream...")+"\n")
+	before, _ := r.executor.CurrentRevision(r.workDir())
+	ok, err := r.runSilentSideEffect("sync", command)
+	if err != nil {
+		return retryableError(ErrCommit, "sync_command error: %w", err)
+	}
+	if !ok {
+		if dirty, err := r.executor.HasUncommittedChanges(r.workDir()); err != nil || dirty {
+			return fatalError(ErrCommit, "sync_command failed and left the working tree changed; resolve it before running again")
+		}
+		fmt.Println(ColorWarning("sync_command failed, continuing without the latest upstream changes"))
+		return nil
+	}
+	if after, _ := r.executor.CurrentRevision(r.workDir()); after != before {
+		fmt.Println(ColorInfo(fmt.Sprintf("Synced with upstream: %s → %s", shortRevision(before), shortRevision(after))))
+		// Pipelined candidate source output is for the old tree
+		r.prefetched = nil
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSyncUpstream(t *testing.T) {
+	tmpDir := t.TempDir()
+	env := &Environment{
+		ProjectDir: tmpDir,
+		Config:     Config{SyncCommand: "git pull --rebase", SyncInterval: time.Hour},
+		Tasks: map[string]Task{
+			"test-task": {Name: "test-task", Dir: tmpDir, Prompt: "test prompt"},
+		},
+	}
+	runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("NewRunner failed: %v", err)
+	}
+	mock := NewMockCommandExecutor()
+	runner.setExecutor(mock)
+
+	// Dry runs never touch the branch
+	if err := runner.syncUpstream(); err != nil || mock.CalledWith("git pull --rebase") {
+		t.Fatalf("dry run synced (err %v)", err)
+	}
+	runner.opts.DryRun = false
+
+	mock.SetHasChanges(true, nil)
+	if err := runner.syncUpstream(); err != nil || mock.CalledWith("git pull --rebase") {
+		t.Fatalf("synced a dirty tree (err %v)", err)
+	}
+
+	mock.SetHasChanges(false, nil)
+	if err := runner.syncUpstream(); err != nil || mock.CallCount("git pull --rebase") != 1 {
+		t.Fatalf("clean tree not synced (err %v)", err)
+	}
+	// Within the interval
+	if err := runner.syncUpstream(); err != nil || mock.CallCount("git pull --rebase") != 1 {
+		t.Errorf("synced again within sync_interval (err %v)", err)
+	}
+
+	// A failure that leaves the tree clean carries on
+	runner.lastSync = time.Time{}
+	mock.SetResult("git pull --rebase", false, nil)
+	if err := runner.syncUpstream(); err != nil {
+		t.Errorf("failed sync on a clean tree stopped the run: %v", err)
+	}
+
+	// A conflict stops it
+	runner.lastSync = time.Time{}
+	mock.SetHasChanges(false, nil)
+	conflicted := &conflictExecutor{MockCommandExecutor: mock}
+	runner.setExecutor(conflicted)
+	err = runner.syncUpstream()
+	if !errors.Is(err, ErrCommit) || isRetryable(err) {
+		t.Errorf("sync conflict returned %v, want a fatal commit error", err)
+	}
+}
+
+// conflictExecutor reports a clean tree until a command has run, as if a
+// rebase stopped on a conflict.
+type conflictExecutor struct {
+	*MockCommandExecutor
+	ran bool
+}
+
+func (c *conflictExecutor) RunWatched(command, workDir string, onLine func(string)) (bool, string, error) {
+	c.ran = true
+	return c.MockCommandExecutor.RunWatched(command, workDir, onLine)
+}
+
+func (c *conflictExecutor) HasUncommittedChanges(workDir string) (bool, error) {
+	return c.ran, nil
+}
@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// outputMediator owns the terminal's cursor and status line. Progress timers,
+// Claude's streamed output and log lines all go through it, and a single
+// goroutine applies them in order: the status line is erased before any text
+// and redrawn after it, and only once the text has ended its line, so a timer
+// tick never lands in the middle of someone else's output.
+type outputMediator struct {
+	requests chan outputRequest
+}
+
+// outputRequest is one change to the terminal.
+type outputRequest struct {
+	text    string        // Text to write above the status line
+	ownLine bool          // Start text on a new line if the cursor is mid-line
+	status  *string       // New status line, "" to remove it; nil leaves it as is
+	done    chan struct{} // Closed once applied
+}
+
+// newOutputMediator starts a mediator writing to w, or to whatever os.Stdout
+// is at the time of each write if w is nil.
+func newOutputMediator(w io.Writer) *outputMediator {
+	m := &outputMediator{requests: make(chan outputRequest)}
+	go m.loop(w)
+	return m
+}
+
+var (
+	stdoutOnce     sync.Once
+	stdoutMediator *outputMediator
+)
+
+// console returns the mediator for stdout, shared by everything that writes
+// to the terminal while a timer may be showing.
+func console() *outputMediator {
+	stdoutOnce.Do(func() {
+		stdoutMediator = newOutputMediator(nil)
+	})
+	return stdoutMediator
+}
+
+// Write writes p above the status line, returning once it's on the terminal.
+func (m *outputMediator) Write(p []byte) (int, error) {
+	m.send(outputRequest{text: string(p)})
+	return len(p), nil
+}
+
+// write writes text above the status line.
+func (m *outputMediator) write(text string) {
+	m.send(outputRequest{text: text})
+}
+
+// printf formats output above the status line.
+func (m *outputMediator) printf(format string, args ...interface{}) {
+	m.write(fmt.Sprintf(format, args...))
+}
+
+// setStatus shows line as the status line, replacing any previous one.
+func (m *outputMediator) setStatus(line string) {
+	m.send(outputRequest{status: &line})
+}
+
+// endStatus removes the status line, leaving final in its place on a line of
+// its own if it isn't "".
+func (m *outputMediator) endStatus(final string) {
+	none := ""
+	req := outputRequest{status: &none}
+	if final != "" {
+		req.text, req.ownLine = final+"\n", true
+	}
+	m.send(req)
+}
+
+func (m *outputMediator) send(req outputRequest) {
+	req.done = make(chan struct{})
+	m.requests <- req
+	<-req.done
+}
+
+func (m *outputMediator) loop(w io.Writer) {
+	var status string // Status line to show, "" for none
+	shown := false    // The status line is drawn and the cursor is at its end
+	hidden := false   // The cursor is hidden while a status line is up
+	lineStart := true // The cursor is at the start of a line, not counting the status line
+	for req := range m.requests {
+		out := w
+		if out == nil {
+			out = os.Stdout
+		}
+
+		if shown {
+			// \r moves to start of line, \033[K clears to end of line
+			fmt.Fprint(out, "\r\033[K")
+			shown = false
+		}
+		if req.status != nil {
+			status = *req.status
+		}
+		if req.text != "" {
+			if req.ownLine && !lineStart {
+				fmt.Fprint(out, "\n")
+			}
+			fmt.Fprint(out, req.text)
+			lineStart = strings.HasSuffix(req.text, "\n")
+		}
+		if status != "" && lineStart {
+			if !hidden {
+				fmt.Fprint(out, "\033[?25l")
+				hidden = true
+			}
+			fmt.Fprint(out, status)
+			shown = true
+		} else if status == "" && hidden {
+			fmt.Fprint(out, "\033[?25h")
+			hidden = false
+		}
+		close(req.done)
+	}
+}
@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutputMediator(t *testing.T) {
+	t.Run("text goes above the status line", func(t *testing.T) {
+		var out bytes.Buffer
+		m := newOutputMediator(&out)
+		m.setStatus("timer (1s)")
+		m.write("hello\n")
+
+		want := "\033[?25ltimer (1s)\r\033[Khello\ntimer (1s)"
+		if out.String() != want {
+			t.Errorf("output = %q, want %q", out.String(), want)
+		}
+	})
+
+	t.Run("status waits for a partial line to end", func(t *testing.T) {
+		var out bytes.Buffer
+		m := newOutputMediator(&out)
+		m.write("partial")
+		m.setStatus("timer (1s)")
+		if strings.Contains(out.String(), "timer") {
+			t.Fatalf("status drawn mid-line: %q", out.String())
+		}
+		m.write(" line\n")
+		if !strings.HasSuffix(out.String(), "partial line\n\033[?25ltimer (1s)") {
+			t.Errorf("status should follow the finished line, got %q", out.String())
+		}
+	})
+
+	t.Run("ending the status keeps the final line on its own line", func(t *testing.T) {
+		var out bytes.Buffer
+		m := newOutputMediator(&out)
+		m.setStatus("timer (1s)")
+		m.write("partial")
+		m.endStatus("timer (2s)")
+		m.write("next\n")
+
+		if !strings.HasSuffix(out.String(), "partial\ntimer (2s)\n\033[?25hnext\n") {
+			t.Errorf("output = %q", out.String())
+		}
+	})
+}
+
+func TestDelayedProgressTimer(t *testing.T) {
+	t.Run("stopping before the delay shows nothing", func(t *testing.T) {
+		var out bytes.Buffer
+		d := NewDelayedProgressTimer("Waiting...", 20*time.Millisecond)
+		d.SetWriter(&out)
+		d.Start()
+		d.Stop()
+		d.Stop()
+		time.Sleep(50 * time.Millisecond)
+		d.out.write("") // Wait for anything queued
+		if out.Len() != 0 {
+			t.Errorf("output = %q, want none", out.String())
+		}
+	})
+
+	t.Run("stopping a shown timer twice is safe", func(t *testing.T) {
+		var out bytes.Buffer
+		d := NewDelayedProgressTimer("Waiting...", time.Millisecond)
+		d.SetWriter(&out)
+		d.Start()
+		time.Sleep(30 * time.Millisecond)
+		d.Stop()
+		d.Stop()
+		d.out.write("")
+		if !strings.Contains(out.String(), "Waiting...") || !strings.HasSuffix(out.String(), "\n\033[?25h") {
+			t.Errorf("the final timer line should stay on screen, got %q", out.String())
+		}
+	})
+
+	t.Run("reset hides the timer and starts the delay over", func(t *testing.T) {
+		var out bytes.Buffer
+		d := NewDelayedProgressTimer("Waiting...", 20*time.Millisecond)
+		d.SetWriter(&out)
+		d.Start()
+		time.Sleep(40 * time.Millisecond)
+		d.Reset()
+		d.out.write("")
+		shown := out.String()
+		if !strings.HasSuffix(shown, "\033[?25h") {
+			t.Errorf("reset should take the timer down, got %q", shown)
+		}
+		d.Stop()
+		time.Sleep(40 * time.Millisecond)
+		d.out.write("")
+		if out.String() != shown {
+			t.Errorf("a stale delay showed the timer again: %q", strings.TrimPrefix(out.String(), shown))
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Theme holds the ANSI codes for each semantic role in nigel's output. An
+// empty code leaves that role unstyled.
+type Theme struct {
+	Success      string // Fixes, passing checks
+	Error        string // Failures and fatal errors
+	Warning      string // Warnings, and Claude's stderr
+	Info         string // Progress messages
+	Dim          string // Timers, trends, the stream gutter and other secondary text
+	ClaudeStream string // Claude's streamed text
+	Banner       string // Startup and iteration banners
+}
+
+// builtinThemes are the themes `theme:` can name.
+var builtinThemes = map[string]Theme{
+	"default": {
+		Success:      colorGreen,
+		Error:        colorRed,
+		Warning:      colorYellow,
+		Info:         colorCyan,
+		Dim:          colorDim,
+		ClaudeStream: colorDim + colorItalic,
+		Banner:       colorCyan,
+	},
+	// Darker shades for light-background terminals, where cyan and grey wash out
+	"light": {
+		Success:      sgr("32"),
+		Error:        sgr("31"),
+		Warning:      sgr("38;5;130"),
+		Info:         sgr("34"),
+		Dim:          sgr("38;5;240"),
+		ClaudeStream: sgr("38;5;240;3"),
+		Banner:       sgr("34"),
+	},
+	// The Solarized accents, readable on both its light and dark backgrounds
+	"solarized": {
+		Success:      sgr("38;5;64"),
+		Error:        sgr("38;5;160"),
+		Warning:      sgr("38;5;136"),
+		Info:         sgr("38;5;33"),
+		Dim:          sgr("38;5;244"),
+		ClaudeStream: sgr("38;5;244;3"),
+		Banner:       sgr("38;5;37"),
+	},
+	// Blue and orange instead of green and red, distinguishable with red-green color blindness
+	"colorblind": {
+		Success:      sgr("38;5;33"),
+		Error:        sgr("38;5;208"),
+		Warning:      sgr("38;5;220"),
+		Info:         sgr("38;5;75"),
+		Dim:          colorDim,
+		ClaudeStream: colorDim + colorItalic,
+		Banner:       sgr("38;5;75"),
+	},
+	// No colors; errors and warnings stand out in bold
+	"mono": {
+		Error:        colorBold,
+		Warning:      colorBold,
+		ClaudeStream: colorItalic,
+	},
+}
+
+// theme is the active theme, used by the Color* helpers.
+var theme = builtinThemes["default"]
+
+// SetTheme changes the colors of all subsequent output.
+func SetTheme(t Theme) {
+	theme = t
+}
+
+func sgr(codes string) string {
+	return "\033[" + codes + "m"
+}
+
+// ThemeConfig is the `theme:` setting: either a built-in theme's name, or a
+// mapping with an optional `base` theme and colors for individual roles, e.g.
+//
+//	theme:
+//	  base: light
+//	  success: bold blue
+//	  claude_stream: "#586e75 italic"
+type ThemeConfig struct {
+	Base  string            // Built-in theme to start from ("" = default)
+	Roles map[string]string // Role name to color spec
+}
+
+func (c *ThemeConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		c.Base = value.Value
+		return nil
+	}
+	var roles map[string]string
+	if err := value.Decode(&roles); err != nil {
+		return err
+	}
+	c.Base = roles["base"]
+	delete(roles, "base")
+	c.Roles = roles
+	return nil
+}
+
+// Resolve returns the theme described by the config.
+func (c ThemeConfig) Resolve() (Theme, error) {
+	base := c.Base
+	if base == "" {
+		base = "default"
+	}
+	t, ok := builtinThemes[base]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown theme %q (built-in themes: %s)", base, strings.Join(themeNames(), ", "))
+	}
+	for role, spec := range c.Roles {
+		code, err := parseColorSpec(spec)
+		if err != nil {
+			return Theme{}, fmt.Errorf("%s: %w", role, err)
+		}
+		field := t.role(role)
+		if field == nil {
+			return Theme{}, fmt.Errorf("unknown role %q (roles: success, error, warning, info, dim, claude_stream, banner)", role)
+		}
+		*field = code
+	}
+	return t, nil
+}
+
+// role returns the field for a role name as written in config.yaml, or nil.
+func (t *Theme) role(name string) *string {
+	switch name {
+	case "success":
+		return &t.Success
+	case "error":
+		return &t.Error
+	case "warning":
+		return &t.Warning
+	case "info":
+		return &t.Info
+	case "dim":
+		return &t.Dim
+	case "claude_stream":
+		return &t.ClaudeStream
+	case "banner":
+		return &t.Banner
+	}
+	return nil
+}
+
+func themeNames() []string {
+	names := make([]string, 0, len(builtinThemes))
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// colorSpecCodes are the SGR codes of the words a color spec may use.
+var colorSpecCodes = map[string]string{
+	"black": "30", "red": "31", "green": "32", "yellow": "33",
+	"blue": "34", "magenta": "35", "cyan": "36", "white": "37",
+	"grey": "90", "gray": "90",
+	"bright-red": "91", "bright-green": "92", "bright-yellow": "93", "bright-blue": "94",
+	"bright-magenta": "95", "bright-cyan": "96", "bright-white": "97",
+	"bold": "1", "dim": "2", "italic": "3", "underline": "4",
+}
+
+// parseColorSpec converts a space-separated color spec to an ANSI code. Each
+// word is a color or style name ("bold red"), a 256-color palette index
+// ("208"), or a #rrggbb hex color. "none" means no styling.
+func parseColorSpec(spec string) (string, error) {
+	words := strings.Fields(spec)
+	if len(words) == 1 && words[0] == "none" {
+		return "", nil
+	}
+	if len(words) == 0 {
+		return "", fmt.Errorf("empty color (use none for no styling)")
+	}
+
+	var codes []string
+	for _, word := range words {
+		word = strings.ToLower(word)
+		if code, ok := colorSpecCodes[word]; ok {
+			codes = append(codes, code)
+			continue
+		}
+		if n, err := strconv.Atoi(word); err == nil && n >= 0 && n <= 255 {
+			codes = append(codes, "38;5;"+word)
+			continue
+		}
+		if hex, ok := strings.CutPrefix(word, "#"); ok && len(hex) == 6 {
+			if rgb, err := strconv.ParseUint(hex, 16, 32); err == nil {
+				codes = append(codes, fmt.Sprintf("38;2;%d;%d;%d", rgb>>16, rgb>>8&0xff, rgb&0xff))
+				continue
+			}
+		}
+		return "", fmt.Errorf("unknown color %q", word)
+	}
+	return sgr(strings.Join(codes, ";")), nil
+}
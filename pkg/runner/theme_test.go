@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestThemeConfigResolve(t *testing.T) {
+	var config struct {
+		Theme ThemeConfig `yaml:"theme"`
+	}
+	doc := "theme:\n  base: light\n  success: bold blue\n  dim: 245\n  claude_stream: \"#586e75 italic\"\n  banner: none\n"
+	if err := yaml.Unmarshal([]byte(doc), &config); err != nil {
+		t.Fatal(err)
+	}
+	got, err := config.Theme.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	want := builtinThemes["light"]
+	want.Success = "\033[1;34m"
+	want.Dim = "\033[38;5;245m"
+	want.ClaudeStream = "\033[38;2;88;110;117;3m"
+	want.Banner = ""
+	if got != want {
+		t.Errorf("Resolve() = %q, want %q", got, want)
+	}
+}
+
+func TestThemeConfigResolveErrors(t *testing.T) {
+	tests := []struct {
+		config ThemeConfig
+		want   string
+	}{
+		{ThemeConfig{Base: "neon"}, "unknown theme"},
+		{ThemeConfig{Roles: map[string]string{"heading": "red"}}, "unknown role"},
+		{ThemeConfig{Roles: map[string]string{"error": "crimson"}}, "unknown color"},
+		{ThemeConfig{Roles: map[string]string{"error": "256"}}, "unknown color"},
+		{ThemeConfig{Roles: map[string]string{"error": ""}}, "empty color"},
+	}
+	for _, tt := range tests {
+		if _, err := tt.config.Resolve(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Resolve(%+v) error = %v, want %q", tt.config, err, tt.want)
+		}
+	}
+}
+
+func TestSetTheme(t *testing.T) {
+	defer SetTheme(builtinThemes["default"])
+
+	SetTheme(builtinThemes["mono"])
+	if got := ColorSuccess("ok"); got != "ok"+colorReset {
+		t.Errorf("ColorSuccess with mono = %q", got)
+	}
+	if got := ColorError("bad"); got != colorBold+"bad"+colorReset {
+		t.Errorf("ColorError with mono = %q", got)
+	}
+}
@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxTitleKeyWidth is how much of the candidate key the terminal title shows.
+const maxTitleKeyWidth = 40
+
+// titleObserver keeps the terminal title on what the run is doing, e.g.
+// "nigel: lint · iter 12 · fixing src/a.go", and rings the bell when the run
+// finishes or dies (--bell), so a run in a background tmux pane or tab is
+// noticed. Only used when stdout is a terminal.
+type titleObserver struct {
+	BaseObserver
+	out       io.Writer
+	title     bool // Update the title (RunnerOptions.TerminalTitle)
+	bell      bool // Ring the bell at the end of the run (RunnerOptions.Bell)
+	task      string
+	iteration int
+}
+
+// newTitleObserver returns the observer for opts, or nil if it has nothing to
+// do: neither option is set, or stdout isn't a terminal.
+func newTitleObserver(opts RunnerOptions) *titleObserver {
+	if (!opts.TerminalTitle && !opts.Bell) || !isTerminal(os.Stdout) {
+		return nil
+	}
+	return &titleObserver{out: os.Stdout, title: opts.TerminalTitle, bell: opts.Bell}
+}
+
+// setTitle sets the terminal title (OSC 0), which tmux shows as the pane title.
+func (o *titleObserver) setTitle(parts ...string) {
+	if o.title {
+		fmt.Fprintf(o.out, "\033]0;nigel: %s\007", strings.Join(parts, " · "))
+	}
+}
+
+func (o *titleObserver) OnIterationStart(summary RunSummary) {
+	o.task, o.iteration = summary.Task, summary.Iterations
+	o.setTitle(o.task, fmt.Sprintf("iter %d", o.iteration))
+}
+
+func (o *titleObserver) OnCandidateSelected(summary RunSummary, candidate *Candidate) {
+	o.setTitle(o.task, fmt.Sprintf("iter %d", o.iteration), "fixing "+truncateKey(candidate.Key, maxTitleKeyWidth))
+}
+
+func (o *titleObserver) OnOutcome(task string, attempt AttemptRecord) {
+	o.setTitle(o.task, fmt.Sprintf("iter %d", o.iteration), string(attempt.Outcome))
+}
+
+func (o *titleObserver) OnRunEnd(summaries []RunSummary, err error) {
+	if err != nil {
+		o.setTitle("failed")
+	} else {
+		o.setTitle("finished")
+	}
+	if o.bell {
+		fmt.Fprint(o.out, "\a")
+	}
+}
@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTitleObserver(t *testing.T) {
+	var out bytes.Buffer
+	o := &titleObserver{out: &out, title: true, bell: true}
+
+	o.OnIterationStart(RunSummary{Task: "lint", Iterations: 12})
+	o.OnCandidateSelected(RunSummary{Task: "lint", Iterations: 12}, &Candidate{Key: "src/a.go"})
+	o.OnOutcome("lint", AttemptRecord{Outcome: OutcomeFixed})
+	o.OnRunEnd(nil, errors.New("boom"))
+
+	want := "\033]0;nigel: lint · iter 12\007" +
+		"\033]0;nigel: lint · iter 12 · fixing src/a.go\007" +
+		"\033]0;nigel: lint · iter 12 · FIXED\007" +
+		"\033]0;nigel: failed\007\a"
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestTitleObserverBellOnly(t *testing.T) {
+	var out bytes.Buffer
+	o := &titleObserver{out: &out, bell: true}
+
+	o.OnIterationStart(RunSummary{Task: "lint", Iterations: 1})
+	o.OnRunEnd(nil, nil)
+
+	if got := out.String(); got != "\a" || strings.Contains(got, "\033]") {
+		t.Errorf("output = %q, want only the bell", got)
+	}
+}
+
+func TestNewTitleObserverWithoutTerminal(t *testing.T) {
+	// Test output isn't a terminal, so there's nothing to title
+	if o := newTitleObserver(RunnerOptions{TerminalTitle: true, Bell: true}); o != nil {
+		t.Errorf("newTitleObserver = %+v, want nil", o)
+	}
+}
@@ -0,0 +1,57 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// trailerArgs returns `git commit --trailer` arguments recording the task and,
+// for each fix in the commit, its candidate, Claude session and outcome.
+func trailerArgs(task string, fixes []pendingCommit) string {
+	args := []string{trailerArg("Nigel-Task", task)}
+	for _, fix := range fixes {
+		args = append(args, trailerArg("Nigel-Candidate", fix.key))
+		if fix.session != "" {
+			args = append(args, trailerArg("Nigel-Session", fix.session))
+		}
+		args = append(args, trailerArg("Nigel-Outcome", string(fix.outcome)))
+	}
+	return strings.Join(args, " ")
+}
+
+// trailerArg formats one trailer, folding a multi-line value onto one line.
+func trailerArg(token, value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	return "--trailer " + shellQuote(token+": "+value)
+}
+
+// unpushedCheck succeeds if no remote-tracking branch contains HEAD.
+const unpushedCheck = `test -z "$(git branch -r --contains HEAD 2>/dev/null)"`
+
+// addTrailers amends the commit the success command just made with nigel's
+// trailers, so rollback and audit tooling can find the commits nigel made and
+// what for. Nothing is amended if HEAD is still at before (the success command
+// didn't commit) or commit_trailers is off. A commit that may already have been
+// pushed, with push_command set or HEAD on a remote branch, is left alone
+// rather than rewritten. A failure only warns: the fix is already committed.
+func (r *Runner) addTrailers(before string, fixes []pendingCommit) {
+	if !r.env.Config.TrailersEnabled() {
+		return
+	}
+	head, err := r.executor.CurrentRevision(r.workDir())
+	if err != nil || head == before {
+		return
+	}
+	if r.env.Config.PushCommand != "" {
+		fmt.Println(ColorWarning("Not adding Nigel-* trailers: success_command pushes (push_command is set)"))
+		return
+	}
+	if ok, err := r.executor.RunSilent(unpushedCheck, r.workDir()); err != nil || !ok {
+		fmt.Println(ColorWarning("Not adding Nigel-* trailers: the commit is already on a remote branch"))
+		return
+	}
+	cmd := r.env.Config.GitEnvPrefix() + "git commit --amend -q --no-edit --no-verify --allow-empty " + trailerArgs(r.task.Name, fixes)
+	if ok, err := r.executor.RunSilent(cmd, r.workDir()); err != nil || !ok {
+		fmt.Println(ColorWarning("Failed to add Nigel-* trailers to the commit"))
+	}
+}
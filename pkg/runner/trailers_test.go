@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestTrailerArgs(t *testing.T) {
+	got := trailerArgs("lint", []pendingCommit{
+		{key: "src/a.go:12", outcome: OutcomeFixed, session: "abc-123"},
+		{key: "{\n  \"file\": \"b.go\"\n}", outcome: OutcomeBestEffort},
+	})
+	want := `--trailer 'Nigel-Task: lint' --trailer 'Nigel-Candidate: src/a.go:12' --trailer 'Nigel-Session: abc-123' --trailer 'Nigel-Outcome: FIXED'` +
+		` --trailer 'Nigel-Candidate: { "file": "b.go" }' --trailer 'Nigel-Outcome: BEST_EFFORT'`
+	if got != want {
+		t.Errorf("trailerArgs() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTrailersAmendCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+		return string(output)
+	}
+	git("init", "-q")
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "a.go")
+	git("commit", "-q", "-m", "Fix: src/a.go:12")
+
+	amend := "git -c user.name=test -c user.email=test@example.com commit --amend -q --no-edit --no-verify --allow-empty " +
+		trailerArgs("lint", []pendingCommit{{key: "src/a.go:12", outcome: OutcomeFixed, session: "abc-123"}})
+	cmd := exec.Command("bash", "-c", amend)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("amend failed: %v\n%s", err, output)
+	}
+
+	trailers := git("log", "-1", "--format=%(trailers:key=Nigel-Candidate,key=Nigel-Session,valueonly)")
+	if trailers != "src/a.go:12\nabc-123\n\n" {
+		t.Errorf("trailers = %q", trailers)
+	}
+}
+
+func TestAddTrailersSkipsPushedCommits(t *testing.T) {
+	tests := []struct {
+		name      string
+		push      string
+		unpushed  bool
+		wantAmend bool
+	}{
+		{"unpushed", "", true, true},
+		{"push_command set", "git push", true, false},
+		{"on a remote branch", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			env := &Environment{
+				ProjectDir: tmpDir,
+				Config:     Config{SuccessCommand: "git commit -m $CANDIDATE", PushCommand: tt.push},
+				Tasks:      map[string]Task{"lint": {Name: "lint", Dir: tmpDir, Prompt: "fix"}},
+			}
+			runner, err := NewRunner(env, "lint", RunnerOptions{DryRun: true})
+			if err != nil {
+				t.Fatalf("NewRunner failed: %v", err)
+			}
+			mock := NewMockCommandExecutor()
+			mock.Revision = "def456"
+			mock.SetResult(unpushedCheck, tt.unpushed, nil)
+			runner.setExecutor(mock)
+
+			runner.addTrailers("abc123", []pendingCommit{{key: "a", outcome: OutcomeFixed}})
+			amend := "git commit --amend -q --no-edit --no-verify --allow-empty " + trailerArgs("lint", []pendingCommit{{key: "a", outcome: OutcomeFixed}})
+			if got := mock.CalledWith(amend); got != tt.wantAmend {
+				t.Errorf("amended = %v, want %v (calls %+v)", got, tt.wantAmend, mock.Calls)
+			}
+		})
+	}
+}
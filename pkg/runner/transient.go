@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// defaultTransientPatterns match Claude failures caused by the API or the
+// network rather than the candidate: overloaded or 5xx API responses and
+// dropped connections.
+var defaultTransientPatterns = []string{
+	`(?i)overloaded`,
+	`API Error: 5\d\d`,
+	`(?i)internal server error|bad gateway|service unavailable|gateway timeout`,
+	`ECONNRESET|ECONNREFUSED|ETIMEDOUT|EAI_AGAIN|socket hang up`,
+}
+
+const (
+	defaultTransientDelay   = 30 * time.Second
+	defaultTransientRetries = 3
+)
+
+// TransientErrors configures retries of Claude invocations that fail for
+// reasons unrelated to the candidate.
+type TransientErrors struct {
+	Patterns   []string      `yaml:"patterns"`    // Regexes matched against Claude's output (default: defaultTransientPatterns; [] disables)
+	Delay      time.Duration `yaml:"delay"`       // Pause before retrying (default 30s)
+	MaxRetries int           `yaml:"max_retries"` // Retries per attempt (default 3)
+}
+
+// transientMatcher recognizes transient Claude failures.
+type transientMatcher struct {
+	patterns   []*regexp.Regexp
+	delay      time.Duration
+	maxRetries int
+}
+
+// newTransientMatcher compiles a transient_errors config, filling in defaults.
+func newTransientMatcher(cfg TransientErrors) (*transientMatcher, error) {
+	m := &transientMatcher{delay: cfg.Delay, maxRetries: cfg.MaxRetries}
+	if m.delay == 0 {
+		m.delay = defaultTransientDelay
+	}
+	if m.maxRetries == 0 {
+		m.maxRetries = defaultTransientRetries
+	}
+	if m.delay < 0 || m.maxRetries < 0 {
+		return nil, fmt.Errorf("delay and max_retries must not be negative")
+	}
+
+	patterns := cfg.Patterns
+	if patterns == nil {
+		patterns = defaultTransientPatterns
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		m.patterns = append(m.patterns, re)
+	}
+	return m, nil
+}
+
+// match returns the text of the first transient error found in output, or "".
+func (m *transientMatcher) match(output string) string {
+	for _, re := range m.patterns {
+		if found := re.FindString(output); found != "" {
+			return found
+		}
+	}
+	return ""
+}
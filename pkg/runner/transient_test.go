@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestTransientMatcher(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := newTransientMatcher(TransientErrors{})
+		if err != nil {
+			t.Fatalf("newTransientMatcher failed: %v", err)
+		}
+		if m.delay != defaultTransientDelay || m.maxRetries != defaultTransientRetries {
+			t.Errorf("delay/maxRetries = %s/%d, want defaults", m.delay, m.maxRetries)
+		}
+		for _, output := range []string{
+			`API Error: 529 {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			"API Error: 502 Bad Gateway",
+			"Error: read ECONNRESET",
+		} {
+			if m.match(output) == "" {
+				t.Errorf("expected %q to be transient", output)
+			}
+		}
+		if got := m.match("Error: Invalid API key"); got != "" {
+			t.Errorf("auth error matched %q, want no match", got)
+		}
+	})
+
+	t.Run("custom patterns replace defaults", func(t *testing.T) {
+		m, err := newTransientMatcher(TransientErrors{Patterns: []string{`proxy: upstream \d+`}})
+		if err != nil {
+			t.Fatalf("newTransientMatcher failed: %v", err)
+		}
+		if got := m.match("proxy: upstream 17 unavailable"); got != "proxy: upstream 17" {
+			t.Errorf("match = %q", got)
+		}
+		if m.match("Overloaded") != "" {
+			t.Error("default patterns should not apply")
+		}
+	})
+
+	t.Run("empty list disables", func(t *testing.T) {
+		var cfg TransientErrors
+		if err := yaml.Unmarshal([]byte("patterns: []"), &cfg); err != nil {
+			t.Fatal(err)
+		}
+		m, err := newTransientMatcher(cfg)
+		if err != nil {
+			t.Fatalf("newTransientMatcher failed: %v", err)
+		}
+		if m.match("Overloaded") != "" {
+			t.Error("expected no patterns")
+		}
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		if _, err := newTransientMatcher(TransientErrors{Patterns: []string{"("}}); err == nil {
+			t.Error("expected error for invalid regex")
+		}
+	})
+}
+
+func TestRetryTransient(t *testing.T) {
+	tmpDir := t.TempDir()
+	env := &Environment{
+		ProjectDir: tmpDir,
+		Config: Config{
+			ResetCommand:    "git reset --hard",
+			TransientErrors: TransientErrors{Delay: time.Nanosecond, MaxRetries: 2},
+		},
+		Tasks: map[string]Task{
+			"test-task": {Name: "test-task", Dir: tmpDir, Prompt: "test prompt"},
+		},
+	}
+	runner, err := NewRunner(env, "test-task", RunnerOptions{})
+	if err != nil {
+		t.Fatalf("NewRunner failed: %v", err)
+	}
+	mock := NewMockCommandExecutor()
+	runner.setExecutor(mock)
+
+	failed := fmt.Errorf("exit status 1")
+	if !runner.retryTransient(failed, "API Error: 529 Overloaded", 1) {
+		t.Error("expected overloaded error to be retried")
+	}
+	if !mock.CalledWith("git reset --hard") {
+		t.Error("expected partial changes to be reset before retrying")
+	}
+	if runner.retryTransient(failed, "API Error: 529 Overloaded", 3) {
+		t.Error("expected no retry past max_retries")
+	}
+	if runner.retryTransient(nil, "Overloaded", 1) {
+		t.Error("successful runs should not be retried")
+	}
+	if runner.retryTransient(failed, "Error: Invalid API key", 1) {
+		t.Error("non-transient errors should not be retried")
+	}
+	if runner.retryTransient(&timeoutError{duration: time.Minute}, "Overloaded", 1) {
+		t.Error("timeouts should not be retried")
+	}
+	if runner.backoffLevel != 0 || len(runner.summary.Outcomes) != 0 {
+		t.Error("transient retries should not count as failures")
+	}
+}
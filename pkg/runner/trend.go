@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+)
+
+// CandidateTrend tracks how a task's candidate count changes across
+// iterations, so a run that isn't converging (or whose fixes keep spawning new
+// candidates) is visible at a glance.
+type CandidateTrend struct {
+	Start int // Candidate count at the first observation
+	Last  int // Candidate count at the latest observation
+	New   int // Candidates that appeared after the first observation
+
+	observations int
+	startTime    time.Time
+	lastTime     time.Time
+	seen         map[string]bool // Keys in the latest observation
+}
+
+// Observe records the candidates from one run of the candidate source.
+func (t *CandidateTrend) Observe(candidates []Candidate, now time.Time) {
+	keys := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		keys[c.Key] = true
+		if t.observations > 0 && !t.seen[c.Key] {
+			t.New++
+		}
+	}
+
+	if t.observations == 0 {
+		t.Start = len(keys)
+		t.startTime = now
+	}
+	t.observations++
+	t.Last = len(keys)
+	t.lastTime = now
+	t.seen = keys
+}
+
+// Observed reports whether any candidates have been recorded.
+func (t *CandidateTrend) Observed() bool {
+	return t.observations > 0
+}
+
+// ReductionPerHour returns the net number of candidates removed per hour.
+// Returns false until enough time has passed for the rate to mean anything.
+func (t *CandidateTrend) ReductionPerHour() (float64, bool) {
+	elapsed := t.lastTime.Sub(t.startTime)
+	if elapsed < time.Minute {
+		return 0, false
+	}
+	return float64(t.Start-t.Last) / elapsed.Hours(), true
+}
+
+// String describes the current count and trend, e.g.
+// "42 candidates (-8 net, +3 new, 4.0/h reduction)". Empty before the first observation.
+func (t *CandidateTrend) String() string {
+	if !t.Observed() {
+		return ""
+	}
+	s := fmt.Sprintf("%d candidates (%+d net, +%d new", t.Last, t.Last-t.Start, t.New)
+	if rate, ok := t.ReductionPerHour(); ok {
+		s += fmt.Sprintf(", %.1f/h reduction", rate)
+	}
+	return s + ")"
+}
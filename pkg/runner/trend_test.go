@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCandidateTrend(t *testing.T) {
+	candidates := func(keys ...string) []Candidate {
+		result := make([]Candidate, len(keys))
+		for i, key := range keys {
+			result[i] = Candidate{Key: key}
+		}
+		return result
+	}
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("empty before first observation", func(t *testing.T) {
+		trend := &CandidateTrend{}
+		if trend.Observed() || trend.String() != "" {
+			t.Errorf("unobserved trend = %q, want empty", trend.String())
+		}
+	})
+
+	t.Run("counts net change and new candidates", func(t *testing.T) {
+		trend := &CandidateTrend{}
+		trend.Observe(candidates("a", "b", "c", "d"), start)
+		trend.Observe(candidates("b", "c", "d", "e"), start.Add(10*time.Second))
+		trend.Observe(candidates("c", "e", "f"), start.Add(20*time.Second))
+
+		if trend.Start != 4 || trend.Last != 3 || trend.New != 2 {
+			t.Errorf("Start/Last/New = %d/%d/%d, want 4/3/2", trend.Start, trend.Last, trend.New)
+		}
+		if got, want := trend.String(), "3 candidates (-1 net, +2 new)"; got != want {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("reports reduction rate after a minute", func(t *testing.T) {
+		trend := &CandidateTrend{}
+		trend.Observe(candidates("a", "b", "c", "d", "e"), start)
+		trend.Observe(candidates("a", "b", "c"), start.Add(30*time.Minute))
+
+		rate, ok := trend.ReductionPerHour()
+		if !ok || rate != 4 {
+			t.Errorf("ReductionPerHour() = %v, %v; want 4, true", rate, ok)
+		}
+		if got, want := trend.String(), "3 candidates (-2 net, +0 new, 4.0/h reduction)"; got != want {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("reappearing candidate counts as new", func(t *testing.T) {
+		trend := &CandidateTrend{}
+		trend.Observe(candidates("a", "b"), start)
+		trend.Observe(candidates("b"), start)
+		trend.Observe(candidates("a", "b"), start)
+		if trend.New != 1 {
+			t.Errorf("New = %d, want 1", trend.New)
+		}
+	})
+}
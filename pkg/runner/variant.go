@@ -0,0 +1,76 @@
+package runner
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// variantAssigner picks which prompt variant each candidate gets. A candidate
+// keeps its variant for the whole run so retries are attributed consistently.
+type variantAssigner struct {
+	mode     string
+	count    int
+	next     int
+	assigned map[string]int
+}
+
+func newVariantAssigner(mode string, count int) *variantAssigner {
+	return &variantAssigner{
+		mode:     mode,
+		count:    count,
+		assigned: make(map[string]int),
+	}
+}
+
+// assign returns the variant index for a candidate key.
+func (a *variantAssigner) assign(key string) int {
+	if idx, ok := a.assigned[key]; ok {
+		return idx
+	}
+
+	var idx int
+	if a.mode == "hash" {
+		// fnv rather than the md5 used by sharding, so variants stay evenly
+		// mixed within each shard.
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		idx = int(h.Sum32() % uint32(a.count))
+	} else {
+		idx = a.next % a.count
+		a.next++
+	}
+
+	a.assigned[key] = idx
+	return idx
+}
+
+// validateVariants checks a task's variants and assignment mode, applying the
+// round-robin default.
+func validateVariants(variants []PromptVariant, mode *string) error {
+	switch *mode {
+	case "":
+		*mode = "round-robin"
+	case "round-robin", "hash":
+	default:
+		return fmt.Errorf("unknown 'variant_assignment' %q (expected round-robin or hash)", *mode)
+	}
+
+	if len(variants) < 2 {
+		return fmt.Errorf("'variants' needs at least two entries")
+	}
+
+	seen := make(map[string]bool)
+	for i, v := range variants {
+		if v.Name == "" {
+			return fmt.Errorf("variant %d missing required field 'name'", i+1)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("duplicate variant name: %s", v.Name)
+		}
+		seen[v.Name] = true
+		if (v.Prompt == "") == (v.Template == "") {
+			return fmt.Errorf("variant %s must have exactly one of 'prompt' or 'template'", v.Name)
+		}
+	}
+	return nil
+}
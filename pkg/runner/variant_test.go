@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVariantAssigner(t *testing.T) {
+	t.Run("round-robin alternates and is sticky", func(t *testing.T) {
+		a := newVariantAssigner("round-robin", 2)
+		got := []int{a.assign("a"), a.assign("b"), a.assign("c"), a.assign("a")}
+		want := []int{0, 1, 0, 0}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("assignments = %v, want %v", got, want)
+			}
+		}
+	})
+
+	t.Run("hash is stable across assigners", func(t *testing.T) {
+		a, b := newVariantAssigner("hash", 3), newVariantAssigner("hash", 3)
+		b.assign("other")
+		for _, key := range []string{"x", "y", "z"} {
+			if a.assign(key) != b.assign(key) {
+				t.Errorf("hash assignment for %q differs between assigners", key)
+			}
+		}
+	})
+}
+
+func TestValidateVariants(t *testing.T) {
+	two := []PromptVariant{{Name: "a", Prompt: "fix $INPUT"}, {Name: "b", Template: "b.md"}}
+
+	tests := []struct {
+		name     string
+		variants []PromptVariant
+		mode     string
+		wantErr  string
+	}{
+		{name: "valid", variants: two},
+		{name: "hash mode", variants: two, mode: "hash"},
+		{name: "unknown mode", variants: two, mode: "random", wantErr: "unknown 'variant_assignment'"},
+		{name: "single variant", variants: two[:1], wantErr: "at least two"},
+		{name: "missing name", variants: []PromptVariant{{Prompt: "x"}, {Name: "b", Prompt: "y"}}, wantErr: "missing required field 'name'"},
+		{name: "duplicate name", variants: []PromptVariant{{Name: "a", Prompt: "x"}, {Name: "a", Prompt: "y"}}, wantErr: "duplicate variant name"},
+		{name: "both prompt and template", variants: []PromptVariant{{Name: "a", Prompt: "x", Template: "t"}, {Name: "b", Prompt: "y"}}, wantErr: "exactly one"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode := tt.mode
+			err := validateVariants(tt.variants, &mode)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if tt.mode == "" && mode != "round-robin" {
+					t.Errorf("default mode = %q, want round-robin", mode)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadTemplateUsesVariant(t *testing.T) {
+	env := &Environment{
+		Tasks: map[string]Task{
+			"test-task": {
+				Name: "test-task",
+				Dir:  "/tmp/test-task",
+				Variants: []PromptVariant{
+					{Name: "terse", Prompt: "Fix $INPUT"},
+					{Name: "verbose", Prompt: "Please carefully fix $INPUT"},
+				},
+				VariantAssignment: "round-robin",
+			},
+		},
+	}
+
+	runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("NewRunner failed: %v", err)
+	}
+
+	for _, want := range []string{"Fix $INPUT", "Please carefully fix $INPUT"} {
+		runner.variant = &runner.task.Variants[runner.variants.assign(want)]
+		got, err := runner.loadTemplate()
+		if err != nil {
+			t.Fatalf("loadTemplate failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("loadTemplate() = %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"time"
+)
+
+// Verbosity is how much diagnostic output a run prints (-v, -vv, -vvv).
+// Each level includes the ones below it.
+type Verbosity int
+
+const (
+	VerbosityNormal     Verbosity = iota // Progress, Claude output and outcomes
+	VerbosityCandidates                  // -v: candidate source output and parsing
+	VerbosityCommands                    // -vv: full prompts and command lines
+	VerbosityStream                      // -vvv: raw Claude stream events
+)
+
+// leveledLogger prints diagnostic messages up to the run's verbosity.
+type leveledLogger struct {
+	level Verbosity
+}
+
+// enabled reports whether messages at level are shown.
+func (l leveledLogger) enabled(level Verbosity) bool {
+	return l.level >= level
+}
+
+// printf prints a message shown from level up.
+func (l leveledLogger) printf(level Verbosity, format string, args ...interface{}) {
+	if l.enabled(level) {
+		console().printf(format, args...)
+	}
+}
+
+// loggingExecutor shows each shell command before running it (-vv).
+type loggingExecutor struct {
+	CommandExecutor
+	log leveledLogger
+}
+
+func (e loggingExecutor) logCommand(command, workDir string) {
+	e.log.printf(VerbosityCommands, ColorDim("$ %s (in %s)")+"\n", command, relativePath(workDir))
+}
+
+func (e loggingExecutor) Run(command, workDir string) (bool, error) {
+	e.logCommand(command, workDir)
+	return e.CommandExecutor.Run(command, workDir)
+}
+
+func (e loggingExecutor) RunSilent(command, workDir string) (bool, error) {
+	e.logCommand(command, workDir)
+	return e.CommandExecutor.RunSilent(command, workDir)
+}
+
+func (e loggingExecutor) RunShowOnFail(command, workDir string) (bool, string, error) {
+	e.logCommand(command, workDir)
+	return e.CommandExecutor.RunShowOnFail(command, workDir)
+}
+
+func (e loggingExecutor) RunWatched(command, workDir string, onLine func(string)) (bool, string, error) {
+	e.logCommand(command, workDir)
+	return e.CommandExecutor.RunWatched(command, workDir, onLine)
+}
+
+func (e loggingExecutor) RunWithTimeout(command, workDir string, timeout time.Duration) (bool, error) {
+	e.logCommand(command, workDir)
+	return e.CommandExecutor.RunWithTimeout(command, workDir, timeout)
+}
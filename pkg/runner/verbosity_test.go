@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"testing"
+)
+
+func TestLeveledLoggerEnabled(t *testing.T) {
+	log := leveledLogger{level: VerbosityCommands}
+	for level, want := range map[Verbosity]bool{
+		VerbosityNormal:     true,
+		VerbosityCandidates: true,
+		VerbosityCommands:   true,
+		VerbosityStream:     false,
+	} {
+		if got := log.enabled(level); got != want {
+			t.Errorf("enabled(%d) at -vv = %v, want %v", level, got, want)
+		}
+	}
+}
+
+func TestNewRunnerLogsCommandsFromVV(t *testing.T) {
+	tmpDir := t.TempDir()
+	env := &Environment{
+		ProjectDir: tmpDir,
+		Tasks:      map[string]Task{"test-task": {Name: "test-task", Dir: tmpDir, CandidateSource: "echo '[]'"}},
+	}
+	mock := NewMockCommandExecutor()
+
+	for _, tt := range []struct {
+		verbosity Verbosity
+		logged    bool
+	}{
+		{VerbosityCandidates, false},
+		{VerbosityCommands, true},
+		{VerbosityStream, true},
+	} {
+		runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true, Executor: mock, Verbosity: tt.verbosity})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, logged := runner.executor.(loggingExecutor)
+		if logged != tt.logged {
+			t.Errorf("verbosity %d: executor logs commands = %v, want %v", tt.verbosity, logged, tt.logged)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// `isolation:` settings.
+const (
+	IsolationWorktree  = "worktree"  // Run each task in its own git worktree
+	IsolationContainer = "container" // Run each task's commands in a container, against a clone
+)
+
+// worktree is a dedicated checkout a run works in, so the user's checkout
+// isn't touched while the run churns. Its commits are merged back at the end.
+type worktree struct {
+	repoDir string // Top level of the user's checkout
+	dir     string // Top level of the worktree
+	branch  string // Branch the run commits to
+	base    string // Revision the branch started from
+	clone   bool   // dir is a standalone clone rather than a git worktree
+}
+
+// addWorktree creates a worktree for a task on a new branch at the HEAD of the
+// checkout containing projectDir.
+func addWorktree(projectDir, taskName string) (*worktree, error) {
+	wt, err := newWorktree(projectDir, taskName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := gitOutput(wt.repoDir, "worktree", "add", "-q", "-b", wt.branch, wt.dir, wt.base); err != nil {
+		os.Remove(wt.dir)
+		return nil, err
+	}
+	return wt, nil
+}
+
+// addClone creates a standalone clone of the checkout containing projectDir,
+// for a container that can't reach the checkout's .git directory. The run's
+// commits are fetched onto a new branch when it ends.
+func addClone(projectDir, taskName string) (*worktree, error) {
+	wt, err := newWorktree(projectDir, taskName)
+	if err != nil {
+		return nil, err
+	}
+	wt.clone = true
+	if _, err := gitOutput(wt.repoDir, "clone", "-q", "--no-hardlinks", wt.repoDir, wt.dir); err != nil {
+		os.RemoveAll(wt.dir)
+		return nil, err
+	}
+	if _, err := gitOutput(wt.dir, "checkout", "-q", "-b", wt.branch, wt.base); err != nil {
+		os.RemoveAll(wt.dir)
+		return nil, err
+	}
+	// Commits made in the container use the same identity as the checkout's
+	for _, key := range []string{"user.name", "user.email"} {
+		if value, err := gitOutput(wt.repoDir, "config", key); err == nil && value != "" {
+			gitOutput(wt.dir, "config", key, value)
+		}
+	}
+	return wt, nil
+}
+
+// newWorktree picks the directory and branch for a task's worktree or clone.
+func newWorktree(projectDir, taskName string) (*worktree, error) {
+	repoDir, err := gitOutput(projectDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	base, err := gitOutput(repoDir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "nigel-"+taskName+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create worktree directory: %w", err)
+	}
+	return &worktree{
+		repoDir: repoDir,
+		dir:     dir,
+		branch:  "nigel/" + taskName + "-" + time.Now().Format("20060102-150405"),
+		base:    base,
+	}, nil
+}
+
+// projectDir maps a directory in the user's checkout to the same directory in
+// the worktree.
+func (w *worktree) projectDir(dir string) string {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	rel, err := filepath.Rel(w.repoDir, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return w.dir
+	}
+	return filepath.Join(w.dir, rel)
+}
+
+// mergeBack fast-forwards the user's checkout to the run's commits and removes
+// the worktree. If the checkout can't be fast-forwarded (it has moved on, or
+// has uncommitted changes to the same files), the commits are left on the
+// branch to merge by hand.
+func (w *worktree) mergeBack() error {
+	head, err := gitOutput(w.dir, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	if w.clone {
+		if head != w.base {
+			if _, err := gitOutput(w.repoDir, "fetch", "-q", w.dir, "HEAD:refs/heads/"+w.branch); err != nil {
+				return err
+			}
+		}
+		if err := os.RemoveAll(w.dir); err != nil {
+			return err
+		}
+		if head == w.base {
+			return nil
+		}
+	} else if _, err := gitOutput(w.repoDir, "worktree", "remove", "--force", w.dir); err != nil {
+		return err
+	}
+
+	if head == w.base {
+		_, err := gitOutput(w.repoDir, "branch", "-D", w.branch)
+		return err
+	}
+	if _, err := gitOutput(w.repoDir, "merge", "-q", "--ff-only", w.branch); err != nil {
+		fmt.Println(ColorWarning(fmt.Sprintf("Couldn't fast-forward your checkout (%v); the fixes are on branch %s", err, w.branch)))
+		return nil
+	}
+	fmt.Println(ColorSuccess(fmt.Sprintf("✓ Merged fixes from worktree branch %s", w.branch)))
+	_, err = gitOutput(w.repoDir, "branch", "-d", w.branch)
+	return err
+}
+
+// gitOutput runs git in dir and returns its trimmed output, or an error
+// carrying git's message.
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
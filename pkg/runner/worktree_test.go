@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorktree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	git := func(dir string, args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	write := func(dir, name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	branchExists := func(branch string) bool {
+		_, err := gitOutput(repo, "rev-parse", "--verify", "-q", "refs/heads/"+branch)
+		return err == nil
+	}
+
+	git(repo, "init", "-q")
+	write(repo, "a.txt", "one")
+	git(repo, "add", "a.txt")
+	git(repo, "commit", "-q", "-m", "initial")
+
+	t.Run("commits are merged back and the worktree removed", func(t *testing.T) {
+		wt, err := addWorktree(repo, "lint")
+		if err != nil {
+			t.Fatalf("addWorktree failed: %v", err)
+		}
+		write(wt.dir, "a.txt", "fixed")
+		git(wt.dir, "commit", "-q", "-am", "fix")
+
+		// The user's checkout is untouched until the merge
+		if data, _ := os.ReadFile(filepath.Join(repo, "a.txt")); string(data) != "one" {
+			t.Errorf("checkout changed before the merge: %q", data)
+		}
+		if err := wt.mergeBack(); err != nil {
+			t.Fatalf("mergeBack failed: %v", err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(repo, "a.txt")); string(data) != "fixed" {
+			t.Errorf("a.txt = %q after the merge, want fixed", data)
+		}
+		if _, err := os.Stat(wt.dir); !os.IsNotExist(err) {
+			t.Error("worktree directory should be removed")
+		}
+		if branchExists(wt.branch) {
+			t.Errorf("merged branch %s should be deleted", wt.branch)
+		}
+	})
+
+	t.Run("a clone's commits are fetched and merged back", func(t *testing.T) {
+		wt, err := addClone(repo, "lint")
+		if err != nil {
+			t.Fatalf("addClone failed: %v", err)
+		}
+		write(wt.dir, "a.txt", "fixed in a clone")
+		git(wt.dir, "commit", "-q", "-am", "fix")
+
+		if err := wt.mergeBack(); err != nil {
+			t.Fatalf("mergeBack failed: %v", err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(repo, "a.txt")); string(data) != "fixed in a clone" {
+			t.Errorf("a.txt = %q after the merge", data)
+		}
+		if _, err := os.Stat(wt.dir); !os.IsNotExist(err) {
+			t.Error("clone should be removed")
+		}
+		if branchExists(wt.branch) {
+			t.Errorf("merged branch %s should be deleted", wt.branch)
+		}
+	})
+
+	t.Run("commits stay on the branch when the checkout moved on", func(t *testing.T) {
+		wt, err := addWorktree(repo, "lint")
+		if err != nil {
+			t.Fatalf("addWorktree failed: %v", err)
+		}
+		write(wt.dir, "b.txt", "from nigel")
+		git(wt.dir, "add", "b.txt")
+		git(wt.dir, "commit", "-q", "-m", "fix")
+		write(repo, "c.txt", "from the user")
+		git(repo, "add", "c.txt")
+		git(repo, "commit", "-q", "-m", "meanwhile")
+
+		if err := wt.mergeBack(); err != nil {
+			t.Fatalf("mergeBack failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(repo, "b.txt")); !os.IsNotExist(err) {
+			t.Error("checkout should not have been merged into")
+		}
+		if !branchExists(wt.branch) {
+			t.Errorf("branch %s should be kept for a manual merge", wt.branch)
+		}
+	})
+
+	t.Run("projectDir maps subdirectories into the worktree", func(t *testing.T) {
+		wt := &worktree{repoDir: repo, dir: "/tmp/wt"}
+		if resolved, err := filepath.EvalSymlinks(repo); err == nil {
+			wt.repoDir = resolved
+		}
+		if got := wt.projectDir(filepath.Join(repo, "services", "api")); got != filepath.Join("/tmp/wt", "services", "api") {
+			t.Errorf("projectDir = %q", got)
+		}
+	})
+}
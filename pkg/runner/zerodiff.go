@@ -0,0 +1,61 @@
+package runner
+
+import "fmt"
+
+// `zero_diff_fix:` settings for a candidate that disappeared from the re-check
+// although the attempt left no changes to commit, which usually means the
+// candidate source is nondeterministic rather than that Claude fixed it.
+// Unset, such a candidate is logged as FIXED like with ZeroDiffFixed, but
+// attempts that changed no files are NOT_FIXED without a re-check.
+const (
+	ZeroDiffFixed   = "fixed"   // Log it as FIXED with no changes to commit
+	ZeroDiffConfirm = "confirm" // Run the candidate source again: FIXED if it's still gone, FLAKY_SOURCE if it's back
+	ZeroDiffFlaky   = "flaky"   // Log it as FLAKY_SOURCE
+)
+
+// handleZeroDiffFix records a candidate the re-check no longer lists although
+// there's nothing to commit. FLAKY_SOURCE isn't counted as a fix and is
+// requeued like any other failed outcome.
+func (r *Runner) handleZeroDiffFix(candidate *Candidate) (bool, error) {
+	switch r.task.ZeroDiffFix {
+	case ZeroDiffFlaky:
+		return r.flakySource(candidate, "candidate disappeared with no changes")
+	case ZeroDiffConfirm:
+		fmt.Println(ColorInfo("No changes to commit, re-running the candidate source to confirm..."))
+		// The pipelined output is the run that lost the candidate
+		r.prefetched = nil
+		back, err := r.stillPresent(candidate)
+		if err != nil {
+			return false, err
+		}
+		if back {
+			return r.flakySource(candidate, "candidate disappeared with no changes, then came back")
+		}
+	}
+	r.logOutcome(OutcomeFixed, "no changes to commit")
+	return false, nil
+}
+
+// stillPresent runs the candidate source afresh and reports whether it lists
+// candidate.
+func (r *Runner) stillPresent(candidate *Candidate) (bool, error) {
+	output, err := r.execCandidateSource()
+	if err != nil {
+		return false, candidateSourceError("candidate source confirmation run failed", err)
+	}
+	candidates, _, err := ParseTaskCandidates(output, r.task)
+	if err != nil {
+		return false, candidateParseError("failed to parse candidates", err)
+	}
+	candidates = FilterByPartition(candidates, r.opts.Partition)
+	return containsKey(candidates, candidate.Key), nil
+}
+
+func (r *Runner) flakySource(candidate *Candidate, details string) (bool, error) {
+	fmt.Println(ColorWarning(fmt.Sprintf("✗ Candidate %s disappeared without any changes: the candidate source looks nondeterministic", displayKey(candidate.Key))))
+	r.logOutcome(OutcomeFlakySource, details)
+	if err := r.requeue(candidate, OutcomeFlakySource); err != nil {
+		return false, err
+	}
+	return false, nil
+}
@@ -0,0 +1,75 @@
+package runner
+
+import "testing"
+
+func TestHandleZeroDiffFix(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		source string
+		want   Outcome
+	}{
+		{"default counts it as fixed", "", `echo '["a"]'`, OutcomeFixed},
+		{"flaky", ZeroDiffFlaky, `echo '[]'`, OutcomeFlakySource},
+		{"confirm, still gone", ZeroDiffConfirm, `echo '["b"]'`, OutcomeFixed},
+		{"confirm, back again", ZeroDiffConfirm, `echo '["a", "b"]'`, OutcomeFlakySource},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			env := &Environment{
+				ProjectDir: tmpDir,
+				Tasks: map[string]Task{
+					"test-task": {Name: "test-task", Dir: tmpDir, Prompt: "test prompt", CandidateSource: tt.source, ZeroDiffFix: tt.policy},
+				},
+			}
+			runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
+			if err != nil {
+				t.Fatalf("NewRunner failed: %v", err)
+			}
+			runner.setExecutor(NewMockCommandExecutor())
+			runner.prefetched = &prefetch{}
+
+			if _, err := runner.handleSuccess(&Candidate{Key: "a"}, true); err != nil {
+				t.Fatalf("handleSuccess failed: %v", err)
+			}
+			if runner.summary.Outcomes[tt.want] != 1 || runner.summary.Attempts() != 1 {
+				t.Errorf("outcomes = %v, want one %s", runner.summary.Outcomes, tt.want)
+			}
+			if tt.policy == ZeroDiffConfirm && runner.prefetched != nil {
+				t.Error("expected the pipelined output to be dropped")
+			}
+			if ignored := runner.ignoredList.Contains("a"); ignored != (tt.want == OutcomeFlakySource) {
+				t.Errorf("ignored = %v after %s", ignored, tt.want)
+			}
+		})
+	}
+}
+
+func TestZeroDiffFixWithoutChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		listings []string
+		want     Outcome
+	}{
+		{"default skips the re-check", "", []string{`["a"]`, `["b"]`}, OutcomeNotFixed},
+		{"still listed", ZeroDiffFlaky, []string{`["a"]`}, OutcomeNotFixed},
+		{"fixed", ZeroDiffFixed, []string{`["a"]`, `["b"]`}, OutcomeFixed},
+		{"flaky", ZeroDiffFlaky, []string{`["a"]`, `["b"]`}, OutcomeFlakySource},
+		{"confirm, still gone", ZeroDiffConfirm, []string{`["a"]`, `["b"]`, `["b"]`}, OutcomeFixed},
+		{"confirm, back again", ZeroDiffConfirm, []string{`["a"]`, `["b"]`, `["a"]`}, OutcomeFlakySource},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, _ := newIterationRunner(t, Config{VerifyCommand: "make"}, Task{ZeroDiffFix: tt.policy}, RunnerOptions{}, tt.listings...)
+
+			if _, err := runner.runIteration(); err != nil {
+				t.Fatalf("runIteration failed: %v", err)
+			}
+			if runner.summary.Outcomes[tt.want] != 1 || runner.summary.Attempts() != 1 {
+				t.Errorf("outcomes = %v, want one %s", runner.summary.Outcomes, tt.want)
+			}
+		})
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/cdlewis/nigel/pkg/runner"
 )
 
 func main() {
@@ -16,14 +18,52 @@ func main() {
 	limitFlag := flag.Int("limit", 0, "Maximum number of iterations (0 = unlimited)")
 	timeLimitFlag := flag.Duration("time-limit", 0*time.Second, "Maximum duration (e.g. 1h30m, 30m, 5s) (0 = unlimited)")
 	taskTimeoutFlag := flag.Duration("task-timeout", 0*time.Second, "Per-candidate timeout (e.g. 5m, 30s) (overrides task.yaml)")
+	minIntervalFlag := flag.Duration("min-interval", 0*time.Second, "Minimum time between Claude invocations (e.g. 2m) (0 = no delay)")
 	claudeCommandFlag := flag.String("claude-command", "", "Claude command to use (overrides task.yaml)")
 	dryRunFlag := flag.Bool("dry-run", false, "Print prompt without executing Claude")
-	verboseFlag := flag.Bool("verbose", false, "Print verbose output")
+	var verbosity runner.Verbosity
+	flag.Var(verbosityFlag{&verbosity, runner.VerbosityCandidates}, "v", "Verbose: show candidate source output and parsing")
+	flag.Var(verbosityFlag{&verbosity, runner.VerbosityCommands}, "vv", "More verbose: also show full prompts and command lines")
+	flag.Var(verbosityFlag{&verbosity, runner.VerbosityStream}, "vvv", "Most verbose: also show raw Claude stream events")
+	flag.Var(verbosityFlag{&verbosity, runner.VerbosityCommands}, "verbose", "Same as -vv")
 	shardFlag := flag.String("shard", "", "Shard index/total (e.g. 1/4 for first of 4 workers)")
+	allFlag := flag.Bool("all", false, "Run all tasks in dependency order")
+	tasksFlag := flag.String("tasks", "", "Comma-separated tasks to run sequentially (alternative to positional args)")
+	resumeSessionFlag := flag.String("resume-session", "", "Reopen the Claude session of a candidate's last attempt (requires one task)")
+	formatFlag := flag.String("format", "csv", "Export format: csv or json (export), text or json (ignore export/merge, default text)")
+	outFlag := flag.String("out", "", "Output file for export and ignore export/merge (default stdout)")
+	minBatteryFlag := flag.Int("min-battery", 0, "Pause while on battery below this percentage, resuming on mains power (0 = never)")
+	pauseOnMeteredFlag := flag.Bool("pause-on-metered", false, "Pause while on a metered connection (Linux with NetworkManager)")
+	notifyDesktopFlag := flag.Bool("notify-desktop", false, "Show desktop notifications on completion, fatal errors and rate limits")
+	socketFlag := flag.String("socket", "nigel.sock", "Unix socket path for the JSON-RPC control server (serve only)")
+	yesFlag := flag.Bool("yes", false, "Start without confirming the preflight summary")
+	streamFlag := flag.String("stream", "full", "Claude output to show: full, or summary (tool calls and a final paragraph; the log keeps the full text)")
+	noTitleFlag := flag.Bool("no-title", false, "Don't show the run's progress in the terminal title")
+	bellFlag := flag.Bool("bell", false, "Ring the terminal bell when the run finishes or hits a fatal error")
+	pruneFlag := flag.Bool("prune", false, "Drop ignored keys the candidate source no longer produces")
+	noCommitFlag := flag.Bool("no-commit", false, "Run Claude and verify, but print the success and reset commands instead of running them")
+	byRuleFlag := flag.String("by-rule", "", "Candidate field (map key or array index) to break down cost and fix rate by (stats only)")
+	durationsFlag := flag.Bool("durations", false, "Show percentiles and a histogram of Claude's run times (stats only)")
+	shardsFlag := flag.Int("shards", 0, "Number of workers to split the logged attempts across (simulate only)")
+	evaluateFlag := flag.Int("evaluate", 0, "Attempt N candidates and report how many would have been fixed, resetting instead of committing")
+	sampleFlag := flag.Int("sample", 0, "Only work on N randomly chosen candidates")
+	maxCommitsFlag := flag.Int("max-commits", 0, "Stop once N commits have been created (0 = no limit)")
+	maxCostFlag := flag.Float64("max-cost", 0, "Stop once Claude has cost this many US dollars (0 = no limit)")
+	repeatFlag := flag.Int("repeat", 0, "Attempt each candidate up to N times (overrides task.yaml)")
+	samplePercentFlag := flag.Float64("sample-percent", 0, "Only work on this percentage of the candidates, chosen at random")
+	httpFlag := flag.String("http", "localhost:8080", "Address for the web dashboard, empty to disable (serve only)")
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: nigel <task> [options]\n")
-		fmt.Fprintf(os.Stderr, "       nigel --list\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: nigel <task> [<task>...] [options]\n")
+		fmt.Fprintf(os.Stderr, "       nigel --all [options]\n")
+		fmt.Fprintf(os.Stderr, "       nigel --list\n")
+		fmt.Fprintf(os.Stderr, "       nigel stats <task> [--by-rule <field> | --durations]\n")
+		fmt.Fprintf(os.Stderr, "       nigel simulate <task> --shards <n>\n")
+		fmt.Fprintf(os.Stderr, "       nigel export <task> [--format csv|json] [--out <file>]\n")
+		fmt.Fprintf(os.Stderr, "       nigel ignore export <task> | import <task> <file>... | merge <file>... [--format text|json] [--out <file>]\n")
+		fmt.Fprintf(os.Stderr, "       nigel <task> --resume-session <candidate>\n")
+		fmt.Fprintf(os.Stderr, "       nigel serve [--socket <path>] [--http <addr>]\n")
+		fmt.Fprintf(os.Stderr, "       nigel doctor\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 	}
@@ -32,10 +72,22 @@ func main() {
 	args := reorderArgs(os.Args[1:])
 	flag.CommandLine.Parse(args)
 
+	// Handle doctor subcommand before discovery so config errors are reported as checks
+	if flag.NArg() > 0 && flag.Arg(0) == "doctor" {
+		checks := runner.RunDoctor()
+		fmt.Print(runner.FormatDoctor(checks))
+		for _, c := range checks {
+			if c.Err != nil {
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
 	// Discover environment
-	env, err := DiscoverEnvironment()
+	env, err := runner.DiscoverEnvironment()
 	if err != nil {
-		fmt.Fprintln(os.Stderr, ColorError(fmt.Sprintf("Error: %v", err)))
+		fmt.Fprintln(os.Stderr, runner.ColorError(fmt.Sprintf("Error: %v", err)))
 		os.Exit(1)
 	}
 
@@ -45,63 +97,215 @@ func main() {
 		return
 	}
 
-	// Get task name from positional args
-	remaining := flag.Args()
-	if len(remaining) == 0 {
-		fmt.Fprintln(os.Stderr, ColorError("Error: task name required"))
+	// Handle stats subcommand
+	if flag.NArg() > 0 && flag.Arg(0) == "stats" {
+		if flag.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, runner.ColorError("Error: usage: nigel stats <task> [--by-rule <field> | --durations]"))
+			os.Exit(1)
+		}
+		if err := runner.ShowStats(env, flag.Arg(1), *byRuleFlag, *durationsFlag); err != nil {
+			fmt.Fprintln(os.Stderr, runner.ColorError(fmt.Sprintf("Error: %v", err)))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle simulate subcommand
+	if flag.NArg() > 0 && flag.Arg(0) == "simulate" {
+		if flag.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, runner.ColorError("Error: usage: nigel simulate <task> --shards <n>"))
+			os.Exit(1)
+		}
+		if err := runner.Simulate(env, flag.Arg(1), *shardsFlag); err != nil {
+			fmt.Fprintln(os.Stderr, runner.ColorError(fmt.Sprintf("Error: %v", err)))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle export subcommand
+	if flag.NArg() > 0 && flag.Arg(0) == "export" {
+		if flag.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, runner.ColorError("Error: usage: nigel export <task> [--format csv|json] [--out <file>]"))
+			os.Exit(1)
+		}
+		if err := runner.ExportTask(env, flag.Arg(1), *formatFlag, *outFlag); err != nil {
+			fmt.Fprintln(os.Stderr, runner.ColorError(fmt.Sprintf("Error: %v", err)))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle ignore subcommand
+	if flag.NArg() > 0 && flag.Arg(0) == "ignore" {
+		// --format defaults to csv for export; ignore lists are text unless asked for json
+		format := "text"
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "format" {
+				format = f.Value.String()
+			}
+		})
+		var err error
+		switch {
+		case flag.NArg() == 3 && flag.Arg(1) == "export":
+			err = runner.ExportIgnored(env, flag.Arg(2), format, *outFlag)
+		case flag.NArg() >= 4 && flag.Arg(1) == "import":
+			err = runner.ImportIgnored(env, flag.Arg(2), flag.Args()[3:])
+		case flag.NArg() >= 3 && flag.Arg(1) == "merge":
+			err = runner.MergeIgnored(flag.Args()[2:], format, *outFlag)
+		default:
+			err = fmt.Errorf("usage: nigel ignore export <task> | import <task> <file>... | merge <file>... [--format text|json] [--out <file>]")
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, runner.ColorError(fmt.Sprintf("Error: %v", err)))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle --resume-session
+	if *resumeSessionFlag != "" {
+		if flag.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, runner.ColorError("Error: usage: nigel <task> --resume-session <candidate>"))
+			os.Exit(1)
+		}
+		if err := runner.ResumeSession(env, flag.Arg(0), *resumeSessionFlag, *claudeCommandFlag); err != nil {
+			fmt.Fprintln(os.Stderr, runner.ColorError(fmt.Sprintf("Error: %v", err)))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Get task names from positional args and --tasks
+	taskNames := flag.Args()
+	if *tasksFlag != "" {
+		for _, name := range strings.Split(*tasksFlag, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				taskNames = append(taskNames, name)
+			}
+		}
+	}
+	if *allFlag {
+		taskNames, err = runner.SortTasksByDependencies(env.Tasks)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, runner.ColorError(fmt.Sprintf("Error: %v", err)))
+			os.Exit(1)
+		}
+	}
+	if len(taskNames) == 0 {
+		fmt.Fprintln(os.Stderr, runner.ColorError("Error: task name required"))
 		fmt.Fprintln(os.Stderr, "Use --list to see available tasks")
 		os.Exit(1)
 	}
 
-	taskName := remaining[0]
-
 	// Parse and validate shard flag (1-based indexing: 1/N through N/N)
-	var partition HashPartition = NoFilter()
+	var partition runner.HashPartition = runner.NoFilter()
 	if *shardFlag != "" {
 		parts := strings.Split(*shardFlag, "/")
 		if len(parts) != 2 {
-			fmt.Fprintln(os.Stderr, ColorError("Error: --shard must be in format INDEX/TOTAL (e.g. 1/4)"))
+			fmt.Fprintln(os.Stderr, runner.ColorError("Error: --shard must be in format INDEX/TOTAL (e.g. 1/4)"))
 			os.Exit(1)
 		}
 		index, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
 		total, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
 		if err1 != nil || err2 != nil || total < 1 || index < 1 || index > total {
-			fmt.Fprintln(os.Stderr, ColorError("Error: invalid shard values"))
+			fmt.Fprintln(os.Stderr, runner.ColorError("Error: invalid shard values"))
+			os.Exit(1)
+		}
+		partition = runner.HashPartition{WorkerCount: total, WorkerIndex: index - 1} // Convert to 0-based internally
+	} else if env.Shards != nil {
+		hostname, err := os.Hostname()
+		if err == nil {
+			partition, err = env.Shards.Partition(hostname)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, runner.ColorError(fmt.Sprintf("Error: %v", err)))
 			os.Exit(1)
 		}
-		partition = HashPartition{WorkerCount: total, WorkerIndex: index - 1} // Convert to 0-based internally
+		fmt.Println(runner.ColorInfo(fmt.Sprintf("Shard %d/%d for host %s (shards.yaml)", partition.WorkerIndex+1, partition.WorkerCount, hostname)))
 	}
 
-	// Create and run the runner
-	opts := RunnerOptions{
-		Limit:         *limitFlag,
-		TimeLimit:     *timeLimitFlag,
-		DryRun:        *dryRunFlag,
-		Verbose:       *verboseFlag,
-		Partition:     partition,
-		Timeout:       *taskTimeoutFlag,
-		ClaudeCommand: *claudeCommandFlag,
+	if *sampleFlag < 0 || *samplePercentFlag < 0 || *samplePercentFlag > 100 || (*sampleFlag > 0 && *samplePercentFlag > 0) {
+		fmt.Fprintln(os.Stderr, runner.ColorError("Error: use one of --sample N or --sample-percent P (0 < P <= 100)"))
+		os.Exit(1)
 	}
-
-	runner, err := NewRunner(env, taskName, opts)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, ColorError(fmt.Sprintf("Error: %v", err)))
+	if *repeatFlag < 0 {
+		fmt.Fprintln(os.Stderr, runner.ColorError("Error: --repeat must not be negative"))
+		os.Exit(1)
+	}
+	if *maxCommitsFlag < 0 {
+		fmt.Fprintln(os.Stderr, runner.ColorError("Error: --max-commits must not be negative"))
+		os.Exit(1)
+	}
+	if *maxCostFlag < 0 {
+		fmt.Fprintln(os.Stderr, runner.ColorError("Error: --max-cost must not be negative"))
 		os.Exit(1)
 	}
 
-	if err := runner.Run(); err != nil {
-		fmt.Fprintln(os.Stderr, ColorError(fmt.Sprintf("Error: %v", err)))
+	if mode := runner.StreamMode(*streamFlag); mode != runner.StreamFull && mode != runner.StreamSummary {
+		fmt.Fprintln(os.Stderr, runner.ColorError("Error: --stream must be full or summary"))
 		os.Exit(1)
 	}
+
+	// Create and run the runner
+	opts := runner.RunnerOptions{
+		Limit:          *limitFlag,
+		TimeLimit:      *timeLimitFlag,
+		DryRun:         *dryRunFlag,
+		Verbosity:      verbosity,
+		Partition:      partition,
+		Timeout:        *taskTimeoutFlag,
+		ClaudeCommand:  *claudeCommandFlag,
+		NotifyDesktop:  *notifyDesktopFlag,
+		MinInterval:    *minIntervalFlag,
+		MinBattery:     *minBatteryFlag,
+		PauseOnMetered: *pauseOnMeteredFlag,
+		Stream:         runner.StreamMode(*streamFlag),
+		TerminalTitle:  !*noTitleFlag,
+		Bell:           *bellFlag,
+		Prune:          *pruneFlag,
+		NoCommit:       *noCommitFlag,
+		Evaluate:       *evaluateFlag,
+		Sample:         *sampleFlag,
+		SamplePercent:  *samplePercentFlag,
+		MaxCommits:     *maxCommitsFlag,
+		MaxCost:        *maxCostFlag,
+		Repeat:         *repeatFlag,
+	}
+
+	// Handle serve subcommand; tasks are chosen by each start request
+	if taskNames[0] == "serve" && len(flag.Args()) == 1 {
+		if err := runner.Serve(env, *socketFlag, *httpFlag, opts); err != nil {
+			fmt.Fprintln(os.Stderr, runner.ColorError(fmt.Sprintf("Error: %v", err)))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Require confirmation of the preflight summary so a mistyped task can't start committing
+	opts.Confirm = runner.ConfirmOnTerminal
+	if *yesFlag {
+		opts.Confirm = runner.AssumeYes
+	}
+
+	run := func() error { return runner.RunTasks(env, taskNames, opts) }
+	if playlist, ok := env.Playlists[taskNames[0]]; ok && len(taskNames) == 1 {
+		run = func() error { return runner.RunPlaylist(env, playlist, opts) }
+	}
+
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, runner.ColorError(fmt.Sprintf("Error: %s", runner.DescribeError(err))))
+		os.Exit(runner.ExitCode(err))
+	}
 }
 
-func listTasks(env *Environment) {
+func listTasks(env *runner.Environment) {
 	if len(env.Tasks) == 0 {
 		fmt.Println("No tasks found.")
 		return
 	}
 
-	fmt.Println(ColorBold("Available tasks:"))
+	fmt.Println(runner.ColorBold("Available tasks:"))
 
 	// Sort task names for consistent output
 	names := make([]string, 0, len(env.Tasks))
@@ -116,8 +320,49 @@ func listTasks(env *Environment) {
 		if task.AcceptBestEffort {
 			mode = "best-effort"
 		}
-		fmt.Printf("  %s [%s]\n", ColorInfo(fmt.Sprintf("%-30s", name)), mode)
+		fmt.Printf("  %s [%s]\n", runner.ColorInfo(fmt.Sprintf("%-30s", name)), mode)
+	}
+
+	if len(env.Playlists) == 0 {
+		return
+	}
+
+	fmt.Println("\n" + runner.ColorBold("Playlists:"))
+	names = names[:0]
+	for name := range env.Playlists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		playlist := env.Playlists[name]
+		tasks := make([]string, len(playlist.Tasks))
+		for i, entry := range playlist.Tasks {
+			tasks[i] = entry.Task
+		}
+		fmt.Printf("  %s [%s] %s\n", runner.ColorInfo(fmt.Sprintf("%-30s", name)), playlist.Mode, strings.Join(tasks, ", "))
+	}
+}
+
+// verbosityFlag is a boolean flag that raises the verbosity to level when set.
+type verbosityFlag struct {
+	verbosity *runner.Verbosity
+	level     runner.Verbosity
+}
+
+func (f verbosityFlag) IsBoolFlag() bool { return true }
+
+func (f verbosityFlag) String() string { return "false" }
+
+func (f verbosityFlag) Set(value string) error {
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	if on && f.level > *f.verbosity {
+		*f.verbosity = f.level
 	}
+	return nil
 }
 
 // reorderArgs moves flags before positional arguments so Go's flag package can parse them.
@@ -135,7 +380,13 @@ func reorderArgs(args []string) []string {
 				switch arg {
 				case "-limit", "--limit", "-time-limit", "--time-limit",
 					"-task-timeout", "--task-timeout", "-claude-command", "--claude-command",
-					"-shard", "--shard":
+					"-min-interval", "--min-interval", "-min-battery", "--min-battery",
+					"-shard", "--shard", "-tasks", "--tasks", "-resume-session", "--resume-session",
+					"-format", "--format", "-out", "--out", "-socket", "--socket", "-http", "--http", "-stream", "--stream",
+					"-evaluate", "--evaluate", "-shards", "--shards",
+					"-sample", "--sample", "-sample-percent", "--sample-percent", "-max-commits", "--max-commits",
+					"-max-cost", "--max-cost", "-repeat", "--repeat",
+					"-by-rule", "--by-rule":
 					i++
 					flags = append(flags, args[i])
 				}
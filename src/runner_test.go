@@ -1,568 +0,0 @@
-package main
-
-import (
-	"fmt"
-	"os"
-	"path/filepath"
-	"testing"
-	"time"
-)
-
-func TestCalculateBackoff(t *testing.T) {
-	tests := []struct {
-		name     string
-		level    int
-		expected time.Duration
-	}{
-		{
-			name:     "level 0 returns base backoff",
-			level:    0,
-			expected: 5 * time.Minute,
-		},
-		{
-			name:     "level 1 doubles",
-			level:    1,
-			expected: 10 * time.Minute,
-		},
-		{
-			name:     "level 2 quadruples",
-			level:    2,
-			expected: 20 * time.Minute,
-		},
-		{
-			name:     "level 3 is 40 minutes",
-			level:    3,
-			expected: 40 * time.Minute,
-		},
-		{
-			name:     "level 4 caps at max",
-			level:    4,
-			expected: 1 * time.Hour,
-		},
-		{
-			name:     "level 5 stays at max",
-			level:    5,
-			expected: 1 * time.Hour,
-		},
-		{
-			name:     "level 10 stays at max",
-			level:    10,
-			expected: 1 * time.Hour,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := calculateBackoff(tt.level)
-			if result != tt.expected {
-				t.Errorf("calculateBackoff(%d) = %v, want %v", tt.level, result, tt.expected)
-			}
-		})
-	}
-}
-
-func TestRateLimitError(t *testing.T) {
-	err := &rateLimitError{msg: "test rate limit"}
-
-	if err.Error() != "test rate limit" {
-		t.Errorf("rateLimitError.Error() = %q, want %q", err.Error(), "test rate limit")
-	}
-
-	// Verify it implements error interface
-	var _ error = err
-}
-
-func TestCandidateVerification(t *testing.T) {
-	tests := []struct {
-		name          string
-		candidateKey  string
-		sourceOutput  string
-		expectedFixed bool
-	}{
-		{
-			name:          "candidate present in JSON array - not fixed",
-			candidateKey:  "func_800BB754_ABF84",
-			sourceOutput:  `["func_800BB754_ABF84", "func_800BB66C_B2C2C"]`,
-			expectedFixed: false,
-		},
-		{
-			name:          "candidate absent from JSON array - fixed",
-			candidateKey:  "func_800BB754_ABF84",
-			sourceOutput:  `["func_800BB66C_B2C2C", "other_function"]`,
-			expectedFixed: true,
-		},
-		{
-			name:          "candidate present in newline-separated output - not fixed",
-			candidateKey:  "func_800BB754_ABF84",
-			sourceOutput:  "func_800BB754_ABF84\nfunc_800BB66C_B2C2C\n",
-			expectedFixed: false,
-		},
-		{
-			name:          "candidate absent from newline-separated output - fixed",
-			candidateKey:  "func_800BB754_ABF84",
-			sourceOutput:  "func_800BB66C_B2C2C\nother_function\n",
-			expectedFixed: true,
-		},
-		{
-			name:          "object candidates - candidate present",
-			candidateKey:  `{"file":"foo.c","line":42}`,
-			sourceOutput:  `[{"file":"foo.c","line":42},{"file":"bar.c","line":10}]`,
-			expectedFixed: false,
-		},
-		{
-			name:          "object candidates - candidate absent",
-			candidateKey:  `{"file":"foo.c","line":42}`,
-			sourceOutput:  `[{"file":"bar.c","line":10},{"file":"baz.c","line":99}]`,
-			expectedFixed: true,
-		},
-		{
-			name:          "object candidates - key order normalized",
-			candidateKey:  `{"file":"foo.c","line":42}`,
-			sourceOutput:  `[{"line":42,"file":"foo.c"},{"file":"bar.c","line":10}]`,
-			expectedFixed: false,
-		},
-		{
-			name:          "array candidate present in array-of-arrays source",
-			candidateKey:  `["a","b"]`,
-			sourceOutput:  `[["b","z"],["a","b"]]`,
-			expectedFixed: false,
-		},
-		{
-			name:          "array candidate absent from array-of-arrays source",
-			candidateKey:  `["a","b"]`,
-			sourceOutput:  `[["b","z"],["c","d"]]`,
-			expectedFixed: true,
-		},
-		{
-			name:          "array candidate with whitespace - compaction normalizes",
-			candidateKey:  `["a","b"]`,
-			sourceOutput:  `[["b","z"], [ "a", "b" ]]`,
-			expectedFixed: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			candidates, err := ParseCandidates([]byte(tt.sourceOutput))
-			if err != nil {
-				t.Fatalf("ParseCandidates failed: %v", err)
-			}
-
-			candidates = FilterByPartition(candidates, NoFilter())
-
-			fixed := !containsKey(candidates, tt.candidateKey)
-			if fixed != tt.expectedFixed {
-				t.Errorf("containsKey result = %v (fixed), want %v. Candidate key: %q, Parsed candidates: %+v",
-					fixed, tt.expectedFixed, tt.candidateKey, candidates)
-			}
-		})
-	}
-}
-
-func TestCandidateVerificationWithPartition(t *testing.T) {
-	// Test that hash partitioning is applied consistently
-	// This verifies that partitioning works correctly across multiple workers
-	sourceOutput := `["candidate1", "candidate2", "candidate3", "candidate4", "candidate5"]`
-
-	candidates, err := ParseCandidates([]byte(sourceOutput))
-	if err != nil {
-		t.Fatalf("ParseCandidates failed: %v", err)
-	}
-
-	// Find a candidate that will be filtered out by partition 0
-	var filteredOutKey string
-	for _, c := range candidates {
-		partition0Filtered := FilterByPartition([]Candidate{c}, HashPartition{WorkerCount: 2, WorkerIndex: 0})
-		if len(partition0Filtered) == 0 {
-			filteredOutKey = c.Key
-			break
-		}
-	}
-
-	if filteredOutKey == "" {
-		t.Skip("Could not find a candidate that gets filtered by partition 0 (need more candidates)")
-	}
-
-	// With partition 0, the filtered-out candidate should appear "fixed" (not found)
-	partition0Candidates := FilterByPartition(candidates, HashPartition{WorkerCount: 2, WorkerIndex: 0})
-	fixedWithPartition0 := !containsKey(partition0Candidates, filteredOutKey)
-	if !fixedWithPartition0 {
-		t.Errorf("Candidate %q should be filtered out by partition 0", filteredOutKey)
-	}
-
-	// With partition 1, the same candidate should be present (not "fixed")
-	partition1Candidates := FilterByPartition(candidates, HashPartition{WorkerCount: 2, WorkerIndex: 1})
-	fixedWithPartition1 := !containsKey(partition1Candidates, filteredOutKey)
-	if fixedWithPartition1 {
-		t.Errorf("Candidate %q should be present in partition 1", filteredOutKey)
-	}
-
-	// With no filter (single worker), candidate should be present
-	noFilterCandidates := FilterByPartition(candidates, NoFilter())
-	fixedWithNoFilter := !containsKey(noFilterCandidates, filteredOutKey)
-	if fixedWithNoFilter {
-		t.Errorf("Candidate %q should be present with no filter", filteredOutKey)
-	}
-}
-
-func TestClaudeErrorCleanup(t *testing.T) {
-	// Test that Claude errors trigger cleanup via reset
-	// This verifies the fix for environment being left in bad state after Claude crashes
-	t.Run("error returns fatalError when reset fails", func(t *testing.T) {
-		// Mock a claude error scenario - we can't easily test the full flow,
-		// but we can verify the error type expectations
-		testErr := fmt.Errorf("simulated claude error")
-
-		// The key behavior: when Claude errors, we should attempt cleanup
-		// If cleanup fails, we should get a fatalError
-		// This is tested indirectly by verifying fatalError type exists and works correctly
-		fatalErr := &fatalError{msg: "failed to reset after claude error"}
-
-		if fatalErr.Error() != "failed to reset after claude error" {
-			t.Errorf("fatalError message incorrect: %v", fatalErr.Error())
-		}
-
-		// Verify it implements error interface
-		var _ error = fatalErr
-
-		// Verify error type can be detected
-		var _ *fatalError = fatalErr
-		if _, isFatal := testErr.(*fatalError); isFatal {
-			t.Error("Regular error should not be detected as fatalError")
-		}
-		// fatalErr is already *fatalError type, just verify it directly
-		if fatalErr == nil {
-			t.Error("fatalErr should not be nil")
-		}
-	})
-}
-
-func TestGetPromptMissingTemplateIsFatal(t *testing.T) {
-	// Create a minimal environment for testing
-	env := &Environment{
-		ProjectDir: "/tmp/test-project",
-		Config: Config{
-			ClaudeCommand: "claude",
-		},
-		Tasks: map[string]Task{
-			"test-task": {
-				Name:     "test-task",
-				Dir:      "/tmp/test-task",
-				Template: "nonexistent.md",
-			},
-		},
-	}
-
-	// Use DryRun to avoid creating a logger (which requires directories to exist)
-	runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
-	if err != nil {
-		t.Fatalf("NewRunner failed: %v", err)
-	}
-
-	candidate := &Candidate{Key: "test-candidate"}
-	_, err = runner.getPrompt(candidate)
-
-	// Error should be a fatalError
-	if _, isFatal := err.(*fatalError); !isFatal {
-		t.Errorf("getPrompt with missing template should return fatalError, got %T", err)
-	}
-}
-
-func TestHandleSuccess_CommitFailureIsFatal(t *testing.T) {
-	// Create a temp directory for testing
-	tmpDir := t.TempDir()
-	taskDir := filepath.Join(tmpDir, "test-task")
-	if err := os.Mkdir(taskDir, 0755); err != nil {
-		t.Fatalf("failed to create task dir: %v", err)
-	}
-
-	// Create a minimal environment for testing
-	env := &Environment{
-		ProjectDir: tmpDir,
-		Config: Config{
-			ClaudeCommand:  "claude",
-			SuccessCommand: "git commit -m $CANDIDATE",
-			VerifyCommand:  "true", // Build passes
-		},
-		Tasks: map[string]Task{
-			"test-task": {
-				Name:           "test-task",
-				Dir:            taskDir,
-				Prompt:         "test prompt",
-				AcceptBestEffort: false,
-			},
-		},
-	}
-
-	runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
-	if err != nil {
-		t.Fatalf("NewRunner failed: %v", err)
-	}
-
-	// Create a mock executor that simulates commit failure
-	mock := NewMockCommandExecutor()
-	// Mock that we have uncommitted changes
-	mock.SetHasChanges(true, nil)
-	// Mock the commit command to fail
-	mock.SetResult("git commit -m 'test-candidate'", false, nil)
-
-	runner.setExecutor(mock)
-
-	candidate := &Candidate{Key: "test-candidate"}
-
-	// Handle success should return fatalError when commit fails
-	_, err = runner.handleSuccess(candidate, true) // buildVerified = true
-
-	if err == nil {
-		t.Fatal("handleSuccess with commit failure should return an error")
-	}
-
-	if _, isFatal := err.(*fatalError); !isFatal {
-		t.Errorf("handleSuccess with commit failure should return fatalError, got %T: %v", err, err)
-	}
-
-	if err.Error() != "success command returned non-zero exit code" {
-		t.Errorf("unexpected error message: %v", err)
-	}
-}
-
-func TestHandleSuccess_CommitSuccess(t *testing.T) {
-	// Create a temp directory for testing
-	tmpDir := t.TempDir()
-	taskDir := filepath.Join(tmpDir, "test-task")
-	if err := os.Mkdir(taskDir, 0755); err != nil {
-		t.Fatalf("failed to create task dir: %v", err)
-	}
-
-	// Create a minimal environment for testing
-	env := &Environment{
-		ProjectDir: tmpDir,
-		Config: Config{
-			ClaudeCommand:  "claude",
-			SuccessCommand: "git commit -m $CANDIDATE",
-			VerifyCommand:  "true",
-		},
-		Tasks: map[string]Task{
-			"test-task": {
-				Name:   "test-task",
-				Dir:    taskDir,
-				Prompt: "test prompt",
-			},
-		},
-	}
-
-	runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
-	if err != nil {
-		t.Fatalf("NewRunner failed: %v", err)
-	}
-
-	// Create a mock executor that simulates success
-	mock := NewMockCommandExecutor()
-	// Mock that we have uncommitted changes
-	mock.SetHasChanges(true, nil)
-	// Mock success command to succeed
-	mock.SetResult("git commit -m 'test-candidate'", true, nil)
-
-	runner.setExecutor(mock)
-
-	candidate := &Candidate{Key: "test-candidate"}
-
-	// Handle success should succeed when commit succeeds
-	_, err = runner.handleSuccess(candidate, true) // buildVerified = true
-
-	if err != nil {
-		t.Errorf("handleSuccess with commit success should not return error, got: %v", err)
-	}
-}
-
-func TestHandleFailure_BestEffortCommitFailureIsFatal(t *testing.T) {
-	// Create a temp directory for testing
-	tmpDir := t.TempDir()
-	taskDir := filepath.Join(tmpDir, "test-task")
-	if err := os.Mkdir(taskDir, 0755); err != nil {
-		t.Fatalf("failed to create task dir: %v", err)
-	}
-
-	// Create a minimal environment for testing with best effort mode
-	env := &Environment{
-		ProjectDir: tmpDir,
-		Config: Config{
-			ClaudeCommand:  "claude",
-			SuccessCommand: "git commit -m $CANDIDATE",
-			VerifyCommand:  "true", // Build passes
-		},
-		Tasks: map[string]Task{
-			"test-task": {
-				Name:           "test-task",
-				Dir:            taskDir,
-				Prompt:         "test prompt",
-				AcceptBestEffort: true,
-			},
-		},
-	}
-
-	runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
-	if err != nil {
-		t.Fatalf("NewRunner failed: %v", err)
-	}
-
-	// Create a mock executor
-	mock := NewMockCommandExecutor()
-	// Mock verify command to succeed (best effort mode only commits if build passes)
-	mock.SetResult("true", true, nil)
-	// Mock that we have uncommitted changes
-	mock.SetHasChanges(true, nil)
-	// Mock commit to fail
-	mock.SetResult("git commit -m 'test-candidate'", false, nil)
-
-	runner.setExecutor(mock)
-
-	candidate := &Candidate{Key: "test-candidate"}
-
-	// Handle failure in best effort mode should return fatalError when commit fails
-	_, err = runner.handleFailure(candidate)
-
-	if err == nil {
-		t.Fatal("handleFailure with commit failure should return an error")
-	}
-
-	if _, isFatal := err.(*fatalError); !isFatal {
-		t.Errorf("handleFailure with commit failure should return fatalError, got %T: %v", err, err)
-	}
-
-	if err.Error() != "best effort commit returned non-zero exit code" {
-		t.Errorf("unexpected error message: %v", err)
-	}
-}
-
-func TestHandleTimeout_CommitFailureIsFatal(t *testing.T) {
-	// Create a temp directory for testing
-	tmpDir := t.TempDir()
-	taskDir := filepath.Join(tmpDir, "test-task")
-	if err := os.Mkdir(taskDir, 0755); err != nil {
-		t.Fatalf("failed to create task dir: %v", err)
-	}
-
-	// Create a minimal environment for testing with best effort mode
-	env := &Environment{
-		ProjectDir: tmpDir,
-		Config: Config{
-			ClaudeCommand:  "claude",
-			SuccessCommand: "git commit -m $CANDIDATE",
-			VerifyCommand:  "true", // Build passes
-		},
-		Tasks: map[string]Task{
-			"test-task": {
-				Name:           "test-task",
-				Dir:            taskDir,
-				Prompt:         "test prompt",
-				AcceptBestEffort: true,
-			},
-		},
-	}
-
-	runner, err := NewRunner(env, "test-task", RunnerOptions{DryRun: true})
-	if err != nil {
-		t.Fatalf("NewRunner failed: %v", err)
-	}
-
-	// Create a mock executor
-	mock := NewMockCommandExecutor()
-	// Mock verify command to succeed (best effort mode only commits if build passes)
-	mock.SetResult("true", true, nil)
-	// Mock that we have uncommitted changes
-	mock.SetHasChanges(true, nil)
-	// Mock commit to fail
-	mock.SetResult("git commit -m 'test-candidate'", false, nil)
-
-	runner.setExecutor(mock)
-
-	candidate := &Candidate{Key: "test-candidate"}
-
-	// Handle timeout in best effort mode should return fatalError when commit fails
-	_, err = runner.handleTimeout(candidate)
-
-	if err == nil {
-		t.Fatal("handleTimeout with commit failure should return an error")
-	}
-
-	if _, isFatal := err.(*fatalError); !isFatal {
-		t.Errorf("handleTimeout with commit failure should return fatalError, got %T: %v", err, err)
-	}
-
-	if err.Error() != "timeout commit returned non-zero exit code" {
-		t.Errorf("unexpected error message: %v", err)
-	}
-}
-
-func TestMockCommandExecutor(t *testing.T) {
-	// Test the mock executor itself
-	t.Run("records calls", func(t *testing.T) {
-		mock := NewMockCommandExecutor()
-
-		success, err := mock.Run("test command", "/tmp")
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
-		}
-		if !success {
-			t.Error("default should be success")
-		}
-
-		if len(mock.Calls) != 1 {
-			t.Errorf("expected 1 call, got %d", len(mock.Calls))
-		}
-
-		if mock.Calls[0].Command != "test command" {
-			t.Errorf("unexpected command: %v", mock.Calls[0].Command)
-		}
-
-		if mock.Calls[0].WorkDir != "/tmp" {
-			t.Errorf("unexpected workDir: %v", mock.Calls[0].WorkDir)
-		}
-	})
-
-	t.Run("returns configured results", func(t *testing.T) {
-		mock := NewMockCommandExecutor()
-		mock.SetResult("failing command", false, nil)
-
-		success, err := mock.Run("failing command", "/tmp")
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
-		}
-		if success {
-			t.Error("expected failure")
-		}
-	})
-
-	t.Run("call count", func(t *testing.T) {
-		mock := NewMockCommandExecutor()
-
-		mock.Run("test", "/tmp")
-		mock.Run("test", "/tmp")
-		mock.Run("other", "/tmp")
-
-		count := mock.CallCount("test")
-		if count != 2 {
-			t.Errorf("expected 2 calls, got %d", count)
-		}
-
-		if !mock.CalledWith("test") {
-			t.Error("expected CalledWith to return true")
-		}
-
-		if mock.CalledWith("not-called") {
-			t.Error("expected CalledWith to return false for uncalled command")
-		}
-	})
-
-	t.Run("clear calls", func(t *testing.T) {
-		mock := NewMockCommandExecutor()
-		mock.Run("test", "/tmp")
-
-		mock.ClearCalls()
-
-		if len(mock.Calls) != 0 {
-			t.Errorf("expected 0 calls after clear, got %d", len(mock.Calls))
-		}
-	})
-}
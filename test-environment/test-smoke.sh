@@ -63,7 +63,7 @@ run_test() {
     header "$name"
     echo -e "${GREEN}Expected: $expect${NC}"
     echo ""
-    eval "env $env_vars timeout $timeout_dur $NIGEL_BIN $task" || {
+    eval "env $env_vars timeout $timeout_dur $NIGEL_BIN $task --yes" || {
         local exit_code=$?
         if [[ $exit_code -eq 124 ]]; then
             echo -e "${RED}Test timed out after ${timeout_dur}s${NC}"
@@ -9,8 +9,8 @@ rm .fixed-item-*
 if [[ "$1" == "--inactivity-test" ]]; then
     # Simulate long delays to test the 30-second inactivity timer
     echo "Testing inactivity timer (will pause for 35s between messages)..."
-    MOCK_CLAUDE_INACTIVITY_TEST=1 nigel demo-task
+    MOCK_CLAUDE_INACTIVITY_TEST=1 nigel demo-task --yes
 else
     # Normal test run
-    nigel demo-task
+    nigel demo-task --yes
 fi